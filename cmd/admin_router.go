@@ -0,0 +1,62 @@
+package main
+
+import (
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/health"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/metrics"
+	"go-notification-sse/internal/interfaces/admin"
+	"go-notification-sse/internal/interfaces/middleware"
+	"go-notification-sse/internal/interfaces/rest/v1/handler"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InitAdminRouter builds the router served on the admin listener, which must not
+// be exposed publicly
+func InitAdminRouter(
+	hubInstance *hub.Hub,
+	registry *health.Registry,
+	auditor *audit.Recorder,
+	log logger.Logger,
+) http.Handler {
+	router := gin.New()
+	setTrustedProxies(router, log)
+	router.Use(middleware.AccessLog(log, "/metrics", "/livez", "/readyz"))
+	router.Use(middleware.ClientAddr())
+	router.Use(gin.Recovery())
+	router.Use(middleware.Metrics())
+
+	rootGroup := router.Group("")
+
+	// Detailed health reports
+	healthHandler := handler.NewHealthHandler(registry, true)
+	rootGroup.GET("/livez", healthHandler.Livez)
+	rootGroup.GET("/readyz", healthHandler.Readyz)
+	rootGroup.GET("/health", healthHandler.Details)
+
+	rootGroup.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Audit trail search; entries name actors and source IPs
+	auditHandler := handler.NewAuditHandler(auditor, log)
+	rootGroup.GET("/api/v1/audit", auditHandler.Query)
+
+	admin.InitAdminRouter(log, hubInstance, auditor, rootGroup)
+
+	// Runtime log level controls
+	if levels, ok := logger.LevelsOf(log); ok {
+		admin.InitLogLevelRouter(log, levels, auditor, rootGroup)
+	}
+
+	// Traffic tap, enabled when TAP_API_KEYS is set
+	if tapKeys := splitSecrets(os.Getenv("TAP_API_KEYS")); len(tapKeys) > 0 {
+		admin.InitTapRouter(log, hubInstance, auditor, rootGroup, middleware.RequireAPIKey(tapKeys))
+	} else {
+		log.Warn("TAP_API_KEYS not set, traffic tap disabled")
+	}
+
+	return router
+}
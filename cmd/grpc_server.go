@@ -0,0 +1,31 @@
+package main
+
+import (
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/ratelimit"
+	"go-notification-sse/internal/interfaces/grpcapi"
+	"os"
+
+	"google.golang.org/grpc"
+)
+
+// InitGRPCServer builds the gRPC gateway into the hub. Calls must present one of
+// GRPC_API_KEYS; the gateway does not start without them.
+func InitGRPCServer(
+	hubInstance *hub.Hub,
+	auditor *audit.Recorder,
+	log logger.Logger,
+) (*grpc.Server, error) {
+	apiKeys := splitSecrets(os.Getenv("GRPC_API_KEYS"))
+
+	gateway := grpcapi.NewServer(
+		hubInstance,
+		log,
+		auditor,
+		ratelimit.NewLimiter(ratelimit.NewDefaultConfig()),
+		10,
+	)
+	return grpcapi.NewGRPCServer(gateway, apiKeys)
+}
@@ -7,11 +7,16 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/errgroup"
 
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/health"
 	"go-notification-sse/internal/infrastructure/hub"
 	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/metrics"
 	"go-notification-sse/internal/infrastructure/server"
+	"go-notification-sse/internal/infrastructure/tracing"
 )
 
 func main() {
@@ -19,7 +24,32 @@ func main() {
 	sctx := WithSignal(ctx)
 
 	lCfg := logger.NewDefaultConfig()
-	log := logger.NewLogrusLogger(lCfg)
+	lCfg.Backend = getEnv("LOG_BACKEND", lCfg.Backend)
+	if getEnv("LOG_SAMPLING", "off") == "on" {
+		lCfg.Sampling = logger.NewDefaultSamplingConfig()
+	}
+	log := logger.New(lCfg)
+
+	tCfg := tracing.NewDefaultConfig()
+	if exporter := os.Getenv("TRACING_EXPORTER"); exporter != "" {
+		tCfg.Exporter = exporter
+	}
+	if filePath := os.Getenv("TRACING_FILE_PATH"); filePath != "" {
+		tCfg.FilePath = filePath
+	}
+	shutdownTracing, err := tracing.Setup(ctx, tCfg)
+	if err != nil {
+		log.Errorf("failed to set up tracing: %v", err)
+		return
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			log.Errorf("failed to shut down tracing: %v", err)
+		}
+	}()
+
 	hubInstance := hub.New(log)
 
 	// Start the hub first
@@ -32,29 +62,89 @@ func main() {
 		hubInstance.IsRunning(),
 	)
 
-	router := InitRouter(hubInstance, log)
-	httpSrv := server.NewHTTPServer(router)
-	app := newApplication(log, httpSrv, hubInstance)
+	auditSink := audit.NewFileSink(audit.NewDefaultConfig())
+	defer auditSink.Close()
+	auditor := audit.NewRecorder(auditSink, log)
+
+	registerHubMetrics(hubInstance)
+
+	registry := health.NewRegistry(2 * time.Second)
+
+	router := InitRouter(hubInstance, registry, auditor, log)
+	httpSrv := server.NewHTTPServer(getEnv("HTTP_ADDR", ":8080"), router)
+
+	// The admin listener is unauthenticated, so by default it only accepts local
+	// connections; set ADMIN_ADDR to expose it on a private network
+	adminRouter := InitAdminRouter(hubInstance, registry, auditor, log)
+	adminSrv := server.NewHTTPServer(getEnv("ADMIN_ADDR", "127.0.0.1:9090"), adminRouter)
+
+	registry.Register("hub", health.Liveness, hubInstance.LivenessCheck)
+	registry.Register("hub_ready", health.Readiness, hubInstance.ReadinessCheck)
+	registry.Register("http_server", health.Liveness|health.Readiness, httpSrv.HealthCheck)
+	registry.Register("admin_server", health.Liveness, adminSrv.HealthCheck)
+
+	app := newApplication(log, httpSrv, adminSrv, hubInstance, registry)
+
+	// gRPC gateway for internal services, enabled when GRPC_ADDR is set
+	if addr := os.Getenv("GRPC_ADDR"); addr != "" {
+		gateway, err := InitGRPCServer(hubInstance, auditor, log)
+		if err != nil {
+			log.Errorf("gRPC gateway disabled: %v (set GRPC_API_KEYS)", err)
+		} else {
+			grpcSrv := server.NewGRPCServer(addr, gateway)
+			registry.Register("grpc_server", health.Liveness|health.Readiness, grpcSrv.HealthCheck)
+			app.grpcSrv = grpcSrv
+		}
+	}
+
+	// Raw socket listeners for on-host agents, enabled by SOCKET_ADDR (TCP) and
+	// SOCKET_PATH (Unix domain socket)
+	listeners := map[string]string{"tcp": os.Getenv("SOCKET_ADDR"), "unix": os.Getenv("SOCKET_PATH")}
+	if listeners["tcp"] != "" || listeners["unix"] != "" {
+		socketServer := InitSocketServer(hubInstance, log)
+		for _, network := range []string{"tcp", "unix"} {
+			if addr := listeners[network]; addr != "" {
+				socketSrv := server.NewSocketServer(network, addr, socketServer.ServeConn)
+				registry.Register("socket_server_"+network, health.Liveness|health.Readiness, socketSrv.HealthCheck)
+				app.socketSrvs = append(app.socketSrvs, socketSrv)
+			}
+		}
+	}
+
+	if grace, err := time.ParseDuration(os.Getenv("DRAIN_GRACE_PERIOD")); err == nil {
+		app.drainGracePeriod = grace
+	}
 	if err := app.Run(sctx); err != nil {
 		log.Errorf("failed to run application: %v", err)
 	}
 }
 
 type Application struct {
-	logger  logger.Logger
-	httpSrv server.Server
-	hub     *hub.Hub
+	logger     logger.Logger
+	httpSrv    server.Server
+	adminSrv   server.Server
+	grpcSrv    server.Server   // optional
+	socketSrvs []server.Server // optional raw TCP and Unix socket listeners
+	hub        *hub.Hub
+	health     *health.Registry
+
+	// drainGracePeriod is how long readiness reports draining before shutdown
+	drainGracePeriod time.Duration
 }
 
 func newApplication(
 	logger logger.Logger,
 	httpSrv *server.HTTPServer,
+	adminSrv *server.HTTPServer,
 	hubInstance *hub.Hub,
+	registry *health.Registry,
 ) *Application {
 	return &Application{
-		logger:  logger.WithField("app", "sse"),
-		httpSrv: httpSrv,
-		hub:     hubInstance,
+		logger:   logger.WithField("app", "sse"),
+		httpSrv:  httpSrv,
+		adminSrv: adminSrv,
+		hub:      hubInstance,
+		health:   registry,
 	}
 }
 
@@ -65,9 +155,33 @@ func (app *Application) Run(ctx context.Context) error {
 		return app.httpSrv.Start(ctx)
 	})
 
+	eg.Go(func() error {
+		return app.adminSrv.Start(ctx)
+	})
+
+	if app.grpcSrv != nil {
+		eg.Go(func() error {
+			return app.grpcSrv.Start(ctx)
+		})
+	}
+
+	for _, socketSrv := range app.socketSrvs {
+		eg.Go(func() error {
+			return socketSrv.Start(ctx)
+		})
+	}
+
 	eg.Go(func() error {
 		<-ctx.Done()
 
+		// Fail readiness and refuse new connections before shutting down
+		app.health.StartDrain()
+		app.hub.Drain()
+		if app.drainGracePeriod > 0 {
+			app.logger.Infof("draining for %s before shutdown", app.drainGracePeriod)
+			time.Sleep(app.drainGracePeriod)
+		}
+
 		gracefulshutdownCtx, cancel := context.WithTimeout(
 			context.Background(),
 			time.Duration(5*time.Second),
@@ -79,6 +193,22 @@ func (app *Application) Run(ctx context.Context) error {
 			app.logger.Errorf("failed to stop hub: %v", err)
 		}
 
+		if err := app.adminSrv.Stop(gracefulshutdownCtx); err != nil {
+			app.logger.Errorf("failed to stop admin server: %v", err)
+		}
+
+		if app.grpcSrv != nil {
+			if err := app.grpcSrv.Stop(gracefulshutdownCtx); err != nil {
+				app.logger.Errorf("failed to stop grpc server: %v", err)
+			}
+		}
+
+		for _, socketSrv := range app.socketSrvs {
+			if err := socketSrv.Stop(gracefulshutdownCtx); err != nil {
+				app.logger.Errorf("failed to stop socket server: %v", err)
+			}
+		}
+
 		return app.httpSrv.Stop(gracefulshutdownCtx)
 	})
 
@@ -90,6 +220,26 @@ func (app *Application) Run(ctx context.Context) error {
 	return nil
 }
 
+// registerHubMetrics exposes the depth of the hub's internal channels
+func registerHubMetrics(hubInstance *hub.Hub) {
+	for queue := range hubInstance.QueueDepths() {
+		metrics.RegisterGaugeFunc(
+			"hub_queue_depth",
+			"Number of pending events in the hub's internal channels.",
+			prometheus.Labels{"queue": queue},
+			func() float64 { return float64(hubInstance.QueueDepths()[queue]) },
+		)
+	}
+}
+
+// getEnv returns the value of an environment variable or a fallback
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
 func WithSignal(pctx context.Context) context.Context {
 	ctx, cancel := context.WithCancel(pctx)
 
@@ -1,26 +1,49 @@
 package main
 
 import (
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/health"
 	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/idempotency"
+	"go-notification-sse/internal/infrastructure/identity"
 	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/ratelimit"
+	"go-notification-sse/internal/infrastructure/webhook"
+	"go-notification-sse/internal/infrastructure/webpush"
+	"go-notification-sse/internal/interfaces/longpoll"
+	"go-notification-sse/internal/interfaces/middleware"
+	"go-notification-sse/internal/interfaces/ndjson"
+	"go-notification-sse/internal/interfaces/publish"
 	"go-notification-sse/internal/interfaces/rest/v1/handler"
+	"go-notification-sse/internal/interfaces/socketio"
 	"go-notification-sse/internal/interfaces/sse"
 	"go-notification-sse/internal/interfaces/websocket"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func InitRouter(hubInstance *hub.Hub, log logger.Logger) http.Handler {
+func InitRouter(
+	hubInstance *hub.Hub,
+	registry *health.Registry,
+	auditor *audit.Recorder,
+	log logger.Logger,
+) http.Handler {
 	router := gin.New()
-	router.Use(gin.Logger())
+	setTrustedProxies(router, log)
+	router.Use(middleware.AccessLog(log, "/livez", "/readyz", "/hub/status"))
+	router.Use(middleware.ClientAddr())
 	router.Use(gin.Recovery())
+	router.Use(middleware.Metrics())
 
 	// CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, X-User-Token, X-Session-Token")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -30,6 +53,20 @@ func InitRouter(hubInstance *hub.Hub, log logger.Logger) http.Handler {
 		c.Next()
 	})
 
+	// Verified user identities, from user tokens signed with USER_TOKEN_SECRETS.
+	// Without them, connections have no user unless TRUST_CLAIMED_USER_IDS=true
+	// accepts the user IDs clients claim.
+	var tokens *identity.Tokens
+	if secrets := splitSecrets(os.Getenv("USER_TOKEN_SECRETS")); len(secrets) > 0 {
+		tokens = identity.NewTokens(secrets)
+		router.Use(middleware.Authenticate(tokens))
+	} else if os.Getenv("TRUST_CLAIMED_USER_IDS") == "true" {
+		router.Use(middleware.TrustClaimedUserIDs())
+		log.Warn("USER_TOKEN_SECRETS not set, trusting unverified user IDs")
+	} else {
+		log.Warn("USER_TOKEN_SECRETS not set, user-targeted delivery is disabled")
+	}
+
 	rootGroup := router.Group("")
 
 	// Simple debug endpoint
@@ -38,10 +75,15 @@ func InitRouter(hubInstance *hub.Hub, log logger.Logger) http.Handler {
 		c.JSON(http.StatusOK, gin.H{"debug": "working"})
 	})
 
-	// Health check endpoint
+	// Liveness and readiness probes
+	healthHandler := handler.NewHealthHandler(registry, false)
+	rootGroup.GET("/livez", healthHandler.Livez)
+	rootGroup.GET("/readyz", healthHandler.Readyz)
+
+	// Hub status endpoint
 	rootGroup.GET("/hub/status", func(c *gin.Context) {
 		isRunning := hubInstance.IsRunning()
-		log.Infof(
+		log.Debugf(
 			"Hub status check - Running: %v, Connections: %d",
 			isRunning,
 			hubInstance.ConnectionCount(),
@@ -53,15 +95,135 @@ func InitRouter(hubInstance *hub.Hub, log logger.Logger) http.Handler {
 		})
 	})
 
+	// Rate limiting for publish APIs and inbound WebSocket frames
+	publishLimiter := ratelimit.NewLimiter(ratelimit.NewDefaultConfig())
+	publishRateLimit := middleware.RateLimit(
+		publishLimiter,
+		middleware.KeyByFirst(middleware.KeyByAPIKey, middleware.KeyByUser, middleware.KeyByIP),
+		log,
+	)
+	inboundLimiter := ratelimit.NewLimiter(ratelimit.NewDefaultConfig())
+
+	// Publish endpoints continue the caller's trace and are rate limited
+	publishMiddleware := []gin.HandlerFunc{middleware.Tracing(), publishRateLimit}
+
 	// Chat API endpoints
-	chatHandler := handler.NewChatHandler(hubInstance, log)
+	chatHandler := handler.NewChatHandler(hubInstance, log, auditor)
 	apiGroup := rootGroup.Group("/api")
 	{
-		apiGroup.POST("/messages", chatHandler.SendMessage)
+		apiGroup.POST("/messages", append(publishMiddleware, chatHandler.SendMessage)...)
+	}
+
+	// Signed webhook ingestion, enabled when WEBHOOK_SECRETS is set
+	webhookCfg := webhook.NewDefaultConfig()
+	webhookCfg.Secrets = splitSecrets(os.Getenv("WEBHOOK_SECRETS"))
+	if len(webhookCfg.Secrets) > 0 {
+		webhookHandler := handler.NewWebhookHandler(
+			hubInstance,
+			log,
+			webhookCfg,
+			idempotency.NewMemoryStore(24*time.Hour),
+			auditor,
+		)
+		rootGroup.POST("/api/v1/webhooks/publish", append(publishMiddleware, webhookHandler.Publish)...)
+	} else {
+		log.Warn("WEBHOOK_SECRETS not set, webhook ingestion endpoint disabled")
+	}
+
+	// Outbound webhook subscriptions, managed with one of WEBHOOK_API_KEYS
+	if keys := splitSecrets(os.Getenv("WEBHOOK_API_KEYS")); len(keys) > 0 {
+		subscriptionHandler := handler.NewWebhookSubscriptionHandler(
+			hubInstance,
+			log,
+			auditor,
+			webhook.NewDefaultDeliveryConfig(),
+			nil,
+		)
+		subscriptionGroup := rootGroup.Group("/api/v1/webhooks/subscriptions", middleware.RequireAPIKey(keys))
+		subscriptionGroup.POST("", subscriptionHandler.Create)
+		subscriptionGroup.GET("", subscriptionHandler.List)
+		subscriptionGroup.GET("/:id", subscriptionHandler.Get)
+		subscriptionGroup.PUT("/:id", subscriptionHandler.Update)
+		subscriptionGroup.DELETE("/:id", subscriptionHandler.Delete)
+		subscriptionGroup.GET("/:id/deliveries", subscriptionHandler.Deliveries)
+		subscriptionGroup.GET("/:id/dead-letters", subscriptionHandler.DeadLetters)
+		subscriptionGroup.POST("/:id/dead-letters/redeliver", subscriptionHandler.Redeliver)
+		subscriptionGroup.DELETE("/:id/dead-letters", subscriptionHandler.PurgeDeadLetters)
+	} else {
+		log.Warn("WEBHOOK_API_KEYS not set, outbound webhook subscriptions disabled")
+	}
+
+	// Web Push to offline users' browsers, signed with VAPID_PRIVATE_KEY
+	if privateKey := os.Getenv("VAPID_PRIVATE_KEY"); privateKey != "" {
+		vapid, err := webpush.NewVAPID(privateKey, getEnv("VAPID_SUBJECT", ""))
+		if err != nil {
+			log.Errorf("Web Push disabled: %v", err)
+		} else {
+			pushCfg := webpush.NewDefaultConfig()
+			pushStore := webpush.NewStore(pushCfg.MaxSubscriptionsPerUser)
+			hubInstance.SetOfflineFallback(hub.NewWebPushFallback(
+				webpush.NewSender(vapid, pushCfg, nil),
+				pushStore,
+				log,
+			))
+
+			pushHandler := handler.NewWebPushHandler(pushStore, vapid, pushCfg.PushServices, log, auditor)
+			pushGroup := rootGroup.Group("/api/v1/push")
+			pushGroup.GET("/vapid-public-key", pushHandler.PublicKey)
+
+			// Subscriptions are managed by the user of a verified user token
+			if tokens == nil {
+				log.Warn("USER_TOKEN_SECRETS not set, Web Push subscriptions cannot be managed")
+			}
+			subscriptionGroup := pushGroup.Group("/subscriptions", middleware.RequireUser())
+			subscriptionGroup.POST("", pushHandler.Subscribe)
+			subscriptionGroup.GET("", pushHandler.List)
+			subscriptionGroup.DELETE("", pushHandler.Unsubscribe)
+		}
+	} else {
+		log.Warn("VAPID_PRIVATE_KEY not set, Web Push fallback disabled")
 	}
 
-	sse.InitSSERouter(log, hubInstance, rootGroup)
-	websocket.InitWebSocketRouter(log, hubInstance, rootGroup)
+	sse.InitSSERouter(log, hubInstance, auditor, rootGroup, publishMiddleware...)
+
+	// Topics clients may publish to over STOMP, MQTT and Socket.IO
+	publishTopics := splitSecrets(os.Getenv("CLIENT_PUBLISH_TOPICS"))
+	if len(publishTopics) == 0 {
+		log.Warn("CLIENT_PUBLISH_TOPICS not set, clients cannot publish over streaming protocols")
+	}
+	publisher := publish.NewPolicy(hubInstance, auditor, publishTopics)
+
+	websocket.InitWebSocketRouter(
+		log,
+		hubInstance,
+		publisher,
+		tokens,
+		rootGroup,
+		hub.WithInboundRateLimit(inboundLimiter, 10),
+	)
+	longpoll.InitLongPollRouter(log, hubInstance, rootGroup)
+	ndjson.InitStreamRouter(log, hubInstance, rootGroup)
+	socketio.InitSocketIORouter(log, hubInstance, publisher, rootGroup, socketio.NewDefaultConfig())
 
 	return router
 }
+
+// splitSecrets parses a comma-separated list of secrets
+func splitSecrets(value string) []string {
+	var secrets []string
+	for _, secret := range strings.Split(value, ",") {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+// setTrustedProxies makes gin honour forwarding headers only from the proxies in
+// TRUSTED_PROXIES (comma-separated IPs or CIDRs); by default none are trusted
+func setTrustedProxies(router *gin.Engine, log logger.Logger) {
+	if err := router.SetTrustedProxies(splitSecrets(os.Getenv("TRUSTED_PROXIES"))); err != nil {
+		log.Errorf("Invalid TRUSTED_PROXIES, trusting no proxies: %v", err)
+		router.SetTrustedProxies(nil)
+	}
+}
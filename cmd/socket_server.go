@@ -0,0 +1,27 @@
+package main
+
+import (
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/socketapi"
+	"os"
+)
+
+// InitSocketServer builds the raw socket protocol for on-host agents. Clients
+// must present one of SOCKET_API_KEYS when it is set; SOCKET_FRAMING selects
+// "lines" (the default) or "length" prefixed frames.
+func InitSocketServer(hubInstance *hub.Hub, log logger.Logger) *socketapi.Server {
+	cfg := socketapi.NewDefaultConfig()
+	cfg.APIKeys = splitSecrets(os.Getenv("SOCKET_API_KEYS"))
+	if len(cfg.APIKeys) == 0 {
+		log.Warn("SOCKET_API_KEYS not set, socket listeners accept unauthenticated clients")
+	}
+	switch framing := hub.SocketFraming(getEnv("SOCKET_FRAMING", string(hub.FramingLines))); framing {
+	case hub.FramingLines, hub.FramingLengthPrefixed:
+		cfg.Framing = framing
+	default:
+		log.Warnf("Unknown SOCKET_FRAMING %q, using line-delimited frames", framing)
+	}
+
+	return socketapi.NewServer(hubInstance, log, cfg)
+}
@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// Action identifies an audited operation
+type Action string
+
+const (
+	ActionBroadcast      Action = "broadcast"
+	ActionSendToConn     Action = "send_to_connection"
+	ActionChatMessage    Action = "chat_message"
+	ActionWebhookPublish Action = "webhook_publish"
+	ActionDisconnect     Action = "disconnect"
+	ActionSendToUser     Action = "send_to_user"
+	ActionPublishToTopic Action = "publish_to_topic"
+	ActionTap            Action = "tap"
+	ActionSetLogLevel    Action = "set_log_level"
+
+	ActionWebhookSubscribe   Action = "webhook_subscribe"
+	ActionWebhookUpdate      Action = "webhook_update"
+	ActionWebhookUnsubscribe Action = "webhook_unsubscribe"
+	ActionWebhookRedeliver   Action = "webhook_redeliver"
+
+	ActionPushSubscribe   Action = "push_subscribe"
+	ActionPushUnsubscribe Action = "push_unsubscribe"
+)
+
+// Outcome describes how an audited operation ended
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+	OutcomeDenied  Outcome = "denied"
+)
+
+// Entry is a single audit record
+type Entry struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Actor     string         `json:"actor"`
+	Action    Action         `json:"action"`
+	Target    string         `json:"target,omitempty"`
+	MessageID string         `json:"message_id,omitempty"`
+	Outcome   Outcome        `json:"outcome"`
+	SourceIP  string         `json:"source_ip,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+// Filter selects audit entries; zero values match everything
+type Filter struct {
+	From   time.Time
+	To     time.Time
+	Actor  string
+	Action Action
+	Limit  int
+}
+
+// Matches reports whether entry satisfies the filter
+func (f *Filter) Matches(entry *Entry) bool {
+	if !f.From.IsZero() && entry.Timestamp.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && entry.Timestamp.After(f.To) {
+		return false
+	}
+	if f.Actor != "" && entry.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && entry.Action != f.Action {
+		return false
+	}
+	return true
+}
+
+// AuditSink persists and searches audit entries
+type AuditSink interface {
+	Record(ctx context.Context, entry *Entry) error
+	Query(ctx context.Context, filter *Filter) ([]*Entry, error)
+	Close() error
+}
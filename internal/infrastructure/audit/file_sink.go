@@ -0,0 +1,235 @@
+package audit
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Config configures the rotating file sink
+type Config struct {
+	FilePath   string `json:"file_path"   yaml:"file_path"`
+	MaxSize    int    `json:"max_size"    yaml:"max_size"` // MB
+	MaxBackups int    `json:"max_backups" yaml:"max_backups"`
+	MaxAge     int    `json:"max_age"     yaml:"max_age"` // days
+	Compress   bool   `json:"compress"    yaml:"compress"`
+}
+
+// NewDefaultConfig returns a config writing to logs/audit.jsonl
+func NewDefaultConfig() *Config {
+	return &Config{
+		FilePath:   "logs/audit.jsonl",
+		MaxSize:    100,
+		MaxBackups: 10,
+		MaxAge:     90,
+		Compress:   true,
+	}
+}
+
+const (
+	// backupTimeFormat is how lumberjack names rotated files, in UTC
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+
+	// rotationSlack allows for entries timestamped shortly before they were
+	// written, which may land on either side of a rotation
+	rotationSlack = time.Minute
+)
+
+// FileSink writes audit entries as JSON lines to a rotating file
+type FileSink struct {
+	config Config
+	writer *lumberjack.Logger
+	mu     sync.Mutex
+}
+
+var _ AuditSink = (*FileSink)(nil)
+
+// NewFileSink creates a JSON-lines sink rotated by lumberjack
+func NewFileSink(config *Config) *FileSink {
+	return &FileSink{
+		config: *config,
+		writer: &lumberjack.Logger{
+			Filename:   config.FilePath,
+			MaxSize:    config.MaxSize,
+			MaxBackups: config.MaxBackups,
+			MaxAge:     config.MaxAge,
+			Compress:   config.Compress,
+		},
+	}
+}
+
+// Record appends an entry to the audit file
+func (s *FileSink) Record(ctx context.Context, entry *Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.writer.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Query scans the audit files newest first, returning up to filter.Limit
+// matching entries, newest first. Files whose entries all fall outside the
+// filter's time range are skipped, and the scan stops once the limit is reached,
+// so a query reads no more than it returns plus the files it has to search.
+func (s *FileSink) Query(ctx context.Context, filter *Filter) ([]*Entry, error) {
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !filter.From.IsZero() && !file.latest.IsZero() && file.latest.Before(filter.From) {
+			break // older files are older still
+		}
+		if !filter.To.IsZero() && file.earliest.After(filter.To) {
+			continue
+		}
+
+		limit := 0
+		if filter.Limit > 0 {
+			limit = filter.Limit - len(entries)
+		}
+		matched, err := scanFile(file.path, filter, limit)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, matched...)
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+	}
+
+	// Entries recorded around a rotation may straddle files
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+	return entries, nil
+}
+
+// Close closes the underlying file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writer.Close()
+}
+
+// auditFile is an audit file and the time range of its entries; zero bounds
+// are unknown
+type auditFile struct {
+	path     string
+	earliest time.Time
+	latest   time.Time
+}
+
+// files lists the active audit file followed by its rotated backups, newest
+// first. Backups are named after the time they were rotated, which bounds
+// their entries and those of the next newer file.
+func (s *FileSink) files() ([]auditFile, error) {
+	ext := filepath.Ext(s.config.FilePath)
+	prefix := strings.TrimSuffix(s.config.FilePath, ext) + "-"
+
+	paths, err := filepath.Glob(prefix + "*" + ext + "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit files: %w", err)
+	}
+
+	backups := make([]auditFile, 0, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(path, prefix), ".gz"), ext)
+		backup := auditFile{path: path}
+		if rotated, err := time.Parse(backupTimeFormat, name); err == nil {
+			backup.latest = rotated.Add(rotationSlack)
+		}
+		backups = append(backups, backup)
+	}
+	// Newest first; backups with unparsable names, never skipped, go last
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].latest.After(backups[j].latest)
+	})
+
+	files := make([]auditFile, 0, len(backups)+1)
+	if _, err := os.Stat(s.config.FilePath); err == nil {
+		files = append(files, auditFile{path: s.config.FilePath})
+	}
+	files = append(files, backups...)
+	for i := range files[:max(len(files)-1, 0)] {
+		if older := files[i+1].latest; !older.IsZero() {
+			files[i].earliest = older.Add(-2 * rotationSlack)
+		}
+	}
+	return files, nil
+}
+
+// scanFile streams a plain or gzip-compressed JSON-lines file, returning the
+// newest limit matching entries (all of them for 0), newest first
+func scanFile(path string, filter *Filter, limit int) ([]*Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer f.Close()
+
+	var reader io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read compressed audit file: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var entries []*Entry
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // skip partially written lines
+		}
+		if !filter.Matches(&entry) {
+			continue
+		}
+		if limit > 0 && len(entries) == limit {
+			entries = append(entries[1:], &entry)
+		} else {
+			entries = append(entries, &entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan audit file: %w", err)
+	}
+	slices.Reverse(entries)
+	return entries, nil
+}
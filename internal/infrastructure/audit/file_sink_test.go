@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSink_RecordAndQuery(t *testing.T) {
+	config := NewDefaultConfig()
+	config.FilePath = filepath.Join(t.TempDir(), "audit.jsonl")
+
+	sink := NewFileSink(config)
+	defer sink.Close()
+
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := []*Entry{
+		{Timestamp: base, Actor: "user:alice", Action: ActionBroadcast, Outcome: OutcomeSuccess},
+		{Timestamp: base.Add(time.Minute), Actor: "user:bob", Action: ActionSendToConn, Target: "conn-1", Outcome: OutcomeFailure},
+		{Timestamp: base.Add(2 * time.Minute), Actor: "user:alice", Action: ActionSendToConn, Target: "conn-2", Outcome: OutcomeSuccess},
+	}
+	for _, entry := range entries {
+		if err := sink.Record(ctx, entry); err != nil {
+			t.Fatalf("Failed to record entry: %v", err)
+		}
+	}
+
+	results, err := sink.Query(ctx, &Filter{Actor: "user:alice"})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 entries for alice, got %d", len(results))
+	}
+	if results[0].Target != "conn-2" {
+		t.Errorf("Expected newest entry first, got target %q", results[0].Target)
+	}
+
+	results, err = sink.Query(ctx, &Filter{From: base.Add(30 * time.Second), To: base.Add(90 * time.Second)})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if len(results) != 1 || results[0].Actor != "user:bob" {
+		t.Errorf("Expected only bob's entry in time range, got %v", results)
+	}
+
+	results, err = sink.Query(ctx, &Filter{Limit: 1})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Expected limit to cap results at 1, got %d", len(results))
+	}
+}
+
+// writeBackup writes a rotated audit file named after its rotation time, gzipped
+// when the name ends in .gz
+func writeBackup(t *testing.T, dir, name string, entries ...*Entry) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, _ := json.Marshal(entry)
+		buf.Write(append(line, '\n'))
+	}
+	data := buf.Bytes()
+	if filepath.Ext(name) == ".gz" {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		gz.Write(data)
+		gz.Close()
+		data = compressed.Bytes()
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func TestFileSink_QueryReadsOnlyTheFilesItNeeds(t *testing.T) {
+	dir := t.TempDir()
+	config := NewDefaultConfig()
+	config.FilePath = filepath.Join(dir, "audit.jsonl")
+	// Keep lumberjack from removing or compressing the old backups written below
+	config.MaxAge = 0
+	config.Compress = false
+
+	sink := NewFileSink(config)
+	defer sink.Close()
+
+	ctx := context.Background()
+	june := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, target := range []string{"active-1", "active-2"} {
+		sink.Record(ctx, &Entry{Timestamp: june.Add(24 * time.Hour), Action: ActionBroadcast, Target: target})
+	}
+	writeBackup(t, dir, "audit-2025-06-01T00-00-00.000.jsonl",
+		&Entry{Timestamp: june.Add(-time.Hour), Action: ActionBroadcast, Target: "june"})
+	// Opening this backup fails the query, so it must be skipped
+	if err := os.WriteFile(filepath.Join(dir, "audit-2025-01-01T00-00-00.000.jsonl.gz"), []byte("corrupt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	targets := func(filter *Filter) []string {
+		t.Helper()
+		results, err := sink.Query(ctx, filter)
+		if err != nil {
+			t.Fatalf("Query(%+v) read a file it should have skipped: %v", filter, err)
+		}
+		var targets []string
+		for _, entry := range results {
+			targets = append(targets, entry.Target)
+		}
+		return targets
+	}
+
+	if got := targets(&Filter{Limit: 2}); len(got) != 2 || got[0] != "active-2" {
+		t.Errorf("Expected the newest entries of the active file, got %v", got)
+	}
+	if got := targets(&Filter{Limit: 3}); len(got) != 3 || got[2] != "june" {
+		t.Errorf("Expected the active file and the newest backup, got %v", got)
+	}
+	if got := targets(&Filter{From: june.Add(-2 * time.Hour)}); len(got) != 3 {
+		t.Errorf("Expected the entries since the start of the range, got %v", got)
+	}
+}
+
+func TestFileSink_QuerySkipsFilesAfterTheRange(t *testing.T) {
+	dir := t.TempDir()
+	config := NewDefaultConfig()
+	config.FilePath = filepath.Join(dir, "audit.jsonl")
+	// Keep lumberjack from removing or compressing the old backups written below
+	config.MaxAge = 0
+	config.Compress = false
+
+	sink := NewFileSink(config)
+	defer sink.Close()
+
+	january := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeBackup(t, dir, "audit-2025-01-01T00-00-00.000.jsonl.gz",
+		&Entry{Timestamp: january.Add(-time.Hour), Action: ActionBroadcast, Target: "december"})
+	if err := os.WriteFile(filepath.Join(dir, "audit-2025-03-01T00-00-00.000.jsonl.gz"), []byte("corrupt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := sink.Query(context.Background(), &Filter{To: january.Add(-2 * time.Minute)})
+	if err != nil {
+		t.Fatalf("Expected the newer backup to be skipped, got %v", err)
+	}
+	if len(results) != 1 || results[0].Target != "december" {
+		t.Errorf("Expected the entry from the oldest backup, got %v", results)
+	}
+}
@@ -0,0 +1,34 @@
+package audit
+
+import (
+	"context"
+
+	"go-notification-sse/internal/infrastructure/logger"
+)
+
+// Recorder writes entries to a sink on behalf of handlers, logging failures
+// instead of returning them so auditing never fails the audited request
+type Recorder struct {
+	sink   AuditSink
+	logger logger.Logger
+}
+
+// NewRecorder creates a recorder backed by sink
+func NewRecorder(sink AuditSink, logger logger.Logger) *Recorder {
+	return &Recorder{
+		sink:   sink,
+		logger: logger.WithField("component", "audit"),
+	}
+}
+
+// Record persists an entry
+func (r *Recorder) Record(ctx context.Context, entry *Entry) {
+	if err := r.sink.Record(ctx, entry); err != nil {
+		r.logger.Errorf("Failed to record audit entry %s by %s: %v", entry.Action, entry.Actor, err)
+	}
+}
+
+// Query searches recorded entries
+func (r *Recorder) Query(ctx context.Context, filter *Filter) ([]*Entry, error) {
+	return r.sink.Query(ctx, filter)
+}
@@ -1 +1,133 @@
 package eventbus
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event is a message published on the bus
+type Event struct {
+	Topic     string    `json:"topic"`
+	Timestamp time.Time `json:"timestamp"`
+	Payload   any       `json:"payload"`
+}
+
+// Subscription receives events published on a bus. Slow subscribers never block
+// publishers; events that do not fit in the buffer are dropped and counted.
+type Subscription struct {
+	id      uint64
+	topics  map[string]struct{}
+	events  chan Event
+	dropped atomic.Int64
+
+	bus       *EventBus
+	closeOnce sync.Once
+}
+
+// Events returns the channel events are delivered on; it is closed by Close
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events dropped because the buffer was full
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes and closes the events channel
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		s.bus.unsubscribe(s.id)
+	})
+}
+
+func (s *Subscription) wants(topic string) bool {
+	if len(s.topics) == 0 {
+		return true
+	}
+	_, ok := s.topics[topic]
+	return ok
+}
+
+// EventBus is an in-process publish/subscribe bus
+type EventBus struct {
+	subs   map[uint64]*Subscription
+	subsMu sync.RWMutex
+	nextID uint64
+
+	subscribers atomic.Int32
+}
+
+// New creates an empty event bus
+func New() *EventBus {
+	return &EventBus{
+		subs: make(map[uint64]*Subscription),
+	}
+}
+
+// Subscribe registers a subscriber for topics (all topics when none are given)
+// with a buffer of the given size
+func (b *EventBus) Subscribe(buffer int, topics ...string) *Subscription {
+	sub := &Subscription{
+		topics: make(map[string]struct{}, len(topics)),
+		events: make(chan Event, buffer),
+		bus:    b,
+	}
+	for _, topic := range topics {
+		sub.topics[topic] = struct{}{}
+	}
+
+	b.subsMu.Lock()
+	b.nextID++
+	sub.id = b.nextID
+	b.subs[sub.id] = sub
+	b.subsMu.Unlock()
+
+	b.subscribers.Add(1)
+	return sub
+}
+
+// HasSubscribers reports whether anyone is listening, letting publishers skip
+// building expensive payloads
+func (b *EventBus) HasSubscribers() bool {
+	return b.subscribers.Load() > 0
+}
+
+// Publish delivers an event to every interested subscriber without blocking
+func (b *EventBus) Publish(topic string, payload any) {
+	if !b.HasSubscribers() {
+		return
+	}
+
+	event := Event{
+		Topic:     topic,
+		Timestamp: time.Now().UTC(),
+		Payload:   payload,
+	}
+
+	b.subsMu.RLock()
+	defer b.subsMu.RUnlock()
+
+	for _, sub := range b.subs {
+		if !sub.wants(topic) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+func (b *EventBus) unsubscribe(id uint64) {
+	b.subsMu.Lock()
+	defer b.subsMu.Unlock()
+
+	if sub, exists := b.subs[id]; exists {
+		delete(b.subs, id)
+		close(sub.events)
+		b.subscribers.Add(-1)
+	}
+}
@@ -0,0 +1,48 @@
+package eventbus
+
+import "testing"
+
+func TestEventBus_TopicFiltering(t *testing.T) {
+	bus := New()
+	all := bus.Subscribe(10)
+	defer all.Close()
+	only := bus.Subscribe(10, "b")
+	defer only.Close()
+
+	bus.Publish("a", 1)
+	bus.Publish("b", 2)
+
+	if got := len(all.Events()); got != 2 {
+		t.Errorf("Expected 2 events for wildcard subscriber, got %d", got)
+	}
+	if got := len(only.Events()); got != 1 {
+		t.Fatalf("Expected 1 event for topic subscriber, got %d", got)
+	}
+	if event := <-only.Events(); event.Topic != "b" || event.Payload != 2 {
+		t.Errorf("Unexpected event %+v", event)
+	}
+}
+
+func TestEventBus_SlowSubscriberDropsEvents(t *testing.T) {
+	bus := New()
+	sub := bus.Subscribe(1)
+
+	bus.Publish("a", 1)
+	bus.Publish("a", 2)
+
+	if sub.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped event, got %d", sub.Dropped())
+	}
+
+	sub.Close()
+	sub.Close()
+	if bus.HasSubscribers() {
+		t.Error("Expected no subscribers after Close")
+	}
+	if _, ok := <-sub.Events(); !ok {
+		t.Error("Expected buffered event to survive Close")
+	}
+	if _, ok := <-sub.Events(); ok {
+		t.Error("Expected events channel to be closed")
+	}
+}
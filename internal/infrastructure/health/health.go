@@ -0,0 +1,176 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kind controls which probes a check participates in
+type Kind int
+
+const (
+	// Liveness checks fail only when the process must be restarted
+	Liveness Kind = 1 << iota
+	// Readiness checks fail when the process should not receive traffic
+	Readiness
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// ErrDraining is reported by readiness while the application is draining
+var ErrDraining = errors.New("application is draining")
+
+// Check reports the health of a dependency; a nil error means healthy
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of a single check
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report aggregates check results for a probe
+type Report struct {
+	Status    string                 `json:"status"`
+	Timestamp time.Time              `json:"timestamp"`
+	Checks    map[string]CheckResult `json:"checks"`
+}
+
+// Healthy reports whether every check passed
+func (r *Report) Healthy() bool {
+	return r.Status == StatusUp
+}
+
+type registration struct {
+	name  string
+	kind  Kind
+	check Check
+}
+
+// Registry holds the health checks registered by the hub, servers, stores and
+// other components
+type Registry struct {
+	checks   map[string]registration
+	checksMu sync.RWMutex
+
+	draining atomic.Bool
+	timeout  time.Duration
+}
+
+// NewRegistry creates a registry running each check with the given timeout
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		checks:  make(map[string]registration),
+		timeout: timeout,
+	}
+}
+
+// Register adds or replaces a named check
+func (r *Registry) Register(name string, kind Kind, check Check) {
+	r.checksMu.Lock()
+	defer r.checksMu.Unlock()
+
+	r.checks[name] = registration{name: name, kind: kind, check: check}
+}
+
+// Unregister removes a named check
+func (r *Registry) Unregister(name string) {
+	r.checksMu.Lock()
+	defer r.checksMu.Unlock()
+
+	delete(r.checks, name)
+}
+
+// StartDrain makes readiness fail so load balancers stop routing new traffic
+func (r *Registry) StartDrain() {
+	r.draining.Store(true)
+}
+
+// IsDraining reports whether the application is draining
+func (r *Registry) IsDraining() bool {
+	return r.draining.Load()
+}
+
+// Live runs the liveness checks
+func (r *Registry) Live(ctx context.Context) *Report {
+	return r.run(ctx, Liveness)
+}
+
+// Ready runs the readiness checks, failing while draining
+func (r *Registry) Ready(ctx context.Context) *Report {
+	report := r.run(ctx, Readiness)
+	if r.IsDraining() {
+		report.Status = StatusDown
+		report.Checks["drain"] = CheckResult{Status: StatusDown, Error: ErrDraining.Error(), Duration: "0s"}
+	}
+	return report
+}
+
+// run executes all checks of kind concurrently
+func (r *Registry) run(ctx context.Context, kind Kind) *Report {
+	r.checksMu.RLock()
+	selected := make([]registration, 0, len(r.checks))
+	for _, reg := range r.checks {
+		if reg.kind&kind != 0 {
+			selected = append(selected, reg)
+		}
+	}
+	r.checksMu.RUnlock()
+
+	sort.Slice(selected, func(i, j int) bool { return selected[i].name < selected[j].name })
+
+	results := make([]CheckResult, len(selected))
+	var wg sync.WaitGroup
+	for i, reg := range selected {
+		wg.Add(1)
+		go func(i int, reg registration) {
+			defer wg.Done()
+			results[i] = r.runCheck(ctx, reg.check)
+		}(i, reg)
+	}
+	wg.Wait()
+
+	report := &Report{
+		Status:    StatusUp,
+		Timestamp: time.Now().UTC(),
+		Checks:    make(map[string]CheckResult, len(selected)),
+	}
+	for i, reg := range selected {
+		report.Checks[reg.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (r *Registry) runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: StatusUp, Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
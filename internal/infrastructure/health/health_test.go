@@ -0,0 +1,68 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Probes(t *testing.T) {
+	registry := NewRegistry(50 * time.Millisecond)
+	ctx := context.Background()
+
+	registry.Register("hub", Liveness|Readiness, func(ctx context.Context) error { return nil })
+	registry.Register("store", Readiness, func(ctx context.Context) error { return errors.New("connection refused") })
+
+	live := registry.Live(ctx)
+	if !live.Healthy() {
+		t.Errorf("Liveness should ignore readiness-only checks, got %+v", live)
+	}
+	if _, exists := live.Checks["store"]; exists {
+		t.Error("Liveness report should not include readiness-only checks")
+	}
+
+	ready := registry.Ready(ctx)
+	if ready.Healthy() {
+		t.Error("Readiness should fail when a readiness check fails")
+	}
+	if ready.Checks["store"].Error != "connection refused" {
+		t.Errorf("Expected store error in report, got %+v", ready.Checks["store"])
+	}
+
+	registry.Unregister("store")
+	if !registry.Ready(ctx).Healthy() {
+		t.Error("Readiness should pass after removing the failing check")
+	}
+}
+
+func TestRegistry_DrainingFailsReadiness(t *testing.T) {
+	registry := NewRegistry(50 * time.Millisecond)
+	ctx := context.Background()
+
+	registry.StartDrain()
+
+	if registry.Ready(ctx).Healthy() {
+		t.Error("Readiness should fail while draining")
+	}
+	if !registry.Live(ctx).Healthy() {
+		t.Error("Liveness should not be affected by draining")
+	}
+}
+
+func TestRegistry_CheckTimeout(t *testing.T) {
+	registry := NewRegistry(20 * time.Millisecond)
+	registry.Register("slow", Readiness, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := registry.Ready(context.Background())
+	if report.Healthy() {
+		t.Error("Slow check should fail with a timeout")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("Probe should not wait for a check beyond its timeout")
+	}
+}
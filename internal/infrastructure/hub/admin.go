@@ -0,0 +1,93 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// flushTimeout bounds how long a disconnect waits for the final message to be written
+const flushTimeout = time.Second
+
+// DisconnectOptions controls the final message sent before a forced disconnect
+type DisconnectOptions struct {
+	Reason     string
+	RetryAfter time.Duration // hint for clients not to reconnect before this elapses
+}
+
+// Disconnect forcibly closes a single connection
+func (h *Hub) Disconnect(ctx context.Context, connID string, opts DisconnectOptions) error {
+	conn, exists := h.GetConnection(connID)
+	if !exists {
+		return fmt.Errorf("connection %s not found", connID)
+	}
+
+	h.disconnect(ctx, conn, opts)
+	return nil
+}
+
+// DisconnectUser closes every connection of a user and returns how many were closed
+func (h *Hub) DisconnectUser(ctx context.Context, userID string, opts DisconnectOptions) int {
+	return h.disconnectAll(ctx, h.GetConnectionsByUser(userID), opts)
+}
+
+// DisconnectMatching closes every connection matching filter and returns how many
+// were closed
+func (h *Hub) DisconnectMatching(ctx context.Context, filter *ConnectionFilter, opts DisconnectOptions) int {
+	infos := h.FindConnections(filter)
+
+	connIDs := make([]string, len(infos))
+	for i, info := range infos {
+		connIDs[i] = info.ID
+	}
+	return h.disconnectAll(ctx, h.resolve(connIDs), opts)
+}
+
+func (h *Hub) disconnectAll(ctx context.Context, connections []Connection, opts DisconnectOptions) int {
+	var wg sync.WaitGroup
+	for _, conn := range connections {
+		wg.Add(1)
+		go func(c Connection) {
+			defer wg.Done()
+			h.disconnect(ctx, c, opts)
+		}(conn)
+	}
+	wg.Wait()
+
+	return len(connections)
+}
+
+// disconnect sends the optional final message, waits for it to be flushed and
+// removes the connection from the hub
+func (h *Hub) disconnect(ctx context.Context, conn Connection, opts DisconnectOptions) {
+	if opts.Reason != "" || opts.RetryAfter > 0 {
+		sendCtx, cancel := context.WithTimeout(ctx, flushTimeout)
+		if err := conn.Send(sendCtx, DisconnectMessage(opts.Reason, opts.RetryAfter)); err != nil {
+			h.logger.WithContext(conn.Context()).Warnf("Failed to send disconnect message to connection %s: %v", conn.ID(), err)
+		} else {
+			h.awaitFlush(sendCtx, conn)
+		}
+		cancel()
+	}
+
+	if err := h.UnregisterConnection(conn.ID()); err != nil {
+		conn.Close()
+	}
+
+	h.logger.WithContext(conn.Context()).Infof("Connection %s disconnected by admin (reason: %s)", conn.ID(), opts.Reason)
+}
+
+// awaitFlush waits until the connection's outbound queue is empty
+func (h *Hub) awaitFlush(ctx context.Context, conn Connection) {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for conn.Info().QueueDepth > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/metrics"
+	"go-notification-sse/internal/infrastructure/ratelimit"
 
 	"github.com/gorilla/websocket"
 )
@@ -30,6 +33,8 @@ type SSEConnection struct {
 	// Keep-alive mechanism
 	lastActivity time.Time
 	activityMu   sync.RWMutex
+
+	stats *connStats
 }
 
 // NewSSEConnection creates a new SSE connection
@@ -48,8 +53,9 @@ func NewSSEConnection(
 		request:      r,
 		ctx:          rctx,
 		cancel:       cancel,
-		logger:       logger.WithField("connection_id", id),
+		logger:       logger.WithField("connection_id", id).WithContext(rctx),
 		lastActivity: time.Now(),
+		stats:        newConnStats("sse", r),
 	}
 
 	// Set up proper SSE headers
@@ -74,14 +80,17 @@ func (c *SSEConnection) Type() string {
 // Send sends a message to this connection via SSE
 func (c *SSEConnection) Send(ctx context.Context, message *Message) error {
 	if c.IsClosed() {
+		c.stats.recordDropped(message)
 		return fmt.Errorf("client is closed")
 	}
 
 	c.updateActivity()
+	start := time.Now()
 
 	// Create SSE formatted message
 	sseMessage, err := c.formatSSEMessage(message)
 	if err != nil {
+		c.stats.recordFailed(message)
 		return fmt.Errorf("failed to format SSE message: %w", err)
 	}
 
@@ -105,17 +114,21 @@ func (c *SSEConnection) Send(ctx context.Context, message *Message) error {
 	select {
 	case err := <-done:
 		if err != nil {
+			c.stats.recordFailed(message)
 			c.logger.Errorf("Failed to write message: %v", err)
 			c.Close()
 			return err
 		}
+		c.stats.recordSent(message, len(sseMessage), start)
 		return nil
 
 	case <-ctx.Done():
+		c.stats.recordFailed(message)
 		c.logger.Warn("Send operation cancelled")
 		return ctx.Err()
 
 	case <-time.After(10 * time.Second):
+		c.stats.recordFailed(message)
 		c.logger.Warn("Send operation timed out")
 		c.Close()
 		return fmt.Errorf("send timeout")
@@ -150,6 +163,15 @@ func (c *SSEConnection) Context() context.Context {
 	return c.ctx
 }
 
+// Info returns a snapshot of the connection's metadata and statistics
+func (c *SSEConnection) Info() ConnectionInfo {
+	c.activityMu.RLock()
+	lastActivity := c.lastActivity
+	c.activityMu.RUnlock()
+
+	return c.stats.info(c.id, lastActivity, 0, c.IsClosed())
+}
+
 // setupSSEHeaders sets up the proper headers for SSE connection
 func (c *SSEConnection) setupSSEHeaders() {
 	c.writer.Header().Set("Content-Type", "text/event-stream")
@@ -234,7 +256,9 @@ func (c *SSEConnection) keepAlive() {
 				},
 			}
 
-			if err := c.Send(context.Background(), keepAliveMsg); err != nil {
+			err := c.Send(context.Background(), keepAliveMsg)
+			c.stats.recordKeepAlive(err)
+			if err != nil {
 				c.logger.Errorf("Failed to send keep-alive: %v", err)
 				c.Close()
 				return
@@ -290,10 +314,20 @@ type WebSocketConnection struct {
 	closed   bool
 	closedMu sync.RWMutex
 
+	// Close frame written by the write pump once the connection is closed
+	closeCode   int
+	closeReason string
+
 	logger logger.Logger
 
-	// Message sending channel
-	send chan *Message
+	// Message sending channel and the number of messages waiting in it;
+	// writerDone is closed once the write pump has exited
+	send       chan wsOutbound
+	queued     atomic.Int64
+	writerDone chan struct{}
+
+	// Negotiated subprotocol, nil for the default JSON messages
+	protocol WebSocketProtocol
 
 	// Keep-alive mechanism
 	lastActivity time.Time
@@ -304,6 +338,41 @@ type WebSocketConnection struct {
 
 	// Pong timeout for connection health
 	pongTimeout time.Duration
+
+	// Inbound frame rate limiting; violations are counted per violationWindow
+	inboundLimiter  *ratelimit.Limiter
+	maxViolations   int
+	violations      int
+	violationsSince time.Time
+
+	stats *connStats
+}
+
+// WebSocketOption configures optional WebSocketConnection behaviour
+type WebSocketOption func(*WebSocketConnection)
+
+// WithRequest records client metadata (remote address, user agent) from the
+// upgrade request and carries its request and user IDs into the connection's logs
+func WithRequest(r *http.Request) WebSocketOption {
+	return func(c *WebSocketConnection) {
+		c.stats = newConnStats(c.Type(), r)
+		c.ctx = logger.ContextWithConnectionID(logger.ContextWithFieldsFrom(c.ctx, r.Context()), c.id)
+		c.logger = c.logger.WithContext(c.ctx)
+	}
+}
+
+// violationWindow is the period over which inbound rate limit violations count
+// towards maxViolations, so occasional bursts never add up to a disconnect
+const violationWindow = time.Minute
+
+// WithInboundRateLimit limits inbound frames using a limiter keyed by connection ID.
+// After maxViolations rejected frames within violationWindow the connection is
+// closed (0 never disconnects).
+func WithInboundRateLimit(limiter *ratelimit.Limiter, maxViolations int) WebSocketOption {
+	return func(c *WebSocketConnection) {
+		c.inboundLimiter = limiter
+		c.maxViolations = maxViolations
+	}
 }
 
 // NewWebSocketConnection creates a new WebSocket connection
@@ -311,6 +380,7 @@ func NewWebSocketConnection(
 	id string,
 	conn *websocket.Conn,
 	logger logger.Logger,
+	opts ...WebSocketOption,
 ) *WebSocketConnection {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -320,15 +390,28 @@ func NewWebSocketConnection(
 		ctx:          ctx,
 		cancel:       cancel,
 		logger:       logger.WithField("connection_id", id),
-		send:         make(chan *Message, 256),
+		send:         make(chan wsOutbound, 256),
+		writerDone:   make(chan struct{}),
 		lastActivity: time.Now(),
 		writeTimeout: 10 * time.Second,
 		pongTimeout:  60 * time.Second,
+		stats:        newConnStats("websocket", nil),
+	}
+	if addr := conn.RemoteAddr(); addr != nil {
+		wsConn.stats.remoteAddr = addr.String()
+	}
+
+	for _, opt := range opts {
+		opt(wsConn)
 	}
 
 	// Set up WebSocket connection settings
 	wsConn.setupWebSocket()
 
+	if wsConn.protocol != nil {
+		wsConn.protocol.Open(wsConn)
+	}
+
 	// Start background routines
 	go wsConn.writePump()
 	go wsConn.readPump()
@@ -348,45 +431,84 @@ func (c *WebSocketConnection) Type() string {
 
 // Send sends a message to this WebSocket connection
 func (c *WebSocketConnection) Send(ctx context.Context, message *Message) error {
+	outbound := OutboundMessage{Message: message}
+	outbound.Cursor, _ = CursorFromContext(ctx)
+	outbound.Target, _ = TargetFromContext(ctx)
+
+	if err := c.enqueue(ctx, wsOutbound{message: outbound}, time.After(5*time.Second)); err != nil {
+		c.stats.recordDropped(message)
+		return err
+	}
+	return nil
+}
+
+// enqueue queues an entry for the write pump, waiting for room without holding
+// any lock so that a slow client never stalls whoever closes it. An entry may
+// still be queued just as the write pump exits; whichever of the two sees the
+// other last takes it off the queue depth.
+func (c *WebSocketConnection) enqueue(ctx context.Context, entry wsOutbound, timeout <-chan time.Time) error {
 	if c.IsClosed() {
 		return fmt.Errorf("WebSocket connection is closed")
 	}
 
 	select {
-	case c.send <- message:
-		return nil
+	case c.send <- entry:
 	case <-ctx.Done():
 		return ctx.Err()
 	case <-c.ctx.Done():
+		return fmt.Errorf("WebSocket connection is closed")
+	case <-c.writerDone:
 		return fmt.Errorf("connection closed")
-	case <-time.After(5 * time.Second):
+	case <-timeout:
 		return fmt.Errorf("send timeout")
 	}
+
+	c.queued.Add(1)
+	metrics.QueueDepth.WithLabelValues(c.Type()).Inc()
+	select {
+	case <-c.writerDone:
+		c.resetQueued()
+	default:
+	}
+	return nil
+}
+
+// resetQueued takes the messages that will never be written off the queue depth
+func (c *WebSocketConnection) resetQueued() {
+	metrics.QueueDepth.WithLabelValues(c.Type()).Sub(float64(c.queued.Swap(0)))
 }
 
 // Close gracefully closes the WebSocket connection
 func (c *WebSocketConnection) Close() error {
-	c.closedMu.Lock()
-	defer c.closedMu.Unlock()
+	return c.closeWithCode(websocket.CloseNormalClosure, "")
+}
 
+// closeWithCode closes the WebSocket connection with the given close code and reason
+func (c *WebSocketConnection) closeWithCode(code int, reason string) error {
+	c.closedMu.Lock()
 	if c.closed {
+		c.closedMu.Unlock()
 		return nil
 	}
 
 	c.closed = true
+	c.closeCode, c.closeReason = code, reason
+	// The send channel is left open: closing it would race with senders that
+	// checked IsClosed. Cancelling the context tells the write pump to drain it.
 	c.cancel()
+	c.closedMu.Unlock()
 
-	// Close the send channel
-	close(c.send)
+	if c.protocol != nil {
+		c.protocol.Close(c)
+	}
 
-	// Send close message and close WebSocket connection
-	c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
-	c.conn.WriteMessage(
-		websocket.CloseMessage,
-		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
-	)
-	c.conn.Close()
+	if c.inboundLimiter != nil {
+		c.inboundLimiter.Forget(c.id)
+	}
 
+	// The write pump writes what is still queued, then the close message, and
+	// closes the WebSocket connection, as gorilla/websocket allows a single
+	// concurrent writer
 	c.logger.Info("WebSocket connection closed")
 	return nil
 }
@@ -403,6 +525,15 @@ func (c *WebSocketConnection) Context() context.Context {
 	return c.ctx
 }
 
+// Info returns a snapshot of the connection's metadata and statistics
+func (c *WebSocketConnection) Info() ConnectionInfo {
+	c.activityMu.RLock()
+	lastActivity := c.lastActivity
+	c.activityMu.RUnlock()
+
+	return c.stats.info(c.id, lastActivity, int(c.queued.Load()), c.IsClosed())
+}
+
 // setupWebSocket configures WebSocket connection settings
 func (c *WebSocketConnection) setupWebSocket() {
 	// Set read deadline and pong handler for keep-alive
@@ -422,40 +553,114 @@ func (c *WebSocketConnection) writePump() {
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		// Messages still queued will never be written; senders that queue one
+		// after this reset see writerDone and reset again
+		close(c.writerDone)
+		c.resetQueued()
 	}()
 
 	for {
 		select {
-		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
-
-			if !ok {
-				// The send channel was closed
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+		case entry := <-c.send:
+			if !c.writeEntry(entry) {
 				return
 			}
 
-			// Send the message as JSON
-			if err := c.conn.WriteJSON(message); err != nil {
-				c.logger.Errorf("Failed to write message: %v", err)
-				return
+		case <-c.ctx.Done():
+			// The connection was closed: write what is still queued, then the
+			// close message
+			for {
+				select {
+				case entry := <-c.send:
+					if !c.writeEntry(entry) {
+						return
+					}
+				default:
+					c.writeClose()
+					return
+				}
 			}
 
-			c.updateActivity()
-
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			err := c.conn.WriteMessage(websocket.PingMessage, nil)
+			c.stats.recordKeepAlive(err)
+			if err != nil {
 				c.logger.Errorf("Failed to send ping: %v", err)
 				return
 			}
 
-		case <-c.ctx.Done():
-			return
 		}
 	}
 }
 
+// writeEntry writes a queued message or frame, reporting false when the
+// connection can no longer be written to
+func (c *WebSocketConnection) writeEntry(entry wsOutbound) bool {
+	c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	c.queued.Add(-1)
+	metrics.QueueDepth.WithLabelValues(c.Type()).Dec()
+
+	if entry.frame != nil {
+		if err := c.conn.WriteMessage(entry.frame.Type, entry.frame.Data); err != nil {
+			c.logger.Errorf("Failed to write frame: %v", err)
+			return false
+		}
+		c.updateActivity()
+		return true
+	}
+
+	message := entry.message.Message
+	start := time.Now()
+
+	frames, err := c.encode(entry.message)
+	if err != nil {
+		c.stats.recordFailed(message)
+		c.logger.Errorf("Failed to marshal message: %v", err)
+		return true
+	}
+	if len(frames) == 0 {
+		return true
+	}
+
+	size := 0
+	for _, frame := range frames {
+		if err := c.conn.WriteMessage(frame.Type, frame.Data); err != nil {
+			c.stats.recordFailed(message)
+			c.logger.Errorf("Failed to write message: %v", err)
+			return false
+		}
+		size += len(frame.Data)
+	}
+
+	c.stats.recordSent(message, size, start)
+	c.updateActivity()
+	return true
+}
+
+// writeClose sends the close message of a closed connection
+func (c *WebSocketConnection) writeClose() {
+	c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	c.conn.WriteMessage(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(c.closeCode, c.closeReason),
+	)
+}
+
+// encode converts a queued message into frames, as JSON text unless a
+// subprotocol was negotiated
+func (c *WebSocketConnection) encode(message OutboundMessage) ([]WebSocketFrame, error) {
+	if c.protocol != nil {
+		return c.protocol.Encode(c, message)
+	}
+
+	payload, err := json.Marshal(message.Message)
+	if err != nil {
+		return nil, err
+	}
+	return []WebSocketFrame{TextFrame(payload)}, nil
+}
+
 // readPump handles reading messages from the WebSocket connection
 func (c *WebSocketConnection) readPump() {
 	defer func() {
@@ -478,6 +683,23 @@ func (c *WebSocketConnection) readPump() {
 
 		c.updateActivity()
 
+		if !c.allowInbound() {
+			if c.maxViolations > 0 && c.violations >= c.maxViolations {
+				c.logger.Warnf("Closing connection after %d rate limit violations", c.violations)
+				c.closeWithCode(websocket.ClosePolicyViolation, "rate limit exceeded")
+				return
+			}
+			continue
+		}
+
+		if c.protocol != nil {
+			if err := c.protocol.HandleFrame(c, messageType, data); err != nil {
+				c.logger.Warnf("Closing connection after protocol error: %v", err)
+				return
+			}
+			continue
+		}
+
 		// Handle different message types
 		switch messageType {
 		case websocket.TextMessage:
@@ -511,6 +733,41 @@ func (c *WebSocketConnection) readPump() {
 	}
 }
 
+// allowInbound applies the inbound rate limit to a received frame. Clients of
+// the default JSON messages are notified when a frame is dropped; frames of a
+// negotiated subprotocol are dropped silently, as its clients could not parse
+// the notice.
+func (c *WebSocketConnection) allowInbound() bool {
+	if c.inboundLimiter == nil {
+		return true
+	}
+
+	res := c.inboundLimiter.Take(c.id)
+	if res.Allowed {
+		return true
+	}
+
+	if now := time.Now(); now.Sub(c.violationsSince) >= violationWindow {
+		c.violations, c.violationsSince = 0, now
+	}
+	c.violations++
+	c.logger.Debugf("Inbound frame dropped by rate limit (%d violations)", c.violations)
+	if c.protocol != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.Send(ctx, ErrorMessage("rate_limited", "Too many messages", map[string]interface{}{
+		"limit":          res.Limit,
+		"remaining":      res.Remaining,
+		"retry_after_ms": res.RetryAfter.Milliseconds(),
+	})); err != nil {
+		c.logger.Debugf("Failed to send rate limit notice: %v", err)
+	}
+	return false
+}
+
 // updateActivity updates the last activity timestamp
 func (c *WebSocketConnection) updateActivity() {
 	c.activityMu.Lock()
@@ -0,0 +1,102 @@
+package hub
+
+import "time"
+
+// Hub event topics published on the hub's event bus
+const (
+	EventConnectionRegistered   = "connection.registered"
+	EventConnectionUnregistered = "connection.unregistered"
+	EventMessagePublished       = "message.published"
+	EventMessageDelivered       = "message.delivered"
+)
+
+// Delivery outcomes reported in DeliveryEvent.Status. Protocols with explicit
+// client acknowledgements follow delivered with acknowledged or rejected.
+const (
+	DeliveryStatusDelivered    = "delivered"
+	DeliveryStatusFailed       = "failed"
+	DeliveryStatusAcknowledged = "acknowledged"
+	DeliveryStatusRejected     = "rejected"
+)
+
+// ConnectionEvent describes a connection lifecycle change
+type ConnectionEvent struct {
+	ConnectionID   string `json:"connection_id"`
+	ConnectionType string `json:"connection_type"`
+}
+
+// MessageEvent describes a message accepted by the hub for delivery
+type MessageEvent struct {
+	Target     string   `json:"target"` // broadcast, type:<t>, user:<id>, topic:<t>, connection:<id>
+	Recipients int      `json:"recipients"`
+	Message    *Message `json:"message"`
+}
+
+// DeliveryEvent describes the outcome of sending a message to one connection
+type DeliveryEvent struct {
+	Target         string        `json:"target"`
+	MessageID      string        `json:"message_id"`
+	MessageType    string        `json:"message_type"`
+	ConnectionID   string        `json:"connection_id"`
+	ConnectionType string        `json:"connection_type"`
+	UserID         string        `json:"user_id,omitempty"`
+	Status         string        `json:"status"`
+	Error          string        `json:"error,omitempty"`
+	Duration       time.Duration `json:"duration"`
+}
+
+func (h *Hub) emitConnection(topic string, conn Connection) {
+	h.events.Publish(topic, ConnectionEvent{
+		ConnectionID:   conn.ID(),
+		ConnectionType: conn.Type(),
+	})
+}
+
+func (h *Hub) emitMessage(target string, recipients int, message *Message) {
+	h.events.Publish(EventMessagePublished, MessageEvent{
+		Target:     target,
+		Recipients: recipients,
+		Message:    message,
+	})
+}
+
+func (h *Hub) emitDelivery(target string, conn Connection, message *Message, err error, duration time.Duration) {
+	// Skip the user lookup when nobody is listening
+	if !h.events.HasSubscribers() {
+		return
+	}
+
+	event := h.deliveryEvent(target, conn, message, DeliveryStatusDelivered)
+	event.Duration = duration
+	if err != nil {
+		event.Status = DeliveryStatusFailed
+		event.Error = err.Error()
+	}
+	h.events.Publish(EventMessageDelivered, event)
+}
+
+// Acknowledge reports that a client acknowledged (accepted) or rejected a
+// message delivered to its connection, for protocols with explicit acks
+func (h *Hub) Acknowledge(target string, conn Connection, message *Message, accepted bool) {
+	if !h.events.HasSubscribers() {
+		return
+	}
+
+	status := DeliveryStatusAcknowledged
+	if !accepted {
+		status = DeliveryStatusRejected
+	}
+	h.events.Publish(EventMessageDelivered, h.deliveryEvent(target, conn, message, status))
+}
+
+func (h *Hub) deliveryEvent(target string, conn Connection, message *Message, status string) DeliveryEvent {
+	return DeliveryEvent{
+		Target:         target,
+		MessageID:      message.ID,
+		MessageType:    message.Type,
+		ConnectionID:   conn.ID(),
+		ConnectionType: conn.Type(),
+		UserID:         h.routes.userOf(conn.ID()),
+		Status:         status,
+	}
+}
@@ -0,0 +1,224 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/metrics"
+)
+
+// GRPCStream is the part of a gRPC server stream used by GRPCConnection; any
+// grpc.ServerStream satisfies it
+type GRPCStream interface {
+	Context() context.Context
+	SendMsg(m any) error
+}
+
+// GRPCConnection implements the Connection interface for a gRPC server stream.
+// Messages are sent as StreamedMessage values. Send only queues; the RPC
+// goroutine sends the queue in Serve, as gRPC streams do not allow concurrent
+// SendMsg calls.
+type GRPCConnection struct {
+	id     string
+	stream GRPCStream
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	closed   bool
+	closedMu sync.RWMutex
+
+	logger logger.Logger
+
+	send   chan streamedMessage
+	queued atomic.Int64
+
+	// Messages up to this cursor were replayed on resume; live copies are skipped
+	replayedUpTo atomic.Uint64
+
+	lastActivity time.Time
+	activityMu   sync.RWMutex
+
+	stats *connStats
+}
+
+// GRPCOption configures optional GRPCConnection behaviour
+type GRPCOption func(*GRPCConnection)
+
+// WithPeer records the client address and user agent of the RPC
+func WithPeer(remoteAddr, userAgent string) GRPCOption {
+	return func(c *GRPCConnection) {
+		c.stats.remoteAddr = remoteAddr
+		c.stats.userAgent = userAgent
+	}
+}
+
+// NewGRPCConnection creates a connection that ends with the stream's RPC
+func NewGRPCConnection(id string, stream GRPCStream, log logger.Logger, opts ...GRPCOption) *GRPCConnection {
+	ctx, cancel := context.WithCancel(logger.ContextWithConnectionID(stream.Context(), id))
+
+	conn := &GRPCConnection{
+		id:           id,
+		stream:       stream,
+		ctx:          ctx,
+		cancel:       cancel,
+		logger:       log.WithField("connection_id", id).WithContext(ctx),
+		send:         make(chan streamedMessage, 256),
+		lastActivity: time.Now(),
+		stats:        newConnStats("grpc", nil),
+	}
+
+	for _, opt := range opts {
+		opt(conn)
+	}
+
+	return conn
+}
+
+// ID returns unique connection identifier
+func (c *GRPCConnection) ID() string {
+	return c.id
+}
+
+// Type returns the connection type
+func (c *GRPCConnection) Type() string {
+	return "grpc"
+}
+
+// Send queues a message for the stream
+func (c *GRPCConnection) Send(ctx context.Context, message *Message) error {
+	if c.IsClosed() {
+		c.stats.recordDropped(message)
+		return fmt.Errorf("gRPC stream is closed")
+	}
+
+	cursor, _ := CursorFromContext(ctx)
+
+	select {
+	case c.send <- streamedMessage{message: message, cursor: cursor}:
+		c.queued.Add(1)
+		metrics.QueueDepth.WithLabelValues(c.Type()).Inc()
+		return nil
+	case <-ctx.Done():
+		c.stats.recordDropped(message)
+		return ctx.Err()
+	case <-c.ctx.Done():
+		c.stats.recordDropped(message)
+		return fmt.Errorf("connection closed")
+	case <-time.After(5 * time.Second):
+		c.stats.recordDropped(message)
+		return fmt.Errorf("send timeout")
+	}
+}
+
+// Replay sends journaled messages before live delivery starts; live copies of
+// them are skipped. Call it before Serve.
+func (c *GRPCConnection) Replay(entries []JournalEntry) error {
+	for _, entry := range entries {
+		if err := c.write(streamedMessage{message: entry.Message, cursor: entry.Cursor}); err != nil {
+			return err
+		}
+		c.replayedUpTo.Store(entry.Cursor)
+	}
+	return nil
+}
+
+// Serve sends queued messages until the RPC ends or the connection is closed.
+// It must be called from the RPC goroutine.
+func (c *GRPCConnection) Serve() error {
+	defer func() {
+		c.Close()
+		metrics.QueueDepth.WithLabelValues(c.Type()).Sub(float64(c.queued.Swap(0)))
+	}()
+
+	for {
+		select {
+		case queued := <-c.send:
+			c.queued.Add(-1)
+			metrics.QueueDepth.WithLabelValues(c.Type()).Dec()
+
+			if queued.cursor != 0 && queued.cursor <= c.replayedUpTo.Load() {
+				continue
+			}
+			if err := c.write(queued); err != nil {
+				c.logger.Errorf("Failed to send message: %v", err)
+				return err
+			}
+
+		case <-c.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// write sends one message on the stream
+func (c *GRPCConnection) write(queued streamedMessage) error {
+	start := time.Now()
+
+	// Size the message as its JSON encoding, which approximates what the stream
+	// sends whatever its codec
+	payload, err := json.Marshal(queued.message)
+	if err != nil {
+		c.stats.recordFailed(queued.message)
+		c.logger.Errorf("Failed to marshal message: %v", err)
+		return nil
+	}
+
+	if err := c.stream.SendMsg(&StreamedMessage{Message: queued.message, Cursor: queued.cursor}); err != nil {
+		c.stats.recordFailed(queued.message)
+		return err
+	}
+
+	c.stats.recordSent(queued.message, len(payload), start)
+	c.updateActivity()
+	return nil
+}
+
+// Close ends the stream; Serve returns once it notices
+func (c *GRPCConnection) Close() error {
+	c.closedMu.Lock()
+	defer c.closedMu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+	c.cancel()
+
+	c.logger.Info("gRPC stream closed")
+	return nil
+}
+
+// IsClosed returns true if connection is closed
+func (c *GRPCConnection) IsClosed() bool {
+	c.closedMu.RLock()
+	defer c.closedMu.RUnlock()
+	return c.closed
+}
+
+// Context returns the connection's context (for cancellation)
+func (c *GRPCConnection) Context() context.Context {
+	return c.ctx
+}
+
+// Info returns a snapshot of the connection's metadata and statistics
+func (c *GRPCConnection) Info() ConnectionInfo {
+	c.activityMu.RLock()
+	lastActivity := c.lastActivity
+	c.activityMu.RUnlock()
+
+	return c.stats.info(c.id, lastActivity, int(c.queued.Load()), c.IsClosed())
+}
+
+// updateActivity updates the last activity timestamp
+func (c *GRPCConnection) updateActivity() {
+	c.activityMu.Lock()
+	c.lastActivity = time.Now()
+	c.activityMu.Unlock()
+}
@@ -0,0 +1,38 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+)
+
+// queueSaturationThreshold is the fill ratio above which an internal channel is
+// considered saturated
+const queueSaturationThreshold = 0.9
+
+// LivenessCheck fails when the hub run loop is not running
+func (h *Hub) LivenessCheck(ctx context.Context) error {
+	if !h.IsRunning() {
+		return fmt.Errorf("hub is not running")
+	}
+	return nil
+}
+
+// ReadinessCheck fails when the hub is not running, is draining, or its register
+// or broadcast channels are saturated
+func (h *Hub) ReadinessCheck(ctx context.Context) error {
+	if err := h.LivenessCheck(ctx); err != nil {
+		return err
+	}
+	if h.IsDraining() {
+		return fmt.Errorf("hub is draining")
+	}
+
+	depths := h.QueueDepths()
+	capacities := h.QueueCapacities()
+	for _, queue := range []string{"register", "broadcast"} {
+		if float64(depths[queue]) >= float64(capacities[queue])*queueSaturationThreshold {
+			return fmt.Errorf("%s queue saturated (%d/%d)", queue, depths[queue], capacities[queue])
+		}
+	}
+	return nil
+}
@@ -4,9 +4,15 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"go-notification-sse/internal/infrastructure/eventbus"
 	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/metrics"
 )
 
 // Hub manages connections without depending on specific interfaces
@@ -14,9 +20,25 @@ type Hub struct {
 	connections   map[string]Connection
 	connectionsMu sync.RWMutex
 
+	// User and topic routes for targeted delivery
+	routes *routingTable
+
+	// Hub lifecycle and message events for observers such as the admin dashboard
+	events *eventbus.EventBus
+
+	// Recently published messages for clients resuming from a cursor
+	journal *journal
+
+	// Delivers user-targeted messages to users with no live connection
+	fallback   OfflineFallback
+	fallbackMu sync.RWMutex
+
 	running   bool
 	runningMu sync.RWMutex
 
+	// Draining hubs keep serving existing connections but refuse new ones
+	draining atomic.Bool
+
 	logger logger.Logger
 
 	// Channels for internal communication
@@ -33,6 +55,9 @@ type Hub struct {
 func New(logger logger.Logger) *Hub {
 	return &Hub{
 		connections: make(map[string]Connection),
+		routes:      newRoutingTable(),
+		events:      eventbus.New(),
+		journal:     newJournal(journalSize),
 		logger:      logger.WithField("component", "hub"),
 		register:    make(chan Connection, 100),
 		unregister:  make(chan string, 100),
@@ -69,15 +94,20 @@ func (h *Hub) Stop(ctx context.Context) error {
 
 	h.cancel()
 
-	// Close all connections
+	// Close all connections, outside the lock as closing may block
 	h.connectionsMu.Lock()
-	for _, conn := range h.connections {
+	connections := h.connections
+	h.connections = make(map[string]Connection)
+	h.connectionsMu.Unlock()
+	h.routes.reset()
+
+	for _, conn := range connections {
 		if err := conn.Close(); err != nil {
 			h.logger.Errorf("Failed to close connection %s: %v", conn.ID(), err)
 		}
+		metrics.ConnectionsActive.WithLabelValues(conn.Type()).Dec()
+		metrics.Unregistrations.WithLabelValues(conn.Type()).Inc()
 	}
-	h.connections = make(map[string]Connection)
-	h.connectionsMu.Unlock()
 
 	h.running = false
 	h.logger.Info("Hub stopped successfully")
@@ -91,11 +121,27 @@ func (h *Hub) IsRunning() bool {
 	return h.running
 }
 
+// Drain stops the hub from accepting new connections while existing ones keep
+// being served
+func (h *Hub) Drain() {
+	if !h.draining.Swap(true) {
+		h.logger.Info("Hub draining, new connections will be rejected")
+	}
+}
+
+// IsDraining returns true once Drain has been called
+func (h *Hub) IsDraining() bool {
+	return h.draining.Load()
+}
+
 // RegisterConnection adds a new connection to the hub
 func (h *Hub) RegisterConnection(conn Connection) error {
 	if !h.IsRunning() {
 		return fmt.Errorf("hub is not running")
 	}
+	if h.IsDraining() {
+		return fmt.Errorf("hub is draining")
+	}
 
 	select {
 	case h.register <- conn:
@@ -158,6 +204,41 @@ func (h *Hub) GetConnectionsByType(connType string) []Connection {
 	return connections
 }
 
+// Events returns the bus on which the hub publishes connection and message events
+func (h *Hub) Events() *eventbus.EventBus {
+	return h.events
+}
+
+// CountByType returns the number of active connections per connection type
+func (h *Hub) CountByType() map[string]int {
+	h.connectionsMu.RLock()
+	defer h.connectionsMu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, conn := range h.connections {
+		counts[conn.Type()]++
+	}
+	return counts
+}
+
+// QueueDepths returns the number of pending events in each of the hub's internal channels
+func (h *Hub) QueueDepths() map[string]int {
+	return map[string]int{
+		"register":   len(h.register),
+		"unregister": len(h.unregister),
+		"broadcast":  len(h.broadcast),
+	}
+}
+
+// QueueCapacities returns the capacity of each of the hub's internal channels
+func (h *Hub) QueueCapacities() map[string]int {
+	return map[string]int{
+		"register":   cap(h.register),
+		"unregister": cap(h.unregister),
+		"broadcast":  cap(h.broadcast),
+	}
+}
+
 // ConnectionCount returns the number of active connections
 func (h *Hub) ConnectionCount() int {
 	h.connectionsMu.RLock()
@@ -166,11 +247,14 @@ func (h *Hub) ConnectionCount() int {
 }
 
 // Broadcast sends a message to all connections
-func (h *Hub) Broadcast(ctx context.Context, message *Message) error {
+func (h *Hub) Broadcast(ctx context.Context, message *Message) (err error) {
 	if !h.IsRunning() {
 		return fmt.Errorf("hub is not running")
 	}
 
+	ctx, span := startPublishSpan(ctx, "hub.broadcast", message)
+	defer func() { endSpan(span, err) }()
+
 	select {
 	case h.broadcast <- message:
 		return nil
@@ -185,31 +269,39 @@ func (h *Hub) Broadcast(ctx context.Context, message *Message) error {
 
 // BroadcastToType sends a message to all connections of a specific type
 func (h *Hub) BroadcastToType(ctx context.Context, connType string, message *Message) error {
-	connections := h.GetConnectionsByType(connType)
+	ctx, span := startPublishSpan(ctx, "hub.broadcast_to_type", message)
+	defer span.End()
 
-	for _, conn := range connections {
-		go func(c Connection) {
-			if err := c.Send(ctx, message); err != nil {
-				h.logger.Errorf("Failed to send message to connection %s: %v", c.ID(), err)
-				// Auto-unregister failed connections
-				h.UnregisterConnection(c.ID())
-			}
-		}(conn)
-	}
+	connections := h.GetConnectionsByType(connType)
+	h.emitMessage("type:"+connType, len(connections), message)
+	h.deliver(ctx, "type:"+connType, connections, message)
 
-	h.logger.Infof("Broadcasted message to %d connections of type %s", len(connections), connType)
+	h.logger.WithContext(ctx).Infof("Broadcasted message to %d connections of type %s", len(connections), connType)
 	return nil
 }
 
 // SendToConnection sends a message to a specific connection
-func (h *Hub) SendToConnection(ctx context.Context, connID string, message *Message) error {
+func (h *Hub) SendToConnection(ctx context.Context, connID string, message *Message) (err error) {
+	ctx, span := startPublishSpan(ctx, "hub.send_to_connection", message)
+	defer func() { endSpan(span, err) }()
+
 	conn, exists := h.GetConnection(connID)
 	if !exists {
 		return fmt.Errorf("connection %s not found", connID)
 	}
 
-	if err := conn.Send(ctx, message); err != nil {
-		h.logger.Errorf("Failed to send message to connection %s: %v", connID, err)
+	h.emitMessage("connection:"+connID, 1, message)
+	ctx = h.record(ctx, "connection:"+connID, message)
+
+	start := time.Now()
+	sendCtx, sendSpan := startSendSpan(ctx, conn, message)
+	err = conn.Send(sendCtx, message)
+	endSpan(sendSpan, err)
+	h.emitDelivery("connection:"+connID, conn, message, err, time.Since(start))
+
+	if err != nil {
+		h.logger.WithContext(logger.ContextWithFieldsFrom(ctx, conn.Context())).
+			Errorf("Failed to send message to connection %s: %v", connID, err)
 		// Auto-unregister failed connections
 		h.UnregisterConnection(connID)
 		return err
@@ -247,10 +339,17 @@ func (h *Hub) run() {
 // handleRegister processes connection registration
 func (h *Hub) handleRegister(conn Connection) {
 	h.connectionsMu.Lock()
+	_, replaced := h.connections[conn.ID()]
 	h.connections[conn.ID()] = conn
 	h.connectionsMu.Unlock()
 
-	h.logger.Infof("Connection %s registered (type: %s)", conn.ID(), conn.Type())
+	if !replaced {
+		metrics.ConnectionsActive.WithLabelValues(conn.Type()).Inc()
+	}
+	metrics.Registrations.WithLabelValues(conn.Type()).Inc()
+
+	h.emitConnection(EventConnectionRegistered, conn)
+	h.logger.WithContext(conn.Context()).Infof("Connection %s registered (type: %s)", conn.ID(), conn.Type())
 
 	// Monitor connection context for disconnection
 	go func() {
@@ -265,39 +364,39 @@ func (h *Hub) handleUnregister(connID string) {
 	conn, exists := h.connections[connID]
 	if exists {
 		delete(h.connections, connID)
-		conn.Close()
 	}
 	h.connectionsMu.Unlock()
 
+	h.routes.remove(connID)
+
 	if exists {
-		h.logger.Infof("Connection %s unregistered", connID)
+		// Closing may block, e.g. on a slow client, so it must not hold up
+		// lookups and broadcasts
+		conn.Close()
+		metrics.ConnectionsActive.WithLabelValues(conn.Type()).Dec()
+		metrics.Unregistrations.WithLabelValues(conn.Type()).Inc()
+		h.emitConnection(EventConnectionUnregistered, conn)
+		h.logger.WithContext(conn.Context()).Infof("Connection %s unregistered", connID)
 	}
 }
 
 // handleBroadcast processes broadcast messages
 func (h *Hub) handleBroadcast(message *Message) {
 	connections := h.GetConnections()
-	successCount := 0
-
-	for _, conn := range connections {
-		go func(c Connection) {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
-
-			if err := c.Send(ctx, message); err != nil {
-				h.logger.Errorf("Failed to send broadcast to connection %s: %v", c.ID(), err)
-				h.UnregisterConnection(c.ID())
-			} else {
-				successCount++
-			}
-		}(conn)
-	}
 
-	h.logger.Infof("Broadcasted message %s to %d connections", message.ID, len(connections))
+	ctx, span := tracer().Start(ExtractTraceContext(context.Background(), message), "hub.fanout",
+		trace.WithAttributes(attribute.Int("hub.connections", len(connections))),
+	)
+	h.emitMessage("broadcast", len(connections), message)
+	h.deliver(ctx, "broadcast", connections, message)
+	h.logger.WithContext(ctx).Infof("Broadcasted message %s to %d connections", message.ID, len(connections))
+	span.End()
 }
 
 // cleanupClosedConnections removes connections that have been closed
 func (h *Hub) cleanupClosedConnections() {
+	defer h.pruneRoutes()
+
 	h.connectionsMu.Lock()
 	defer h.connectionsMu.Unlock()
 
@@ -309,6 +408,10 @@ func (h *Hub) cleanupClosedConnections() {
 	}
 
 	for _, id := range closedConnections {
+		conn := h.connections[id]
+		metrics.ConnectionsActive.WithLabelValues(conn.Type()).Dec()
+		metrics.Unregistrations.WithLabelValues(conn.Type()).Inc()
+		h.emitConnection(EventConnectionUnregistered, conn)
 		delete(h.connections, id)
 		h.logger.Infof("Cleaned up closed connection %s", id)
 	}
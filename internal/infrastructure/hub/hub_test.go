@@ -2,7 +2,10 @@ package hub
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
@@ -91,6 +94,52 @@ func TestHub_ConnectionManagement(t *testing.T) {
 	}
 }
 
+// slowClosingConnection blocks in Close until released, like a connection
+// flushing to a slow client
+type slowClosingConnection struct {
+	*mockConnection
+	closing chan struct{}
+	release chan struct{}
+}
+
+func (c *slowClosingConnection) Close() error {
+	close(c.closing)
+	<-c.release
+	return c.mockConnection.Close()
+}
+
+func TestHub_UnregisterDoesNotHoldConnectionsWhileClosing(t *testing.T) {
+	hub := New(&mockLogger{})
+
+	ctx := context.Background()
+	hub.Start(ctx)
+	defer hub.Stop(ctx)
+
+	conn := &slowClosingConnection{
+		mockConnection: &mockConnection{id: "slow", ctx: ctx},
+		closing:        make(chan struct{}),
+		release:        make(chan struct{}),
+	}
+	hub.RegisterConnection(conn)
+	time.Sleep(100 * time.Millisecond)
+
+	hub.UnregisterConnection("slow")
+	<-conn.closing
+	defer close(conn.release)
+
+	looked := make(chan struct{})
+	go func() {
+		hub.GetConnection("slow")
+		hub.ConnectionCount()
+		close(looked)
+	}()
+	select {
+	case <-looked:
+	case <-time.After(time.Second):
+		t.Fatal("Expected lookups to proceed while a connection is closing")
+	}
+}
+
 func TestHub_Broadcasting(t *testing.T) {
 	logger := &mockLogger{}
 	hub := New(logger)
@@ -133,11 +182,11 @@ func TestHub_Broadcasting(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	// Check that both connections received the message
-	if len(conn1.receivedMessages) != 1 {
-		t.Errorf("Connection1 should have received 1 message, got %d", len(conn1.receivedMessages))
+	if received := conn1.messages(); len(received) != 1 {
+		t.Errorf("Connection1 should have received 1 message, got %d", len(received))
 	}
-	if len(conn2.receivedMessages) != 1 {
-		t.Errorf("Connection2 should have received 1 message, got %d", len(conn2.receivedMessages))
+	if received := conn2.messages(); len(received) != 1 {
+		t.Errorf("Connection2 should have received 1 message, got %d", len(received))
 	}
 }
 
@@ -161,9 +210,12 @@ func (m *mockLogger) WithContext(ctx context.Context) logger.Logger { return m }
 func (m *mockLogger) SetLevel(level logger.Level)                   {}
 func (m *mockLogger) SetOutput(output io.Writer)                    {}
 
+// mockConnection records what the hub sends it; the hub calls it from its own
+// goroutines, so tests read it through messages and IsClosed
 type mockConnection struct {
 	id               string
 	ctx              context.Context
+	mu               sync.Mutex
 	closed           bool
 	receivedMessages []*Message
 }
@@ -171,9 +223,286 @@ type mockConnection struct {
 func (m *mockConnection) ID() string   { return m.id }
 func (m *mockConnection) Type() string { return "mock" }
 func (m *mockConnection) Send(ctx context.Context, message *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.receivedMessages = append(m.receivedMessages, message)
 	return nil
 }
-func (m *mockConnection) Close() error             { m.closed = true; return nil }
-func (m *mockConnection) IsClosed() bool           { return m.closed }
+func (m *mockConnection) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+func (m *mockConnection) IsClosed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.closed
+}
 func (m *mockConnection) Context() context.Context { return m.ctx }
+func (m *mockConnection) Info() ConnectionInfo {
+	return ConnectionInfo{ID: m.id, Type: m.Type(), LastActivity: time.Now(), Closed: m.IsClosed()}
+}
+
+// messages returns a copy of the messages received so far
+func (m *mockConnection) messages() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.receivedMessages)
+}
+
+func TestHub_TopicAndUserRouting(t *testing.T) {
+	logger := &mockLogger{}
+	hub := New(logger)
+
+	ctx := context.Background()
+	hub.Start(ctx)
+	defer hub.Stop(ctx)
+
+	conn1 := &mockConnection{id: "conn-1", ctx: ctx}
+	conn2 := &mockConnection{id: "conn-2", ctx: ctx}
+	hub.RegisterConnection(conn1)
+	hub.RegisterConnection(conn2)
+	time.Sleep(100 * time.Millisecond)
+
+	hub.BindUser("conn-1", "alice")
+	hub.Subscribe("conn-2", "orders")
+
+	sent, err := hub.SendToUser(ctx, "alice", &Message{ID: "user-msg", Type: "test"})
+	if err != nil {
+		t.Fatalf("Failed to send to user: %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("Expected 1 targeted connection for user, got %d", sent)
+	}
+
+	sent, err = hub.PublishToTopic(ctx, "orders", &Message{ID: "topic-msg", Type: "test"})
+	if err != nil {
+		t.Fatalf("Failed to publish to topic: %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("Expected 1 targeted connection for topic, got %d", sent)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if received := conn1.messages(); len(received) != 1 || received[0].ID != "user-msg" {
+		t.Errorf("Connection1 should have received only the user message, got %v", received)
+	}
+	if received := conn2.messages(); len(received) != 1 || received[0].ID != "topic-msg" {
+		t.Errorf("Connection2 should have received only the topic message, got %v", received)
+	}
+
+	// Routes are dropped when the connection unregisters
+	hub.UnregisterConnection("conn-2")
+	time.Sleep(100 * time.Millisecond)
+
+	if len(hub.GetConnectionsByTopic("orders")) != 0 {
+		t.Error("Topic should have no subscribers after unregistration")
+	}
+}
+
+func TestHub_PatternRouting(t *testing.T) {
+	logger := &mockLogger{}
+	hub := New(logger)
+
+	ctx := context.Background()
+	hub.Start(ctx)
+	defer hub.Stop(ctx)
+
+	conn := &mockConnection{id: "conn-1", ctx: ctx}
+	hub.RegisterConnection(conn)
+	time.Sleep(100 * time.Millisecond)
+
+	hub.SubscribePattern("conn-1", "devices/+/temperature", "alerts/#")
+
+	for topic, expected := range map[string]int{
+		"devices/1/temperature":   1,
+		"devices/1/humidity":      0,
+		"alerts":                  1,
+		"alerts/fire/building-a":  1,
+		"devices/1/2/temperature": 0,
+	} {
+		if sent, _ := hub.PublishToTopic(ctx, topic, &Message{ID: topic, Type: "test"}); sent != expected {
+			t.Errorf("Expected %d targeted connections for %s, got %d", expected, topic, sent)
+		}
+	}
+
+	hub.UnsubscribePattern("conn-1", "alerts/#")
+	if len(hub.GetConnectionsByTopic("alerts/fire")) != 0 {
+		t.Error("Topic should have no subscribers after the pattern is removed")
+	}
+}
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		matches        bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/+", "a/b", true},
+		{"a/+", "a/b/c", false},
+		{"+/+", "/b", true},
+		{"a/#", "a", true},
+		{"a/#", "a/b/c", true},
+		{"#", "a/b", true},
+		{"#", "$SYS/uptime", false},
+		{"+/uptime", "$SYS/uptime", false},
+		{"$SYS/#", "$SYS/uptime", true},
+		{"a/b", "a/c", false},
+	}
+	for _, tt := range tests {
+		if got := TopicMatches(tt.pattern, tt.topic); got != tt.matches {
+			t.Errorf("TopicMatches(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.matches)
+		}
+	}
+}
+
+func TestHub_FindConnections(t *testing.T) {
+	logger := &mockLogger{}
+	hub := New(logger)
+
+	ctx := context.Background()
+	hub.Start(ctx)
+	defer hub.Stop(ctx)
+
+	hub.RegisterConnection(&mockConnection{id: "conn-1", ctx: ctx})
+	hub.RegisterConnection(&mockConnection{id: "conn-2", ctx: ctx})
+	hub.RegisterConnection(&mockConnection{id: "conn-3", ctx: ctx})
+	time.Sleep(100 * time.Millisecond)
+
+	hub.BindUser("conn-1", "alice")
+	hub.BindUser("conn-2", "alice")
+	hub.Subscribe("conn-2", "orders", "alerts")
+
+	if got := hub.FindConnections(&ConnectionFilter{}); len(got) != 3 {
+		t.Errorf("Expected 3 connections without filter, got %d", len(got))
+	}
+	if got := hub.FindConnections(&ConnectionFilter{UserID: "alice"}); len(got) != 2 {
+		t.Errorf("Expected 2 connections for alice, got %d", len(got))
+	}
+	if got := hub.FindConnections(&ConnectionFilter{UserID: "alice", Topic: "orders"}); len(got) != 1 || got[0].ID != "conn-2" {
+		t.Errorf("Expected only conn-2 for alice on orders, got %v", got)
+	}
+	if got := hub.FindConnections(&ConnectionFilter{MinIdle: time.Hour}); len(got) != 0 {
+		t.Errorf("Expected no connections idle for an hour, got %d", len(got))
+	}
+
+	info, exists := hub.DescribeConnection("conn-2")
+	if !exists {
+		t.Fatal("Expected conn-2 to be described")
+	}
+	if info.Principal != "alice" {
+		t.Errorf("Expected principal alice, got %q", info.Principal)
+	}
+	if len(info.Subscriptions) != 2 || info.Subscriptions[0] != "alerts" {
+		t.Errorf("Expected sorted subscriptions [alerts orders], got %v", info.Subscriptions)
+	}
+}
+
+func TestHub_DisconnectUser(t *testing.T) {
+	logger := &mockLogger{}
+	hub := New(logger)
+
+	ctx := context.Background()
+	hub.Start(ctx)
+	defer hub.Stop(ctx)
+
+	conn1 := &mockConnection{id: "conn-1", ctx: ctx}
+	conn2 := &mockConnection{id: "conn-2", ctx: ctx}
+	conn3 := &mockConnection{id: "conn-3", ctx: ctx}
+	hub.RegisterConnection(conn1)
+	hub.RegisterConnection(conn2)
+	hub.RegisterConnection(conn3)
+	time.Sleep(100 * time.Millisecond)
+
+	hub.BindUser("conn-1", "alice")
+	hub.BindUser("conn-2", "alice")
+
+	count := hub.DisconnectUser(ctx, "alice", DisconnectOptions{Reason: "logged out", RetryAfter: time.Minute})
+	if count != 2 {
+		t.Errorf("Expected 2 connections disconnected, got %d", count)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	for _, conn := range []*mockConnection{conn1, conn2} {
+		if !conn.IsClosed() {
+			t.Errorf("Connection %s should be closed", conn.id)
+		}
+		if received := conn.messages(); len(received) != 1 || received[0].Type != string(MessageTypeSystem) {
+			t.Errorf("Connection %s should have received a disconnect message, got %v", conn.id, received)
+		}
+	}
+	if conn3.IsClosed() {
+		t.Error("Connection of another user should stay open")
+	}
+	if hub.ConnectionCount() != 1 {
+		t.Errorf("Expected 1 remaining connection, got %d", hub.ConnectionCount())
+	}
+}
+
+func TestHub_TapFiltersTraffic(t *testing.T) {
+	logger := &mockLogger{}
+	hub := New(logger)
+
+	ctx := context.Background()
+	hub.Start(ctx)
+	defer hub.Stop(ctx)
+
+	hub.RegisterConnection(&mockConnection{id: "conn-1", ctx: ctx})
+	hub.RegisterConnection(&mockConnection{id: "conn-2", ctx: ctx})
+	time.Sleep(100 * time.Millisecond)
+	hub.BindUser("conn-1", "alice")
+
+	tap := hub.Tap(ctx, TapFilter{UserID: "alice"})
+	defer tap.Close()
+
+	hub.SendToUser(ctx, "bob", &Message{ID: "bob-msg", Type: "test"})
+	hub.Broadcast(ctx, &Message{ID: "broadcast-msg", Type: "test", Data: "secret"})
+	time.Sleep(100 * time.Millisecond)
+	tap.Close()
+
+	var deliveries []DeliveryEvent
+	for event := range tap.Events() {
+		switch payload := event.Payload.(type) {
+		case MessageEvent:
+			t.Errorf("Unexpected published event for target %s", payload.Target)
+		case DeliveryEvent:
+			deliveries = append(deliveries, payload)
+		}
+	}
+
+	// Only the broadcast delivery to alice's connection matches
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected 1 delivery event, got %d", len(deliveries))
+	}
+	if deliveries[0].ConnectionID != "conn-1" || deliveries[0].MessageID != "broadcast-msg" {
+		t.Errorf("Unexpected delivery %+v", deliveries[0])
+	}
+	if deliveries[0].Status != DeliveryStatusDelivered {
+		t.Errorf("Expected delivered status, got %s", deliveries[0].Status)
+	}
+}
+
+func TestTapFilter_SamplesByMessage(t *testing.T) {
+	filter := TapFilter{SampleRate: 0.5}
+
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("msg-%d", i)
+		published := TapEvent{Payload: MessageEvent{Target: "broadcast", Message: &Message{ID: id}}}
+		delivered := TapEvent{Payload: DeliveryEvent{Target: "broadcast", MessageID: id}}
+
+		if filter.Matches(published) != filter.Matches(delivered) {
+			t.Fatalf("Message %s and its delivery were sampled differently", id)
+		}
+		if filter.Matches(published) {
+			kept++
+		}
+	}
+
+	if kept < 400 || kept > 600 {
+		t.Errorf("Expected about half of the messages to be sampled, got %d", kept)
+	}
+}
@@ -0,0 +1,47 @@
+package hub
+
+import (
+	"time"
+
+	"go-notification-sse/internal/infrastructure/metrics"
+)
+
+// messageLabels returns bounded metric labels for a message; custom message
+// types are grouped to keep label cardinality low
+func messageLabels(transport string, message *Message) []string {
+	msgType := message.Type
+	if !IsValidMessageType(msgType) {
+		msgType = "custom"
+	}
+	return []string{transport, msgType, string(GetMessagePriority(message))}
+}
+
+// recordSent records a successful write of n bytes that started at start
+func (s *connStats) recordSent(message *Message, n int, start time.Time) {
+	s.messagesSent.Add(1)
+	s.bytesSent.Add(int64(n))
+
+	metrics.MessagesSent.WithLabelValues(messageLabels(s.transport, message)...).Inc()
+	metrics.BytesWritten.WithLabelValues(s.transport).Add(float64(n))
+	metrics.SendLatency.WithLabelValues(s.transport).Observe(time.Since(start).Seconds())
+}
+
+// recordFailed records a failed write
+func (s *connStats) recordFailed(message *Message) {
+	metrics.MessagesFailed.WithLabelValues(messageLabels(s.transport, message)...).Inc()
+}
+
+// recordDropped records a message that never reached the wire
+func (s *connStats) recordDropped(message *Message) {
+	s.dropped.Add(1)
+	metrics.MessagesDropped.WithLabelValues(messageLabels(s.transport, message)...).Inc()
+}
+
+// recordKeepAlive records a keep-alive attempt
+func (s *connStats) recordKeepAlive(err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	metrics.KeepAlives.WithLabelValues(s.transport, result).Inc()
+}
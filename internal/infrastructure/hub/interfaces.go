@@ -10,6 +10,7 @@ type Connection interface {
 	Close() error
 	IsClosed() bool
 	Context() context.Context
+	Info() ConnectionInfo
 }
 
 // Message represents a message to be sent through connections
@@ -0,0 +1,119 @@
+package hub
+
+import (
+	"sort"
+	"time"
+)
+
+// ConnectionFilter selects connections; zero values match everything
+type ConnectionFilter struct {
+	UserID     string        `json:"user"`
+	Topic      string        `json:"topic"`
+	Type       string        `json:"type"`
+	RemoteAddr string        `json:"ip"`
+	Tag        string        `json:"tag"`
+	MinIdle    time.Duration `json:"-"`
+}
+
+// IsEmpty reports whether the filter would match every connection, as it does
+// when MinIdle is its only criterion but is not positive
+func (f *ConnectionFilter) IsEmpty() bool {
+	criteria := *f
+	criteria.MinIdle = max(criteria.MinIdle, 0)
+	return criteria == ConnectionFilter{}
+}
+
+// Matches reports whether a connection snapshot satisfies the filter
+func (f *ConnectionFilter) Matches(info *ConnectionInfo, now time.Time) bool {
+	if f.UserID != "" && info.Principal != f.UserID {
+		return false
+	}
+	if f.Type != "" && info.Type != f.Type {
+		return false
+	}
+	if f.RemoteAddr != "" && info.RemoteAddr != f.RemoteAddr {
+		return false
+	}
+	if f.Tag != "" && !contains(info.Tags, f.Tag) {
+		return false
+	}
+	if f.MinIdle > 0 && info.IdleFor(now) < f.MinIdle {
+		return false
+	}
+	if f.Topic != "" && !contains(info.Subscriptions, f.Topic) {
+		return false
+	}
+	return true
+}
+
+// DescribeConnection returns a snapshot of a connection enriched with the
+// principal and subscriptions known to the hub
+func (h *Hub) DescribeConnection(connID string) (ConnectionInfo, bool) {
+	conn, exists := h.GetConnection(connID)
+	if !exists {
+		return ConnectionInfo{}, false
+	}
+	return h.describe(conn), true
+}
+
+// FindConnections returns snapshots of the connections matching filter, ordered
+// by connection time
+func (h *Hub) FindConnections(filter *ConnectionFilter) []ConnectionInfo {
+	now := time.Now()
+
+	infos := make([]ConnectionInfo, 0)
+	for _, conn := range h.GetConnections() {
+		info := h.describe(conn)
+		if filter.Matches(&info, now) {
+			infos = append(infos, info)
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].ConnectedAt.Equal(infos[j].ConnectedAt) {
+			return infos[i].ID < infos[j].ID
+		}
+		return infos[i].ConnectedAt.Before(infos[j].ConnectedAt)
+	})
+	return infos
+}
+
+// ConnectionSummary is the public view of a connection; ConnectionInfo, which
+// names clients and their subscriptions, is for the admin API only
+type ConnectionSummary struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Closed bool   `json:"closed"`
+}
+
+// SummarizeConnections returns the public view of the connections matching filter
+func (h *Hub) SummarizeConnections(filter *ConnectionFilter) []ConnectionSummary {
+	infos := h.FindConnections(filter)
+	summaries := make([]ConnectionSummary, len(infos))
+	for i, info := range infos {
+		summaries[i] = ConnectionSummary{ID: info.ID, Type: info.Type, Closed: info.Closed}
+	}
+	return summaries
+}
+
+func (h *Hub) describe(conn Connection) ConnectionInfo {
+	info := conn.Info()
+	if info.Principal == "" {
+		info.Principal = h.UserOf(conn.ID())
+	}
+
+	info.Subscriptions = h.TopicsOf(conn.ID())
+	sort.Strings(info.Subscriptions)
+	info.Tags = h.TagsOf(conn.ID())
+	sort.Strings(info.Tags)
+	return info
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package hub
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// journalSize is the number of recent messages kept for resuming clients
+const journalSize = 1024
+
+// JournalEntry is a published message and the cursor that identifies it
+type JournalEntry struct {
+	Cursor  uint64
+	Target  string
+	Message *Message
+}
+
+// journal is a ring buffer of recently published messages and their audience,
+// used to replay what a client missed while it was disconnected
+type journal struct {
+	mu      sync.RWMutex
+	entries []JournalEntry
+	next    int
+	cursor  uint64
+}
+
+func newJournal(size int) *journal {
+	return &journal{entries: make([]JournalEntry, 0, size)}
+}
+
+// append records a message and returns its cursor
+func (j *journal) append(target string, message *Message) uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.cursor++
+	entry := JournalEntry{Cursor: j.cursor, Target: target, Message: message}
+	if len(j.entries) < cap(j.entries) {
+		j.entries = append(j.entries, entry)
+	} else {
+		j.entries[j.next] = entry
+		j.next = (j.next + 1) % len(j.entries)
+	}
+	return j.cursor
+}
+
+// since returns the entries after cursor accepted by match, oldest first. complete
+// is false when entries after cursor have already been evicted.
+func (j *journal) since(cursor uint64, match func(target string) bool) (entries []JournalEntry, complete bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	ordered := append(slices.Clone(j.entries[j.next:]), j.entries[:j.next]...)
+	complete = cursor >= j.cursor || len(ordered) == 0 || ordered[0].Cursor <= cursor+1
+
+	for _, entry := range ordered {
+		if entry.Cursor > cursor && match(entry.Target) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, complete
+}
+
+// current returns the cursor of the most recent message
+func (j *journal) current() uint64 {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.cursor
+}
+
+type deliveryKey struct{}
+
+// delivery is how a message being sent was addressed
+type delivery struct {
+	cursor uint64
+	target string
+}
+
+// record journals a message and carries its cursor and target to connections in ctx
+func (h *Hub) record(ctx context.Context, target string, message *Message) context.Context {
+	return context.WithValue(ctx, deliveryKey{}, delivery{
+		cursor: h.journal.append(target, message),
+		target: target,
+	})
+}
+
+// CursorFromContext returns the journal cursor of the message being sent, for
+// connections that let clients resume from the last message they saw
+func CursorFromContext(ctx context.Context) (uint64, bool) {
+	d, ok := ctx.Value(deliveryKey{}).(delivery)
+	return d.cursor, ok
+}
+
+// TargetFromContext returns how the message being sent was addressed (see
+// MessageEvent.Target), for connections that demultiplex it, e.g. by topic
+func TargetFromContext(ctx context.Context) (string, bool) {
+	d, ok := ctx.Value(deliveryKey{}).(delivery)
+	return d.target, ok
+}
+
+// Cursor returns the cursor of the most recently published message
+func (h *Hub) Cursor() uint64 {
+	return h.journal.current()
+}
+
+// Replay returns the journaled messages after cursor that the connection's
+// current routes would have received. complete is false when some of them are
+// no longer retained.
+func (h *Hub) Replay(connID string, cursor uint64) (entries []JournalEntry, complete bool) {
+	userID := h.routes.userOf(connID)
+	topics := h.routes.topicsOf(connID)
+	patterns := h.routes.patternsOf(connID)
+
+	var connType string
+	if conn, exists := h.GetConnection(connID); exists {
+		connType = conn.Type()
+	}
+
+	return h.journal.since(cursor, func(target string) bool {
+		kind, value, _ := strings.Cut(target, ":")
+		switch kind {
+		case "broadcast":
+			return true
+		case "user":
+			return userID != "" && value == userID
+		case "topic":
+			return slices.Contains(topics, value) || slices.ContainsFunc(patterns, func(pattern string) bool {
+				return TopicMatches(pattern, value)
+			})
+		case "type":
+			return value == connType
+		case "connection":
+			return value == connID
+		}
+		return false
+	})
+}
@@ -0,0 +1,327 @@
+package hub
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/metrics"
+)
+
+// LongPollConnection implements the Connection interface for HTTP long-polling.
+// Messages are buffered in the session between polls; each poll returns the
+// messages after the client's cursor, which also acknowledges everything up to it.
+type LongPollConnection struct {
+	id    string
+	token string // secret the client presents on every request
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	closed   bool
+	closedMu sync.RWMutex
+
+	logger logger.Logger
+
+	// Buffered messages, ordered by sequence number
+	mu      sync.Mutex
+	pending []pendingMessage
+	nextSeq uint64
+	arrived chan struct{} // closed and replaced whenever a message is buffered
+
+	// Session expiry: the session is closed when no poll is in progress and the
+	// last one finished more than sessionTimeout ago
+	polling        int
+	lastPoll       time.Time
+	sessionTimeout time.Duration
+	bufferSize     int
+
+	stats *connStats
+}
+
+// pendingMessage is a buffered message awaiting delivery to a poll
+type pendingMessage struct {
+	seq       uint64
+	message   *Message
+	size      int
+	queuedAt  time.Time
+	delivered bool
+}
+
+// PollResult is the batch of messages returned by a poll
+type PollResult struct {
+	Cursor   uint64     `json:"cursor"`
+	Messages []*Message `json:"messages"`
+}
+
+// LongPollOption configures optional LongPollConnection behaviour
+type LongPollOption func(*LongPollConnection)
+
+// WithPollRequest records client metadata from the request that created the
+// session and carries its request and user IDs into the session's logs
+func WithPollRequest(r *http.Request) LongPollOption {
+	return func(c *LongPollConnection) {
+		c.stats = newConnStats(c.Type(), r)
+		c.ctx = logger.ContextWithConnectionID(logger.ContextWithFieldsFrom(c.ctx, r.Context()), c.id)
+		c.logger = c.logger.WithContext(c.ctx)
+	}
+}
+
+// WithSessionToken requires token on every request for the session; session IDs
+// are listed by the admin API, so they cannot serve as credentials
+func WithSessionToken(token string) LongPollOption {
+	return func(c *LongPollConnection) {
+		c.token = token
+	}
+}
+
+// WithSessionTimeout sets how long a session survives without being polled
+func WithSessionTimeout(timeout time.Duration) LongPollOption {
+	return func(c *LongPollConnection) {
+		if timeout > 0 {
+			c.sessionTimeout = timeout
+		}
+	}
+}
+
+// WithPollBufferSize sets how many undelivered messages a session keeps; the
+// oldest are dropped once it is full
+func WithPollBufferSize(size int) LongPollOption {
+	return func(c *LongPollConnection) {
+		if size > 0 {
+			c.bufferSize = size
+		}
+	}
+}
+
+// NewLongPollConnection creates a new long-polling session
+func NewLongPollConnection(id string, logger logger.Logger, opts ...LongPollOption) *LongPollConnection {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	conn := &LongPollConnection{
+		id:             id,
+		ctx:            ctx,
+		cancel:         cancel,
+		logger:         logger.WithField("connection_id", id),
+		nextSeq:        1,
+		arrived:        make(chan struct{}),
+		lastPoll:       time.Now(),
+		sessionTimeout: 60 * time.Second,
+		bufferSize:     256,
+		stats:          newConnStats("longpoll", nil),
+	}
+
+	for _, opt := range opts {
+		opt(conn)
+	}
+
+	go conn.expire()
+
+	return conn
+}
+
+// ID returns unique connection identifier
+func (c *LongPollConnection) ID() string {
+	return c.id
+}
+
+// Type returns the connection type
+func (c *LongPollConnection) Type() string {
+	return "longpoll"
+}
+
+// Authorize reports whether token is the session's token
+func (c *LongPollConnection) Authorize(token string) bool {
+	return c.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(c.token)) == 1
+}
+
+// Send buffers a message until the next poll
+func (c *LongPollConnection) Send(ctx context.Context, message *Message) error {
+	if c.IsClosed() {
+		c.stats.recordDropped(message)
+		return fmt.Errorf("long-poll session is closed")
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		c.stats.recordFailed(message)
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The session may have closed while the message was being encoded
+	if c.ctx.Err() != nil {
+		c.stats.recordDropped(message)
+		return fmt.Errorf("long-poll session is closed")
+	}
+
+	if len(c.pending) >= c.bufferSize {
+		oldest := c.pending[0]
+		c.pending = c.pending[1:]
+		c.stats.recordDropped(oldest.message)
+		metrics.QueueDepth.WithLabelValues(c.Type()).Dec()
+		c.logger.Warnf("Poll buffer full, dropped message %s", oldest.message.ID)
+	}
+
+	c.pending = append(c.pending, pendingMessage{
+		seq:      c.nextSeq,
+		message:  message,
+		size:     len(payload),
+		queuedAt: time.Now(),
+	})
+	c.nextSeq++
+	metrics.QueueDepth.WithLabelValues(c.Type()).Inc()
+
+	close(c.arrived)
+	c.arrived = make(chan struct{})
+	return nil
+}
+
+// Poll acknowledges the messages up to cursor and waits up to wait for newer
+// ones. It returns as soon as at least one message is available, or an empty
+// batch with the same cursor when the wait elapses.
+func (c *LongPollConnection) Poll(ctx context.Context, cursor uint64, wait time.Duration) (*PollResult, error) {
+	if c.IsClosed() {
+		return nil, fmt.Errorf("long-poll session is closed")
+	}
+
+	c.mu.Lock()
+	c.polling++
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.polling--
+		c.lastPoll = time.Now()
+		c.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		c.mu.Lock()
+		c.ack(cursor)
+		if len(c.pending) > 0 {
+			result := c.batch()
+			c.mu.Unlock()
+			return result, nil
+		}
+		arrived := c.arrived
+		c.mu.Unlock()
+
+		select {
+		case <-arrived:
+		case <-timer.C:
+			return &PollResult{Cursor: cursor, Messages: []*Message{}}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.ctx.Done():
+			return nil, fmt.Errorf("long-poll session is closed")
+		}
+	}
+}
+
+// ack drops buffered messages up to and including cursor; callers hold mu
+func (c *LongPollConnection) ack(cursor uint64) {
+	n := 0
+	for n < len(c.pending) && c.pending[n].seq <= cursor {
+		n++
+	}
+	if n > 0 {
+		c.pending = c.pending[n:]
+		metrics.QueueDepth.WithLabelValues(c.Type()).Sub(float64(n))
+	}
+}
+
+// batch returns every buffered message; callers hold mu. Messages stay buffered
+// until acknowledged so a lost response can be retried with the same cursor.
+func (c *LongPollConnection) batch() *PollResult {
+	result := &PollResult{Messages: make([]*Message, 0, len(c.pending))}
+	for i := range c.pending {
+		p := &c.pending[i]
+		result.Messages = append(result.Messages, p.message)
+		result.Cursor = p.seq
+		if !p.delivered {
+			p.delivered = true
+			c.stats.recordSent(p.message, p.size, p.queuedAt)
+		}
+	}
+	return result
+}
+
+// Close ends the session, discarding undelivered messages
+func (c *LongPollConnection) Close() error {
+	c.closedMu.Lock()
+	defer c.closedMu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+	c.cancel()
+
+	c.mu.Lock()
+	metrics.QueueDepth.WithLabelValues(c.Type()).Sub(float64(len(c.pending)))
+	c.pending = nil
+	c.mu.Unlock()
+
+	c.logger.Info("Long-poll session closed")
+	return nil
+}
+
+// IsClosed returns true if connection is closed
+func (c *LongPollConnection) IsClosed() bool {
+	c.closedMu.RLock()
+	defer c.closedMu.RUnlock()
+	return c.closed
+}
+
+// Context returns the connection's context (for cancellation)
+func (c *LongPollConnection) Context() context.Context {
+	return c.ctx
+}
+
+// Info returns a snapshot of the connection's metadata and statistics
+func (c *LongPollConnection) Info() ConnectionInfo {
+	c.mu.Lock()
+	lastActivity := c.lastPoll
+	if c.polling > 0 {
+		lastActivity = time.Now()
+	}
+	queued := len(c.pending)
+	c.mu.Unlock()
+
+	return c.stats.info(c.id, lastActivity, queued, c.IsClosed())
+}
+
+// expire closes the session once the client stops polling
+func (c *LongPollConnection) expire() {
+	ticker := time.NewTicker(c.sessionTimeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.mu.Lock()
+			idle := c.polling == 0 && time.Since(c.lastPoll) > c.sessionTimeout
+			c.mu.Unlock()
+
+			if idle {
+				c.logger.Infof("Long-poll session not polled for %s, closing", c.sessionTimeout)
+				c.Close()
+				return
+			}
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLongPollConnection_PollAndAck(t *testing.T) {
+	conn := NewLongPollConnection("poll-1", &mockLogger{})
+	defer conn.Close()
+
+	ctx := context.Background()
+
+	// An empty session times out with the same cursor
+	result, err := conn.Poll(ctx, 0, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if result.Cursor != 0 || len(result.Messages) != 0 {
+		t.Errorf("Expected empty batch at cursor 0, got %+v", result)
+	}
+
+	// A waiting poll returns as soon as a message arrives
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		conn.Send(ctx, &Message{ID: "m1", Type: "test"})
+		conn.Send(ctx, &Message{ID: "m2", Type: "test"})
+	}()
+	result, err = conn.Poll(ctx, 0, time.Second)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(result.Messages) == 0 || result.Messages[0].ID != "m1" {
+		t.Fatalf("Expected m1 first, got %+v", result.Messages)
+	}
+
+	// Re-polling with the old cursor redelivers; the new cursor acknowledges
+	time.Sleep(20 * time.Millisecond)
+	retry, _ := conn.Poll(ctx, 0, 0)
+	if len(retry.Messages) != 2 {
+		t.Errorf("Expected both messages to be redelivered, got %d", len(retry.Messages))
+	}
+	next, _ := conn.Poll(ctx, retry.Cursor, 10*time.Millisecond)
+	if len(next.Messages) != 0 || next.Cursor != retry.Cursor {
+		t.Errorf("Expected acknowledged messages to be gone, got %+v", next)
+	}
+	if depth := conn.Info().QueueDepth; depth != 0 {
+		t.Errorf("Expected empty buffer, got %d", depth)
+	}
+}
+
+func TestLongPollConnection_BufferAndExpiry(t *testing.T) {
+	conn := NewLongPollConnection("poll-2", &mockLogger{},
+		WithPollBufferSize(2),
+		WithSessionTimeout(40*time.Millisecond),
+	)
+
+	ctx := context.Background()
+	for _, id := range []string{"m1", "m2", "m3"} {
+		conn.Send(ctx, &Message{ID: id, Type: "test"})
+	}
+
+	result, _ := conn.Poll(ctx, 0, 0)
+	if len(result.Messages) != 2 || result.Messages[0].ID != "m2" {
+		t.Errorf("Expected the oldest message to be dropped, got %+v", result.Messages)
+	}
+	if dropped := conn.Info().Dropped; dropped != 1 {
+		t.Errorf("Expected 1 dropped message, got %d", dropped)
+	}
+
+	select {
+	case <-conn.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected session to expire without polls")
+	}
+	if err := conn.Send(ctx, &Message{ID: "m4"}); err == nil {
+		t.Error("Expected send to an expired session to fail")
+	}
+}
+
+func TestLongPollConnection_Authorize(t *testing.T) {
+	conn := NewLongPollConnection("poll-3", &mockLogger{}, WithSessionToken("secret"))
+	defer conn.Close()
+
+	if !conn.Authorize("secret") {
+		t.Error("Expected the session token to be accepted")
+	}
+	for _, token := range []string{"", "secre", "poll-3"} {
+		if conn.Authorize(token) {
+			t.Errorf("Expected %q to be rejected", token)
+		}
+	}
+
+	untokened := NewLongPollConnection("poll-4", &mockLogger{})
+	defer untokened.Close()
+	if untokened.Authorize("") {
+		t.Error("Expected a session without a token to reject every request")
+	}
+}
@@ -132,6 +132,22 @@ func SystemMessage(action string, data interface{}) *Message {
 		Build()
 }
 
+// DisconnectMessage creates the final system message sent to a connection that is
+// being disconnected, with an optional hint not to reconnect before retryAfter
+func DisconnectMessage(reason string, retryAfter time.Duration) *Message {
+	data := map[string]interface{}{
+		"reason": reason,
+	}
+	if retryAfter > 0 {
+		data["retry_after_seconds"] = int(retryAfter.Seconds())
+		data["reconnect_not_before"] = time.Now().Add(retryAfter).UTC().Format(time.RFC3339)
+	}
+
+	message := SystemMessage("disconnect", data)
+	message.Headers["priority"] = string(PriorityHigh)
+	return message
+}
+
 // UpdateMessage creates an update message
 func UpdateMessage(resource string, data interface{}) *Message {
 	return NewMessageBuilder().
@@ -0,0 +1,289 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/metrics"
+)
+
+// StreamedMessage is a message as written to an NDJSON stream; Cursor lets the
+// client resume after it
+type StreamedMessage struct {
+	*Message
+	Cursor uint64 `json:"cursor,omitempty"`
+}
+
+// streamedMessage is a queued message and its journal cursor
+type streamedMessage struct {
+	message *Message
+	cursor  uint64
+}
+
+// NDJSONConnection implements the Connection interface for newline-delimited JSON
+// over a chunked HTTP/1.1 response or an HTTP/2 stream. Send only queues; the
+// handler goroutine writes the queue in Serve so the response is never written
+// concurrently.
+type NDJSONConnection struct {
+	id      string
+	writer  http.ResponseWriter
+	flusher *http.ResponseController
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	closed   bool
+	closedMu sync.RWMutex
+
+	logger logger.Logger
+
+	send   chan streamedMessage
+	queued atomic.Int64
+
+	// Messages up to this cursor were replayed on resume; live copies are skipped
+	replayedUpTo atomic.Uint64
+
+	lastActivity time.Time
+	activityMu   sync.RWMutex
+
+	heartbeat    time.Duration
+	writeTimeout time.Duration
+
+	stats *connStats
+}
+
+// NDJSONOption configures optional NDJSONConnection behaviour
+type NDJSONOption func(*NDJSONConnection)
+
+// WithHeartbeat sets the interval between heartbeat (empty) lines
+func WithHeartbeat(interval time.Duration) NDJSONOption {
+	return func(c *NDJSONConnection) {
+		if interval > 0 {
+			c.heartbeat = interval
+		}
+	}
+}
+
+// NewNDJSONConnection creates a new NDJSON stream bound to the request's lifetime
+func NewNDJSONConnection(
+	id string,
+	w http.ResponseWriter,
+	r *http.Request,
+	log logger.Logger,
+	opts ...NDJSONOption,
+) *NDJSONConnection {
+	// The stream ends with the request; its logs carry the request's IDs
+	ctx, cancel := context.WithCancel(logger.ContextWithConnectionID(r.Context(), id))
+
+	conn := &NDJSONConnection{
+		id:           id,
+		writer:       w,
+		flusher:      http.NewResponseController(w),
+		ctx:          ctx,
+		cancel:       cancel,
+		logger:       log.WithField("connection_id", id).WithContext(ctx),
+		send:         make(chan streamedMessage, 256),
+		lastActivity: time.Now(),
+		heartbeat:    15 * time.Second,
+		writeTimeout: 10 * time.Second,
+		stats:        newConnStats("ndjson", r),
+	}
+
+	for _, opt := range opts {
+		opt(conn)
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	return conn
+}
+
+// ID returns unique connection identifier
+func (c *NDJSONConnection) ID() string {
+	return c.id
+}
+
+// Type returns the connection type
+func (c *NDJSONConnection) Type() string {
+	return "ndjson"
+}
+
+// Send queues a message for the stream
+func (c *NDJSONConnection) Send(ctx context.Context, message *Message) error {
+	if c.IsClosed() {
+		c.stats.recordDropped(message)
+		return fmt.Errorf("NDJSON stream is closed")
+	}
+
+	cursor, _ := CursorFromContext(ctx)
+
+	select {
+	case c.send <- streamedMessage{message: message, cursor: cursor}:
+		c.queued.Add(1)
+		metrics.QueueDepth.WithLabelValues(c.Type()).Inc()
+		return nil
+	case <-ctx.Done():
+		c.stats.recordDropped(message)
+		return ctx.Err()
+	case <-c.ctx.Done():
+		c.stats.recordDropped(message)
+		return fmt.Errorf("connection closed")
+	case <-time.After(5 * time.Second):
+		c.stats.recordDropped(message)
+		return fmt.Errorf("send timeout")
+	}
+}
+
+// Replay writes journaled messages before live delivery starts; live copies of
+// them are skipped. Call it before Serve.
+func (c *NDJSONConnection) Replay(entries []JournalEntry) error {
+	for _, entry := range entries {
+		if err := c.write(streamedMessage{message: entry.Message, cursor: entry.Cursor}); err != nil {
+			return err
+		}
+		c.replayedUpTo.Store(entry.Cursor)
+	}
+	return c.flush()
+}
+
+// Serve writes queued messages and heartbeats until the client goes away or the
+// connection is closed. It must be called from the handler goroutine.
+func (c *NDJSONConnection) Serve() {
+	ticker := time.NewTicker(c.heartbeat)
+	defer func() {
+		ticker.Stop()
+		c.Close()
+		metrics.QueueDepth.WithLabelValues(c.Type()).Sub(float64(c.queued.Swap(0)))
+	}()
+
+	for {
+		select {
+		case queued := <-c.send:
+			if err := c.dequeue(queued); err != nil {
+				c.logger.Errorf("Failed to write message: %v", err)
+				return
+			}
+			// Batch whatever else is queued into the same flush
+			for drained := false; !drained; {
+				select {
+				case queued := <-c.send:
+					if err := c.dequeue(queued); err != nil {
+						c.logger.Errorf("Failed to write message: %v", err)
+						return
+					}
+				default:
+					drained = true
+				}
+			}
+			if err := c.flush(); err != nil {
+				c.logger.Errorf("Failed to flush stream: %v", err)
+				return
+			}
+
+		case <-ticker.C:
+			_ = c.flusher.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+			_, err := c.writer.Write([]byte("\n"))
+			if err == nil {
+				err = c.flush()
+			}
+			c.stats.recordKeepAlive(err)
+			if err != nil {
+				c.logger.Errorf("Failed to send heartbeat: %v", err)
+				return
+			}
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// dequeue writes a queued message unless it was already replayed
+func (c *NDJSONConnection) dequeue(queued streamedMessage) error {
+	c.queued.Add(-1)
+	metrics.QueueDepth.WithLabelValues(c.Type()).Dec()
+
+	if queued.cursor != 0 && queued.cursor <= c.replayedUpTo.Load() {
+		return nil
+	}
+	return c.write(queued)
+}
+
+// write encodes one message as a line
+func (c *NDJSONConnection) write(queued streamedMessage) error {
+	start := time.Now()
+
+	line, err := json.Marshal(StreamedMessage{Message: queued.message, Cursor: queued.cursor})
+	if err != nil {
+		c.stats.recordFailed(queued.message)
+		c.logger.Errorf("Failed to marshal message: %v", err)
+		return nil
+	}
+	line = append(line, '\n')
+
+	_ = c.flusher.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	if _, err := c.writer.Write(line); err != nil {
+		c.stats.recordFailed(queued.message)
+		return err
+	}
+
+	c.stats.recordSent(queued.message, len(line), start)
+	c.updateActivity()
+	return nil
+}
+
+func (c *NDJSONConnection) flush() error {
+	return c.flusher.Flush()
+}
+
+// Close ends the stream; Serve returns once it notices
+func (c *NDJSONConnection) Close() error {
+	c.closedMu.Lock()
+	defer c.closedMu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+	c.cancel()
+
+	c.logger.Info("NDJSON stream closed")
+	return nil
+}
+
+// IsClosed returns true if connection is closed
+func (c *NDJSONConnection) IsClosed() bool {
+	c.closedMu.RLock()
+	defer c.closedMu.RUnlock()
+	return c.closed
+}
+
+// Context returns the connection's context (for cancellation)
+func (c *NDJSONConnection) Context() context.Context {
+	return c.ctx
+}
+
+// Info returns a snapshot of the connection's metadata and statistics
+func (c *NDJSONConnection) Info() ConnectionInfo {
+	c.activityMu.RLock()
+	lastActivity := c.lastActivity
+	c.activityMu.RUnlock()
+
+	return c.stats.info(c.id, lastActivity, int(c.queued.Load()), c.IsClosed())
+}
+
+// updateActivity updates the last activity timestamp
+func (c *NDJSONConnection) updateActivity() {
+	c.activityMu.Lock()
+	c.lastActivity = time.Now()
+	c.activityMu.Unlock()
+}
@@ -0,0 +1,126 @@
+package hub
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHub_ReplayFollowsRoutes(t *testing.T) {
+	hub := New(&mockLogger{})
+
+	ctx := context.Background()
+	hub.Start(ctx)
+	defer hub.Stop(ctx)
+
+	hub.RegisterConnection(&mockConnection{id: "conn-1", ctx: ctx})
+	time.Sleep(100 * time.Millisecond)
+
+	start := hub.Cursor()
+	hub.PublishToTopic(ctx, "orders", &Message{ID: "orders-1", Type: "test"})
+	hub.PublishToTopic(ctx, "billing", &Message{ID: "billing-1", Type: "test"})
+	hub.SendToUser(ctx, "alice", &Message{ID: "alice-1", Type: "test"})
+	hub.BroadcastToType(ctx, "mock", &Message{ID: "mock-1", Type: "test"})
+
+	// Routes bound after publishing still select what the connection would have received
+	hub.Subscribe("conn-1", "orders")
+	hub.BindUser("conn-1", "alice")
+
+	entries, complete := hub.Replay("conn-1", start)
+	if !complete {
+		t.Error("Expected a complete replay")
+	}
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.Message.ID)
+	}
+	if len(ids) != 3 || ids[0] != "orders-1" || ids[1] != "alice-1" || ids[2] != "mock-1" {
+		t.Errorf("Expected orders-1, alice-1 and mock-1, got %v", ids)
+	}
+
+	entries, _ = hub.Replay("conn-1", entries[0].Cursor)
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 entries after the first cursor, got %d", len(entries))
+	}
+}
+
+func TestJournal_ReportsEvictedCursors(t *testing.T) {
+	j := newJournal(2)
+	for _, id := range []string{"m1", "m2", "m3"} {
+		j.append("broadcast", &Message{ID: id})
+	}
+	all := func(string) bool { return true }
+
+	if entries, complete := j.since(0, all); complete || len(entries) != 2 {
+		t.Errorf("Expected 2 entries and an incomplete replay, got %d (complete %v)", len(entries), complete)
+	}
+	if entries, complete := j.since(1, all); !complete || len(entries) != 2 || entries[0].Message.ID != "m2" {
+		t.Errorf("Expected m2 and m3 in a complete replay, got %+v (complete %v)", entries, complete)
+	}
+}
+
+func TestNDJSONConnection_StreamsLines(t *testing.T) {
+	hub := New(&mockLogger{})
+
+	ctx := context.Background()
+	hub.Start(ctx)
+	defer hub.Stop(ctx)
+
+	// Published before the client connects; the stream resumes from before it
+	hub.Broadcast(ctx, &Message{ID: "replayed", Type: "test"})
+	time.Sleep(100 * time.Millisecond)
+
+	registered := make(chan *NDJSONConnection, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := NewNDJSONConnection("ndjson-1", w, r, &mockLogger{}, WithHeartbeat(50*time.Millisecond))
+		hub.RegisterConnection(conn)
+		entries, _ := hub.Replay(conn.ID(), 0)
+		conn.Replay(entries)
+		registered <- conn
+		conn.Serve()
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON content type, got %q", contentType)
+	}
+
+	<-registered
+	time.Sleep(100 * time.Millisecond)
+	hub.SendToConnection(ctx, "ndjson-1", &Message{ID: "live", Type: "test"})
+
+	var lines []StreamedMessage
+	heartbeats := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for len(lines) < 2 && scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			heartbeats++
+			continue
+		}
+		var line StreamedMessage
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Invalid line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) != 2 || lines[0].ID != "replayed" || lines[0].Cursor != 1 {
+		t.Fatalf("Expected the replayed message first, got %+v", lines)
+	}
+	if lines[1].ID != "live" || lines[1].Cursor != 2 {
+		t.Errorf("Expected the live message with a cursor, got %+v", lines[1])
+	}
+	if heartbeats == 0 {
+		t.Error("Expected heartbeat lines while idle")
+	}
+}
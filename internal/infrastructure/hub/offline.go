@@ -0,0 +1,30 @@
+package hub
+
+import "context"
+
+// OfflineFallback delivers user-targeted messages to users with no live
+// connection, e.g. through Web Push
+type OfflineFallback interface {
+	// DeliverOffline is called in its own goroutine with a context carrying the
+	// message's journal cursor and target
+	DeliverOffline(ctx context.Context, userID string, message *Message)
+}
+
+// SetOfflineFallback routes messages sent to offline users to fallback; nil
+// drops them as before
+func (h *Hub) SetOfflineFallback(fallback OfflineFallback) {
+	h.fallbackMu.Lock()
+	defer h.fallbackMu.Unlock()
+	h.fallback = fallback
+}
+
+// deliverOffline hands a message for an offline user to the fallback, if any
+func (h *Hub) deliverOffline(ctx context.Context, userID string, message *Message) {
+	h.fallbackMu.RLock()
+	fallback := h.fallback
+	h.fallbackMu.RUnlock()
+
+	if fallback != nil {
+		go fallback.DeliverOffline(ctx, userID, message)
+	}
+}
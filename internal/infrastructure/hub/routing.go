@@ -0,0 +1,420 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/logger"
+)
+
+// routingTable indexes connection IDs by user and topic
+type routingTable struct {
+	mu sync.RWMutex
+
+	users     map[string]map[string]struct{} // userID -> connIDs
+	connUsers map[string]string              // connID -> userID
+
+	topics     map[string]map[string]struct{} // topic -> connIDs
+	connTopics map[string]map[string]struct{} // connID -> topics
+
+	patterns     map[string]map[string]struct{} // topic pattern -> connIDs
+	connPatterns map[string]map[string]struct{} // connID -> topic patterns
+
+	connTags map[string]map[string]struct{} // connID -> tags
+}
+
+func newRoutingTable() *routingTable {
+	return &routingTable{
+		users:        make(map[string]map[string]struct{}),
+		connUsers:    make(map[string]string),
+		topics:       make(map[string]map[string]struct{}),
+		connTopics:   make(map[string]map[string]struct{}),
+		patterns:     make(map[string]map[string]struct{}),
+		connPatterns: make(map[string]map[string]struct{}),
+		connTags:     make(map[string]map[string]struct{}),
+	}
+}
+
+// reset drops all routes
+func (rt *routingTable) reset() {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.users = make(map[string]map[string]struct{})
+	rt.connUsers = make(map[string]string)
+	rt.topics = make(map[string]map[string]struct{})
+	rt.connTopics = make(map[string]map[string]struct{})
+	rt.patterns = make(map[string]map[string]struct{})
+	rt.connPatterns = make(map[string]map[string]struct{})
+	rt.connTags = make(map[string]map[string]struct{})
+}
+
+func (rt *routingTable) bindUser(connID, userID string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if previous, exists := rt.connUsers[connID]; exists {
+		removeFromIndex(rt.users, previous, connID)
+	}
+
+	rt.connUsers[connID] = userID
+	addToIndex(rt.users, userID, connID)
+}
+
+func (rt *routingTable) subscribe(connID string, topics ...string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	for _, topic := range topics {
+		addToIndex(rt.topics, topic, connID)
+		addToIndex(rt.connTopics, connID, topic)
+	}
+}
+
+func (rt *routingTable) subscribePattern(connID string, patterns ...string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	for _, pattern := range patterns {
+		addToIndex(rt.patterns, pattern, connID)
+		addToIndex(rt.connPatterns, connID, pattern)
+	}
+}
+
+func (rt *routingTable) unsubscribePattern(connID string, patterns ...string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	for _, pattern := range patterns {
+		removeFromIndex(rt.patterns, pattern, connID)
+		removeFromIndex(rt.connPatterns, connID, pattern)
+	}
+}
+
+func (rt *routingTable) tag(connID string, tags ...string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	for _, tag := range tags {
+		addToIndex(rt.connTags, connID, tag)
+	}
+}
+
+func (rt *routingTable) unsubscribe(connID string, topics ...string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	for _, topic := range topics {
+		removeFromIndex(rt.topics, topic, connID)
+		removeFromIndex(rt.connTopics, connID, topic)
+	}
+}
+
+// remove drops every route of a connection
+func (rt *routingTable) remove(connID string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if userID, exists := rt.connUsers[connID]; exists {
+		removeFromIndex(rt.users, userID, connID)
+		delete(rt.connUsers, connID)
+	}
+
+	for topic := range rt.connTopics[connID] {
+		removeFromIndex(rt.topics, topic, connID)
+	}
+	delete(rt.connTopics, connID)
+
+	for pattern := range rt.connPatterns[connID] {
+		removeFromIndex(rt.patterns, pattern, connID)
+	}
+	delete(rt.connPatterns, connID)
+	delete(rt.connTags, connID)
+}
+
+func (rt *routingTable) userOf(connID string) string {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return rt.connUsers[connID]
+}
+
+func (rt *routingTable) topicsOf(connID string) []string {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return keysOf(rt.connTopics[connID])
+}
+
+func (rt *routingTable) tagsOf(connID string) []string {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return keysOf(rt.connTags[connID])
+}
+
+func (rt *routingTable) userConnIDs(userID string) []string {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return keysOf(rt.users[userID])
+}
+
+func (rt *routingTable) patternsOf(connID string) []string {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return keysOf(rt.connPatterns[connID])
+}
+
+// topicConnIDs returns the connections subscribed to a topic, directly or
+// through a matching pattern
+func (rt *routingTable) topicConnIDs(topic string) []string {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	if len(rt.patterns) == 0 {
+		return keysOf(rt.topics[topic])
+	}
+
+	ids := maps.Clone(rt.topics[topic])
+	for pattern, connIDs := range rt.patterns {
+		if !TopicMatches(pattern, topic) {
+			continue
+		}
+		if ids == nil {
+			ids = make(map[string]struct{}, len(connIDs))
+		}
+		maps.Copy(ids, connIDs)
+	}
+	return keysOf(ids)
+}
+
+// connIDs returns every connection ID that has at least one route
+func (rt *routingTable) connIDs() []string {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	seen := make(map[string]struct{}, len(rt.connUsers)+len(rt.connTopics))
+	for id := range rt.connUsers {
+		seen[id] = struct{}{}
+	}
+	for id := range rt.connTopics {
+		seen[id] = struct{}{}
+	}
+	for id := range rt.connPatterns {
+		seen[id] = struct{}{}
+	}
+	for id := range rt.connTags {
+		seen[id] = struct{}{}
+	}
+	return keysOf(seen)
+}
+
+func addToIndex(index map[string]map[string]struct{}, key, value string) {
+	set, exists := index[key]
+	if !exists {
+		set = make(map[string]struct{})
+		index[key] = set
+	}
+	set[value] = struct{}{}
+}
+
+func removeFromIndex(index map[string]map[string]struct{}, key, value string) {
+	set, exists := index[key]
+	if !exists {
+		return
+	}
+	delete(set, value)
+	if len(set) == 0 {
+		delete(index, key)
+	}
+}
+
+func keysOf(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// BindUser associates a connection with a user so it receives user-targeted messages
+func (h *Hub) BindUser(connID, userID string) {
+	if userID == "" {
+		return
+	}
+	h.routes.bindUser(connID, userID)
+}
+
+// Subscribe adds topic subscriptions to a connection
+func (h *Hub) Subscribe(connID string, topics ...string) {
+	h.routes.subscribe(connID, topics...)
+}
+
+// Unsubscribe removes topic subscriptions from a connection
+func (h *Hub) Unsubscribe(connID string, topics ...string) {
+	h.routes.unsubscribe(connID, topics...)
+}
+
+// SubscribePattern subscribes a connection to every topic matching a pattern.
+// Patterns are split into levels by "/"; "+" matches exactly one level and a
+// trailing "#" matches any number of remaining levels, as in MQTT.
+func (h *Hub) SubscribePattern(connID string, patterns ...string) {
+	h.routes.subscribePattern(connID, patterns...)
+}
+
+// UnsubscribePattern removes pattern subscriptions from a connection
+func (h *Hub) UnsubscribePattern(connID string, patterns ...string) {
+	h.routes.unsubscribePattern(connID, patterns...)
+}
+
+// TopicMatches reports whether a topic matches a pattern (see SubscribePattern).
+// Wildcards do not match topics starting with "$" at the first level.
+func TopicMatches(pattern, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(pattern, "+") || strings.HasPrefix(pattern, "#")) {
+		return false
+	}
+
+	for {
+		patternLevel, patternRest, patternMore := strings.Cut(pattern, "/")
+		if patternLevel == "#" && !patternMore {
+			return true
+		}
+
+		topicLevel, topicRest, topicMore := strings.Cut(topic, "/")
+		if patternLevel != "+" && patternLevel != topicLevel {
+			return false
+		}
+
+		switch {
+		case !patternMore && !topicMore:
+			return true
+		case !topicMore:
+			// "a/#" also matches "a"
+			return patternRest == "#"
+		case !patternMore:
+			return false
+		}
+		pattern, topic = patternRest, topicRest
+	}
+}
+
+// Tag attaches free-form labels to a connection, used to select connections
+// for administrative actions
+func (h *Hub) Tag(connID string, tags ...string) {
+	h.routes.tag(connID, tags...)
+}
+
+// TagsOf returns the labels attached to a connection
+func (h *Hub) TagsOf(connID string) []string {
+	return h.routes.tagsOf(connID)
+}
+
+// UserOf returns the user bound to a connection, if any
+func (h *Hub) UserOf(connID string) string {
+	return h.routes.userOf(connID)
+}
+
+// TopicsOf returns the topics a connection is subscribed to
+func (h *Hub) TopicsOf(connID string) []string {
+	return h.routes.topicsOf(connID)
+}
+
+// GetConnectionsByUser returns the active connections bound to a user
+func (h *Hub) GetConnectionsByUser(userID string) []Connection {
+	return h.resolve(h.routes.userConnIDs(userID))
+}
+
+// GetConnectionsByTopic returns the active connections subscribed to a topic
+func (h *Hub) GetConnectionsByTopic(topic string) []Connection {
+	return h.resolve(h.routes.topicConnIDs(topic))
+}
+
+// SendToUser sends a message to every connection of a user and returns the number of
+// targeted connections
+func (h *Hub) SendToUser(ctx context.Context, userID string, message *Message) (int, error) {
+	if !h.IsRunning() {
+		return 0, fmt.Errorf("hub is not running")
+	}
+
+	ctx, span := startPublishSpan(ctx, "hub.send_to_user", message)
+	defer span.End()
+
+	connections := h.GetConnectionsByUser(userID)
+	h.emitMessage("user:"+userID, len(connections), message)
+	deliveryCtx := h.deliver(ctx, "user:"+userID, connections, message)
+	if len(connections) == 0 {
+		h.deliverOffline(deliveryCtx, userID, message)
+	}
+
+	h.logger.WithContext(ctx).Infof("Sent message %s to %d connections of user %s", message.ID, len(connections), userID)
+	return len(connections), nil
+}
+
+// PublishToTopic sends a message to every connection subscribed to a topic and returns
+// the number of targeted connections
+func (h *Hub) PublishToTopic(ctx context.Context, topic string, message *Message) (int, error) {
+	if !h.IsRunning() {
+		return 0, fmt.Errorf("hub is not running")
+	}
+
+	ctx, span := startPublishSpan(ctx, "hub.publish_to_topic", message)
+	defer span.End()
+
+	connections := h.GetConnectionsByTopic(topic)
+	h.emitMessage("topic:"+topic, len(connections), message)
+	h.deliver(ctx, "topic:"+topic, connections, message)
+
+	h.logger.WithContext(ctx).Infof("Published message %s to %d connections on topic %s", message.ID, len(connections), topic)
+	return len(connections), nil
+}
+
+// deliver sends a message to each connection concurrently, unregistering failed connections.
+// target identifies the audience in delivery events (see MessageEvent.Target). The returned
+// context carries the message's journal cursor and outlives ctx.
+func (h *Hub) deliver(ctx context.Context, target string, connections []Connection, message *Message) context.Context {
+	ctx = h.record(context.WithoutCancel(ctx), target, message)
+
+	for _, conn := range connections {
+		go func(c Connection) {
+			sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+
+			start := time.Now()
+			sendCtx, span := startSendSpan(sendCtx, c, message)
+			err := c.Send(sendCtx, message)
+			endSpan(span, err)
+			h.emitDelivery(target, c, message, err, time.Since(start))
+
+			if err != nil {
+				h.logger.WithContext(logger.ContextWithFieldsFrom(sendCtx, c.Context())).
+					Errorf("Failed to send message to connection %s: %v", c.ID(), err)
+				h.UnregisterConnection(c.ID())
+			}
+		}(conn)
+	}
+	return ctx
+}
+
+// resolve maps connection IDs to active connections, skipping unknown IDs
+func (h *Hub) resolve(connIDs []string) []Connection {
+	h.connectionsMu.RLock()
+	defer h.connectionsMu.RUnlock()
+
+	connections := make([]Connection, 0, len(connIDs))
+	for _, id := range connIDs {
+		if conn, exists := h.connections[id]; exists {
+			connections = append(connections, conn)
+		}
+	}
+	return connections
+}
+
+// pruneRoutes drops routes for connections that are no longer registered
+func (h *Hub) pruneRoutes() {
+	for _, id := range h.routes.connIDs() {
+		if _, exists := h.GetConnection(id); !exists {
+			h.routes.remove(id)
+		}
+	}
+}
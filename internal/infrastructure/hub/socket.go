@@ -0,0 +1,392 @@
+package hub
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/metrics"
+)
+
+// SocketFraming is how JSON frames are delimited on a raw socket
+type SocketFraming string
+
+const (
+	// FramingLines writes one JSON document per line
+	FramingLines SocketFraming = "lines"
+
+	// FramingLengthPrefixed precedes each JSON document with its length as a
+	// 4-byte big-endian integer
+	FramingLengthPrefixed SocketFraming = "length"
+)
+
+// SocketMessage is a hub message as written to a socket connection
+type SocketMessage struct {
+	Type    string   `json:"type"` // always "message"
+	Target  string   `json:"target,omitempty"`
+	Cursor  uint64   `json:"cursor,omitempty"`
+	Message *Message `json:"message"`
+}
+
+// socketHeartbeat is written when nothing else was sent for a heartbeat interval
+var socketHeartbeat = map[string]string{"type": "heartbeat"}
+
+// SocketConnection implements the Connection interface for a raw TCP or Unix
+// socket carrying JSON frames. Send only queues; Serve writes the queue and
+// heartbeats, and WriteFrame lets the protocol handler reply to commands, so
+// writes are serialized under writeMu.
+type SocketConnection struct {
+	id     string
+	conn   net.Conn
+	reader *bufio.Reader
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	closed   bool
+	closedMu sync.RWMutex
+
+	logger logger.Logger
+
+	send   chan OutboundMessage
+	queued atomic.Int64
+
+	writeMu sync.Mutex
+
+	lastActivity time.Time
+	activityMu   sync.RWMutex
+
+	framing      SocketFraming
+	maxFrameSize int
+	heartbeat    time.Duration
+	idleTimeout  time.Duration
+	writeTimeout time.Duration
+
+	stats *connStats
+}
+
+// SocketOption configures optional SocketConnection behaviour
+type SocketOption func(*SocketConnection)
+
+// WithSocketFraming sets how frames are delimited (FramingLines by default)
+func WithSocketFraming(framing SocketFraming) SocketOption {
+	return func(c *SocketConnection) {
+		if framing != "" {
+			c.framing = framing
+		}
+	}
+}
+
+// WithSocketHeartbeat sets the interval between heartbeat frames
+func WithSocketHeartbeat(interval time.Duration) SocketOption {
+	return func(c *SocketConnection) {
+		if interval > 0 {
+			c.heartbeat = interval
+		}
+	}
+}
+
+// WithSocketIdleTimeout closes connections that send nothing for the timeout;
+// zero disables it
+func WithSocketIdleTimeout(timeout time.Duration) SocketOption {
+	return func(c *SocketConnection) {
+		c.idleTimeout = timeout
+	}
+}
+
+// WithSocketMaxFrameSize bounds the size of frames read from the client
+func WithSocketMaxFrameSize(size int) SocketOption {
+	return func(c *SocketConnection) {
+		if size > 0 {
+			c.maxFrameSize = size
+		}
+	}
+}
+
+// NewSocketConnection creates a connection over an accepted socket, bound to ctx
+func NewSocketConnection(
+	ctx context.Context,
+	id string,
+	conn net.Conn,
+	log logger.Logger,
+	opts ...SocketOption,
+) *SocketConnection {
+	ctx, cancel := context.WithCancel(logger.ContextWithConnectionID(ctx, id))
+
+	c := &SocketConnection{
+		id:           id,
+		conn:         conn,
+		reader:       bufio.NewReader(conn),
+		ctx:          ctx,
+		cancel:       cancel,
+		logger:       log.WithField("connection_id", id).WithContext(ctx),
+		send:         make(chan OutboundMessage, 256),
+		lastActivity: time.Now(),
+		framing:      FramingLines,
+		maxFrameSize: 1 << 20,
+		heartbeat:    30 * time.Second,
+		idleTimeout:  90 * time.Second,
+		writeTimeout: 10 * time.Second,
+		stats:        newConnStats("socket", nil),
+	}
+	c.stats.remoteAddr = conn.RemoteAddr().String()
+	if c.stats.remoteAddr == "" || c.stats.remoteAddr == "@" {
+		c.stats.remoteAddr = conn.RemoteAddr().Network()
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// SetClient records the client's self-reported name, shown as its user agent.
+// Call it before registering the connection.
+func (c *SocketConnection) SetClient(name string) {
+	c.stats.userAgent = name
+}
+
+// ID returns unique connection identifier
+func (c *SocketConnection) ID() string {
+	return c.id
+}
+
+// Type returns the connection type
+func (c *SocketConnection) Type() string {
+	return "socket"
+}
+
+// Send queues a message for the socket
+func (c *SocketConnection) Send(ctx context.Context, message *Message) error {
+	if c.IsClosed() {
+		c.stats.recordDropped(message)
+		return fmt.Errorf("socket connection is closed")
+	}
+
+	outbound := OutboundMessage{Message: message}
+	outbound.Cursor, _ = CursorFromContext(ctx)
+	outbound.Target, _ = TargetFromContext(ctx)
+
+	select {
+	case c.send <- outbound:
+		c.queued.Add(1)
+		metrics.QueueDepth.WithLabelValues(c.Type()).Inc()
+		return nil
+	case <-ctx.Done():
+		c.stats.recordDropped(message)
+		return ctx.Err()
+	case <-c.ctx.Done():
+		c.stats.recordDropped(message)
+		return fmt.Errorf("connection closed")
+	case <-time.After(5 * time.Second):
+		c.stats.recordDropped(message)
+		return fmt.Errorf("send timeout")
+	}
+}
+
+// Serve writes queued messages and heartbeats until the connection is closed or
+// a write fails
+func (c *SocketConnection) Serve() {
+	ticker := time.NewTicker(c.heartbeat)
+	defer func() {
+		ticker.Stop()
+		c.Close()
+	}()
+
+	for {
+		select {
+		case outbound := <-c.send:
+			c.queued.Add(-1)
+			metrics.QueueDepth.WithLabelValues(c.Type()).Dec()
+			if err := c.writeMessage(outbound); err != nil {
+				c.logger.Errorf("Failed to write message: %v", err)
+				return
+			}
+			ticker.Reset(c.heartbeat)
+
+		case <-ticker.C:
+			err := c.WriteFrame(socketHeartbeat)
+			c.stats.recordKeepAlive(err)
+			if err != nil {
+				c.logger.Errorf("Failed to send heartbeat: %v", err)
+				return
+			}
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// writeMessage writes one hub message
+func (c *SocketConnection) writeMessage(outbound OutboundMessage) error {
+	start := time.Now()
+
+	data, err := json.Marshal(SocketMessage{
+		Type:    "message",
+		Target:  outbound.Target,
+		Cursor:  outbound.Cursor,
+		Message: outbound.Message,
+	})
+	if err != nil {
+		c.stats.recordFailed(outbound.Message)
+		c.logger.Errorf("Failed to marshal message: %v", err)
+		return nil
+	}
+
+	n, err := c.write(data)
+	if err != nil {
+		c.stats.recordFailed(outbound.Message)
+		return err
+	}
+
+	c.stats.recordSent(outbound.Message, n, start)
+	c.updateActivity()
+	return nil
+}
+
+// WriteFrame writes v as a JSON frame
+func (c *SocketConnection) WriteFrame(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = c.write(data)
+	return err
+}
+
+// write frames data and writes it, returning the number of bytes written
+func (c *SocketConnection) write(data []byte) (int, error) {
+	var frame []byte
+	switch c.framing {
+	case FramingLengthPrefixed:
+		frame = binary.BigEndian.AppendUint32(make([]byte, 0, len(data)+4), uint32(len(data)))
+		frame = append(frame, data...)
+	default:
+		frame = append(data, '\n')
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	return c.conn.Write(frame)
+}
+
+// ReadFrame reads the next frame from the client, skipping empty keep-alive
+// frames. It fails when the client is idle for longer than the idle timeout.
+func (c *SocketConnection) ReadFrame() ([]byte, error) {
+	for {
+		if c.idleTimeout > 0 {
+			_ = c.conn.SetReadDeadline(time.Now().Add(c.idleTimeout))
+		}
+
+		var frame []byte
+		var err error
+		switch c.framing {
+		case FramingLengthPrefixed:
+			frame, err = c.readLengthPrefixed()
+		default:
+			frame, err = c.readLine()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		c.updateActivity()
+		if len(frame) > 0 {
+			return frame, nil
+		}
+	}
+}
+
+// readLine reads a line, without its line ending
+func (c *SocketConnection) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := c.reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > c.maxFrameSize+2 {
+			return nil, fmt.Errorf("frame exceeds %d bytes", c.maxFrameSize)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return bytes.TrimRight(line, "\r\n"), nil
+	}
+}
+
+// readLengthPrefixed reads a frame preceded by its 4-byte big-endian length
+func (c *SocketConnection) readLengthPrefixed() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > uint32(c.maxFrameSize) {
+		return nil, fmt.Errorf("frame of %d bytes exceeds %d bytes", size, c.maxFrameSize)
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(c.reader, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// Close closes the socket, discarding queued messages
+func (c *SocketConnection) Close() error {
+	c.closedMu.Lock()
+	defer c.closedMu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+	c.cancel()
+	metrics.QueueDepth.WithLabelValues(c.Type()).Sub(float64(c.queued.Swap(0)))
+
+	c.logger.Info("Socket connection closed")
+	return c.conn.Close()
+}
+
+// IsClosed returns true if connection is closed
+func (c *SocketConnection) IsClosed() bool {
+	c.closedMu.RLock()
+	defer c.closedMu.RUnlock()
+	return c.closed
+}
+
+// Context returns the connection's context (for cancellation)
+func (c *SocketConnection) Context() context.Context {
+	return c.ctx
+}
+
+// Info returns a snapshot of the connection's metadata and statistics
+func (c *SocketConnection) Info() ConnectionInfo {
+	c.activityMu.RLock()
+	lastActivity := c.lastActivity
+	c.activityMu.RUnlock()
+
+	return c.stats.info(c.id, lastActivity, int(c.queued.Load()), c.IsClosed())
+}
+
+// updateActivity updates the last activity timestamp
+func (c *SocketConnection) updateActivity() {
+	c.activityMu.Lock()
+	c.lastActivity = time.Now()
+	c.activityMu.Unlock()
+}
@@ -0,0 +1,181 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/metrics"
+)
+
+// SocketIOConnection implements the Connection interface for a Socket.IO
+// session. An Engine.IO session moves between transports (HTTP polling, then
+// usually WebSocket), so the connection only queues messages; the session
+// takes them with Next and writes them on whichever transport is current.
+type SocketIOConnection struct {
+	id string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	closed   bool
+	closedMu sync.RWMutex
+
+	logger logger.Logger
+
+	send   chan OutboundMessage
+	queued atomic.Int64
+
+	lastActivity time.Time
+	activityMu   sync.RWMutex
+
+	stats *connStats
+}
+
+// SocketIOOption configures optional SocketIOConnection behaviour
+type SocketIOOption func(*SocketIOConnection)
+
+// WithSocketIORequest records client metadata from the handshake request and
+// carries its request and user IDs into the session's logs
+func WithSocketIORequest(r *http.Request) SocketIOOption {
+	return func(c *SocketIOConnection) {
+		c.stats = newConnStats(c.Type(), r)
+		c.ctx = logger.ContextWithConnectionID(logger.ContextWithFieldsFrom(c.ctx, r.Context()), c.id)
+		c.logger = c.logger.WithContext(c.ctx)
+	}
+}
+
+// NewSocketIOConnection creates the connection of a Socket.IO session
+func NewSocketIOConnection(id string, logger logger.Logger, opts ...SocketIOOption) *SocketIOConnection {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	conn := &SocketIOConnection{
+		id:           id,
+		ctx:          ctx,
+		cancel:       cancel,
+		logger:       logger.WithField("connection_id", id),
+		send:         make(chan OutboundMessage, 256),
+		lastActivity: time.Now(),
+		stats:        newConnStats("socketio", nil),
+	}
+
+	for _, opt := range opts {
+		opt(conn)
+	}
+
+	return conn
+}
+
+// ID returns unique connection identifier
+func (c *SocketIOConnection) ID() string {
+	return c.id
+}
+
+// Type returns the connection type
+func (c *SocketIOConnection) Type() string {
+	return "socketio"
+}
+
+// Send queues a message for the session
+func (c *SocketIOConnection) Send(ctx context.Context, message *Message) error {
+	if c.IsClosed() {
+		c.stats.recordDropped(message)
+		return fmt.Errorf("Socket.IO session is closed")
+	}
+
+	outbound := OutboundMessage{Message: message}
+	outbound.Cursor, _ = CursorFromContext(ctx)
+	outbound.Target, _ = TargetFromContext(ctx)
+
+	select {
+	case c.send <- outbound:
+		c.queued.Add(1)
+		metrics.QueueDepth.WithLabelValues(c.Type()).Inc()
+		return nil
+	case <-ctx.Done():
+		c.stats.recordDropped(message)
+		return ctx.Err()
+	case <-c.ctx.Done():
+		c.stats.recordDropped(message)
+		return fmt.Errorf("connection closed")
+	case <-time.After(5 * time.Second):
+		c.stats.recordDropped(message)
+		return fmt.Errorf("send timeout")
+	}
+}
+
+// Next waits for the next queued message. It fails once ctx is done or the
+// connection is closed.
+func (c *SocketIOConnection) Next(ctx context.Context) (OutboundMessage, error) {
+	select {
+	case outbound := <-c.send:
+		c.queued.Add(-1)
+		metrics.QueueDepth.WithLabelValues(c.Type()).Dec()
+		return outbound, nil
+	case <-ctx.Done():
+		return OutboundMessage{}, ctx.Err()
+	case <-c.ctx.Done():
+		return OutboundMessage{}, fmt.Errorf("connection closed")
+	}
+}
+
+// RecordSent records that a message was handed to the transport as n bytes,
+// start being when the session took it from the queue
+func (c *SocketIOConnection) RecordSent(message *Message, n int, start time.Time) {
+	c.stats.recordSent(message, n, start)
+	c.Touch()
+}
+
+// RecordFailed records a message the session could not encode
+func (c *SocketIOConnection) RecordFailed(message *Message) {
+	c.stats.recordFailed(message)
+}
+
+// Touch records client activity, such as a poll or a heartbeat
+func (c *SocketIOConnection) Touch() {
+	c.activityMu.Lock()
+	c.lastActivity = time.Now()
+	c.activityMu.Unlock()
+}
+
+// Close ends the session, discarding queued messages
+func (c *SocketIOConnection) Close() error {
+	c.closedMu.Lock()
+	defer c.closedMu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+	c.cancel()
+	metrics.QueueDepth.WithLabelValues(c.Type()).Sub(float64(c.queued.Swap(0)))
+
+	c.logger.Info("Socket.IO session closed")
+	return nil
+}
+
+// IsClosed returns true if connection is closed
+func (c *SocketIOConnection) IsClosed() bool {
+	c.closedMu.RLock()
+	defer c.closedMu.RUnlock()
+	return c.closed
+}
+
+// Context returns the connection's context (for cancellation)
+func (c *SocketIOConnection) Context() context.Context {
+	return c.ctx
+}
+
+// Info returns a snapshot of the connection's metadata and statistics
+func (c *SocketIOConnection) Info() ConnectionInfo {
+	c.activityMu.RLock()
+	lastActivity := c.lastActivity
+	c.activityMu.RUnlock()
+
+	return c.stats.info(c.id, lastActivity, int(c.queued.Load()), c.IsClosed())
+}
@@ -0,0 +1,93 @@
+package hub
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ConnectionInfo is a point-in-time snapshot of a connection's metadata and statistics
+type ConnectionInfo struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	ConnectedAt   time.Time `json:"connected_at"`
+	RemoteAddr    string    `json:"remote_addr,omitempty"`
+	UserAgent     string    `json:"user_agent,omitempty"`
+	Principal     string    `json:"principal,omitempty"`
+	Subscriptions []string  `json:"subscriptions"`
+	Tags          []string  `json:"tags"`
+	MessagesSent  int64     `json:"messages_sent"`
+	BytesSent     int64     `json:"bytes_sent"`
+	LastActivity  time.Time `json:"last_activity"`
+	QueueDepth    int       `json:"queue_depth"`
+	Dropped       int64     `json:"dropped"`
+	Closed        bool      `json:"closed"`
+}
+
+// IdleFor returns how long the connection has been inactive at now
+func (i *ConnectionInfo) IdleFor(now time.Time) time.Duration {
+	return now.Sub(i.LastActivity)
+}
+
+// connStats tracks per-connection counters and mirrors them into Prometheus metrics
+type connStats struct {
+	transport   string
+	connectedAt time.Time
+	remoteAddr  string
+	userAgent   string
+
+	messagesSent atomic.Int64
+	bytesSent    atomic.Int64
+	dropped      atomic.Int64
+}
+
+func newConnStats(transport string, r *http.Request) *connStats {
+	stats := &connStats{
+		transport:   transport,
+		connectedAt: time.Now(),
+	}
+	if r != nil {
+		stats.remoteAddr = clientAddr(r)
+		stats.userAgent = r.UserAgent()
+	}
+	return stats
+}
+
+// info fills the statistics portion of a ConnectionInfo
+func (s *connStats) info(id string, lastActivity time.Time, queueDepth int, closed bool) ConnectionInfo {
+	return ConnectionInfo{
+		ID:           id,
+		Type:         s.transport,
+		ConnectedAt:  s.connectedAt,
+		RemoteAddr:   s.remoteAddr,
+		UserAgent:    s.userAgent,
+		MessagesSent: s.messagesSent.Load(),
+		BytesSent:    s.bytesSent.Load(),
+		LastActivity: lastActivity,
+		QueueDepth:   queueDepth,
+		Dropped:      s.dropped.Load(),
+		Closed:       closed,
+	}
+}
+
+type clientAddrKey struct{}
+
+// ContextWithClientAddr records the client address resolved by the HTTP layer,
+// which knows the trusted proxies whose forwarding headers may be believed
+func ContextWithClientAddr(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, clientAddrKey{}, addr)
+}
+
+// clientAddr returns the originating client address recorded by
+// ContextWithClientAddr, falling back to the peer address
+func clientAddr(r *http.Request) string {
+	if addr, ok := r.Context().Value(clientAddrKey{}).(string); ok && addr != "" {
+		return addr
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
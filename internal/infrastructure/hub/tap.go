@@ -0,0 +1,179 @@
+package hub
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"sync/atomic"
+
+	"go-notification-sse/internal/infrastructure/eventbus"
+	"go-notification-sse/internal/infrastructure/ratelimit"
+)
+
+const (
+	// defaultTapBuffer is the number of events buffered for a tap subscriber
+	defaultTapBuffer = 256
+
+	// sampleBuckets is the resolution of TapFilter.SampleRate
+	sampleBuckets = 10000
+)
+
+// TapFilter selects the traffic mirrored to a tap; zero values match everything
+type TapFilter struct {
+	Topic        string `json:"topic"`
+	UserID       string `json:"user"`
+	ConnectionID string `json:"connection"`
+	MessageType  string `json:"type"`
+
+	// SampleRate is the fraction of messages mirrored, in (0, 1]. Sampling is keyed
+	// on the message ID so a message and all of its deliveries are kept or dropped
+	// together. Zero means 1.
+	SampleRate float64 `json:"sample_rate"`
+
+	// MaxRate caps the number of events per second sent to the tap; zero means
+	// unlimited
+	MaxRate float64 `json:"max_rate"`
+
+	// IncludeData mirrors message payloads; by default they are redacted
+	IncludeData bool `json:"include_data"`
+}
+
+// TapEvent is a message or delivery outcome mirrored to a tap
+type TapEvent = eventbus.Event
+
+// Tap mirrors hub traffic matching a filter. Events that the consumer cannot
+// keep up with, or that exceed MaxRate, are dropped rather than slowing the hub.
+type Tap struct {
+	filter  TapFilter
+	sub     *eventbus.Subscription
+	events  chan TapEvent
+	limiter *ratelimit.Bucket
+	dropped atomic.Int64
+	cancel  context.CancelFunc
+}
+
+// Tap starts mirroring messages published through the hub and their delivery
+// outcomes. The tap runs until Close is called or ctx is cancelled.
+func (h *Hub) Tap(ctx context.Context, filter TapFilter) *Tap {
+	ctx, cancel := context.WithCancel(ctx)
+
+	t := &Tap{
+		filter: filter,
+		sub:    h.events.Subscribe(defaultTapBuffer, EventMessagePublished, EventMessageDelivered),
+		events: make(chan TapEvent, defaultTapBuffer),
+		cancel: cancel,
+	}
+	if filter.MaxRate > 0 {
+		t.limiter = ratelimit.NewBucket(filter.MaxRate, max(1, int(filter.MaxRate)))
+	}
+
+	go t.run(ctx)
+	return t
+}
+
+// Events returns the mirrored events; the channel is closed when the tap stops
+func (t *Tap) Events() <-chan TapEvent {
+	return t.events
+}
+
+// Dropped returns the number of matching events that were not delivered to the tap
+func (t *Tap) Dropped() int64 {
+	return t.dropped.Load() + t.sub.Dropped()
+}
+
+// Close stops the tap
+func (t *Tap) Close() {
+	t.cancel()
+}
+
+func (t *Tap) run(ctx context.Context) {
+	defer close(t.events)
+	defer t.sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-t.sub.Events():
+			if !ok {
+				return
+			}
+			if !t.filter.Matches(event) {
+				continue
+			}
+			if t.limiter != nil && !t.limiter.Take().Allowed {
+				t.dropped.Add(1)
+				continue
+			}
+			select {
+			case t.events <- t.redact(event):
+			default:
+				t.dropped.Add(1)
+			}
+		}
+	}
+}
+
+// redact strips the message payload unless the filter asks for it
+func (t *Tap) redact(event TapEvent) TapEvent {
+	if t.filter.IncludeData {
+		return event
+	}
+	if published, ok := event.Payload.(MessageEvent); ok && published.Message != nil {
+		message := *published.Message
+		message.Data = nil
+		published.Message = &message
+		event.Payload = published
+	}
+	return event
+}
+
+// Matches reports whether a hub event passes the filter and the sample
+func (f *TapFilter) Matches(event TapEvent) bool {
+	switch payload := event.Payload.(type) {
+	case MessageEvent:
+		if payload.Message == nil {
+			return false
+		}
+		return f.matchesMessage(payload.Message.ID, payload.Message.Type) &&
+			f.matchesTarget(payload.Target, "", "")
+	case DeliveryEvent:
+		return f.matchesMessage(payload.MessageID, payload.MessageType) &&
+			f.matchesTarget(payload.Target, payload.ConnectionID, payload.UserID)
+	default:
+		return false
+	}
+}
+
+func (f *TapFilter) matchesMessage(messageID, messageType string) bool {
+	if f.MessageType != "" && f.MessageType != messageType {
+		return false
+	}
+	return f.sampled(messageID)
+}
+
+// matchesTarget checks the topic, user and connection criteria against the audience
+// of a message and, for deliveries, the receiving connection and its user
+func (f *TapFilter) matchesTarget(target, connID, userID string) bool {
+	kind, value, _ := strings.Cut(target, ":")
+
+	if f.Topic != "" && !(kind == "topic" && value == f.Topic) {
+		return false
+	}
+	if f.UserID != "" && userID != f.UserID && !(kind == "user" && value == f.UserID) {
+		return false
+	}
+	if f.ConnectionID != "" && connID != f.ConnectionID && !(kind == "connection" && value == f.ConnectionID) {
+		return false
+	}
+	return true
+}
+
+func (f *TapFilter) sampled(messageID string) bool {
+	if f.SampleRate <= 0 || f.SampleRate >= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(messageID))
+	return float64(h.Sum32()%sampleBuckets) < f.SampleRate*sampleBuckets
+}
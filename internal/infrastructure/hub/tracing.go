@@ -0,0 +1,78 @@
+package hub
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer returns the hub's tracer from the global provider, looked up on each use
+// so that spans go to whichever provider is installed at the time
+func tracer() trace.Tracer {
+	return otel.Tracer("go-notification-sse/internal/infrastructure/hub")
+}
+
+// InjectTraceContext writes the W3C trace context of ctx into the message headers
+func InjectTraceContext(ctx context.Context, message *Message) {
+	if message.Headers == nil {
+		message.Headers = make(map[string]string)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(message.Headers))
+}
+
+// ExtractTraceContext returns ctx carrying the trace context found in the message headers
+func ExtractTraceContext(ctx context.Context, message *Message) context.Context {
+	if message.Headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(message.Headers))
+}
+
+// startPublishSpan starts the span for accepting a message into the hub and injects
+// it into the message headers so downstream spans join the same trace. If ctx
+// carries no span, the trace context already present in the message is continued.
+func startPublishSpan(ctx context.Context, name string, message *Message) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = ExtractTraceContext(ctx, message)
+	}
+
+	ctx, span := tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(messageAttributes(message)...),
+	)
+	InjectTraceContext(ctx, message)
+	return ctx, span
+}
+
+// startSendSpan starts the span for delivering a message to a single connection
+func startSendSpan(ctx context.Context, conn Connection, message *Message) (context.Context, trace.Span) {
+	attrs := append(messageAttributes(message),
+		attribute.String("connection.id", conn.ID()),
+		attribute.String("connection.type", conn.Type()),
+	)
+	return tracer().Start(ctx, "hub.send",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endSpan records err on span, if any, and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func messageAttributes(message *Message) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("message.id", message.ID),
+		attribute.String("message.type", message.Type),
+		attribute.String("message.priority", string(GetMessagePriority(message))),
+	}
+}
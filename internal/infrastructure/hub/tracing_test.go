@@ -0,0 +1,67 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestHub_BroadcastPropagatesTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	hub := New(&mockLogger{})
+	ctx := context.Background()
+	hub.Start(ctx)
+	defer hub.Stop(ctx)
+
+	conn := &mockConnection{id: "conn-1", ctx: ctx}
+	hub.RegisterConnection(conn)
+	time.Sleep(100 * time.Millisecond)
+
+	parentCtx, parent := provider.Tracer("test").Start(ctx, "publish-request")
+	message := &Message{ID: "traced-msg", Type: "test", Data: "hello"}
+	if err := hub.Broadcast(parentCtx, message); err != nil {
+		t.Fatalf("Failed to broadcast: %v", err)
+	}
+	parent.End()
+	time.Sleep(100 * time.Millisecond)
+
+	if message.Headers["traceparent"] == "" {
+		t.Fatal("Expected traceparent header to be injected into message")
+	}
+
+	delivered := conn.messages()
+	if len(delivered) != 1 {
+		t.Fatalf("Expected 1 delivered message, got %d", len(delivered))
+	}
+	received := ExtractTraceContext(context.Background(), delivered[0])
+	if got := trace.SpanContextFromContext(received).TraceID(); got != parent.SpanContext().TraceID() {
+		t.Errorf("Expected delivered message to carry trace %s, got %s", parent.SpanContext().TraceID(), got)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	for _, name := range []string{"hub.broadcast", "hub.fanout", "hub.send"} {
+		span, exists := spans[name]
+		if !exists {
+			t.Errorf("Expected span %s to be recorded", name)
+			continue
+		}
+		if span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("Span %s should belong to the publish trace", name)
+		}
+	}
+}
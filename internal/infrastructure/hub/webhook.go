@@ -0,0 +1,287 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/metrics"
+	"go-notification-sse/internal/infrastructure/webhook"
+)
+
+// WebhookPayload is the JSON body POSTed for each message
+type WebhookPayload struct {
+	SubscriptionID string   `json:"subscription_id"`
+	Target         string   `json:"target,omitempty"`
+	Cursor         uint64   `json:"cursor,omitempty"`
+	Message        *Message `json:"message"`
+}
+
+// webhookItem is a queued message, or a dead letter queued for redelivery
+type webhookItem struct {
+	outbound OutboundMessage
+	letter   *webhook.DeadLetter
+}
+
+// WebhookConnection implements the Connection interface for an outbound webhook
+// subscription: a server that wants messages POSTed to it. It has no client
+// socket and stays registered until closed; Serve delivers queued messages one
+// at a time through the sender, which retries, breaks the circuit and
+// dead-letters on its own.
+type WebhookConnection struct {
+	id     string
+	sender *webhook.Sender
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	closed   bool
+	closedMu sync.RWMutex
+
+	logger logger.Logger
+
+	send   chan webhookItem
+	queued atomic.Int64
+
+	// Message types to deliver; empty delivers every type
+	types   []string
+	typesMu sync.RWMutex
+
+	lastActivity time.Time
+	activityMu   sync.RWMutex
+
+	stats *connStats
+}
+
+// WebhookOption configures optional WebhookConnection behaviour
+type WebhookOption func(*WebhookConnection)
+
+// WithWebhookMessageTypes delivers only messages of the given types
+func WithWebhookMessageTypes(types ...string) WebhookOption {
+	return func(c *WebhookConnection) {
+		c.types = types
+	}
+}
+
+// NewWebhookConnection creates the connection of a webhook subscription
+func NewWebhookConnection(id string, sender *webhook.Sender, log logger.Logger, opts ...WebhookOption) *WebhookConnection {
+	ctx, cancel := context.WithCancel(logger.ContextWithConnectionID(context.Background(), id))
+
+	conn := &WebhookConnection{
+		id:           id,
+		sender:       sender,
+		ctx:          ctx,
+		cancel:       cancel,
+		logger:       log.WithField("connection_id", id).WithContext(ctx),
+		send:         make(chan webhookItem, 256),
+		lastActivity: time.Now(),
+		stats:        newConnStats("webhook", nil),
+	}
+
+	for _, opt := range opts {
+		opt(conn)
+	}
+
+	return conn
+}
+
+// ID returns unique connection identifier
+func (c *WebhookConnection) ID() string {
+	return c.id
+}
+
+// Type returns the connection type
+func (c *WebhookConnection) Type() string {
+	return "webhook"
+}
+
+// Sender returns the sender POSTing to the subscription's endpoint
+func (c *WebhookConnection) Sender() *webhook.Sender {
+	return c.sender
+}
+
+// SetMessageTypes changes the message types delivered; empty delivers every type
+func (c *WebhookConnection) SetMessageTypes(types []string) {
+	c.typesMu.Lock()
+	defer c.typesMu.Unlock()
+	c.types = types
+}
+
+// MessageTypes returns the message types delivered
+func (c *WebhookConnection) MessageTypes() []string {
+	c.typesMu.RLock()
+	defer c.typesMu.RUnlock()
+	return slices.Clone(c.types)
+}
+
+// Send queues a message for delivery, skipping types the subscription does not want
+func (c *WebhookConnection) Send(ctx context.Context, message *Message) error {
+	if c.IsClosed() {
+		c.stats.recordDropped(message)
+		return fmt.Errorf("webhook subscription is closed")
+	}
+
+	c.typesMu.RLock()
+	wanted := len(c.types) == 0 || slices.Contains(c.types, message.Type)
+	c.typesMu.RUnlock()
+	if !wanted {
+		return nil
+	}
+
+	outbound := OutboundMessage{Message: message}
+	outbound.Cursor, _ = CursorFromContext(ctx)
+	outbound.Target, _ = TargetFromContext(ctx)
+
+	return c.enqueue(ctx, webhookItem{outbound: outbound}, message)
+}
+
+// Redeliver queues the dead letters for another round of delivery attempts and
+// returns how many were queued; those that do not fit stay dead-lettered
+func (c *WebhookConnection) Redeliver(ctx context.Context) int {
+	letters := c.sender.DeadLetters().Take()
+	for i := range letters {
+		if c.enqueue(ctx, webhookItem{letter: &letters[i]}, nil) != nil {
+			for _, letter := range letters[i:] {
+				c.sender.DeadLetters().Restore(letter)
+			}
+			return i
+		}
+	}
+	return len(letters)
+}
+
+func (c *WebhookConnection) enqueue(ctx context.Context, item webhookItem, message *Message) error {
+	select {
+	case c.send <- item:
+		c.queued.Add(1)
+		metrics.QueueDepth.WithLabelValues(c.Type()).Inc()
+		return nil
+	case <-ctx.Done():
+		c.recordDropped(message)
+		return ctx.Err()
+	case <-c.ctx.Done():
+		c.recordDropped(message)
+		return fmt.Errorf("connection closed")
+	case <-time.After(5 * time.Second):
+		c.recordDropped(message)
+		return fmt.Errorf("send timeout")
+	}
+}
+
+func (c *WebhookConnection) recordDropped(message *Message) {
+	if message != nil {
+		c.stats.recordDropped(message)
+	}
+}
+
+// Serve delivers queued messages until the connection is closed
+func (c *WebhookConnection) Serve() {
+	defer c.Close()
+
+	for {
+		select {
+		case item := <-c.send:
+			c.queued.Add(-1)
+			metrics.QueueDepth.WithLabelValues(c.Type()).Dec()
+			c.deliver(item)
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// deliver POSTs one queued item
+func (c *WebhookConnection) deliver(item webhookItem) {
+	start := time.Now()
+
+	if item.letter != nil {
+		err := c.sender.Deliver(c.ctx, item.letter.MessageID, item.letter.Payload)
+		if err != nil && !errors.Is(err, webhook.ErrDeadLettered) {
+			// Closed mid-delivery; keep it for the next redelivery
+			c.sender.DeadLetters().Restore(*item.letter)
+		}
+		c.updateActivity()
+		return
+	}
+
+	message := item.outbound.Message
+	payload, err := json.Marshal(WebhookPayload{
+		SubscriptionID: c.id,
+		Target:         item.outbound.Target,
+		Cursor:         item.outbound.Cursor,
+		Message:        message,
+	})
+	if err != nil {
+		c.stats.recordFailed(message)
+		c.logger.Errorf("Failed to marshal message: %v", err)
+		return
+	}
+
+	switch err := c.sender.Deliver(c.ctx, message.ID, payload); {
+	case err == nil:
+		c.stats.recordSent(message, len(payload), start)
+	case errors.Is(err, webhook.ErrDeadLettered):
+		c.stats.recordFailed(message)
+		c.logger.Warnf("Webhook delivery of message %s failed: %v", message.ID, err)
+	default:
+		c.stats.recordDropped(message)
+	}
+	c.updateActivity()
+}
+
+// Close stops deliveries, discarding queued messages
+func (c *WebhookConnection) Close() error {
+	c.closedMu.Lock()
+	defer c.closedMu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+	c.cancel()
+	metrics.QueueDepth.WithLabelValues(c.Type()).Sub(float64(c.queued.Swap(0)))
+
+	c.logger.Info("Webhook subscription closed")
+	return nil
+}
+
+// IsClosed returns true if connection is closed
+func (c *WebhookConnection) IsClosed() bool {
+	c.closedMu.RLock()
+	defer c.closedMu.RUnlock()
+	return c.closed
+}
+
+// Context returns the connection's context (for cancellation)
+func (c *WebhookConnection) Context() context.Context {
+	return c.ctx
+}
+
+// Info returns a snapshot of the connection's metadata and statistics
+func (c *WebhookConnection) Info() ConnectionInfo {
+	c.activityMu.RLock()
+	lastActivity := c.lastActivity
+	c.activityMu.RUnlock()
+
+	info := c.stats.info(c.id, lastActivity, int(c.queued.Load()), c.IsClosed())
+	if endpoint, err := url.Parse(c.sender.Endpoint().URL); err == nil {
+		info.RemoteAddr = endpoint.Host
+	}
+	return info
+}
+
+// updateActivity updates the last activity timestamp
+func (c *WebhookConnection) updateActivity() {
+	c.activityMu.Lock()
+	c.lastActivity = time.Now()
+	c.activityMu.Unlock()
+}
@@ -0,0 +1,79 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/webhook"
+)
+
+func TestWebhookConnection_DeliversAndRedelivers(t *testing.T) {
+	hub := New(&mockLogger{})
+
+	ctx := context.Background()
+	hub.Start(ctx)
+	defer hub.Stop(ctx)
+
+	var healthy atomic.Bool
+	received := make(chan WebhookPayload, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	config := webhook.NewDefaultDeliveryConfig()
+	config.InitialBackoff = time.Millisecond
+	sender := webhook.NewSender(webhook.Endpoint{URL: server.URL}, config, server.Client())
+	conn := NewWebhookConnection("webhook-1", sender, &mockLogger{}, WithWebhookMessageTypes("order"))
+	hub.RegisterConnection(conn)
+	go conn.Serve()
+	defer conn.Close()
+	time.Sleep(100 * time.Millisecond)
+	hub.Subscribe("webhook-1", "orders")
+
+	// Rejected by the endpoint and dead-lettered
+	hub.PublishToTopic(ctx, "orders", &Message{ID: "order-1", Type: "order"})
+	deadline := time.Now().Add(2 * time.Second)
+	for sender.DeadLetters().Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if sender.DeadLetters().Len() != 1 {
+		t.Fatal("Expected order-1 to be dead-lettered")
+	}
+
+	healthy.Store(true)
+	if queued := conn.Redeliver(ctx); queued != 1 {
+		t.Fatalf("Expected 1 dead letter to be queued, got %d", queued)
+	}
+
+	// Filtered out by type
+	hub.PublishToTopic(ctx, "orders", &Message{ID: "chat-1", Type: "chat"})
+	hub.PublishToTopic(ctx, "orders", &Message{ID: "order-2", Type: "order"})
+
+	for _, want := range []string{"order-1", "order-2"} {
+		select {
+		case payload := <-received:
+			if payload.Message.ID != want || payload.SubscriptionID != "webhook-1" || payload.Target != "topic:orders" || payload.Cursor == 0 {
+				t.Errorf("Expected %s for webhook-1 on orders, got %+v", want, payload)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for %s", want)
+		}
+	}
+	if sender.DeadLetters().Len() != 0 {
+		t.Error("Expected the dead-letter queue to be empty after redelivery")
+	}
+}
@@ -0,0 +1,129 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/webpush"
+)
+
+// WebPushPayload is the JSON pushed to a browser's service worker. Messages too
+// large for a push are sent without their data and marked truncated; the page
+// can fetch them by resuming from the cursor once it reconnects.
+type WebPushPayload struct {
+	Target    string   `json:"target,omitempty"`
+	Cursor    uint64   `json:"cursor,omitempty"`
+	Truncated bool     `json:"truncated,omitempty"`
+	Message   *Message `json:"message"`
+}
+
+// WebPushFallback is an OfflineFallback pushing messages to the browsers a user
+// registered for Web Push. Subscriptions the push service reports gone are
+// removed.
+type WebPushFallback struct {
+	sender *webpush.Sender
+	store  *webpush.Store
+	logger logger.Logger
+
+	// Message types pushed; others are dropped as for any offline user
+	types []string
+
+	stats *connStats
+}
+
+// WebPushOption configures optional WebPushFallback behaviour
+type WebPushOption func(*WebPushFallback)
+
+// WithWebPushMessageTypes pushes only messages of the given types
+func WithWebPushMessageTypes(types ...string) WebPushOption {
+	return func(f *WebPushFallback) {
+		f.types = types
+	}
+}
+
+// NewWebPushFallback creates a fallback pushing notification messages
+func NewWebPushFallback(sender *webpush.Sender, store *webpush.Store, log logger.Logger, opts ...WebPushOption) *WebPushFallback {
+	f := &WebPushFallback{
+		sender: sender,
+		store:  store,
+		logger: log.WithField("component", "webpush"),
+		types:  []string{string(MessageTypeNotification)},
+		stats:  newConnStats("webpush", nil),
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f
+}
+
+// DeliverOffline pushes a message to each of the user's subscriptions
+func (f *WebPushFallback) DeliverOffline(ctx context.Context, userID string, message *Message) {
+	if !slices.Contains(f.types, message.Type) {
+		return
+	}
+	registrations := f.store.List(userID)
+	if len(registrations) == 0 {
+		return
+	}
+
+	log := f.logger.WithField("user_id", userID).WithContext(ctx)
+	payload, err := webPushPayload(ctx, message)
+	if err != nil {
+		f.stats.recordFailed(message)
+		log.Errorf("Failed to marshal message %s for Web Push: %v", message.ID, err)
+		return
+	}
+	opts := webpush.Options{Urgency: webPushUrgency(GetMessagePriority(message))}
+
+	for _, registration := range registrations {
+		start := time.Now()
+		err := f.sender.Send(ctx, registration.Subscription, payload, opts)
+		switch {
+		case err == nil:
+			f.stats.recordSent(message, len(payload), start)
+		case errors.Is(err, webpush.ErrSubscriptionGone):
+			f.stats.recordDropped(message)
+			f.store.Remove(userID, registration.Subscription.Endpoint)
+			log.Infof("Removed gone push subscription %s", registration.Subscription.Endpoint)
+		default:
+			f.stats.recordFailed(message)
+			log.Warnf("Web Push of message %s failed: %v", message.ID, err)
+		}
+	}
+}
+
+// webPushPayload encodes a message with its cursor and target, dropping its data
+// if it does not fit in a push
+func webPushPayload(ctx context.Context, message *Message) ([]byte, error) {
+	p := WebPushPayload{Message: message}
+	p.Cursor, _ = CursorFromContext(ctx)
+	p.Target, _ = TargetFromContext(ctx)
+
+	payload, err := json.Marshal(p)
+	if err != nil || len(payload) <= webpush.MaxPayloadSize {
+		return payload, err
+	}
+
+	stripped := *message
+	stripped.Data = nil
+	p.Message, p.Truncated = &stripped, true
+	return json.Marshal(p)
+}
+
+// webPushUrgency maps message priorities onto push urgencies
+func webPushUrgency(priority MessagePriority) webpush.Urgency {
+	switch priority {
+	case PriorityLow:
+		return webpush.UrgencyLow
+	case PriorityHigh, PriorityCritical:
+		return webpush.UrgencyHigh
+	default:
+		return webpush.UrgencyNormal
+	}
+}
@@ -0,0 +1,108 @@
+package hub
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/webpush"
+)
+
+func TestWebPushFallback_PushesToOfflineUsers(t *testing.T) {
+	hub := New(&mockLogger{})
+
+	ctx := context.Background()
+	hub.Start(ctx)
+	defer hub.Stop(ctx)
+
+	// Stand-in push service
+	pushes := make(chan string, 4)
+	service := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "vapid t=") || r.Header.Get("Content-Encoding") != "aes128gcm" {
+			t.Errorf("Unexpected headers %v", r.Header)
+		}
+		pushes <- r.URL.Path
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer service.Close()
+
+	_, privateKey, _ := webpush.GenerateVAPIDKeys()
+	vapid, err := webpush.NewVAPID(privateKey, "mailto:ops@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	browser, _ := ecdh.P256().GenerateKey(rand.Reader)
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	keys := webpush.Keys{
+		P256DH: base64.RawURLEncoding.EncodeToString(browser.PublicKey().Bytes()),
+		Auth:   base64.RawURLEncoding.EncodeToString(auth),
+	}
+
+	store := webpush.NewStore(10)
+	store.Add("alice", webpush.Subscription{Endpoint: service.URL + "/alice", Keys: keys})
+	store.Add("alice", webpush.Subscription{Endpoint: service.URL + "/gone", Keys: keys})
+	store.Add("bob", webpush.Subscription{Endpoint: service.URL + "/bob", Keys: keys})
+	sender := webpush.NewSender(vapid, webpush.NewDefaultConfig(), service.Client())
+	hub.SetOfflineFallback(NewWebPushFallback(sender, store, &mockLogger{}))
+
+	// bob is online, so nothing is pushed to him
+	hub.RegisterConnection(&mockConnection{id: "conn-bob", ctx: ctx})
+	time.Sleep(100 * time.Millisecond)
+	hub.BindUser("conn-bob", "bob")
+	hub.SendToUser(ctx, "bob", NotificationMessage("Hi", "bob"))
+
+	// Only notifications are pushed
+	hub.SendToUser(ctx, "alice", AlertMessage("info", "not pushed"))
+	hub.SendToUser(ctx, "alice", NotificationMessage("Hi", "alice"))
+
+	received := map[string]bool{}
+	for range 2 {
+		select {
+		case path := <-pushes:
+			received[path] = true
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for pushes")
+		}
+	}
+	if !received["/alice"] || !received["/gone"] {
+		t.Errorf("Expected pushes to both of alice's browsers, got %v", received)
+	}
+	select {
+	case path := <-pushes:
+		t.Errorf("Unexpected push to %s", path)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if registrations := store.List("alice"); len(registrations) != 1 || registrations[0].Subscription.Endpoint != service.URL+"/alice" {
+		t.Errorf("Expected the gone subscription to be removed, got %+v", registrations)
+	}
+}
+
+func TestWebPushPayload_TruncatesLargeMessages(t *testing.T) {
+	ctx := context.WithValue(context.Background(), deliveryKey{}, delivery{cursor: 7, target: "user:alice"})
+
+	payload, err := webPushPayload(ctx, NotificationMessage("Hi", strings.Repeat("x", webpush.MaxPayloadSize)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded WebPushPayload
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload) > webpush.MaxPayloadSize || !decoded.Truncated || decoded.Message.Data != nil ||
+		decoded.Cursor != 7 || decoded.Target != "user:alice" || decoded.Message.Type != "notification" {
+		t.Errorf("Expected a truncated payload with cursor and target, got %s", payload)
+	}
+}
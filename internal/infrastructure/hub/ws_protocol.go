@@ -0,0 +1,140 @@
+package hub
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocketProtocol implements a negotiated WebSocket subprotocol on top of a
+// WebSocketConnection. Without one, hub messages are written as JSON text frames
+// and text frames from the client are echoed.
+type WebSocketProtocol interface {
+	// Open is called once before the connection starts reading and writing
+	Open(conn *WebSocketConnection)
+
+	// HandleFrame processes a frame from the client. Returning an error closes
+	// the connection; protocols that need a specific close code call
+	// CloseWithCode themselves.
+	HandleFrame(conn *WebSocketConnection, messageType int, data []byte) error
+
+	// Encode converts a hub message into the frames written to the client. No
+	// frames skips the message, e.g. when no subscription of the client matches.
+	Encode(conn *WebSocketConnection, message OutboundMessage) ([]WebSocketFrame, error)
+
+	// Close is called once when the connection closes
+	Close(conn *WebSocketConnection)
+}
+
+// OutboundMessage is a hub message queued for a WebSocket connection along with
+// how it was addressed
+type OutboundMessage struct {
+	Message *Message
+	Target  string // see MessageEvent.Target; empty for connection-local messages
+	Cursor  uint64
+}
+
+// WebSocketFrame is a frame written by a WebSocketProtocol
+type WebSocketFrame struct {
+	Type int // websocket.TextMessage or websocket.BinaryMessage
+	Data []byte
+}
+
+// TextFrame creates a text frame
+func TextFrame(data []byte) WebSocketFrame {
+	return WebSocketFrame{Type: websocket.TextMessage, Data: data}
+}
+
+// BinaryFrame creates a binary frame
+func BinaryFrame(data []byte) WebSocketFrame {
+	return WebSocketFrame{Type: websocket.BinaryMessage, Data: data}
+}
+
+// wsOutbound is an entry of a WebSocket connection's write queue: either a hub
+// message or a frame written by the connection's protocol
+type wsOutbound struct {
+	message OutboundMessage
+	frame   *WebSocketFrame
+}
+
+// WithProtocol runs a subprotocol on the connection
+func WithProtocol(protocol WebSocketProtocol) WebSocketOption {
+	return func(c *WebSocketConnection) {
+		c.protocol = protocol
+	}
+}
+
+// WriteFrame queues a protocol frame behind any messages already queued
+func (c *WebSocketConnection) WriteFrame(ctx context.Context, frame WebSocketFrame) error {
+	return c.enqueue(ctx, wsOutbound{frame: &frame}, nil)
+}
+
+// CloseWithCode closes the connection with a WebSocket close code and reason
+func (c *WebSocketConnection) CloseWithCode(code int, reason string) error {
+	return c.closeWithCode(code, reason)
+}
+
+// TopicRefs subscribes a connection to topics on behalf of a protocol's
+// subscriptions, counting references so that a topic stays subscribed while any
+// subscription still uses it. Topics the connection was already subscribed to,
+// e.g. from the upgrade request, are left alone.
+type TopicRefs struct {
+	hub    *Hub
+	connID string
+
+	mu    sync.Mutex
+	refs  map[string]int
+	owned map[string]bool
+}
+
+// NewTopicRefs creates the topic references of a connection
+func NewTopicRefs(h *Hub, connID string) *TopicRefs {
+	return &TopicRefs{
+		hub:    h,
+		connID: connID,
+		refs:   make(map[string]int),
+		owned:  make(map[string]bool),
+	}
+}
+
+// Acquire adds a reference to each topic, subscribing the connection as needed
+func (t *TopicRefs) Acquire(topics ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var subscribe []string
+	current := t.hub.TopicsOf(t.connID)
+	for _, topic := range topics {
+		if t.refs[topic] == 0 && !slices.Contains(current, topic) {
+			t.owned[topic] = true
+			subscribe = append(subscribe, topic)
+		}
+		t.refs[topic]++
+	}
+	t.hub.Subscribe(t.connID, subscribe...)
+}
+
+// Release drops a reference to each topic, unsubscribing the connection from
+// topics it no longer needs
+func (t *TopicRefs) Release(topics ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var unsubscribe []string
+	for _, topic := range topics {
+		if t.refs[topic] == 0 {
+			continue
+		}
+		if t.refs[topic]--; t.refs[topic] > 0 {
+			continue
+		}
+		delete(t.refs, topic)
+		if t.owned[topic] {
+			delete(t.owned, topic)
+			unsubscribe = append(unsubscribe, topic)
+		}
+	}
+	t.hub.Unsubscribe(t.connID, unsubscribe...)
+}
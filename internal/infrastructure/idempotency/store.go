@@ -0,0 +1,97 @@
+package idempotency
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the processing state of an idempotency key
+type State int
+
+const (
+	StatePending State = iota
+	StateCompleted
+)
+
+// Store tracks idempotency keys so retried requests are processed at most once
+type Store interface {
+	// Reserve claims key for processing. It returns false and the current state
+	// if the key has already been claimed.
+	Reserve(key string) (State, bool)
+	// Complete marks a reserved key as successfully processed
+	Complete(key string)
+	// Release forgets a reserved key so that a retry can process it again
+	Release(key string)
+}
+
+type record struct {
+	state     State
+	expiresAt time.Time
+}
+
+// MemoryStore is an in-memory Store whose keys expire after a TTL
+type MemoryStore struct {
+	ttl time.Duration
+
+	records map[string]record
+	mu      sync.Mutex
+
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an in-memory store remembering keys for ttl
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		ttl:       ttl,
+		records:   make(map[string]record),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (s *MemoryStore) Reserve(key string) (State, bool) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	if existing, exists := s.records[key]; exists && now.Before(existing.expiresAt) {
+		return existing.state, false
+	}
+
+	s.records[key] = record{state: StatePending, expiresAt: now.Add(s.ttl)}
+	return StatePending, true
+}
+
+func (s *MemoryStore) Complete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[key] = record{state: StateCompleted, expiresAt: s.now().Add(s.ttl)}
+}
+
+func (s *MemoryStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+}
+
+// sweep drops expired keys at most once per TTL; callers must hold mu
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+
+	for key, rec := range s.records {
+		if !now.Before(rec.expiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Reserve(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	if _, reserved := store.Reserve("msg-1"); !reserved {
+		t.Fatal("Expected the first reservation to succeed")
+	}
+
+	// A retry while the first attempt is still processing
+	if state, reserved := store.Reserve("msg-1"); reserved || state != StatePending {
+		t.Errorf("Expected a pending conflict, got state %v (reserved %v)", state, reserved)
+	}
+
+	// A retry after processing succeeded is a duplicate
+	store.Complete("msg-1")
+	if state, reserved := store.Reserve("msg-1"); reserved || state != StateCompleted {
+		t.Errorf("Expected a completed duplicate, got state %v (reserved %v)", state, reserved)
+	}
+
+	// Keys are forgotten after the TTL
+	now = now.Add(time.Minute)
+	if _, reserved := store.Reserve("msg-1"); !reserved {
+		t.Error("Expected the key to be reservable after expiry")
+	}
+}
+
+func TestMemoryStore_ReleaseAllowsRetry(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+
+	store.Reserve("msg-1")
+	store.Release("msg-1")
+
+	if state, reserved := store.Reserve("msg-1"); !reserved || state != StatePending {
+		t.Errorf("Expected a released key to be reserved again, got state %v (reserved %v)", state, reserved)
+	}
+}
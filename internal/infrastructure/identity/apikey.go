@@ -0,0 +1,19 @@
+package identity
+
+import "crypto/subtle"
+
+// ValidAPIKey reports whether presented is one of keys. Every key is compared in
+// constant time so the response time does not reveal which, if any, matched.
+func ValidAPIKey(presented string, keys []string) bool {
+	if presented == "" {
+		return false
+	}
+
+	valid := false
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
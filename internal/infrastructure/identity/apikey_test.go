@@ -0,0 +1,21 @@
+package identity
+
+import "testing"
+
+func TestValidAPIKey(t *testing.T) {
+	keys := []string{"current", "previous"}
+
+	for _, presented := range keys {
+		if !ValidAPIKey(presented, keys) {
+			t.Errorf("Expected %q to be valid", presented)
+		}
+	}
+	for _, presented := range []string{"", "curren", "current ", "other"} {
+		if ValidAPIKey(presented, keys) {
+			t.Errorf("Expected %q to be invalid", presented)
+		}
+	}
+	if ValidAPIKey("", []string{""}) {
+		t.Error("Expected an empty key never to match")
+	}
+}
@@ -0,0 +1,41 @@
+package identity
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokens_Verify(t *testing.T) {
+	tokens := NewTokens([]string{"new", "old"})
+	expires := time.Now().Add(time.Hour)
+
+	userID, err := tokens.Verify(tokens.Issue("alice", expires))
+	if err != nil || userID != "alice" {
+		t.Fatalf("Expected alice, got %q (%v)", userID, err)
+	}
+
+	// Tokens signed with a rotated-out secret still verify while it is listed
+	old := NewTokens([]string{"old"}).Issue("bob", expires)
+	if userID, err := tokens.Verify(old); err != nil || userID != "bob" {
+		t.Errorf("Expected bob, got %q (%v)", userID, err)
+	}
+	if _, err := NewTokens([]string{"other"}).Verify(old); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for an unknown secret, got %v", err)
+	}
+
+	// The user ID cannot be swapped without invalidating the signature
+	forged := NewTokens([]string{"new"}).Issue("mallory", expires)
+	if _, err := tokens.Verify(forged[:len("bWFsbG9yeQ")] + old[len("Ym9i"):]); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for a tampered token, got %v", err)
+	}
+
+	if _, err := tokens.Verify(tokens.Issue("alice", time.Now().Add(-time.Second))); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired, got %v", err)
+	}
+	for _, token := range []string{"", "alice", "YWxpY2U.1.", "YWxpY2U.x.sig"} {
+		if _, err := tokens.Verify(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Expected ErrInvalidToken for %q, got %v", token, err)
+		}
+	}
+}
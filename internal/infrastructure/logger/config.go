@@ -1,11 +1,21 @@
 package logger
 
 import (
+	"io"
 	"os"
 	"runtime"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Logger backends selectable via Config.Backend
+const (
+	BackendLogrus = "logrus"
+	BackendSlog   = "slog"
 )
 
 type Config struct {
+	Backend    string            `json:"backend"     yaml:"backend"` // logrus, slog
 	Level      Level             `json:"level"       yaml:"level"`
 	Format     string            `json:"format"      yaml:"format"` // json, text
 	Output     string            `json:"output"      yaml:"output"` // stdout, stderr, file
@@ -14,7 +24,16 @@ type Config struct {
 	MaxBackups int               `json:"max_backups" yaml:"max_backups"`
 	MaxAge     int               `json:"max_age"     yaml:"max_age"` // days
 	Compress   bool              `json:"compress"    yaml:"compress"`
-	Fields     map[string]string `json:"fields"      yaml:"fields"` // static fields for k8s/docker
+	Fields     map[string]string `json:"fields"      yaml:"fields"`     // static fields for k8s/docker
+	AddSource  bool              `json:"add_source"  yaml:"add_source"` // include caller file:line (slog only)
+
+	// ComponentLevels overrides Level for loggers scoped with a "component" or
+	// "handler" field (hub, sse, websocket, chat, http...)
+	ComponentLevels map[string]Level `json:"component_levels" yaml:"component_levels"`
+
+	// Sampling throttles repetitive debug and info messages; nil, the default,
+	// logs everything
+	Sampling *SamplingConfig `json:"sampling" yaml:"sampling"`
 }
 
 func GetDefaultFields() Fields {
@@ -75,6 +94,7 @@ func GetDefaultFields() Fields {
 
 func NewDefaultConfig() *Config {
 	config := &Config{
+		Backend:    BackendLogrus,
 		Level:      LevelInfo,
 		Format:     "console", // Default to console for development
 		Output:     "stdout",
@@ -95,3 +115,24 @@ func NewDefaultConfig() *Config {
 
 	return config
 }
+
+// newOutput opens the writer described by config (stdout, stderr or a rotated file)
+func newOutput(config *Config) io.Writer {
+	switch config.Output {
+	case "stderr":
+		return os.Stderr
+	case "file":
+		if config.FilePath != "" {
+			return &lumberjack.Logger{
+				Filename:   config.FilePath,
+				MaxSize:    config.MaxSize,
+				MaxBackups: config.MaxBackups,
+				MaxAge:     config.MaxAge,
+				Compress:   config.Compress,
+			}
+		}
+		return os.Stdout
+	default:
+		return os.Stdout
+	}
+}
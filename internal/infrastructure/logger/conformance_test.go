@@ -0,0 +1,314 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// backends lists every Logger implementation; each must pass the conformance suite
+var backends = map[string]func(*Config) Logger{
+	BackendLogrus: NewLogrusLogger,
+	BackendSlog:   NewSlogLogger,
+}
+
+func TestConformance(t *testing.T) {
+	for name, newLogger := range backends {
+		t.Run(name, func(t *testing.T) {
+			t.Run("Levels", func(t *testing.T) { testLevels(t, newLogger) })
+			t.Run("RuntimeLevel", func(t *testing.T) { testRuntimeLevel(t, newLogger) })
+			t.Run("Fields", func(t *testing.T) { testFields(t, newLogger) })
+			t.Run("StaticFields", func(t *testing.T) { testStaticFields(t, newLogger) })
+			t.Run("Context", func(t *testing.T) { testContext(t, newLogger) })
+			t.Run("SetOutput", func(t *testing.T) { testSetOutput(t, newLogger) })
+			t.Run("ComponentLevels", func(t *testing.T) { testComponentLevels(t, newLogger) })
+			t.Run("ChildControls", func(t *testing.T) { testChildControls(t, newLogger) })
+			t.Run("Sampling", func(t *testing.T) { testSampling(t, newLogger) })
+		})
+	}
+}
+
+func newTestLogger(newLogger func(*Config) Logger, cfg *Config) (Logger, *bytes.Buffer) {
+	cfg.Format = "json"
+	buf := &bytes.Buffer{}
+	log := newLogger(cfg)
+	log.SetOutput(buf)
+	return log, buf
+}
+
+// decodeEntries parses one JSON log entry per line
+func decodeEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Invalid JSON log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func testLevels(t *testing.T, newLogger func(*Config) Logger) {
+	log, buf := newTestLogger(newLogger, &Config{Level: LevelInfo})
+
+	log.Debug("debug")
+	log.Info("info")
+	log.Warnf("warn %d", 1)
+	log.Errorf("error %s", "x")
+
+	entries := decodeEntries(t, buf)
+	expected := []struct{ level, message string }{
+		{"info", "info"},
+		{"warning", "warn 1"},
+		{"error", "error x"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d: %v", len(expected), len(entries), entries)
+	}
+	for i, want := range expected {
+		if entries[i]["level"] != want.level || entries[i]["message"] != want.message {
+			t.Errorf("Entry %d: expected %s %q, got %v", i, want.level, want.message, entries[i])
+		}
+		if _, ok := entries[i]["timestamp"]; !ok {
+			t.Errorf("Entry %d has no timestamp", i)
+		}
+	}
+}
+
+func testRuntimeLevel(t *testing.T, newLogger func(*Config) Logger) {
+	log, buf := newTestLogger(newLogger, &Config{Level: LevelWarn})
+
+	log.Info("hidden")
+	log.SetLevel(LevelDebug)
+	log.Debug("visible")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 || entries[0]["message"] != "visible" {
+		t.Errorf("Expected only the entry logged after SetLevel, got %v", entries)
+	}
+}
+
+func testFields(t *testing.T, newLogger func(*Config) Logger) {
+	log, buf := newTestLogger(newLogger, &Config{Level: LevelInfo})
+
+	child := log.WithField("component", "hub").WithFields(Fields{"count": 3})
+	child.Info("child")
+	log.Info("parent")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0]["component"] != "hub" || entries[0]["count"] != float64(3) {
+		t.Errorf("Child entry is missing fields: %v", entries[0])
+	}
+	if _, ok := entries[1]["component"]; ok {
+		t.Errorf("Parent entry should not carry child fields: %v", entries[1])
+	}
+}
+
+func testStaticFields(t *testing.T, newLogger func(*Config) Logger) {
+	log, buf := newTestLogger(newLogger, &Config{
+		Level:  LevelInfo,
+		Fields: map[string]string{"k8s_pod": "pod-1"},
+	})
+
+	log.Info("hello")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 || entries[0]["k8s_pod"] != "pod-1" {
+		t.Errorf("Expected static field on every entry, got %v", entries)
+	}
+}
+
+func testContext(t *testing.T, newLogger func(*Config) Logger) {
+	log, buf := newTestLogger(newLogger, &Config{Level: LevelInfo})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	ctx = ContextWithRequestID(ctx, "req-1")
+	ctx = ContextWithUserID(ctx, "alice")
+	ctx = ContextWithConnectionID(ctx, "conn-1")
+
+	log.WithField(FieldUserID, "explicit").WithContext(ctx).Info("hello")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	expected := map[string]string{
+		FieldRequestID:    "req-1",
+		FieldTraceID:      traceID.String(),
+		FieldSpanID:       spanID.String(),
+		FieldConnectionID: "conn-1",
+		FieldUserID:       "explicit", // explicit fields win over the context
+	}
+	for key, value := range expected {
+		if entries[0][key] != value {
+			t.Errorf("Expected %s=%q, got %v", key, value, entries[0][key])
+		}
+	}
+}
+
+func testSetOutput(t *testing.T, newLogger func(*Config) Logger) {
+	log, buf := newTestLogger(newLogger, &Config{Level: LevelInfo})
+
+	log.Info("first")
+	other := &bytes.Buffer{}
+	log.SetOutput(other)
+	log.Info("second")
+
+	if entries := decodeEntries(t, buf); len(entries) != 1 {
+		t.Errorf("Expected 1 entry in the original output, got %d", len(entries))
+	}
+	if entries := decodeEntries(t, other); len(entries) != 1 || entries[0]["message"] != "second" {
+		t.Errorf("Expected the second entry in the new output, got %v", entries)
+	}
+}
+
+func testComponentLevels(t *testing.T, newLogger func(*Config) Logger) {
+	log, buf := newTestLogger(newLogger, &Config{
+		Level:           LevelInfo,
+		ComponentLevels: map[string]Level{"http": LevelError},
+	})
+
+	hub := log.WithField("component", "hub")
+	sse := log.WithField("handler", "sse")
+	http := log.WithField("component", "http")
+
+	hub.SetLevel(LevelDebug)
+	hub.WithField("connection_id", "conn-1").Debug("hub debug") // inherits the component
+	sse.Debug("sse debug")
+	http.Warn("http warn")
+	log.Debug("root debug")
+
+	levels, ok := LevelsOf(log)
+	if !ok {
+		t.Fatal("Expected logger to expose its levels")
+	}
+	levels.Set("sse", LevelDebug)
+	sse.Debug("sse debug after override")
+	levels.Reset("hub")
+	hub.Debug("hub debug after reset")
+
+	var messages []string
+	for _, entry := range decodeEntries(t, buf) {
+		messages = append(messages, entry["message"].(string))
+	}
+	expected := []string{"hub debug", "sse debug after override"}
+	if strings.Join(messages, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, messages)
+	}
+	if levels.Level("http") != LevelError || levels.Base() != LevelInfo {
+		t.Errorf("Unexpected levels: base %s, http %s", levels.Base(), levels.Level("http"))
+	}
+}
+
+func testChildControls(t *testing.T, newLogger func(*Config) Logger) {
+	log, _ := newTestLogger(newLogger, &Config{Level: LevelInfo})
+
+	child := log.WithFields(Fields{"request_id": "req-1"}).WithContext(context.Background())
+	buf := &bytes.Buffer{}
+	child.SetOutput(buf)
+	child.SetLevel(LevelWarn)
+
+	log.Info("hidden")
+	child.Warn("visible")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 || entries[0]["message"] != "visible" {
+		t.Errorf("Expected child SetOutput/SetLevel to apply to the root, got %v", entries)
+	}
+}
+
+func testSampling(t *testing.T, newLogger func(*Config) Logger) {
+	log, buf := newTestLogger(newLogger, &Config{
+		Level: LevelInfo,
+		Sampling: &SamplingConfig{
+			Interval:   50 * time.Millisecond,
+			Components: map[string]SamplingRule{"hub": {First: 1}},
+			Messages:   map[string]SamplingRule{"noisy %d": {First: 2, Thereafter: 3}},
+		},
+	})
+	hub := log.WithField("component", "hub")
+	http := log.WithField("component", ComponentHTTP)
+
+	for i := 1; i <= 8; i++ {
+		log.Infof("noisy %d", i)
+		hub.Info("hub message")
+		log.Info("quiet")
+		// Warnings, errors and access log lines are never sampled
+		hub.Warn("hub warning")
+		hub.Errorf("hub error %d", i)
+		http.Info("HTTP request")
+	}
+	// Summaries are written when the interval ends, whether or not the
+	// message is logged again
+	time.Sleep(100 * time.Millisecond)
+	log.Infof("noisy %d", 9)
+
+	var messages []string
+	unsampled := map[string]int{}
+	summaries := map[string]float64{}
+	for _, entry := range decodeEntries(t, buf) {
+		message := entry["message"].(string)
+		if strings.HasPrefix(message, "Suppressed") {
+			summaries[message] = entry["suppressed"].(float64)
+			continue
+		}
+		switch {
+		case message == "hub warning" || message == "HTTP request" || strings.HasPrefix(message, "hub error"):
+			unsampled[strings.TrimRight(message, " 0123456789")]++
+		case message != "quiet":
+			messages = append(messages, message)
+		}
+	}
+
+	expected := []string{"noisy 1", "hub message", "noisy 2", "noisy 5", "noisy 8", "noisy 9"}
+	if strings.Join(messages, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, messages)
+	}
+	expectedUnsampled := map[string]int{"hub warning": 8, "hub error": 8, "HTTP request": 8}
+	if !maps.Equal(unsampled, expectedUnsampled) {
+		t.Errorf("Expected every warning, error and access log line, got %v", unsampled)
+	}
+	expectedSummaries := map[string]float64{
+		`Suppressed 4 messages like "noisy %d"`:    4,
+		`Suppressed 7 messages like "hub message"`: 7,
+	}
+	if !maps.Equal(summaries, expectedSummaries) {
+		t.Errorf("Expected summaries %v, got %v", expectedSummaries, summaries)
+	}
+}
+
+func TestSlogLogger_AddSource(t *testing.T) {
+	log, buf := newTestLogger(NewSlogLogger, &Config{Level: LevelInfo, AddSource: true})
+
+	log.WithField("component", "test").Infof("hello %s", "world")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	caller, _ := entries[0]["caller"].(map[string]any)
+	if file, _ := caller["file"].(string); !strings.HasSuffix(file, "conformance_test.go") {
+		t.Errorf("Expected caller in conformance_test.go, got %v", entries[0]["caller"])
+	}
+}
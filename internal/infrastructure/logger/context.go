@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey string
+
+const (
+	requestIDKey    contextKey = "request_id"
+	userIDKey       contextKey = "user_id"
+	connectionIDKey contextKey = "connection_id"
+)
+
+// Field names added to log entries from the context
+const (
+	FieldRequestID    = "request_id"
+	FieldTraceID      = "trace_id"
+	FieldSpanID       = "span_id"
+	FieldUserID       = "user_id"
+	FieldConnectionID = "connection_id"
+)
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// ContextWithUserID returns a copy of ctx carrying the user ID
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserIDFromContext returns the user ID stored in ctx, if any
+func UserIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey).(string)
+	return userID
+}
+
+// ContextWithConnectionID returns a copy of ctx carrying the hub connection ID
+func ContextWithConnectionID(ctx context.Context, connID string) context.Context {
+	return context.WithValue(ctx, connectionIDKey, connID)
+}
+
+// ConnectionIDFromContext returns the hub connection ID stored in ctx, if any
+func ConnectionIDFromContext(ctx context.Context) string {
+	connID, _ := ctx.Value(connectionIDKey).(string)
+	return connID
+}
+
+// TraceFromContext returns the trace and span IDs of the span active in ctx, if any
+func TraceFromContext(ctx context.Context) (traceID, spanID string) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", ""
+	}
+	return sc.TraceID().String(), sc.SpanID().String()
+}
+
+// ContextWithFieldsFrom copies the request, user and connection IDs of src onto
+// dst, for work that outlives the request but should still be correlated with it
+func ContextWithFieldsFrom(dst, src context.Context) context.Context {
+	if requestID := RequestIDFromContext(src); requestID != "" {
+		dst = ContextWithRequestID(dst, requestID)
+	}
+	if userID := UserIDFromContext(src); userID != "" {
+		dst = ContextWithUserID(dst, userID)
+	}
+	if connID := ConnectionIDFromContext(src); connID != "" {
+		dst = ContextWithConnectionID(dst, connID)
+	}
+	return dst
+}
+
+// ContextFields returns the log fields carried by ctx
+func ContextFields(ctx context.Context) Fields {
+	fields := Fields{}
+	if ctx == nil {
+		return fields
+	}
+
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		fields[FieldRequestID] = requestID
+	}
+	if traceID, spanID := TraceFromContext(ctx); traceID != "" {
+		fields[FieldTraceID] = traceID
+		fields[FieldSpanID] = spanID
+	}
+	if userID := UserIDFromContext(ctx); userID != "" {
+		fields[FieldUserID] = userID
+	}
+	if connID := ConnectionIDFromContext(ctx); connID != "" {
+		fields[FieldConnectionID] = connID
+	}
+	return fields
+}
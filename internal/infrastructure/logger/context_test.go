@@ -0,0 +1,21 @@
+package logger
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextWithFieldsFrom(t *testing.T) {
+	src := ContextWithUserID(ContextWithRequestID(context.Background(), "req-1"), "alice")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dst := ContextWithFieldsFrom(ctx, src)
+	cancel()
+
+	if RequestIDFromContext(dst) != "req-1" || UserIDFromContext(dst) != "alice" {
+		t.Errorf("Expected fields to be copied, got %v", ContextFields(dst))
+	}
+	if dst.Err() == nil {
+		t.Error("Expected the destination context to keep its cancellation")
+	}
+}
@@ -0,0 +1,32 @@
+package logger
+
+import "github.com/sirupsen/logrus"
+
+// ContextHook is a logrus hook that adds the fields carried by an entry's context
+// (request, trace, span, user and connection IDs). Fields already set on the
+// entry take precedence.
+type ContextHook struct{}
+
+// NewContextHook creates a context hook
+func NewContextHook() *ContextHook {
+	return &ContextHook{}
+}
+
+// Levels returns the levels the hook fires for
+func (h *ContextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire adds the context fields to the entry
+func (h *ContextHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	for key, value := range ContextFields(entry.Context) {
+		if _, exists := entry.Data[key]; !exists {
+			entry.Data[key] = value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// componentKeys are the field names that scope a logger to a component; later
+// keys take precedence when a single WithFields call sets several
+var componentKeys = []string{"handler", "component"}
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+	LevelFatal: "fatal",
+}
+
+// String returns the lower-case name of the level
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel converts a level name (debug, info, warn, error, fatal) to a Level
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "warning" {
+		name = "warn"
+	}
+	for level, levelName := range levelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+// levelSnapshot is an immutable view of the configured levels
+type levelSnapshot struct {
+	base       Level
+	components map[string]Level
+	min        Level
+}
+
+// Levels holds the base log level and per-component overrides shared by a root
+// logger and every logger derived from it. Levels can be changed at runtime.
+type Levels struct {
+	mu       sync.Mutex // serialises writers; readers use the snapshot
+	snapshot atomic.Pointer[levelSnapshot]
+}
+
+// NewLevels creates a level set with the given base level and overrides
+func NewLevels(base Level, components map[string]Level) *Levels {
+	lv := &Levels{}
+	lv.store(base, maps.Clone(components))
+	return lv
+}
+
+// Base returns the level used by components without an override
+func (lv *Levels) Base() Level {
+	return lv.snapshot.Load().base
+}
+
+// Components returns a copy of the per-component overrides
+func (lv *Levels) Components() map[string]Level {
+	return maps.Clone(lv.snapshot.Load().components)
+}
+
+// Level returns the effective level of a component
+func (lv *Levels) Level(component string) Level {
+	s := lv.snapshot.Load()
+	if level, ok := s.components[component]; ok {
+		return level
+	}
+	return s.base
+}
+
+// Enabled reports whether a message at level should be logged for a component
+func (lv *Levels) Enabled(component string, level Level) bool {
+	return level >= lv.Level(component)
+}
+
+// SetBase changes the level of components without an override
+func (lv *Levels) SetBase(level Level) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	s := lv.snapshot.Load()
+	lv.store(level, s.components)
+}
+
+// Set overrides the level of a component; an empty component sets the base level
+func (lv *Levels) Set(component string, level Level) {
+	if component == "" {
+		lv.SetBase(level)
+		return
+	}
+
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	s := lv.snapshot.Load()
+	components := maps.Clone(s.components)
+	components[component] = level
+	lv.store(s.base, components)
+}
+
+// Reset removes the override of a component so it follows the base level again
+func (lv *Levels) Reset(component string) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	s := lv.snapshot.Load()
+	components := maps.Clone(s.components)
+	delete(components, component)
+	lv.store(s.base, components)
+}
+
+// min returns the most verbose level enabled for any component
+func (lv *Levels) min() Level {
+	return lv.snapshot.Load().min
+}
+
+func (lv *Levels) store(base Level, components map[string]Level) {
+	if components == nil {
+		components = map[string]Level{}
+	}
+
+	minLevel := base
+	for _, level := range components {
+		minLevel = min(minLevel, level)
+	}
+
+	lv.snapshot.Store(&levelSnapshot{
+		base:       base,
+		components: components,
+		min:        minLevel,
+	})
+}
+
+// LevelController is implemented by loggers whose levels can be changed at runtime
+type LevelController interface {
+	Levels() *Levels
+}
+
+// LevelsOf returns the runtime level controls of a logger, if it has any
+func LevelsOf(l Logger) (*Levels, bool) {
+	controller, ok := l.(LevelController)
+	if !ok {
+		return nil, false
+	}
+	return controller.Levels(), true
+}
+
+// componentOf returns the component named by fields, or current if none is set
+func componentOf(current string, fields Fields) string {
+	for _, key := range componentKeys {
+		if value, ok := fields[key].(string); ok {
+			current = value
+		}
+	}
+	return current
+}
@@ -36,3 +36,13 @@ type Logger interface {
 	SetLevel(level Level)
 	SetOutput(output io.Writer)
 }
+
+// New creates a Logger using the backend selected in config (logrus by default)
+func New(config *Config) Logger {
+	switch config.Backend {
+	case BackendSlog:
+		return NewSlogLogger(config)
+	default:
+		return NewLogrusLogger(config)
+	}
+}
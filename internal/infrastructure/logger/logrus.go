@@ -3,35 +3,24 @@ package logger
 import (
 	"context"
 	"io"
-	"os"
 
 	"github.com/sirupsen/logrus"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 type logrusLogger struct {
-	logger *logrus.Logger
-	entry  *logrus.Entry
+	logger    *logrus.Logger
+	entry     *logrus.Entry
+	levels    *Levels
+	sampler   *sampler
+	component string
 }
 
 func NewLogrusLogger(config *Config) Logger {
 	logger := logrus.New()
 
-	// Set level
-	switch config.Level {
-	case LevelDebug:
-		logger.SetLevel(logrus.DebugLevel)
-	case LevelInfo:
-		logger.SetLevel(logrus.InfoLevel)
-	case LevelWarn:
-		logger.SetLevel(logrus.WarnLevel)
-	case LevelError:
-		logger.SetLevel(logrus.ErrorLevel)
-	case LevelFatal:
-		logger.SetLevel(logrus.FatalLevel)
-	default:
-		logger.SetLevel(logrus.InfoLevel)
-	}
+	// Levels are enforced per component by logrusLogger; logrus itself lets
+	// everything through
+	logger.SetLevel(logrus.DebugLevel)
 
 	// Set formatter
 	switch config.Format {
@@ -68,25 +57,10 @@ func NewLogrusLogger(config *Config) Logger {
 		})
 	}
 
-	// Set output
-	switch config.Output {
-	case "stderr":
-		logger.SetOutput(os.Stderr)
-	case "file":
-		if config.FilePath != "" {
-			logger.SetOutput(&lumberjack.Logger{
-				Filename:   config.FilePath,
-				MaxSize:    config.MaxSize,
-				MaxBackups: config.MaxBackups,
-				MaxAge:     config.MaxAge,
-				Compress:   config.Compress,
-			})
-		} else {
-			logger.SetOutput(os.Stdout)
-		}
-	default:
-		logger.SetOutput(os.Stdout)
-	}
+	logger.SetOutput(newOutput(config))
+
+	// Correlate entries with the request, trace and connection in their context
+	logger.AddHook(NewContextHook())
 
 	// Add static fields for container environments
 	fields := logrus.Fields{}
@@ -97,55 +71,126 @@ func NewLogrusLogger(config *Config) Logger {
 	baseEntry := logrus.NewEntry(logger).WithFields(fields)
 
 	return &logrusLogger{
-		logger: logger,
-		entry:  baseEntry,
+		logger:  logger,
+		entry:   baseEntry,
+		levels:  NewLevels(config.Level, config.ComponentLevels),
+		sampler: newSampler(config.Sampling),
+	}
+}
+
+func (l *logrusLogger) Debug(msg string) {
+	if l.allow(LevelDebug, msg) {
+		l.entry.Debug(msg)
+	}
+}
+
+func (l *logrusLogger) Debugf(format string, args ...any) {
+	if l.allow(LevelDebug, format) {
+		l.entry.Debugf(format, args...)
+	}
+}
+
+func (l *logrusLogger) Info(msg string) {
+	if l.allow(LevelInfo, msg) {
+		l.entry.Info(msg)
+	}
+}
+
+func (l *logrusLogger) Infof(format string, args ...any) {
+	if l.allow(LevelInfo, format) {
+		l.entry.Infof(format, args...)
+	}
+}
+
+func (l *logrusLogger) Warn(msg string) {
+	if l.allow(LevelWarn, msg) {
+		l.entry.Warn(msg)
+	}
+}
+
+func (l *logrusLogger) Warnf(format string, args ...any) {
+	if l.allow(LevelWarn, format) {
+		l.entry.Warnf(format, args...)
+	}
+}
+
+func (l *logrusLogger) Error(msg string) {
+	if l.allow(LevelError, msg) {
+		l.entry.Error(msg)
+	}
+}
+
+func (l *logrusLogger) Errorf(format string, args ...any) {
+	if l.allow(LevelError, format) {
+		l.entry.Errorf(format, args...)
 	}
 }
 
-func (l *logrusLogger) Debug(msg string)                  { l.entry.Debug(msg) }
-func (l *logrusLogger) Debugf(format string, args ...any) { l.entry.Debugf(format, args...) }
-func (l *logrusLogger) Info(msg string)                   { l.entry.Info(msg) }
-func (l *logrusLogger) Infof(format string, args ...any)  { l.entry.Infof(format, args...) }
-func (l *logrusLogger) Warn(msg string)                   { l.entry.Warn(msg) }
-func (l *logrusLogger) Warnf(format string, args ...any)  { l.entry.Warnf(format, args...) }
-func (l *logrusLogger) Error(msg string)                  { l.entry.Error(msg) }
-func (l *logrusLogger) Errorf(format string, args ...any) { l.entry.Errorf(format, args...) }
 func (l *logrusLogger) Fatal(msg string)                  { l.entry.Fatal(msg) }
 func (l *logrusLogger) Fatalf(format string, args ...any) { l.entry.Fatalf(format, args...) }
 
 func (l *logrusLogger) WithField(key string, value interface{}) Logger {
-	return &logrusLogger{
-		entry: l.entry.WithField(key, value),
-	}
+	return l.WithFields(Fields{key: value})
 }
 
 func (l *logrusLogger) WithFields(fields Fields) Logger {
-	return &logrusLogger{
-		entry: l.entry.WithFields(logrus.Fields(fields)),
-	}
+	child := *l
+	child.entry = l.entry.WithFields(logrus.Fields(fields))
+	child.component = componentOf(l.component, fields)
+	return &child
 }
 
+// WithContext attaches ctx to the entry; the context hook adds the request, trace,
+// user and connection IDs it carries when the entry is logged
 func (l *logrusLogger) WithContext(ctx context.Context) Logger {
-	return &logrusLogger{
-		entry: l.entry.WithContext(ctx),
-	}
+	child := *l
+	child.entry = l.entry.WithContext(ctx)
+	return &child
 }
 
+// SetLevel sets the level of the logger's component, or the base level when the
+// logger is not scoped to a component
 func (l *logrusLogger) SetLevel(level Level) {
+	l.levels.Set(l.component, level)
+}
+
+// SetOutput redirects every logger sharing the same root
+func (l *logrusLogger) SetOutput(output io.Writer) {
+	l.logger.SetOutput(output)
+}
+
+// Levels returns the runtime level controls shared with the root logger
+func (l *logrusLogger) Levels() *Levels {
+	return l.levels
+}
+
+// allow applies the component level and sampling to a message; messages sampled
+// out are summarised once their interval ends
+func (l *logrusLogger) allow(level Level, message string) bool {
+	if !l.levels.Enabled(l.component, level) {
+		return false
+	}
+	if level >= LevelWarn {
+		return true
+	}
+
+	return l.sampler.check(l.component, message, func(suppressed int) {
+		l.entry.WithField("suppressed", suppressed).
+			Logf(toLogrusLevel(level), "Suppressed %d messages like %q", suppressed, message)
+	})
+}
+
+func toLogrusLevel(level Level) logrus.Level {
 	switch level {
 	case LevelDebug:
-		l.logger.SetLevel(logrus.DebugLevel)
-	case LevelInfo:
-		l.logger.SetLevel(logrus.InfoLevel)
+		return logrus.DebugLevel
 	case LevelWarn:
-		l.logger.SetLevel(logrus.WarnLevel)
+		return logrus.WarnLevel
 	case LevelError:
-		l.logger.SetLevel(logrus.ErrorLevel)
+		return logrus.ErrorLevel
 	case LevelFatal:
-		l.logger.SetLevel(logrus.FatalLevel)
+		return logrus.FatalLevel
+	default:
+		return logrus.InfoLevel
 	}
 }
-
-func (l *logrusLogger) SetOutput(output io.Writer) {
-	l.logger.SetOutput(output)
-}
@@ -0,0 +1,189 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// maxSampledKeys bounds the number of message keys tracked by a sampler; the
+// counters are reset when it is exceeded
+const maxSampledKeys = 10000
+
+// ComponentHTTP is the component of the access log. Its lines record requests
+// rather than diagnostics, so they are never sampled.
+const ComponentHTTP = "http"
+
+// SamplingRule logs the first First messages with the same key in each interval,
+// then every Thereafter-th one (none when Thereafter is zero). A zero First
+// disables sampling.
+type SamplingRule struct {
+	First      int `json:"first"      yaml:"first"`
+	Thereafter int `json:"thereafter" yaml:"thereafter"`
+}
+
+// SamplingConfig throttles repetitive log messages. Messages are keyed by
+// component and message (the format string for the *f methods); rules for a
+// message take precedence over rules for a component, which take precedence over
+// the default. Only debug and info messages are sampled, and never those of
+// ComponentHTTP.
+type SamplingConfig struct {
+	Interval   time.Duration           `json:"interval"   yaml:"interval"`
+	Default    SamplingRule            `json:"default"    yaml:"default"`
+	Components map[string]SamplingRule `json:"components" yaml:"components"`
+	Messages   map[string]SamplingRule `json:"messages"   yaml:"messages"`
+}
+
+// NewDefaultSamplingConfig logs up to 100 identical messages per second, then
+// one in 100
+func NewDefaultSamplingConfig() *SamplingConfig {
+	return &SamplingConfig{
+		Interval: time.Second,
+		Default:  SamplingRule{First: 100, Thereafter: 100},
+	}
+}
+
+type sampleKey struct {
+	component string
+	message   string
+}
+
+type sampleCounter struct {
+	start      time.Time
+	count      int
+	suppressed int
+
+	// report logs a summary of the suppressed messages on behalf of the logger
+	// that last dropped one
+	report func(suppressed int)
+}
+
+// sampler decides which messages are logged and counts the rest. Summaries of
+// the suppressed messages are logged once their interval ends, even when the
+// message is not logged again.
+type sampler struct {
+	config SamplingConfig
+
+	counters map[sampleKey]*sampleCounter
+	flush    *time.Timer // pending flush of expired counters, if any
+	mu       sync.Mutex
+
+	now func() time.Time
+}
+
+// newSampler returns nil when sampling is disabled
+func newSampler(config *SamplingConfig) *sampler {
+	if config == nil || config.Interval <= 0 {
+		return nil
+	}
+	return &sampler{
+		config:   *config,
+		counters: make(map[sampleKey]*sampleCounter),
+		now:      time.Now,
+	}
+}
+
+// check reports whether a message should be logged. Suppressed messages are
+// later summarised through report: when a new interval starts for the key, when
+// the counters are reset, or by a timer when the interval expires.
+func (s *sampler) check(component, message string, report func(suppressed int)) bool {
+	if s == nil || component == ComponentHTTP {
+		return true
+	}
+
+	rule := s.rule(component, message)
+	if rule.First <= 0 {
+		return true
+	}
+
+	key := sampleKey{component: component, message: message}
+	now := s.now()
+
+	s.mu.Lock()
+	var summaries []func()
+
+	counter, exists := s.counters[key]
+	if !exists {
+		if len(s.counters) >= maxSampledKeys {
+			for _, c := range s.counters {
+				summaries = appendSummary(summaries, c)
+			}
+			s.counters = make(map[sampleKey]*sampleCounter)
+		}
+		counter = &sampleCounter{start: now}
+		s.counters[key] = counter
+	}
+
+	if now.Sub(counter.start) >= s.config.Interval {
+		summaries = appendSummary(summaries, counter)
+		counter.start = now
+		counter.count = 0
+	}
+
+	counter.count++
+	allow := counter.count <= rule.First ||
+		(rule.Thereafter > 0 && (counter.count-rule.First)%rule.Thereafter == 0)
+	if !allow {
+		counter.suppressed++
+		counter.report = report
+		if s.flush == nil {
+			s.flush = time.AfterFunc(counter.start.Add(s.config.Interval).Sub(now), s.flushExpired)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, summary := range summaries {
+		summary()
+	}
+	return allow
+}
+
+// flushExpired summarises and drops the counters whose interval has ended,
+// rescheduling itself while other counters still hold suppressed messages
+func (s *sampler) flushExpired() {
+	s.mu.Lock()
+	var summaries []func()
+
+	now := s.now()
+	var next time.Duration
+	for key, counter := range s.counters {
+		remaining := counter.start.Add(s.config.Interval).Sub(now)
+		if remaining <= 0 {
+			summaries = appendSummary(summaries, counter)
+			delete(s.counters, key)
+		} else if counter.suppressed > 0 && (next == 0 || remaining < next) {
+			next = remaining
+		}
+	}
+
+	s.flush = nil
+	if next > 0 {
+		s.flush = time.AfterFunc(next, s.flushExpired)
+	}
+	s.mu.Unlock()
+
+	for _, summary := range summaries {
+		summary()
+	}
+}
+
+// appendSummary queues the summary of a counter's suppressed messages, if any,
+// and clears them
+func appendSummary(summaries []func(), counter *sampleCounter) []func() {
+	if counter.suppressed == 0 {
+		return summaries
+	}
+	suppressed, report := counter.suppressed, counter.report
+	counter.suppressed = 0
+	counter.report = nil
+	return append(summaries, func() { report(suppressed) })
+}
+
+func (s *sampler) rule(component, message string) SamplingRule {
+	if rule, ok := s.config.Messages[message]; ok {
+		return rule
+	}
+	if rule, ok := s.config.Components[component]; ok {
+		return rule
+	}
+	return s.config.Default
+}
@@ -0,0 +1,257 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// levelFatal is the slog level used for Fatal; slog has no built-in fatal level
+const levelFatal = slog.Level(12)
+
+type slogLogger struct {
+	logger    *slog.Logger
+	levels    *Levels
+	sampler   *sampler
+	component string
+	output    *swapWriter
+	ctx       context.Context
+
+	// keys set explicitly with WithField(s); context fields never override them
+	keys map[string]struct{}
+}
+
+// NewSlogLogger creates a Logger backed by log/slog. Levels can be changed at
+// runtime, per component, from any logger derived from it.
+func NewSlogLogger(config *Config) Logger {
+	levels := NewLevels(config.Level, config.ComponentLevels)
+
+	output := &swapWriter{w: newOutput(config)}
+	opts := &slog.HandlerOptions{
+		AddSource:   config.AddSource,
+		Level:       slogLeveler{levels},
+		ReplaceAttr: replaceSlogAttr,
+	}
+
+	var handler slog.Handler
+	switch config.Format {
+	case "json":
+		handler = slog.NewJSONHandler(output, opts)
+	default:
+		handler = slog.NewTextHandler(output, opts)
+	}
+
+	l := &slogLogger{
+		logger:  slog.New(handler),
+		levels:  levels,
+		sampler: newSampler(config.Sampling),
+		output:  output,
+		keys:    map[string]struct{}{},
+	}
+
+	// Add static fields for container environments
+	fields := make(Fields, len(config.Fields))
+	for k, v := range config.Fields {
+		fields[k] = v
+	}
+	return l.WithFields(fields)
+}
+
+func (l *slogLogger) Debug(msg string)                  { l.log(slog.LevelDebug, msg) }
+func (l *slogLogger) Debugf(format string, args ...any) { l.logf(slog.LevelDebug, format, args...) }
+func (l *slogLogger) Info(msg string)                   { l.log(slog.LevelInfo, msg) }
+func (l *slogLogger) Infof(format string, args ...any)  { l.logf(slog.LevelInfo, format, args...) }
+func (l *slogLogger) Warn(msg string)                   { l.log(slog.LevelWarn, msg) }
+func (l *slogLogger) Warnf(format string, args ...any)  { l.logf(slog.LevelWarn, format, args...) }
+func (l *slogLogger) Error(msg string)                  { l.log(slog.LevelError, msg) }
+func (l *slogLogger) Errorf(format string, args ...any) { l.logf(slog.LevelError, format, args...) }
+
+func (l *slogLogger) Fatal(msg string) {
+	l.log(levelFatal, msg)
+	os.Exit(1)
+}
+
+func (l *slogLogger) Fatalf(format string, args ...any) {
+	l.logf(levelFatal, format, args...)
+	os.Exit(1)
+}
+
+func (l *slogLogger) WithField(key string, value any) Logger {
+	return l.WithFields(Fields{key: value})
+}
+
+func (l *slogLogger) WithFields(fields Fields) Logger {
+	child := *l
+	child.keys = make(map[string]struct{}, len(l.keys)+len(fields))
+	for key := range l.keys {
+		child.keys[key] = struct{}{}
+	}
+
+	args := make([]any, 0, len(fields))
+	for key, value := range fields {
+		child.keys[key] = struct{}{}
+		args = append(args, slog.Any(key, value))
+	}
+	child.logger = l.logger.With(args...)
+	child.component = componentOf(l.component, fields)
+	return &child
+}
+
+// WithContext attaches ctx to the logger; the request, trace, user and connection
+// IDs it carries are added to every entry
+func (l *slogLogger) WithContext(ctx context.Context) Logger {
+	child := *l
+	child.ctx = ctx
+	return &child
+}
+
+// SetLevel sets the level of the logger's component, or the base level when the
+// logger is not scoped to a component
+func (l *slogLogger) SetLevel(level Level) {
+	l.levels.Set(l.component, level)
+}
+
+// SetOutput redirects every logger sharing the same root
+func (l *slogLogger) SetOutput(output io.Writer) {
+	l.output.set(output)
+}
+
+// Levels returns the runtime level controls shared with the root logger
+func (l *slogLogger) Levels() *Levels {
+	return l.levels
+}
+
+func (l *slogLogger) logf(level slog.Level, format string, args ...any) {
+	if !l.allow(level, format) {
+		return
+	}
+	l.write(level, fmt.Sprintf(format, args...))
+}
+
+func (l *slogLogger) log(level slog.Level, msg string) {
+	if !l.allow(level, msg) {
+		return
+	}
+	l.write(level, msg)
+}
+
+// allow applies the component level and sampling to a message; messages sampled
+// out are summarised once their interval ends
+func (l *slogLogger) allow(level slog.Level, message string) bool {
+	if level < toSlogLevel(l.levels.Level(l.component)) || !l.logger.Enabled(l.context(), level) {
+		return false
+	}
+	if level >= slog.LevelWarn {
+		return true
+	}
+
+	return l.sampler.check(l.component, message, func(suppressed int) {
+		l.logger.Log(l.context(), level,
+			fmt.Sprintf("Suppressed %d messages like %q", suppressed, message),
+			slog.Int("suppressed", suppressed),
+		)
+	})
+}
+
+// write emits a record; it must be called exactly two frames below the public
+// logging method so the source location points at the caller
+func (l *slogLogger) write(level slog.Level, msg string) {
+	var pcs [1]uintptr
+	runtime.Callers(4, pcs[:]) // skip Callers, write, log/logf and the public method
+
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	if l.ctx != nil {
+		for key, value := range ContextFields(l.ctx) {
+			if _, exists := l.keys[key]; !exists {
+				record.AddAttrs(slog.Any(key, value))
+			}
+		}
+	}
+	_ = l.logger.Handler().Handle(l.context(), record)
+}
+
+func (l *slogLogger) context() context.Context {
+	if l.ctx != nil {
+		return l.ctx
+	}
+	return context.Background()
+}
+
+// replaceSlogAttr aligns slog's built-in keys and level names with the logrus
+// JSON output so both backends feed the same log pipeline
+func replaceSlogAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = "timestamp"
+	case slog.MessageKey:
+		a.Key = "message"
+	case slog.SourceKey:
+		a.Key = "caller"
+	case slog.LevelKey:
+		level, _ := a.Value.Any().(slog.Level)
+		switch {
+		case level >= levelFatal:
+			a.Value = slog.StringValue("fatal")
+		case level >= slog.LevelError:
+			a.Value = slog.StringValue("error")
+		case level >= slog.LevelWarn:
+			a.Value = slog.StringValue("warning")
+		case level >= slog.LevelInfo:
+			a.Value = slog.StringValue("info")
+		default:
+			a.Value = slog.StringValue("debug")
+		}
+	}
+	return a
+}
+
+func toSlogLevel(level Level) slog.Level {
+	switch level {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	case LevelFatal:
+		return levelFatal
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// slogLeveler lets handlers skip records below the most verbose component level
+type slogLeveler struct {
+	levels *Levels
+}
+
+func (s slogLeveler) Level() slog.Level {
+	return toSlogLevel(s.levels.min())
+}
+
+// swapWriter lets SetOutput replace the destination of handlers already in use
+type swapWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *swapWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+func (s *swapWriter) set(w io.Writer) {
+	s.mu.Lock()
+	s.w = w
+	s.mu.Unlock()
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "connection_hub"
+
+var (
+	// Hub connection lifecycle
+	ConnectionsActive = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "connections_active",
+		Help:      "Number of connections currently registered with the hub.",
+	}, []string{"transport"})
+
+	Registrations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Total number of connections registered with the hub.",
+	}, []string{"transport"})
+
+	Unregistrations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "unregistrations_total",
+		Help:      "Total number of connections removed from the hub.",
+	}, []string{"transport"})
+
+	// Message delivery
+	MessagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_sent_total",
+		Help:      "Total number of messages written to connections.",
+	}, []string{"transport", "message_type", "priority"})
+
+	MessagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_failed_total",
+		Help:      "Total number of messages that failed to be written to connections.",
+	}, []string{"transport", "message_type", "priority"})
+
+	MessagesDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_dropped_total",
+		Help:      "Total number of messages dropped because a connection was closed or its queue was full.",
+	}, []string{"transport", "message_type", "priority"})
+
+	SendLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "send_duration_seconds",
+		Help:      "Time taken to write a message to a connection.",
+		Buckets:   []float64{.0005, .001, .005, .01, .05, .1, .5, 1, 5, 10},
+	}, []string{"transport"})
+
+	QueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "transport_queue_depth",
+		Help:      "Number of messages queued for writing across all connections of a transport.",
+	}, []string{"transport"})
+
+	BytesWritten = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bytes_written_total",
+		Help:      "Total number of payload bytes written to connections.",
+	}, []string{"transport"})
+
+	KeepAlives = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "keepalives_total",
+		Help:      "Total number of keep-alive frames sent, by result.",
+	}, []string{"transport", "result"})
+
+	// HTTP server
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// RegisterGaugeFunc exposes a value computed at scrape time, e.g. hub channel depths
+func RegisterGaugeFunc(name, help string, labels prometheus.Labels, fn func() float64) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        name,
+		Help:        help,
+		ConstLabels: labels,
+	}, fn))
+}
+
+// Handler serves all registered metrics in Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
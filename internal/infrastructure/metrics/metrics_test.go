@@ -0,0 +1,139 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/metrics"
+)
+
+// fakeConnection is a connection of its own transport, so that only these
+// tests count towards its metrics
+type fakeConnection struct {
+	id  string
+	ctx context.Context
+}
+
+func (c *fakeConnection) ID() string                               { return c.id }
+func (c *fakeConnection) Type() string                             { return "fake" }
+func (c *fakeConnection) Send(context.Context, *hub.Message) error { return nil }
+func (c *fakeConnection) Close() error                             { return nil }
+func (c *fakeConnection) IsClosed() bool                           { return false }
+func (c *fakeConnection) Context() context.Context                 { return c.ctx }
+func (c *fakeConnection) Info() hub.ConnectionInfo {
+	return hub.ConnectionInfo{ID: c.id, Type: c.Type()}
+}
+
+func newLogger() logger.Logger {
+	log := logger.New(logger.NewDefaultConfig())
+	log.SetOutput(io.Discard)
+	return log
+}
+
+// counter tracks a metric from its value when the test started, as metrics are
+// global and tests may run repeatedly
+type counter struct {
+	name      string
+	collector prometheus.Collector
+	start     float64
+}
+
+func track(name string, collector prometheus.Collector) *counter {
+	return &counter{name: name, collector: collector, start: testutil.ToFloat64(collector)}
+}
+
+// delta returns the change since the test started
+func (c *counter) delta() float64 {
+	return testutil.ToFloat64(c.collector) - c.start
+}
+
+// waitFor waits up to a second for the change to reach expected, as the hub
+// registers and unregisters connections asynchronously
+func (c *counter) waitFor(t *testing.T, expected float64) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for c.delta() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %s to change by %v, got %v", c.name, expected, c.delta())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHubConnectionMetrics(t *testing.T) {
+	hubInstance := hub.New(newLogger())
+	ctx := context.Background()
+	hubInstance.Start(ctx)
+	defer hubInstance.Stop(ctx)
+
+	active := track("active connections", metrics.ConnectionsActive.WithLabelValues("fake"))
+	registrations := track("registrations", metrics.Registrations.WithLabelValues("fake"))
+	unregistrations := track("unregistrations", metrics.Unregistrations.WithLabelValues("fake"))
+
+	hubInstance.RegisterConnection(&fakeConnection{id: "fake-1", ctx: ctx})
+	registrations.waitFor(t, 1)
+	active.waitFor(t, 1)
+
+	// Replacing a connection registers it again without adding one
+	hubInstance.RegisterConnection(&fakeConnection{id: "fake-1", ctx: ctx})
+	registrations.waitFor(t, 2)
+	active.waitFor(t, 1)
+
+	hubInstance.RegisterConnection(&fakeConnection{id: "fake-2", ctx: ctx})
+	active.waitFor(t, 2)
+
+	hubInstance.UnregisterConnection("fake-1")
+	unregistrations.waitFor(t, 1)
+	active.waitFor(t, 1)
+
+	// Unregistering an unknown connection counts nothing
+	hubInstance.UnregisterConnection("fake-1")
+	hubInstance.UnregisterConnection("fake-2")
+	unregistrations.waitFor(t, 2)
+	active.waitFor(t, 0)
+}
+
+func TestTransportMessageMetrics(t *testing.T) {
+	ctx := context.Background()
+	request := httptest.NewRequest("GET", "/events", nil)
+	conn := hub.NewSSEConnection(ctx, "sse-1", httptest.NewRecorder(), request, newLogger())
+
+	notifications := track("notifications sent", metrics.MessagesSent.WithLabelValues("sse", "notification", "high"))
+	custom := track("custom messages sent", metrics.MessagesSent.WithLabelValues("sse", "custom", "normal"))
+	written := track("bytes written", metrics.BytesWritten.WithLabelValues("sse"))
+	dropped := track("messages dropped", metrics.MessagesDropped.WithLabelValues("sse", "notification", "high"))
+
+	notification := &hub.Message{ID: "m-1", Type: string(hub.MessageTypeNotification), Headers: map[string]string{"priority": "high"}}
+	if err := conn.Send(ctx, notification); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := conn.Send(ctx, &hub.Message{ID: "m-2", Type: "chat.message"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if notifications.delta() != 1 {
+		t.Errorf("Expected 1 high priority notification sent, got %v", notifications.delta())
+	}
+	// Custom message types share a label
+	if custom.delta() != 1 {
+		t.Errorf("Expected 1 custom message sent, got %v", custom.delta())
+	}
+	if written.delta() <= 0 {
+		t.Errorf("Expected bytes written, got %v", written.delta())
+	}
+
+	conn.Close()
+	conn.Send(ctx, notification)
+	if dropped.delta() != 1 {
+		t.Errorf("Expected the message sent after closing to be dropped, got %v", dropped.delta())
+	}
+}
@@ -0,0 +1,177 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Config describes a token bucket: Rate tokens are added per second up to Burst
+type Config struct {
+	Rate    float64       `json:"rate"     yaml:"rate"`
+	Burst   int           `json:"burst"    yaml:"burst"`
+	IdleTTL time.Duration `json:"idle_ttl" yaml:"idle_ttl"` // evict buckets unused for this long
+}
+
+// NewDefaultConfig returns a config allowing 10 requests per second with bursts of 20
+func NewDefaultConfig() *Config {
+	return &Config{
+		Rate:    10,
+		Burst:   20,
+		IdleTTL: 10 * time.Minute,
+	}
+}
+
+// Result describes the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // time until the bucket is full again
+	RetryAfter time.Duration // time until the next token is available (zero if allowed)
+}
+
+// Bucket is a thread-safe token bucket
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket creates a full token bucket
+func NewBucket(rate float64, burst int) *Bucket {
+	return newBucket(rate, burst, time.Now())
+}
+
+func newBucket(rate float64, burst int, now time.Time) *Bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Bucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+// Take consumes a single token if one is available
+func (b *Bucket) Take() Result {
+	return b.take(time.Now())
+}
+
+func (b *Bucket) take(now time.Time) Result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+
+	res := Result{Limit: b.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = b.durationFor(1 - b.tokens)
+	}
+
+	res.Remaining = int(math.Floor(b.tokens))
+	res.ResetAfter = b.durationFor(float64(b.burst) - b.tokens)
+	return res
+}
+
+// lastUsed returns the time the bucket was last refilled
+func (b *Bucket) lastUsed() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.last
+}
+
+func (b *Bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.burst), b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+func (b *Bucket) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if b.rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(math.Ceil(tokens / b.rate * float64(time.Second)))
+}
+
+// Limiter maintains one token bucket per key (API key, user, IP, connection...)
+type Limiter struct {
+	config Config
+
+	buckets   map[string]*Bucket
+	bucketsMu sync.Mutex
+
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewLimiter creates a keyed limiter
+func NewLimiter(config *Config) *Limiter {
+	return &Limiter{
+		config:    *config,
+		buckets:   make(map[string]*Bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Config returns the limiter configuration
+func (l *Limiter) Config() Config {
+	return l.config
+}
+
+// Take consumes a token from the bucket identified by key
+func (l *Limiter) Take(key string) Result {
+	now := l.now()
+
+	l.bucketsMu.Lock()
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = newBucket(l.config.Rate, l.config.Burst, now)
+		l.buckets[key] = bucket
+	}
+	l.sweep(now)
+	l.bucketsMu.Unlock()
+
+	return bucket.take(now)
+}
+
+// Forget drops the bucket for key, e.g. when a connection closes
+func (l *Limiter) Forget(key string) {
+	l.bucketsMu.Lock()
+	delete(l.buckets, key)
+	l.bucketsMu.Unlock()
+}
+
+// Len returns the number of tracked keys
+func (l *Limiter) Len() int {
+	l.bucketsMu.Lock()
+	defer l.bucketsMu.Unlock()
+	return len(l.buckets)
+}
+
+// sweep evicts idle buckets; callers must hold bucketsMu
+func (l *Limiter) sweep(now time.Time) {
+	if l.config.IdleTTL <= 0 || now.Sub(l.lastSweep) < l.config.IdleTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.lastUsed()) > l.config.IdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket_TakeAndRefill(t *testing.T) {
+	now := time.Now()
+	bucket := newBucket(1, 2, now)
+
+	// Burst allows two immediate tokens
+	for i := 0; i < 2; i++ {
+		if res := bucket.take(now); !res.Allowed {
+			t.Fatalf("Take %d should be allowed", i)
+		}
+	}
+
+	res := bucket.take(now)
+	if res.Allowed {
+		t.Fatal("Third take should be rejected")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("Expected retry after 1s, got %v", res.RetryAfter)
+	}
+	if res.Remaining != 0 {
+		t.Errorf("Expected 0 remaining, got %d", res.Remaining)
+	}
+
+	// One second later a single token is available again
+	if res := bucket.take(now.Add(time.Second)); !res.Allowed {
+		t.Error("Take after refill should be allowed")
+	}
+}
+
+func TestLimiter_KeysAreIndependent(t *testing.T) {
+	limiter := NewLimiter(&Config{Rate: 1, Burst: 1})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	if !limiter.Take("a").Allowed {
+		t.Error("First take for key a should be allowed")
+	}
+	if limiter.Take("a").Allowed {
+		t.Error("Second take for key a should be rejected")
+	}
+	if !limiter.Take("b").Allowed {
+		t.Error("First take for key b should be allowed")
+	}
+
+	limiter.Forget("a")
+	if !limiter.Take("a").Allowed {
+		t.Error("Take after Forget should be allowed")
+	}
+}
+
+func TestLimiter_EvictsIdleBuckets(t *testing.T) {
+	limiter := NewLimiter(&Config{Rate: 1, Burst: 1, IdleTTL: time.Minute})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	limiter.Take("a")
+	limiter.Take("b")
+	if limiter.Len() != 2 {
+		t.Fatalf("Expected 2 buckets, got %d", limiter.Len())
+	}
+
+	now = now.Add(2 * time.Minute)
+	limiter.Take("c")
+	if limiter.Len() != 1 {
+		t.Errorf("Expected idle buckets to be evicted, got %d buckets", limiter.Len())
+	}
+}
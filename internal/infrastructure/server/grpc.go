@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+)
+
+type GRPCServer struct {
+	addr      string
+	srv       *grpc.Server
+	listening atomic.Bool
+}
+
+var _ Server = (*GRPCServer)(nil)
+
+func NewGRPCServer(addr string, srv *grpc.Server) *GRPCServer {
+	return &GRPCServer{
+		addr: addr,
+		srv:  srv,
+	}
+}
+
+func (g *GRPCServer) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", g.addr)
+	if err != nil {
+		return err
+	}
+	g.listening.Store(true)
+	defer g.listening.Store(false)
+
+	if err := g.srv.Serve(ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Stop waits for in-flight RPCs to finish, cancelling long-lived streams when
+// ctx expires
+func (g *GRPCServer) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		g.srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		g.srv.Stop()
+		return ctx.Err()
+	}
+}
+
+// HealthCheck fails when the server is not accepting connections
+func (g *GRPCServer) HealthCheck(ctx context.Context) error {
+	if !g.listening.Load() {
+		return fmt.Errorf("grpc server on %s is not listening", g.addr)
+	}
+	return nil
+}
@@ -3,38 +3,51 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 )
 
 type HTTPServer struct {
-	handler http.Handler
-	srv     *http.Server
+	addr      string
+	handler   http.Handler
+	srv       *http.Server
+	listening atomic.Bool
 }
 
 var _ Server = (*HTTPServer)(nil)
 
-func NewHTTPServer(handler http.Handler) *HTTPServer {
+func NewHTTPServer(addr string, handler http.Handler) *HTTPServer {
 	srv := &HTTPServer{
+		addr:    addr,
 		handler: handler,
+		srv: &http.Server{
+			Addr:         addr,
+			Handler:      handler,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		},
 	}
 	return srv
 }
 
 func (h *HTTPServer) Start(ctx context.Context) error {
-	h.srv = &http.Server{
-		Addr:         ":8080",
-		Handler:      h.handler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	ln, err := net.Listen("tcp", h.addr)
+	if err != nil {
+		return err
 	}
+	h.listening.Store(true)
 
 	var eg errgroup.Group
 	eg.Go(func() error {
-		err := h.srv.ListenAndServe()
+		defer h.listening.Store(false)
+
+		err := h.srv.Serve(ln)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			return err
 		}
@@ -48,3 +61,11 @@ func (h *HTTPServer) Start(ctx context.Context) error {
 func (h *HTTPServer) Stop(ctx context.Context) error {
 	return h.srv.Shutdown(ctx)
 }
+
+// HealthCheck fails when the server is not accepting connections
+func (h *HTTPServer) HealthCheck(ctx context.Context) error {
+	if !h.listening.Load() {
+		return fmt.Errorf("http server on %s is not listening", h.addr)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnHandler serves one accepted connection and returns when it is done. ctx
+// is cancelled when the server stops.
+type ConnHandler func(ctx context.Context, conn net.Conn)
+
+// SocketServer accepts raw TCP or Unix domain socket connections and serves
+// each with a ConnHandler
+type SocketServer struct {
+	network   string
+	addr      string
+	handler   ConnHandler
+	listening atomic.Bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	ln      net.Listener
+	conns   map[net.Conn]struct{}
+	stopped bool
+}
+
+var _ Server = (*SocketServer)(nil)
+
+// NewSocketServer creates a server listening on network ("tcp" or "unix") at addr
+func NewSocketServer(network, addr string, handler ConnHandler) *SocketServer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SocketServer{
+		network: network,
+		addr:    addr,
+		handler: handler,
+		ctx:     ctx,
+		cancel:  cancel,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+func (s *SocketServer) Start(ctx context.Context) error {
+	if s.network == "unix" {
+		removeStaleSocket(s.addr)
+	}
+	ln, err := net.Listen(s.network, s.addr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return ln.Close()
+	}
+	s.ln = ln
+	s.mu.Unlock()
+
+	s.listening.Store(true)
+	defer s.listening.Store(false)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		if !s.track(conn) {
+			conn.Close()
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			defer conn.Close()
+			s.handler(s.ctx, conn)
+		}()
+	}
+}
+
+// Stop stops accepting connections and waits for handlers to return, closing
+// the remaining connections when ctx expires
+func (s *SocketServer) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	if s.ln != nil {
+		s.ln.Close()
+	}
+	s.mu.Unlock()
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// HealthCheck fails when the server is not accepting connections
+func (s *SocketServer) HealthCheck(ctx context.Context) error {
+	if !s.listening.Load() {
+		return fmt.Errorf("%s socket server on %s is not listening", s.network, s.addr)
+	}
+	return nil
+}
+
+// Addr returns the address the server listens on, once started
+func (s *SocketServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+func (s *SocketServer) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *SocketServer) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+// removeStaleSocket removes a socket file left behind by a previous process,
+// which would otherwise make listening fail
+func removeStaleSocket(path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		// Another process is serving it; let Listen report the conflict
+		conn.Close()
+		return
+	}
+	os.Remove(path)
+}
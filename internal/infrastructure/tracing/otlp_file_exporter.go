@@ -0,0 +1,251 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// OTLPFileExporter writes each batch of spans as one line of OTLP/JSON
+// (an ExportTraceServiceRequest), the format read by the collector's
+// otlpjsonfile receiver
+type OTLPFileExporter struct {
+	writer io.Writer
+	mu     sync.Mutex
+}
+
+var _ sdktrace.SpanExporter = (*OTLPFileExporter)(nil)
+
+// NewOTLPFileExporter creates an exporter writing to w
+func NewOTLPFileExporter(w io.Writer) *OTLPFileExporter {
+	return &OTLPFileExporter{writer: w}
+}
+
+// ExportSpans encodes spans grouped by resource and instrumentation scope
+func (e *OTLPFileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	line, err := json.Marshal(toOTLP(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+	line = append(line, '\n')
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, err := e.writer.Write(line); err != nil {
+		return fmt.Errorf("failed to write spans: %w", err)
+	}
+	return nil
+}
+
+// Shutdown is a no-op; the owner of the writer closes it
+func (e *OTLPFileExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Links             []otlpLink     `json:"links,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpLink struct {
+	TraceID    string         `json:"traceId"`
+	SpanID     string         `json:"spanId"`
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+// toOTLP groups spans by resource and scope
+func toOTLP(spans []sdktrace.ReadOnlySpan) *otlpTraces {
+	traces := &otlpTraces{}
+	resourceIndex := make(map[string]int)
+	scopeIndex := make(map[string]int)
+
+	for _, span := range spans {
+		resKey := span.Resource().Encoded(attribute.DefaultEncoder())
+		ri, exists := resourceIndex[resKey]
+		if !exists {
+			ri = len(traces.ResourceSpans)
+			resourceIndex[resKey] = ri
+			traces.ResourceSpans = append(traces.ResourceSpans, otlpResourceSpans{
+				Resource: otlpResource{Attributes: toKeyValues(span.Resource().Attributes())},
+			})
+		}
+
+		scope := span.InstrumentationScope()
+		scopeKey := resKey + "|" + scope.Name + "|" + scope.Version
+		si, exists := scopeIndex[scopeKey]
+		if !exists {
+			si = len(traces.ResourceSpans[ri].ScopeSpans)
+			scopeIndex[scopeKey] = si
+			traces.ResourceSpans[ri].ScopeSpans = append(traces.ResourceSpans[ri].ScopeSpans, otlpScopeSpans{
+				Scope: otlpScope{Name: scope.Name, Version: scope.Version},
+			})
+		}
+
+		scopeSpans := &traces.ResourceSpans[ri].ScopeSpans[si]
+		scopeSpans.Spans = append(scopeSpans.Spans, toSpan(span))
+	}
+
+	return traces
+}
+
+func toSpan(span sdktrace.ReadOnlySpan) otlpSpan {
+	out := otlpSpan{
+		TraceID:           span.SpanContext().TraceID().String(),
+		SpanID:            span.SpanContext().SpanID().String(),
+		Name:              span.Name(),
+		Kind:              int(span.SpanKind()),
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime().UnixNano(), 10),
+		Attributes:        toKeyValues(span.Attributes()),
+		Status: otlpStatus{
+			Code:    toStatusCode(span.Status().Code),
+			Message: span.Status().Description,
+		},
+	}
+	if span.Parent().HasSpanID() {
+		out.ParentSpanID = span.Parent().SpanID().String()
+	}
+
+	for _, event := range span.Events() {
+		out.Events = append(out.Events, otlpEvent{
+			TimeUnixNano: strconv.FormatInt(event.Time.UnixNano(), 10),
+			Name:         event.Name,
+			Attributes:   toKeyValues(event.Attributes),
+		})
+	}
+	for _, link := range span.Links() {
+		out.Links = append(out.Links, otlpLink{
+			TraceID:    link.SpanContext.TraceID().String(),
+			SpanID:     link.SpanContext.SpanID().String(),
+			Attributes: toKeyValues(link.Attributes),
+		})
+	}
+
+	return out
+}
+
+// OTLP status codes, which are numbered differently from codes.Code
+const (
+	otlpStatusUnset = 0
+	otlpStatusOk    = 1
+	otlpStatusError = 2
+)
+
+func toStatusCode(code codes.Code) int {
+	switch code {
+	case codes.Ok:
+		return otlpStatusOk
+	case codes.Error:
+		return otlpStatusError
+	default:
+		return otlpStatusUnset
+	}
+}
+
+func toKeyValues(attrs []attribute.KeyValue) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		out = append(out, otlpKeyValue{Key: string(attr.Key), Value: toAnyValue(attr.Value)})
+	}
+	return out
+}
+
+// toAnyValue encodes an attribute value as an OTLP AnyValue; 64-bit integers
+// are strings in OTLP/JSON
+func toAnyValue(v attribute.Value) map[string]any {
+	switch v.Type() {
+	case attribute.BOOL:
+		return map[string]any{"boolValue": v.AsBool()}
+	case attribute.INT64:
+		return map[string]any{"intValue": strconv.FormatInt(v.AsInt64(), 10)}
+	case attribute.FLOAT64:
+		return map[string]any{"doubleValue": v.AsFloat64()}
+	case attribute.STRING:
+		return map[string]any{"stringValue": v.AsString()}
+	default:
+		values := make([]map[string]any, 0)
+		switch v.Type() {
+		case attribute.BOOLSLICE:
+			for _, b := range v.AsBoolSlice() {
+				values = append(values, toAnyValue(attribute.BoolValue(b)))
+			}
+		case attribute.INT64SLICE:
+			for _, i := range v.AsInt64Slice() {
+				values = append(values, toAnyValue(attribute.Int64Value(i)))
+			}
+		case attribute.FLOAT64SLICE:
+			for _, f := range v.AsFloat64Slice() {
+				values = append(values, toAnyValue(attribute.Float64Value(f)))
+			}
+		case attribute.STRINGSLICE:
+			for _, s := range v.AsStringSlice() {
+				values = append(values, toAnyValue(attribute.StringValue(s)))
+			}
+		}
+		return map[string]any{"arrayValue": map[string]any{"values": values}}
+	}
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestOTLPFileExporter_StatusCodes(t *testing.T) {
+	var buf bytes.Buffer
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(NewOTLPFileExporter(&buf)))
+	defer provider.Shutdown(context.Background())
+	tracer := provider.Tracer("test")
+
+	_, failed := tracer.Start(context.Background(), "failed")
+	failed.RecordError(errors.New("boom"))
+	failed.SetStatus(codes.Error, "boom")
+	failed.End()
+
+	_, succeeded := tracer.Start(context.Background(), "succeeded")
+	succeeded.SetStatus(codes.Ok, "")
+	succeeded.End()
+
+	_, unset := tracer.Start(context.Background(), "unset")
+	unset.End()
+
+	got := map[string]otlpStatus{}
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var traces otlpTraces
+		if err := json.Unmarshal(line, &traces); err != nil {
+			t.Fatalf("Invalid OTLP/JSON line: %v", err)
+		}
+		for _, rs := range traces.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					got[span.Name] = span.Status
+				}
+			}
+		}
+	}
+
+	if status := got["failed"]; status.Code != 2 || status.Message != "boom" {
+		t.Errorf("Expected STATUS_CODE_ERROR (2) with message, got %+v", status)
+	}
+	if status := got["succeeded"]; status.Code != 1 {
+		t.Errorf("Expected STATUS_CODE_OK (1), got %+v", status)
+	}
+	if status := got["unset"]; status.Code != 0 {
+		t.Errorf("Expected STATUS_CODE_UNSET (0), got %+v", status)
+	}
+}
@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Delivery records the outcome of delivering one payload
+type Delivery struct {
+	MessageID  string        `json:"message_id"`
+	Status     string        `json:"status"` // delivered or dead_lettered
+	Attempts   int           `json:"attempts"`
+	StatusCode int           `json:"status_code,omitempty"` // of the last attempt
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Timestamp  time.Time     `json:"timestamp"`
+}
+
+// DeliveryLog keeps the most recent deliveries
+type DeliveryLog struct {
+	size int
+
+	mu         sync.Mutex
+	deliveries []Delivery
+}
+
+// NewDeliveryLog creates a log keeping up to size deliveries
+func NewDeliveryLog(size int) *DeliveryLog {
+	return &DeliveryLog{size: size}
+}
+
+func (l *DeliveryLog) add(delivery Delivery) {
+	if l.size <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.deliveries) >= l.size {
+		l.deliveries = slices.Delete(l.deliveries, 0, len(l.deliveries)-l.size+1)
+	}
+	l.deliveries = append(l.deliveries, delivery)
+}
+
+// Recent returns up to limit deliveries, newest first; limit <= 0 returns all
+func (l *DeliveryLog) Recent(limit int) []Delivery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := slices.Clone(l.deliveries)
+	slices.Reverse(recent)
+	if limit > 0 && len(recent) > limit {
+		recent = recent[:limit]
+	}
+	return recent
+}
+
+// DeadLetter is a payload that could not be delivered
+type DeadLetter struct {
+	MessageID  string          `json:"message_id"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempts   int             `json:"attempts"`
+	StatusCode int             `json:"status_code,omitempty"`
+	Error      string          `json:"error"`
+	FailedAt   time.Time       `json:"failed_at"`
+}
+
+// DeadLetterQueue keeps undeliverable payloads for inspection and redelivery,
+// dropping the oldest beyond its size
+type DeadLetterQueue struct {
+	size int
+
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+// NewDeadLetterQueue creates a queue keeping up to size dead letters
+func NewDeadLetterQueue(size int) *DeadLetterQueue {
+	return &DeadLetterQueue{size: size}
+}
+
+func (q *DeadLetterQueue) add(letter DeadLetter) {
+	if q.size <= 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.letters) >= q.size {
+		q.letters = slices.Delete(q.letters, 0, len(q.letters)-q.size+1)
+	}
+	q.letters = append(q.letters, letter)
+}
+
+// Restore puts back a dead letter taken for redelivery that was not attempted
+func (q *DeadLetterQueue) Restore(letter DeadLetter) {
+	q.add(letter)
+}
+
+// List returns the dead letters, oldest first
+func (q *DeadLetterQueue) List() []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return slices.Clone(q.letters)
+}
+
+// Len returns the number of dead letters
+func (q *DeadLetterQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.letters)
+}
+
+// Take removes and returns the dead letters, oldest first
+func (q *DeadLetterQueue) Take() []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	letters := q.letters
+	q.letters = nil
+	return letters
+}
+
+// Remove removes the dead letter of a message, reporting whether it was queued
+func (q *DeadLetterQueue) Remove(messageID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	before := len(q.letters)
+	q.letters = slices.DeleteFunc(q.letters, func(l DeadLetter) bool { return l.MessageID == messageID })
+	return len(q.letters) < before
+}
+
+// Clear removes every dead letter and returns how many there were
+func (q *DeadLetterQueue) Clear() int {
+	return len(q.Take())
+}
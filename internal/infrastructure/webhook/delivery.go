@@ -0,0 +1,289 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// HeaderMessageID carries the ID of the delivered message, for idempotent
+	// processing of redeliveries
+	HeaderMessageID = "X-Webhook-ID"
+	// HeaderAttempt carries the delivery attempt number, starting at 1
+	HeaderAttempt = "X-Webhook-Attempt"
+
+	userAgent = "go-notification-sse-webhook/1.0"
+)
+
+// Delivery outcomes reported in Delivery.Status
+const (
+	DeliveryStatusDelivered    = "delivered"
+	DeliveryStatusDeadLettered = "dead_lettered"
+)
+
+var (
+	// ErrCircuitOpen is returned for deliveries not attempted because the
+	// endpoint's circuit breaker is open
+	ErrCircuitOpen = errors.New("webhook circuit breaker is open")
+	// ErrDeadLettered is returned for deliveries moved to the dead-letter queue
+	ErrDeadLettered = errors.New("webhook delivery dead-lettered")
+)
+
+// DeliveryConfig holds outbound delivery settings
+type DeliveryConfig struct {
+	Timeout        time.Duration `json:"timeout"         yaml:"timeout"`         // per attempt
+	MaxAttempts    int           `json:"max_attempts"    yaml:"max_attempts"`    // before dead-lettering
+	InitialBackoff time.Duration `json:"initial_backoff" yaml:"initial_backoff"` // doubled after each failed attempt
+	MaxBackoff     time.Duration `json:"max_backoff"     yaml:"max_backoff"`
+
+	// The circuit opens after BreakerThreshold consecutive failed deliveries and
+	// lets a trial delivery through after BreakerCooldown
+	BreakerThreshold int           `json:"breaker_threshold" yaml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `json:"breaker_cooldown"  yaml:"breaker_cooldown"`
+
+	LogSize        int `json:"log_size"         yaml:"log_size"`         // deliveries kept in the log
+	DeadLetterSize int `json:"dead_letter_size" yaml:"dead_letter_size"` // dead letters kept
+}
+
+// NewDefaultDeliveryConfig returns five attempts backing off from one second to
+// a minute, opening the circuit after five failed deliveries for 30 seconds
+func NewDefaultDeliveryConfig() *DeliveryConfig {
+	return &DeliveryConfig{
+		Timeout:          10 * time.Second,
+		MaxAttempts:      5,
+		InitialBackoff:   time.Second,
+		MaxBackoff:       time.Minute,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+		LogSize:          100,
+		DeadLetterSize:   1000,
+	}
+}
+
+// Endpoint is where a Sender POSTs payloads
+type Endpoint struct {
+	URL     string
+	Secret  string // signs payloads when set
+	Headers map[string]string
+}
+
+// Sender POSTs signed payloads to an endpoint, retrying failed attempts with
+// exponential backoff. Payloads that still fail, or that the endpoint rejects
+// permanently, go to the dead-letter queue.
+type Sender struct {
+	config  *DeliveryConfig
+	client  *http.Client
+	breaker *Breaker
+	log     *DeliveryLog
+	dead    *DeadLetterQueue
+
+	mu       sync.RWMutex
+	endpoint Endpoint
+}
+
+// NewSender creates a sender for endpoint; a nil client uses NewClient, which
+// only connects to public addresses
+func NewSender(endpoint Endpoint, config *DeliveryConfig, client *http.Client) *Sender {
+	if client == nil {
+		client = NewClient()
+	}
+	return &Sender{
+		config:   config,
+		client:   client,
+		breaker:  NewBreaker(config.BreakerThreshold, config.BreakerCooldown),
+		log:      NewDeliveryLog(config.LogSize),
+		dead:     NewDeadLetterQueue(config.DeadLetterSize),
+		endpoint: endpoint,
+	}
+}
+
+// Endpoint returns the current endpoint
+func (s *Sender) Endpoint() Endpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.endpoint
+}
+
+// SetEndpoint changes the endpoint for subsequent attempts
+func (s *Sender) SetEndpoint(endpoint Endpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endpoint = endpoint
+}
+
+// Breaker returns the endpoint's circuit breaker
+func (s *Sender) Breaker() *Breaker {
+	return s.breaker
+}
+
+// Log returns the log of recent deliveries
+func (s *Sender) Log() *DeliveryLog {
+	return s.log
+}
+
+// DeadLetters returns the dead-letter queue
+func (s *Sender) DeadLetters() *DeadLetterQueue {
+	return s.dead
+}
+
+// Deliver POSTs payload until the endpoint accepts it, the attempts run out or
+// ctx is done. Failed deliveries are dead-lettered and return an error wrapping
+// ErrDeadLettered; deliveries cancelled by ctx return its error and are neither
+// logged nor dead-lettered.
+func (s *Sender) Deliver(ctx context.Context, messageID string, payload []byte) error {
+	start := time.Now()
+	delivery := Delivery{MessageID: messageID}
+
+	var err error
+	if !s.breaker.Allow() {
+		err = ErrCircuitOpen
+	} else {
+		err = s.attempt(ctx, messageID, payload, &delivery)
+		if ctx.Err() != nil {
+			// Shutting down: neither the endpoint nor the breaker is to blame
+			s.breaker.Release()
+			return ctx.Err()
+		}
+		if err == nil {
+			s.breaker.Success()
+		} else {
+			s.breaker.Failure()
+		}
+	}
+
+	delivery.Duration = time.Since(start)
+	delivery.Timestamp = time.Now().UTC()
+	if err == nil {
+		delivery.Status = DeliveryStatusDelivered
+		s.log.add(delivery)
+		return nil
+	}
+
+	delivery.Status = DeliveryStatusDeadLettered
+	delivery.Error = err.Error()
+	s.log.add(delivery)
+	s.dead.add(DeadLetter{
+		MessageID:  messageID,
+		Payload:    payload,
+		Attempts:   delivery.Attempts,
+		StatusCode: delivery.StatusCode,
+		Error:      delivery.Error,
+		FailedAt:   delivery.Timestamp,
+	})
+	return fmt.Errorf("%w: %v", ErrDeadLettered, err)
+}
+
+// attempt makes up to MaxAttempts attempts, recording their outcome in delivery
+func (s *Sender) attempt(ctx context.Context, messageID string, payload []byte, delivery *Delivery) error {
+	backoff := s.config.InitialBackoff
+	for {
+		delivery.Attempts++
+		retryAfter, err := s.post(ctx, messageID, payload, delivery)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || delivery.Attempts >= s.config.MaxAttempts {
+			return err
+		}
+
+		wait := max(jitter(backoff), retryAfter)
+		wait = min(wait, s.config.MaxBackoff)
+		backoff = min(backoff*2, s.config.MaxBackoff)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// post makes one attempt. It returns how long the endpoint asked to wait before
+// retrying, if it did.
+func (s *Sender) post(ctx context.Context, messageID string, payload []byte, delivery *Delivery) (time.Duration, error) {
+	endpoint := s.Endpoint()
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, &permanentError{err}
+	}
+	for key, value := range endpoint.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderMessageID, messageID)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(delivery.Attempts))
+	if endpoint.Secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, payload))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		delivery.StatusCode = 0
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	delivery.StatusCode = resp.StatusCode
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		return parseRetryAfter(resp.Header.Get("Retry-After")), fmt.Errorf("endpoint responded %s", resp.Status)
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500:
+		return 0, fmt.Errorf("endpoint responded %s", resp.Status)
+	default:
+		// Other client errors will not succeed on retry
+		return 0, &permanentError{fmt.Errorf("endpoint responded %s", resp.Status)}
+	}
+}
+
+// permanentError is an attempt failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// jitter randomizes a backoff between half and all of it, so that retries from
+// many subscriptions do not synchronize
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + rand.N(d/2)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testDeliveryConfig() *DeliveryConfig {
+	config := NewDefaultDeliveryConfig()
+	config.Timeout = time.Second
+	config.MaxAttempts = 3
+	config.InitialBackoff = time.Millisecond
+	config.MaxBackoff = 5 * time.Millisecond
+	return config
+}
+
+func TestSender_SignsAndRetries(t *testing.T) {
+	verifier := NewVerifier(&Config{Secrets: []string{"secret"}, Tolerance: time.Minute})
+	payload := []byte(`{"message":{"id":"msg-1"}}`)
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := attempts.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if err := verifier.Verify(r.Header.Get(HeaderSignature), r.Header.Get(HeaderTimestamp), body); err != nil {
+			t.Errorf("Attempt %d: %v", n, err)
+		}
+		if r.Header.Get(HeaderMessageID) != "msg-1" || r.Header.Get(HeaderAttempt) != string(rune('0'+n)) || r.Header.Get("X-Tenant") != "acme" {
+			t.Errorf("Attempt %d: unexpected headers %v", n, r.Header)
+		}
+		if n < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sender := NewSender(Endpoint{URL: server.URL, Secret: "secret", Headers: map[string]string{"X-Tenant": "acme"}}, testDeliveryConfig(), server.Client())
+	if err := sender.Deliver(context.Background(), "msg-1", payload); err != nil {
+		t.Fatalf("Expected the third attempt to succeed, got %v", err)
+	}
+
+	deliveries := sender.Log().Recent(0)
+	if len(deliveries) != 1 || deliveries[0].Status != DeliveryStatusDelivered || deliveries[0].Attempts != 3 || deliveries[0].StatusCode != http.StatusNoContent {
+		t.Errorf("Unexpected delivery log %+v", deliveries)
+	}
+	if sender.DeadLetters().Len() != 0 {
+		t.Error("Expected no dead letters")
+	}
+}
+
+func TestSender_DeadLetters(t *testing.T) {
+	var attempts atomic.Int32
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sender := NewSender(Endpoint{URL: server.URL}, testDeliveryConfig(), server.Client())
+
+	// Retryable failures are attempted MaxAttempts times
+	err := sender.Deliver(context.Background(), "msg-1", []byte(`{}`))
+	if !errors.Is(err, ErrDeadLettered) || attempts.Load() != 3 {
+		t.Fatalf("Expected dead-lettering after 3 attempts, got %v after %d", err, attempts.Load())
+	}
+
+	// Client errors are not retried
+	status = http.StatusGone
+	attempts.Store(0)
+	if err := sender.Deliver(context.Background(), "msg-2", []byte(`{}`)); !errors.Is(err, ErrDeadLettered) || attempts.Load() != 1 {
+		t.Fatalf("Expected dead-lettering after 1 attempt, got %v after %d", err, attempts.Load())
+	}
+
+	letters := sender.DeadLetters().List()
+	if len(letters) != 2 || letters[0].MessageID != "msg-1" || letters[0].Attempts != 3 ||
+		letters[1].StatusCode != http.StatusGone || !strings.Contains(letters[1].Error, "410") {
+		t.Errorf("Unexpected dead letters %+v", letters)
+	}
+	if !sender.DeadLetters().Remove("msg-1") || sender.DeadLetters().Len() != 1 {
+		t.Error("Expected msg-1 to be removed")
+	}
+
+	// Cancelled deliveries are neither logged nor dead-lettered
+	status = http.StatusInternalServerError
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sender.Deliver(ctx, "msg-3", []byte(`{}`)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected cancellation, got %v", err)
+	}
+	if sender.DeadLetters().Len() != 1 || len(sender.Log().Recent(0)) != 2 {
+		t.Error("Expected the cancelled delivery not to be recorded")
+	}
+}
+
+func TestSender_CircuitBreaker(t *testing.T) {
+	var attempts atomic.Int32
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	config := testDeliveryConfig()
+	config.MaxAttempts = 1
+	config.BreakerThreshold = 2
+	config.BreakerCooldown = time.Minute
+	sender := NewSender(Endpoint{URL: server.URL}, config, server.Client())
+	now := time.Now()
+	sender.Breaker().now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		sender.Deliver(context.Background(), "msg", []byte(`{}`))
+	}
+	if state := sender.Breaker().State(); state != BreakerOpen {
+		t.Fatalf("Expected the circuit to open, got %s", state)
+	}
+
+	// Open circuits dead-letter without attempting
+	if err := sender.Deliver(context.Background(), "msg-open", []byte(`{}`)); !errors.Is(err, ErrDeadLettered) || attempts.Load() != 2 {
+		t.Fatalf("Expected no attempt while open, got %v after %d attempts", err, attempts.Load())
+	}
+	if letters := sender.DeadLetters().List(); letters[len(letters)-1].Error != ErrCircuitOpen.Error() {
+		t.Errorf("Expected the dead letter to record the open circuit, got %+v", letters[len(letters)-1])
+	}
+
+	// After the cooldown a successful trial closes the circuit
+	now = now.Add(time.Minute)
+	healthy.Store(true)
+	if state := sender.Breaker().State(); state != BreakerHalfOpen {
+		t.Fatalf("Expected the circuit to be half-open, got %s", state)
+	}
+	if err := sender.Deliver(context.Background(), "msg-trial", []byte(`{}`)); err != nil {
+		t.Fatalf("Expected the trial to succeed, got %v", err)
+	}
+	if state := sender.Breaker().State(); state != BreakerClosed {
+		t.Errorf("Expected the circuit to close, got %s", state)
+	}
+}
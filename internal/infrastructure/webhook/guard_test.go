@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckURL(t *testing.T) {
+	for rawURL, valid := range map[string]bool{
+		"https://93.184.216.34/hooks":                               true,
+		"http://[2606:2800:220:1:248:1893:25c8:1946]:8080/hooks":    true,
+		"ftp://93.184.216.34/hooks":                                 false,
+		"/hooks":                                                    false,
+		"http://127.0.0.1:9090/api/v1/admin/users/alice/disconnect": false,
+		"http://localhost/hooks":                                    false,
+		"http://10.0.0.1/hooks":                                     false,
+		"http://192.168.1.1/hooks":                                  false,
+		"http://169.254.169.254/latest/meta-data":                   false,
+		"http://100.100.100.200/latest/meta-data":                   false,
+		"http://0.0.0.0/hooks":                                      false,
+		"http://[::1]/hooks":                                        false,
+		"http://[::ffff:127.0.0.1]/hooks":                           false,
+		"http://[fe80::1]/hooks":                                    false,
+	} {
+		if err := CheckURL(context.Background(), rawURL); (err == nil) != valid {
+			t.Errorf("CheckURL(%s) = %v, expected valid %v", rawURL, err, valid)
+		}
+	}
+}
+
+func TestNewClient_RefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no request to reach a loopback endpoint")
+	}))
+	defer server.Close()
+
+	// The host name passes no check of its own: the dialer must refuse it
+	_, err := NewClient().Get(server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Expected ErrForbiddenAddress, got %v", err)
+	}
+}
+
+func TestNewClient_DoesNotFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/hooks" {
+			t.Errorf("Expected the redirect not to be followed to %s", r.URL.Path)
+		}
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.Transport = server.Client().Transport // reach the loopback test server
+	resp, err := client.Get(server.URL + "/hooks")
+	if err != nil {
+		t.Fatalf("Expected the redirect response, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("Expected 302, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"msg-1","type":"notification"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	verifier := NewVerifier(&Config{
+		Secrets:   []string{"new-secret", "old-secret"},
+		Tolerance: 5 * time.Minute,
+	})
+	verifier.now = func() time.Time { return now }
+
+	tests := []struct {
+		name      string
+		signature string
+		timestamp string
+		body      []byte
+		wantErr   error
+	}{
+		{
+			name:      "current secret",
+			signature: Sign("new-secret", now.Unix(), body),
+			timestamp: timestamp,
+			body:      body,
+		},
+		{
+			name:      "rotated secret",
+			signature: Sign("old-secret", now.Unix(), body),
+			timestamp: timestamp,
+			body:      body,
+		},
+		{
+			name:      "one of several signatures",
+			signature: Sign("unknown", now.Unix(), body) + ", " + Sign("new-secret", now.Unix(), body),
+			timestamp: timestamp,
+			body:      body,
+		},
+		{
+			name:      "missing signature",
+			timestamp: timestamp,
+			body:      body,
+			wantErr:   ErrMissingSignature,
+		},
+		{
+			name:      "unknown secret",
+			signature: Sign("unknown", now.Unix(), body),
+			timestamp: timestamp,
+			body:      body,
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "tampered body",
+			signature: Sign("new-secret", now.Unix(), body),
+			timestamp: timestamp,
+			body:      []byte(`{"id":"msg-2"}`),
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "replayed outside window",
+			signature: Sign("new-secret", now.Add(-time.Hour).Unix(), body),
+			timestamp: strconv.FormatInt(now.Add(-time.Hour).Unix(), 10),
+			body:      body,
+			wantErr:   ErrTimestampExpired,
+		},
+		{
+			name:      "malformed timestamp",
+			signature: Sign("new-secret", now.Unix(), body),
+			timestamp: "yesterday",
+			body:      body,
+			wantErr:   ErrInvalidTimestamp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifier.Verify(tt.signature, tt.timestamp, tt.body)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Expected valid signature, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// recordSize is the aes128gcm record size; push services accept 4096 byte
+	// bodies, so every message is a single record
+	recordSize = 4096
+
+	saltSize   = 16
+	authSize   = 16
+	keySize    = 65 // uncompressed P-256 point
+	headerSize = saltSize + 4 + 1 + keySize
+	tagSize    = 16
+
+	// MaxPayloadSize is the largest payload that fits in one record after the
+	// header, the padding delimiter and the authentication tag
+	MaxPayloadSize = recordSize - headerSize - 1 - tagSize
+)
+
+var (
+	ErrInvalidSubscriptionKeys = errors.New("invalid push subscription keys")
+	ErrPayloadTooLarge         = fmt.Errorf("push payload exceeds %d bytes", MaxPayloadSize)
+)
+
+// Encrypt encrypts payload for the user agent holding the subscription's keys
+// using the aes128gcm content encoding (RFC 8291, RFC 8188)
+func Encrypt(keys Keys, payload []byte) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+
+	uaPublicBytes, err := decode(keys.P256DH)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubscriptionKeys, err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubscriptionKeys, err)
+	}
+	authSecret, err := decode(keys.Auth)
+	if err != nil || len(authSecret) != authSize {
+		return nil, fmt.Errorf("%w: auth secret must be %d bytes", ErrInvalidSubscriptionKeys, authSize)
+	}
+
+	// A fresh key pair and salt per message
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return encrypt(uaPublic, authSecret, asPrivate, salt, payload)
+}
+
+// encrypt encrypts payload as a single aes128gcm record with the given
+// application server key pair and salt
+func encrypt(uaPublic *ecdh.PublicKey, authSecret []byte, asPrivate *ecdh.PrivateKey, salt, payload []byte) ([]byte, error) {
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+	uaPublicBytes := uaPublic.Bytes()
+
+	cek, nonce, err := deriveKeys(asPrivate, uaPublic, asPublicBytes, uaPublicBytes, authSecret, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, headerSize, headerSize+len(payload)+1+tagSize)
+	copy(body, salt)
+	binary.BigEndian.PutUint32(body[saltSize:], recordSize)
+	body[saltSize+4] = keySize
+	copy(body[saltSize+5:], asPublicBytes)
+
+	// The last (and only) record is delimited by 0x02
+	plaintext := append(append(make([]byte, 0, len(payload)+1), payload...), 0x02)
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+// deriveKeys derives the content encryption key and nonce from the ECDH shared
+// secret, mixing in the subscription's auth secret and both public keys
+func deriveKeys(private *ecdh.PrivateKey, peer *ecdh.PublicKey, asPublic, uaPublic, authSecret, salt []byte) (cek, nonce []byte, err error) {
+	shared, err := private.ECDH(peer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSubscriptionKeys, err)
+	}
+
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, shared, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cek, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16); err != nil {
+		return nil, nil, err
+	}
+	if nonce, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12); err != nil {
+		return nil, nil, err
+	}
+	return cek, nonce, nil
+}
@@ -0,0 +1,144 @@
+// Package webpush sends Web Push messages (RFC 8030) to browsers that are not
+// connected: payloads are encrypted for the subscribing browser (RFC 8291) and
+// POSTed to its push service, identifying the application server with VAPID
+// (RFC 8292).
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Urgency tells the push service how soon to wake the device (RFC 8030 §5.3)
+type Urgency string
+
+const (
+	UrgencyVeryLow Urgency = "very-low"
+	UrgencyLow     Urgency = "low"
+	UrgencyNormal  Urgency = "normal"
+	UrgencyHigh    Urgency = "high"
+)
+
+// vapidLifetime is how long VAPID tokens are valid; push services reject more
+// than 24 hours
+const vapidLifetime = 12 * time.Hour
+
+// ErrSubscriptionGone is returned when the push service no longer knows the
+// subscription; it should be removed
+var ErrSubscriptionGone = errors.New("push subscription expired or unsubscribed")
+
+// Config holds Web Push settings
+type Config struct {
+	TTL                     time.Duration `json:"ttl"                        yaml:"ttl"`     // how long push services keep undelivered messages
+	Timeout                 time.Duration `json:"timeout"                    yaml:"timeout"` // per push
+	MaxSubscriptionsPerUser int           `json:"max_subscriptions_per_user" yaml:"max_subscriptions_per_user"`
+	PushServices            []string      `json:"push_services"              yaml:"push_services"` // hosts subscription endpoints may point at
+}
+
+// NewDefaultConfig returns a config keeping messages for a day, with ten
+// browsers per user, accepting the push services of the major browsers
+func NewDefaultConfig() *Config {
+	return &Config{
+		TTL:                     24 * time.Hour,
+		Timeout:                 10 * time.Second,
+		MaxSubscriptionsPerUser: 10,
+		PushServices: []string{
+			"fcm.googleapis.com",
+			"updates.push.services.mozilla.com",
+			"*.notify.windows.com",
+			"web.push.apple.com",
+		},
+	}
+}
+
+// Options are per-message push settings
+type Options struct {
+	TTL     time.Duration // zero uses the config's TTL
+	Urgency Urgency       // empty leaves it to the push service (normal)
+	Topic   string        // replaces an undelivered message with the same topic
+}
+
+// Sender encrypts payloads and POSTs them to push services
+type Sender struct {
+	vapid  *VAPID
+	config *Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewSender creates a sender; a nil client uses one with the config's timeout
+func NewSender(vapid *VAPID, config *Config, client *http.Client) *Sender {
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+	return &Sender{
+		vapid:  vapid,
+		config: config,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// VAPID returns the application server identity
+func (s *Sender) VAPID() *VAPID {
+	return s.vapid
+}
+
+// Send pushes an encrypted payload to a subscription, returning
+// ErrSubscriptionGone if the push service has dropped it
+func (s *Sender) Send(ctx context.Context, sub Subscription, payload []byte, opts Options) error {
+	if sub.Expired(s.now()) {
+		return ErrSubscriptionGone
+	}
+
+	body, err := Encrypt(sub.Keys, payload)
+	if err != nil {
+		return err
+	}
+	authorization, err := s.vapid.Authorization(sub.Endpoint, s.now().Add(vapidLifetime))
+	if err != nil {
+		return err
+	}
+
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = s.config.TTL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", string(opts.Urgency))
+	}
+	if opts.Topic != "" {
+		req.Header.Set("Topic", opts.Topic)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	default:
+		return fmt.Errorf("push service responded %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+}
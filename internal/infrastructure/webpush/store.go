@@ -0,0 +1,164 @@
+package webpush
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrEndpointInUse is returned when a user registers an endpoint that is
+// already registered for another user
+var ErrEndpointInUse = errors.New("push subscription endpoint is registered for another user")
+
+// Keys are the user agent's message encryption keys
+type Keys struct {
+	P256DH string `json:"p256dh"`
+	Auth   string `json:"auth"`
+}
+
+// Subscription is a browser PushSubscription, as serialised by
+// PushSubscription.toJSON()
+type Subscription struct {
+	Endpoint       string `json:"endpoint"`
+	ExpirationTime *int64 `json:"expirationTime,omitempty"` // unix milliseconds
+	Keys           Keys   `json:"keys"`
+}
+
+// Validate checks the endpoint is an absolute https URL on one of the push
+// services, so that pushes cannot be aimed at arbitrary hosts, and the keys
+// decode. A service is a host, optionally with a port, or a "*." wildcard
+// matching its subdomains.
+func (s *Subscription) Validate(pushServices []string) error {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil || u.Host == "" || u.Scheme != "https" {
+		return errors.New("push subscription endpoint must be an absolute https URL")
+	}
+	if !slices.ContainsFunc(pushServices, func(service string) bool { return matchHost(service, u.Host) }) {
+		return errors.New("push subscription endpoint is not a known push service")
+	}
+	if public, err := decode(s.Keys.P256DH); err != nil || len(public) != keySize {
+		return ErrInvalidSubscriptionKeys
+	}
+	if auth, err := decode(s.Keys.Auth); err != nil || len(auth) != authSize {
+		return ErrInvalidSubscriptionKeys
+	}
+	return nil
+}
+
+// matchHost reports whether host matches a push service
+func matchHost(service, host string) bool {
+	host = strings.ToLower(host)
+	if suffix, ok := strings.CutPrefix(service, "*"); ok {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return host == service
+}
+
+// Expired reports whether the browser said the subscription ends before now
+func (s *Subscription) Expired(now time.Time) bool {
+	return s.ExpirationTime != nil && *s.ExpirationTime > 0 && now.UnixMilli() >= *s.ExpirationTime
+}
+
+// Registration is a subscription stored for a user
+type Registration struct {
+	UserID       string       `json:"user_id"`
+	Subscription Subscription `json:"subscription"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// Store keeps each user's push subscriptions, one per browser. An endpoint
+// belongs to a single user until they remove it.
+type Store struct {
+	maxPerUser int
+
+	mu    sync.RWMutex
+	users map[string][]Registration
+}
+
+// NewStore creates a store keeping up to maxPerUser subscriptions per user,
+// dropping the oldest beyond that; zero is unlimited
+func NewStore(maxPerUser int) *Store {
+	return &Store{
+		maxPerUser: maxPerUser,
+		users:      make(map[string][]Registration),
+	}
+}
+
+// Add stores a subscription for a user, returning the registration and whether
+// it is new. It returns ErrEndpointInUse if another user registered the
+// endpoint.
+func (s *Store) Add(userID string, sub Subscription) (Registration, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, registrations := range s.users {
+		if id != userID && slices.ContainsFunc(registrations, func(r Registration) bool {
+			return r.Subscription.Endpoint == sub.Endpoint
+		}) {
+			return Registration{}, false, ErrEndpointInUse
+		}
+	}
+
+	registrations := s.users[userID]
+	before := len(registrations)
+	registrations = slices.DeleteFunc(registrations, func(r Registration) bool {
+		return r.Subscription.Endpoint == sub.Endpoint
+	})
+	created := len(registrations) == before
+
+	registration := Registration{
+		UserID:       userID,
+		Subscription: sub,
+		CreatedAt:    time.Now().UTC(),
+	}
+	registrations = append(registrations, registration)
+	if s.maxPerUser > 0 && len(registrations) > s.maxPerUser {
+		registrations = registrations[len(registrations)-s.maxPerUser:]
+	}
+	s.users[userID] = registrations
+	return registration, created, nil
+}
+
+// Remove deletes a user's subscription, reporting whether it was stored
+func (s *Store) Remove(userID, endpoint string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	registrations := s.users[userID]
+	before := len(registrations)
+	registrations = slices.DeleteFunc(registrations, func(r Registration) bool {
+		return r.Subscription.Endpoint == endpoint
+	})
+	s.set(userID, registrations)
+	return len(registrations) < before
+}
+
+// List returns a user's subscriptions, oldest first
+func (s *Store) List(userID string) []Registration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.users[userID])
+}
+
+// Len returns the number of stored subscriptions
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, registrations := range s.users {
+		n += len(registrations)
+	}
+	return n
+}
+
+func (s *Store) set(userID string, registrations []Registration) {
+	if len(registrations) == 0 {
+		delete(s.users, userID)
+		return
+	}
+	s.users[userID] = registrations
+}
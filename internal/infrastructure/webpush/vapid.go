@@ -0,0 +1,113 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrInvalidVAPIDKey is returned for application server keys that are not P-256 keys
+var ErrInvalidVAPIDKey = errors.New("invalid VAPID key")
+
+// VAPID identifies the application server to push services (RFC 8292). Browsers
+// are given PublicKey as the applicationServerKey when subscribing, and push
+// services only accept messages for those subscriptions signed with the
+// matching private key.
+type VAPID struct {
+	key     *ecdsa.PrivateKey
+	public  []byte // uncompressed P-256 point
+	subject string // mailto: or https: contact for the push service operator
+}
+
+// GenerateVAPIDKeys returns a new key pair as unpadded base64url strings, the
+// format browsers and other Web Push libraries use
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return encode(key.PublicKey().Bytes()), encode(key.Bytes()), nil
+}
+
+// NewVAPID creates the application server identity from a base64url-encoded
+// private key
+func NewVAPID(privateKey, subject string) (*VAPID, error) {
+	raw, err := decode(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVAPIDKey, err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVAPIDKey, err)
+	}
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https:") {
+		return nil, fmt.Errorf("VAPID subject must be a mailto: or https: URL")
+	}
+
+	public := key.PublicKey().Bytes()
+	return &VAPID{
+		key: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(public[1:33]),
+				Y:     new(big.Int).SetBytes(public[33:]),
+			},
+			D: new(big.Int).SetBytes(raw),
+		},
+		public:  public,
+		subject: subject,
+	}, nil
+}
+
+// PublicKey returns the base64url-encoded public key
+func (v *VAPID) PublicKey() string {
+	return encode(v.public)
+}
+
+// Authorization returns the Authorization header value for a push to endpoint,
+// valid until expires
+func (v *VAPID) Authorization(endpoint string, expires time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid push endpoint %q", endpoint)
+	}
+
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	claims, _ := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": expires.Unix(),
+		"sub": v.subject,
+	})
+	unsigned := encode(header) + "." + encode(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, v.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return "vapid t=" + unsigned + "." + encode(signature) + ", k=" + v.PublicKey(), nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decode accepts base64url with or without padding, as browsers and libraries
+// differ
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
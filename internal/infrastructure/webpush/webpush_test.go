@@ -0,0 +1,281 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := decode(s)
+	if err != nil {
+		t.Fatalf("Failed to decode %q: %v", s, err)
+	}
+	return b
+}
+
+// decrypt decrypts a single-record aes128gcm body as the user agent would
+func decrypt(t *testing.T, uaPrivate *ecdh.PrivateKey, authSecret, body []byte) []byte {
+	t.Helper()
+	if len(body) < headerSize || binary.BigEndian.Uint32(body[saltSize:]) != recordSize || body[saltSize+4] != keySize {
+		t.Fatalf("Malformed aes128gcm header")
+	}
+	salt, asPublicBytes := body[:saltSize], body[saltSize+5:headerSize]
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		t.Fatalf("Invalid application server key: %v", err)
+	}
+
+	cek, nonce, err := deriveKeys(uaPrivate, asPublic, asPublicBytes, uaPrivate.PublicKey().Bytes(), authSecret, salt)
+	if err != nil {
+		t.Fatalf("Failed to derive keys: %v", err)
+	}
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, body[headerSize:], nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatalf("Expected a last-record delimiter")
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+// newBrowserKeys returns a user agent key pair and the subscription keys for it
+func newBrowserKeys(t *testing.T) (*ecdh.PrivateKey, []byte, Keys) {
+	t.Helper()
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, authSize)
+	rand.Read(auth)
+	return private, auth, Keys{P256DH: encode(private.PublicKey().Bytes()), Auth: encode(auth)}
+}
+
+func TestEncrypt_RFC8291Example(t *testing.T) {
+	// RFC 8291 Appendix A
+	asPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(mustDecode(t, "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authSecret := mustDecode(t, "BTBZMqHH6r4Tts7J_aSIgg")
+	salt := mustDecode(t, "DGv6ra1nlYgDCS1FRnbzlw")
+
+	body, err := encrypt(uaPublic, authSecret, asPrivate, salt, []byte("When I grow up, I want to be a watermelon"))
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	want := "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+	if got := encode(body); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestEncrypt_RoundTrip(t *testing.T) {
+	uaPrivate, auth, keys := newBrowserKeys(t)
+
+	payload := []byte(`{"message":{"id":"msg-1"}}`)
+	body, err := Encrypt(keys, payload)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if got := decrypt(t, uaPrivate, auth, body); !bytes.Equal(got, payload) {
+		t.Errorf("Expected %s, got %s", payload, got)
+	}
+
+	if _, err := Encrypt(keys, make([]byte, MaxPayloadSize+1)); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("Expected ErrPayloadTooLarge, got %v", err)
+	}
+	if _, err := Encrypt(Keys{P256DH: keys.P256DH, Auth: "c2hvcnQ"}, payload); !errors.Is(err, ErrInvalidSubscriptionKeys) {
+		t.Errorf("Expected ErrInvalidSubscriptionKeys, got %v", err)
+	}
+}
+
+// verifyVAPID checks a vapid Authorization header and returns its claims
+func verifyVAPID(t *testing.T, authorization, publicKey string) map[string]any {
+	t.Helper()
+	token, key, ok := strings.Cut(strings.TrimPrefix(authorization, "vapid t="), ", k=")
+	if !ok || key != publicKey {
+		t.Fatalf("Malformed VAPID authorization %q", authorization)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Malformed JWT %q", token)
+	}
+	public := mustDecode(t, key)
+	signature := mustDecode(t, parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	verifier := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(public[1:33]),
+		Y:     new(big.Int).SetBytes(public[33:]),
+	}
+	if !ecdsa.Verify(verifier, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		t.Fatal("Invalid VAPID signature")
+	}
+
+	var claims map[string]any
+	if err := json.Unmarshal(mustDecode(t, parts[1]), &claims); err != nil {
+		t.Fatalf("Invalid JWT claims: %v", err)
+	}
+	return claims
+}
+
+func newTestVAPID(t *testing.T) *VAPID {
+	t.Helper()
+	public, private, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vapid, err := NewVAPID(private, "mailto:ops@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create VAPID: %v", err)
+	}
+	if vapid.PublicKey() != public {
+		t.Fatal("Expected the public key to match the generated one")
+	}
+	return vapid
+}
+
+func TestVAPID_Authorization(t *testing.T) {
+	vapid := newTestVAPID(t)
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	authorization, err := vapid.Authorization("https://push.example.net/wpush/v2/abc?x=1", expires)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	claims := verifyVAPID(t, authorization, vapid.PublicKey())
+	if claims["aud"] != "https://push.example.net" || claims["sub"] != "mailto:ops@example.com" || claims["exp"] != float64(expires.Unix()) {
+		t.Errorf("Unexpected claims %v", claims)
+	}
+
+	if _, err := NewVAPID("not-a-key", "mailto:ops@example.com"); !errors.Is(err, ErrInvalidVAPIDKey) {
+		t.Errorf("Expected ErrInvalidVAPIDKey, got %v", err)
+	}
+}
+
+func TestSender_PushesToService(t *testing.T) {
+	vapid := newTestVAPID(t)
+	uaPrivate, auth, keys := newBrowserKeys(t)
+
+	pushed := make(chan []byte, 1)
+	service := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		claims := verifyVAPID(t, r.Header.Get("Authorization"), vapid.PublicKey())
+		if claims["aud"] != "https://"+r.Host {
+			t.Errorf("Expected audience https://%s, got %v", r.Host, claims["aud"])
+		}
+		if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") != "60" || r.Header.Get("Urgency") != "high" {
+			t.Errorf("Unexpected headers %v", r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		pushed <- decrypt(t, uaPrivate, auth, body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer service.Close()
+
+	sender := NewSender(vapid, NewDefaultConfig(), service.Client())
+	opts := Options{TTL: time.Minute, Urgency: UrgencyHigh}
+
+	sub := Subscription{Endpoint: service.URL + "/push/1", Keys: keys}
+	if err := sub.Validate([]string{strings.TrimPrefix(service.URL, "https://")}); err != nil {
+		t.Fatalf("Expected a valid subscription, got %v", err)
+	}
+	if err := sender.Send(context.Background(), sub, []byte("hello"), opts); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if got := <-pushed; string(got) != "hello" {
+		t.Errorf("Expected hello, got %s", got)
+	}
+
+	gone := Subscription{Endpoint: service.URL + "/gone", Keys: keys}
+	if err := sender.Send(context.Background(), gone, []byte("hello"), opts); !errors.Is(err, ErrSubscriptionGone) {
+		t.Errorf("Expected ErrSubscriptionGone, got %v", err)
+	}
+
+	expired := time.Now().Add(-time.Minute).UnixMilli()
+	sub.ExpirationTime = &expired
+	if err := sender.Send(context.Background(), sub, []byte("hello"), opts); !errors.Is(err, ErrSubscriptionGone) {
+		t.Errorf("Expected an expired subscription to be gone, got %v", err)
+	}
+}
+
+func TestStore_OneUserPerEndpoint(t *testing.T) {
+	store := NewStore(2)
+
+	if _, created, _ := store.Add("alice", Subscription{Endpoint: "https://push/1"}); !created {
+		t.Error("Expected a new subscription")
+	}
+	if _, created, _ := store.Add("alice", Subscription{Endpoint: "https://push/1"}); created {
+		t.Error("Expected re-registering to update the subscription")
+	}
+	store.Add("alice", Subscription{Endpoint: "https://push/2"})
+	store.Add("alice", Subscription{Endpoint: "https://push/3"})
+	if registrations := store.List("alice"); len(registrations) != 2 || registrations[0].Subscription.Endpoint != "https://push/2" {
+		t.Errorf("Expected the oldest subscription to be dropped, got %+v", registrations)
+	}
+
+	// Another user cannot take over alice's browser until she removes it
+	if _, _, err := store.Add("bob", Subscription{Endpoint: "https://push/3"}); !errors.Is(err, ErrEndpointInUse) {
+		t.Errorf("Expected ErrEndpointInUse, got %v", err)
+	}
+	if len(store.List("alice")) != 2 || len(store.List("bob")) != 0 {
+		t.Errorf("Expected the endpoint to stay with alice")
+	}
+	if !store.Remove("alice", "https://push/3") || store.Remove("alice", "https://push/3") {
+		t.Error("Expected the subscription to be removed once")
+	}
+	if _, created, err := store.Add("bob", Subscription{Endpoint: "https://push/3"}); err != nil || !created {
+		t.Errorf("Expected the removed endpoint to be new for bob, got %v", err)
+	}
+}
+
+func TestSubscription_ValidatePushService(t *testing.T) {
+	_, _, keys := newBrowserKeys(t)
+	services := NewDefaultConfig().PushServices
+
+	for endpoint, valid := range map[string]bool{
+		"https://fcm.googleapis.com/fcm/send/abc":         true,
+		"https://wns2-by3p.notify.windows.com/w/?token=a": true,
+		"https://FCM.googleapis.com/fcm/send/abc":         true,
+		"https://notify.windows.com/w/":                   false,
+		"https://evilnotify.windows.com/w/":               false,
+		"https://fcm.googleapis.com:8443/fcm/send/abc":    false,
+		"https://169.254.169.254/latest/meta-data":        false,
+		"http://fcm.googleapis.com/fcm/send/abc":          false,
+	} {
+		sub := Subscription{Endpoint: endpoint, Keys: keys}
+		if err := sub.Validate(services); (err == nil) != valid {
+			t.Errorf("Validate(%s) = %v, expected valid %v", endpoint, err, valid)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package admin
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/interfaces/middleware"
+)
+
+const (
+	// eventStreamBuffer is the number of hub events buffered per dashboard client
+	eventStreamBuffer = 256
+
+	// statsInterval is how often the event stream emits a stats snapshot
+	statsInterval = 5 * time.Second
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// DashboardFS returns the embedded dashboard assets
+func DashboardFS() http.FileSystem {
+	sub, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+	return http.FS(sub)
+}
+
+// PublishRequest is a test message published from the admin API
+type PublishRequest struct {
+	Type     string `json:"type" binding:"required"`
+	Data     any    `json:"data"`
+	Priority string `json:"priority"`
+}
+
+// SendRequest is a test message sent to exactly one connection, user or topic
+type SendRequest struct {
+	PublishRequest
+	ConnectionID string `json:"connection_id"`
+	UserID       string `json:"user_id"`
+	Topic        string `json:"topic"`
+}
+
+// Stats is a snapshot of the hub's connections
+type Stats struct {
+	Running     bool           `json:"running"`
+	Draining    bool           `json:"draining"`
+	Connections int            `json:"connections"`
+	ByType      map[string]int `json:"by_type"`
+	Queues      map[string]int `json:"queues"`
+	Timestamp   time.Time      `json:"timestamp"`
+}
+
+// Stats returns connection counts by transport and the hub queue depths
+func (h *AdminHandler) Stats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.stats())
+}
+
+// Events streams hub events (connection lifecycle and published messages) as
+// server-sent events, interleaved with periodic stats snapshots
+func (h *AdminHandler) Events(c *gin.Context) {
+	log := h.logger.WithContext(c.Request.Context())
+
+	sub := h.hub.Events().Subscribe(
+		eventStreamBuffer,
+		hub.EventConnectionRegistered,
+		hub.EventConnectionUnregistered,
+		hub.EventMessagePublished,
+	)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	log.Infof("Admin event stream opened by %s", c.ClientIP())
+	defer log.Infof("Admin event stream closed for %s", c.ClientIP())
+
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	c.Render(-1, sse.Event{Event: "stats", Data: h.stats()})
+	c.Writer.Flush()
+
+	var dropped int64
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			c.Render(-1, sse.Event{Event: event.Topic, Data: event})
+		case <-ticker.C:
+			c.Render(-1, sse.Event{Event: "stats", Data: h.stats()})
+			if n := sub.Dropped(); n != dropped {
+				c.Render(-1, sse.Event{Event: "dropped", Data: gin.H{"dropped": n - dropped}})
+				dropped = n
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// Broadcast publishes a test message to every connection
+func (h *AdminHandler) Broadcast(c *gin.Context) {
+	log := h.logger.WithContext(c.Request.Context())
+
+	var req PublishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid message format",
+		})
+		return
+	}
+
+	message := buildAdminMessage(&req)
+	recipients := h.hub.ConnectionCount()
+
+	entry := middleware.NewAuditEntry(c, audit.ActionBroadcast, "broadcast")
+	entry.MessageID = message.ID
+	entry.Details = map[string]any{"source": "admin"}
+	defer h.auditor.Record(c.Request.Context(), entry)
+
+	if err := h.hub.Broadcast(c.Request.Context(), message); err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		log.Errorf("Failed to broadcast admin message: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to broadcast message",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":      "broadcasted",
+		"message_id":  message.ID,
+		"connections": recipients,
+	})
+}
+
+// Send delivers a test message to a single connection, user or topic
+func (h *AdminHandler) Send(c *gin.Context) {
+	log := h.logger.WithContext(c.Request.Context())
+
+	var req SendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid message format",
+		})
+		return
+	}
+
+	targets := 0
+	for _, target := range []string{req.ConnectionID, req.UserID, req.Topic} {
+		if target != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Specify exactly one of connection_id, user_id or topic",
+		})
+		return
+	}
+
+	message := buildAdminMessage(&req.PublishRequest)
+	ctx := c.Request.Context()
+
+	var (
+		action     audit.Action
+		target     string
+		recipients int
+		err        error
+	)
+	switch {
+	case req.ConnectionID != "":
+		action, target, recipients = audit.ActionSendToConn, "connection:"+req.ConnectionID, 1
+		err = h.hub.SendToConnection(ctx, req.ConnectionID, message)
+	case req.UserID != "":
+		action, target = audit.ActionSendToUser, "user:"+req.UserID
+		recipients, err = h.hub.SendToUser(ctx, req.UserID, message)
+	default:
+		action, target = audit.ActionPublishToTopic, "topic:"+req.Topic
+		recipients, err = h.hub.PublishToTopic(ctx, req.Topic, message)
+	}
+
+	entry := middleware.NewAuditEntry(c, action, target)
+	entry.MessageID = message.ID
+	entry.Details = map[string]any{"source": "admin"}
+	defer h.auditor.Record(ctx, entry)
+
+	if err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		log.Errorf("Failed to send admin message to %s: %v", target, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to send message",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":      "sent",
+		"message_id":  message.ID,
+		"target":      target,
+		"connections": recipients,
+	})
+}
+
+func (h *AdminHandler) stats() Stats {
+	return Stats{
+		Running:     h.hub.IsRunning(),
+		Draining:    h.hub.IsDraining(),
+		Connections: h.hub.ConnectionCount(),
+		ByType:      h.hub.CountByType(),
+		Queues:      h.hub.QueueDepths(),
+		Timestamp:   time.Now().UTC(),
+	}
+}
+
+// buildAdminMessage maps an admin publish request onto a hub message
+func buildAdminMessage(req *PublishRequest) *hub.Message {
+	builder := hub.NewMessageBuilder().
+		WithType(hub.MessageType(req.Type)).
+		WithData(req.Data).
+		WithHeader("source", "admin")
+
+	if req.Priority != "" {
+		builder.WithPriority(hub.MessagePriority(req.Priority))
+	}
+	return builder.Build()
+}
@@ -0,0 +1,189 @@
+(function () {
+  'use strict';
+
+  var base = '/admin';
+  var maxTail = 500;
+  var maxRows = 500;
+
+  var el = function (id) { return document.getElementById(id); };
+  var connections = [];
+  var droppedTotal = 0;
+
+  function text(tag, value, className) {
+    var node = document.createElement(tag);
+    node.textContent = value == null ? '' : String(value);
+    if (className) node.className = className;
+    return node;
+  }
+
+  function renderStats(stats) {
+    el('total').textContent = stats.connections;
+    var byType = el('by-type');
+    byType.replaceChildren();
+    Object.keys(stats.by_type || {}).sort().forEach(function (type) {
+      var card = text('div', '', 'card');
+      card.append(text('span', stats.by_type[type], 'value'), text('span', type, 'label'));
+      byType.append(card);
+    });
+  }
+
+  function matches(conn, query) {
+    if (!query) return true;
+    var haystack = [conn.id, conn.type, conn.principal, conn.remote_addr]
+      .concat(conn.subscriptions || [], conn.tags || [])
+      .join(' ')
+      .toLowerCase();
+    return haystack.indexOf(query) !== -1;
+  }
+
+  function renderConnections() {
+    var query = el('search').value.trim().toLowerCase();
+    var body = el('connections');
+    body.replaceChildren();
+
+    connections.filter(function (conn) { return matches(conn, query); })
+      .slice(0, maxRows)
+      .forEach(function (conn) {
+        var row = document.createElement('tr');
+        var disconnect = text('button', 'Disconnect');
+        disconnect.type = 'button';
+        disconnect.onclick = function () { disconnectConnection(conn.id); };
+        var action = document.createElement('td');
+        action.append(disconnect);
+
+        row.append(
+          text('td', conn.id, 'id'),
+          text('td', conn.type),
+          text('td', conn.principal),
+          text('td', conn.remote_addr),
+          text('td', (conn.subscriptions || []).join(', ')),
+          text('td', conn.messages_sent),
+          text('td', conn.queue_depth),
+          text('td', new Date(conn.connected_at).toLocaleTimeString()),
+          action
+        );
+        body.append(row);
+      });
+  }
+
+  function loadConnections() {
+    return fetch(base + '/connections?limit=' + maxRows)
+      .then(function (res) { return res.json(); })
+      .then(function (page) {
+        connections = page.connections || [];
+        renderConnections();
+      });
+  }
+
+  function disconnectConnection(id) {
+    if (!confirm('Disconnect ' + id + '?')) return;
+    fetch(base + '/connections/' + encodeURIComponent(id) + '/disconnect', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ reason: 'disconnected from admin dashboard' })
+    }).then(loadConnections);
+  }
+
+  function appendTail(event) {
+    if (el('pause').checked) return;
+
+    var item = document.createElement('li');
+    item.append(text('span', new Date(event.timestamp).toLocaleTimeString(), 'time'));
+    item.append(text('span', event.topic, 'topic'));
+
+    var payload = event.payload || {};
+    if (event.topic === 'message.published') {
+      var msg = payload.message || {};
+      item.append(text('span', payload.target + ' (' + payload.recipients + ') ' +
+        msg.type + ' ' + msg.id + ' ' + JSON.stringify(msg.data)));
+    } else {
+      item.append(text('span', payload.connection_type + ' ' + payload.connection_id, 'connection'));
+    }
+
+    var tail = el('tail');
+    tail.prepend(item);
+    while (tail.children.length > maxTail) tail.lastChild.remove();
+  }
+
+  function connect() {
+    var status = el('status');
+    var source = new EventSource(base + '/events');
+    var refreshTimer = null;
+
+    // Connection lifecycle events arrive in bursts; coalesce table reloads
+    function scheduleRefresh() {
+      if (refreshTimer) return;
+      refreshTimer = setTimeout(function () {
+        refreshTimer = null;
+        loadConnections();
+      }, 1000);
+    }
+
+    source.onopen = function () {
+      status.textContent = 'live';
+      status.className = 'badge live';
+    };
+    source.onerror = function () {
+      status.textContent = 'reconnecting';
+      status.className = 'badge down';
+    };
+
+    source.addEventListener('stats', function (e) {
+      renderStats(JSON.parse(e.data));
+    });
+    source.addEventListener('dropped', function (e) {
+      droppedTotal += JSON.parse(e.data).dropped;
+      el('dropped').textContent = droppedTotal + ' events dropped';
+    });
+    ['connection.registered', 'connection.unregistered'].forEach(function (topic) {
+      source.addEventListener(topic, function (e) {
+        appendTail(JSON.parse(e.data));
+        scheduleRefresh();
+      });
+    });
+    source.addEventListener('message.published', function (e) {
+      appendTail(JSON.parse(e.data));
+    });
+  }
+
+  function send(event) {
+    event.preventDefault();
+    var form = event.target;
+    var result = el('send-result');
+
+    var data;
+    try {
+      data = form.data.value.trim() ? JSON.parse(form.data.value) : null;
+    } catch (err) {
+      result.textContent = 'Invalid JSON: ' + err.message;
+      return;
+    }
+
+    var body = { type: form.type.value, data: data, priority: form.priority.value };
+    var url = base + '/messages/broadcast';
+    if (form.target.value !== 'broadcast') {
+      url = base + '/messages/send';
+      body[form.target.value] = form.value.value.trim();
+    }
+
+    fetch(url, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(body)
+    })
+      .then(function (res) { return res.json(); })
+      .then(function (res) {
+        result.textContent = res.error
+          ? 'Error: ' + res.error
+          : res.status + ' ' + res.message_id + ' to ' + res.connections + ' connection(s)';
+      });
+  }
+
+  el('search').addEventListener('input', renderConnections);
+  el('refresh').addEventListener('click', loadConnections);
+  el('clear').addEventListener('click', function () { el('tail').replaceChildren(); });
+  el('send-form').addEventListener('submit', send);
+
+  loadConnections();
+  connect();
+})();
@@ -0,0 +1,166 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/transporttest"
+)
+
+// routes registers handlers the way InitAdminRouter does
+type routes func(logger.Logger, *hub.Hub, *audit.Recorder, *gin.RouterGroup)
+
+// testServer serves admin routes with a running hub
+type testServer struct {
+	hub     *hub.Hub
+	log     logger.Logger
+	auditor *audit.Recorder
+	url     string
+}
+
+// newTestServer serves the routes registered by init
+func newTestServer(t *testing.T, init routes) *testServer {
+	t.Helper()
+
+	hubInstance, log := transporttest.NewHub(t)
+	auditor := transporttest.NewAuditor(t, log)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	init(log, hubInstance, auditor, &router.RouterGroup)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return &testServer{hub: hubInstance, log: log, auditor: auditor, url: server.URL}
+}
+
+// newConnection returns an SSE connection writing to a recorder
+func (s *testServer) newConnection(id string) hub.Connection {
+	request := httptest.NewRequest(http.MethodGet, "/events", nil)
+	return hub.NewSSEConnection(context.Background(), id, httptest.NewRecorder(), request, s.log)
+}
+
+// sseEvent is a server-sent event read from a stream
+type sseEvent struct {
+	Event string
+	Data  string
+}
+
+// eventStream reads server-sent events from a response
+type eventStream struct {
+	t       *testing.T
+	scanner *bufio.Scanner
+}
+
+// openStream requests an event stream, failing the test unless it is served.
+// The stream is closed when the test ends and reads time out after 5 seconds.
+func openStream(t *testing.T, url string, header http.Header) *eventStream {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	return &eventStream{t: t, scanner: bufio.NewScanner(resp.Body)}
+}
+
+// next returns the next event on the stream
+func (s *eventStream) next() sseEvent {
+	s.t.Helper()
+
+	var event sseEvent
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "" && event.Event != "":
+			return event
+		case strings.HasPrefix(line, "event:"):
+			event.Event = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			event.Data = strings.TrimPrefix(line, "data:")
+		}
+	}
+	s.t.Fatalf("Stream ended before the next event: %v", s.scanner.Err())
+	return event
+}
+
+func TestStats(t *testing.T) {
+	srv := newTestServer(t, InitAdminRouter)
+	srv.hub.RegisterConnection(srv.newConnection("sse-1"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := http.Get(srv.url + "/admin/stats")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var stats Stats
+		err = json.NewDecoder(resp.Body).Decode(&stats)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || err != nil {
+			t.Fatalf("Expected a stats snapshot, got %d: %v", resp.StatusCode, err)
+		}
+
+		// Registration is asynchronous
+		if stats.Connections == 1 {
+			if !stats.Running || stats.Draining || stats.ByType["sse"] != 1 || stats.Timestamp.IsZero() {
+				t.Errorf("Unexpected stats %+v", stats)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 1 connection, got %+v", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	srv := newTestServer(t, InitAdminRouter)
+	stream := openStream(t, srv.url+"/admin/events", nil)
+
+	event := stream.next()
+	var stats Stats
+	if event.Event != "stats" || json.Unmarshal([]byte(event.Data), &stats) != nil || !stats.Running {
+		t.Fatalf("Expected a stats snapshot first, got %+v", event)
+	}
+
+	srv.hub.RegisterConnection(srv.newConnection("sse-1"))
+	event = stream.next()
+	if event.Event != hub.EventConnectionRegistered || !strings.Contains(event.Data, `"connection_id":"sse-1"`) {
+		t.Errorf("Expected sse-1 to be registered, got %+v", event)
+	}
+
+	srv.hub.PublishToTopic(context.Background(), "news", &hub.Message{ID: "m1", Type: "test"})
+	event = stream.next()
+	if event.Event != hub.EventMessagePublished || !strings.Contains(event.Data, `"target":"topic:news"`) {
+		t.Errorf("Expected m1 to be published, got %+v", event)
+	}
+
+	srv.hub.UnregisterConnection("sse-1")
+	event = stream.next()
+	if event.Event != hub.EventConnectionUnregistered || !strings.Contains(event.Data, `"connection_id":"sse-1"`) {
+		t.Errorf("Expected sse-1 to be unregistered, got %+v", event)
+	}
+}
@@ -0,0 +1,244 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// AdminHandler serves connection introspection and management endpoints
+type AdminHandler struct {
+	hub     *hub.Hub
+	logger  logger.Logger
+	auditor *audit.Recorder
+}
+
+// DisconnectRequest carries the optional final message for a forced disconnect
+type DisconnectRequest struct {
+	Reason     string `json:"reason"`
+	RetryAfter string `json:"retry_after"` // Go duration, e.g. "30s"
+}
+
+// DisconnectMatchingRequest selects the connections to disconnect
+type DisconnectMatchingRequest struct {
+	DisconnectRequest
+	Filter  hub.ConnectionFilter `json:"filter"`
+	MinIdle string               `json:"min_idle"` // Go duration, e.g. "10m"
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(hubInstance *hub.Hub, logger logger.Logger, auditor *audit.Recorder) *AdminHandler {
+	return &AdminHandler{
+		hub:     hubInstance,
+		logger:  logger.WithField("handler", "admin"),
+		auditor: auditor,
+	}
+}
+
+// ListConnections lists connections filtered by user, topic, type, ip, tag and
+// minimum idle time, paginated with offset/limit
+func (h *AdminHandler) ListConnections(c *gin.Context) {
+	filter := &hub.ConnectionFilter{
+		UserID:     c.Query("user"),
+		Topic:      c.Query("topic"),
+		Type:       c.Query("type"),
+		RemoteAddr: c.Query("ip"),
+		Tag:        c.Query("tag"),
+	}
+
+	if idle := c.Query("idle"); idle != "" {
+		d, err := time.ParseDuration(idle)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid idle duration",
+			})
+			return
+		}
+		filter.MinIdle = d
+	}
+
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid offset",
+		})
+		return
+	}
+	limit, err := queryInt(c, "limit", defaultPageSize)
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid limit",
+		})
+		return
+	}
+	limit = min(limit, maxPageSize)
+
+	connections := h.hub.FindConnections(filter)
+	total := len(connections)
+
+	start := min(offset, total)
+	end := min(start+limit, total)
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":       total,
+		"offset":      offset,
+		"limit":       limit,
+		"connections": connections[start:end],
+	})
+}
+
+// GetConnection returns the detail of a single connection
+func (h *AdminHandler) GetConnection(c *gin.Context) {
+	info, exists := h.hub.DescribeConnection(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Connection not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// DisconnectConnection forcibly closes a single connection
+func (h *AdminHandler) DisconnectConnection(c *gin.Context) {
+	connID := c.Param("id")
+
+	opts, ok := h.bindDisconnectOptions(c, &DisconnectRequest{})
+	if !ok {
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionDisconnect, "connection:"+connID)
+	entry.Details = map[string]any{"reason": opts.Reason}
+	defer h.auditor.Record(c.Request.Context(), entry)
+
+	if err := h.hub.Disconnect(c.Request.Context(), connID, opts); err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Connection not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":       "disconnected",
+		"disconnected": 1,
+	})
+}
+
+// DisconnectUser closes every connection of a user, logging them out everywhere
+func (h *AdminHandler) DisconnectUser(c *gin.Context) {
+	userID := c.Param("userId")
+
+	opts, ok := h.bindDisconnectOptions(c, &DisconnectRequest{})
+	if !ok {
+		return
+	}
+
+	count := h.hub.DisconnectUser(c.Request.Context(), userID, opts)
+
+	entry := middleware.NewAuditEntry(c, audit.ActionDisconnect, "user:"+userID)
+	entry.Details = map[string]any{"reason": opts.Reason, "disconnected": count}
+	h.auditor.Record(c.Request.Context(), entry)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":       "disconnected",
+		"disconnected": count,
+	})
+}
+
+// DisconnectMatching closes every connection matching a filter (ip, tag, type,
+// user, topic, min_idle); an empty filter or a min_idle that is not positive is
+// rejected
+func (h *AdminHandler) DisconnectMatching(c *gin.Context) {
+	var req DisconnectMatchingRequest
+	opts, ok := h.bindDisconnectOptions(c, &req)
+	if !ok {
+		return
+	}
+
+	if req.MinIdle != "" {
+		d, err := time.ParseDuration(req.MinIdle)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid min_idle duration",
+			})
+			return
+		}
+		req.Filter.MinIdle = d
+	}
+
+	if req.Filter.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "At least one filter criterion is required",
+		})
+		return
+	}
+
+	count := h.hub.DisconnectMatching(c.Request.Context(), &req.Filter, opts)
+
+	entry := middleware.NewAuditEntry(c, audit.ActionDisconnect, "filter")
+	entry.Details = map[string]any{"reason": opts.Reason, "filter": req.Filter, "min_idle": req.MinIdle, "disconnected": count}
+	h.auditor.Record(c.Request.Context(), entry)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":       "disconnected",
+		"disconnected": count,
+	})
+}
+
+// bindDisconnectOptions binds an optional JSON body into req and converts it to
+// hub options, writing a 400 response on invalid input
+func (h *AdminHandler) bindDisconnectOptions(c *gin.Context, req any) (hub.DisconnectOptions, bool) {
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request format",
+			})
+			return hub.DisconnectOptions{}, false
+		}
+	}
+
+	var base *DisconnectRequest
+	switch r := req.(type) {
+	case *DisconnectRequest:
+		base = r
+	case *DisconnectMatchingRequest:
+		base = &r.DisconnectRequest
+	}
+
+	opts := hub.DisconnectOptions{Reason: base.Reason}
+	if base.RetryAfter != "" {
+		d, err := time.ParseDuration(base.RetryAfter)
+		if err != nil || d < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid retry_after duration",
+			})
+			return hub.DisconnectOptions{}, false
+		}
+		opts.RetryAfter = d
+	}
+	return opts, true
+}
+
+func queryInt(c *gin.Context, key string, fallback int) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
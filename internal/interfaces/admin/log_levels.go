@@ -0,0 +1,103 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
+)
+
+// LogLevelHandler changes log levels at runtime, globally or per component
+type LogLevelHandler struct {
+	levels  *logger.Levels
+	logger  logger.Logger
+	auditor *audit.Recorder
+}
+
+// LogLevelRequest sets a log level by name (debug, info, warn, error)
+type LogLevelRequest struct {
+	Level string `json:"level" binding:"required"`
+}
+
+// NewLogLevelHandler creates a new log level handler
+func NewLogLevelHandler(levels *logger.Levels, logger logger.Logger, auditor *audit.Recorder) *LogLevelHandler {
+	return &LogLevelHandler{
+		levels:  levels,
+		logger:  logger.WithField("handler", "log_levels"),
+		auditor: auditor,
+	}
+}
+
+// Get returns the base level and the per-component overrides
+func (h *LogLevelHandler) Get(c *gin.Context) {
+	c.JSON(http.StatusOK, h.snapshot())
+}
+
+// SetBase changes the level of every component without an override
+func (h *LogLevelHandler) SetBase(c *gin.Context) {
+	h.set(c, "")
+}
+
+// SetComponent overrides the level of a single component (hub, sse, websocket,
+// chat, http...)
+func (h *LogLevelHandler) SetComponent(c *gin.Context) {
+	h.set(c, c.Param("component"))
+}
+
+// ResetComponent removes a component override so it follows the base level again
+func (h *LogLevelHandler) ResetComponent(c *gin.Context) {
+	component := c.Param("component")
+	h.levels.Reset(component)
+
+	entry := middleware.NewAuditEntry(c, audit.ActionSetLogLevel, "component:"+component)
+	entry.Details = map[string]any{"level": "reset"}
+	h.auditor.Record(c.Request.Context(), entry)
+
+	h.logger.WithContext(c.Request.Context()).Infof("Log level override for %s removed", component)
+	c.JSON(http.StatusOK, h.snapshot())
+}
+
+func (h *LogLevelHandler) set(c *gin.Context, component string) {
+	var req LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil || level == logger.LevelFatal {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid level, expected one of debug, info, warn, error",
+		})
+		return
+	}
+
+	h.levels.Set(component, level)
+
+	target := "base"
+	if component != "" {
+		target = "component:" + component
+	}
+	entry := middleware.NewAuditEntry(c, audit.ActionSetLogLevel, target)
+	entry.Details = map[string]any{"level": level.String()}
+	h.auditor.Record(c.Request.Context(), entry)
+
+	h.logger.WithContext(c.Request.Context()).Infof("Log level for %s set to %s", target, level)
+	c.JSON(http.StatusOK, h.snapshot())
+}
+
+func (h *LogLevelHandler) snapshot() gin.H {
+	components := make(map[string]string)
+	for component, level := range h.levels.Components() {
+		components[component] = level.String()
+	}
+	return gin.H{
+		"level":      h.levels.Base().String(),
+		"components": components,
+	}
+}
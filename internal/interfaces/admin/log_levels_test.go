@@ -0,0 +1,120 @@
+package admin
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/transporttest"
+)
+
+// levelsResponse is the body of every log level response
+type levelsResponse struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+}
+
+// newLevelsServer serves the log level routes for levels starting at info with
+// an error override for http
+func newLevelsServer(t *testing.T) (*testServer, *logger.Levels) {
+	t.Helper()
+
+	levels := logger.NewLevels(logger.LevelInfo, map[string]logger.Level{"http": logger.LevelError})
+	srv := newTestServer(t, func(log logger.Logger, _ *hub.Hub, auditor *audit.Recorder, rg *gin.RouterGroup) {
+		InitLogLevelRouter(log, levels, auditor, rg)
+	})
+	return srv, levels
+}
+
+// do sends a log level request and decodes the levels it returns on success
+func (s *testServer) do(t *testing.T, method, path, body string) (int, levelsResponse) {
+	t.Helper()
+
+	req, _ := http.NewRequest(method, s.url+path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var levels levelsResponse
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&levels); err != nil {
+			t.Fatalf("Invalid response: %v", err)
+		}
+	}
+	return resp.StatusCode, levels
+}
+
+func TestLogLevels(t *testing.T) {
+	srv, levels := newLevelsServer(t)
+
+	steps := []struct {
+		method, path, body string
+		level              string
+		components         map[string]string
+	}{
+		{http.MethodGet, "/admin/log-levels", "", "info", map[string]string{"http": "error"}},
+		{http.MethodPut, "/admin/log-levels", `{"level":"warn"}`, "warn", map[string]string{"http": "error"}},
+		{http.MethodPut, "/admin/log-levels/hub", `{"level":"debug"}`, "warn", map[string]string{"http": "error", "hub": "debug"}},
+		{http.MethodDelete, "/admin/log-levels/http", "", "warn", map[string]string{"hub": "debug"}},
+	}
+	for _, step := range steps {
+		status, got := srv.do(t, step.method, step.path, step.body)
+		if status != http.StatusOK {
+			t.Fatalf("%s %s: expected 200, got %d", step.method, step.path, status)
+		}
+		if got.Level != step.level || !maps.Equal(got.Components, step.components) {
+			t.Errorf("%s %s: expected %s %v, got %+v", step.method, step.path, step.level, step.components, got)
+		}
+	}
+
+	if levels.Base() != logger.LevelWarn || levels.Level("hub") != logger.LevelDebug || levels.Level("http") != logger.LevelWarn {
+		t.Errorf("Expected the changes to apply to the levels, got base %s, hub %s, http %s",
+			levels.Base(), levels.Level("hub"), levels.Level("http"))
+	}
+
+	entries := transporttest.AuditEntries(t, srv.auditor, &audit.Filter{Action: audit.ActionSetLogLevel}, 3)
+	targets := map[string]any{}
+	for _, entry := range entries {
+		targets[entry.Target] = entry.Details["level"]
+	}
+	expected := map[string]any{"base": "warn", "component:hub": "debug", "component:http": "reset"}
+	if !maps.Equal(targets, expected) {
+		t.Errorf("Expected changes %v to be audited, got %v", expected, targets)
+	}
+}
+
+func TestLogLevels_InvalidRequests(t *testing.T) {
+	srv, levels := newLevelsServer(t)
+
+	tests := []struct {
+		name, path, body string
+	}{
+		{"malformed JSON", "/admin/log-levels", `{"level":`},
+		{"missing level", "/admin/log-levels", `{}`},
+		{"unknown level", "/admin/log-levels", `{"level":"verbose"}`},
+		{"fatal", "/admin/log-levels", `{"level":"fatal"}`},
+		{"unknown component level", "/admin/log-levels/hub", `{"level":"loud"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _ := srv.do(t, http.MethodPut, tt.path, tt.body); status != http.StatusBadRequest {
+				t.Errorf("Expected 400, got %d", status)
+			}
+		})
+	}
+
+	if levels.Base() != logger.LevelInfo || len(levels.Components()) != 1 {
+		t.Errorf("Expected rejected requests to leave the levels unchanged, got base %s and %v",
+			levels.Base(), levels.Components())
+	}
+}
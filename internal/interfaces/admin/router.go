@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
+)
+
+// InitAdminRouter registers connection introspection and management routes and
+// the live dashboard
+func InitAdminRouter(
+	logger logger.Logger,
+	hubInstance *hub.Hub,
+	auditor *audit.Recorder,
+	rg *gin.RouterGroup,
+) {
+	adminHandler := NewAdminHandler(hubInstance, logger, auditor)
+
+	connGroup := rg.Group("/admin/connections")
+	connGroup.GET("", adminHandler.ListConnections)
+	connGroup.GET("/:id", adminHandler.GetConnection)
+	connGroup.POST("/:id/disconnect", adminHandler.DisconnectConnection)
+	connGroup.POST("/disconnect", adminHandler.DisconnectMatching)
+
+	rg.POST("/admin/users/:userId/disconnect", adminHandler.DisconnectUser)
+
+	rg.GET("/admin/stats", adminHandler.Stats)
+	rg.GET("/admin/events", middleware.Streaming(), adminHandler.Events)
+	rg.POST("/admin/messages/broadcast", adminHandler.Broadcast)
+	rg.POST("/admin/messages/send", adminHandler.Send)
+
+	rg.StaticFS("/admin/dashboard", DashboardFS())
+}
+
+// InitTapRouter registers the traffic tap streams behind the given authentication
+// middleware
+func InitTapRouter(
+	logger logger.Logger,
+	hubInstance *hub.Hub,
+	auditor *audit.Recorder,
+	rg *gin.RouterGroup,
+	authMiddleware ...gin.HandlerFunc,
+) {
+	tapHandler := NewTapHandler(hubInstance, logger, auditor)
+
+	tapGroup := rg.Group("/admin/tap", authMiddleware...)
+	tapGroup.GET("", middleware.Streaming(), tapHandler.StreamSSE)
+	tapGroup.GET("/ws", tapHandler.StreamWebSocket)
+}
+
+// InitLogLevelRouter registers runtime log level controls
+func InitLogLevelRouter(
+	logger logger.Logger,
+	levels *logger.Levels,
+	auditor *audit.Recorder,
+	rg *gin.RouterGroup,
+) {
+	logLevelHandler := NewLogLevelHandler(levels, logger, auditor)
+
+	levelGroup := rg.Group("/admin/log-levels")
+	levelGroup.GET("", logLevelHandler.Get)
+	levelGroup.PUT("", logLevelHandler.SetBase)
+	levelGroup.PUT("/:component", logLevelHandler.SetComponent)
+	levelGroup.DELETE("/:component", logLevelHandler.ResetComponent)
+}
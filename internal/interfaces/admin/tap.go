@@ -0,0 +1,185 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
+)
+
+const (
+	// defaultTapMaxRate caps tap traffic unless the operator asks for more
+	defaultTapMaxRate = 100
+
+	tapWriteWait  = 10 * time.Second
+	tapPingPeriod = 30 * time.Second
+)
+
+// TapHandler streams a filtered, sampled mirror of hub traffic to operators
+type TapHandler struct {
+	hub      *hub.Hub
+	logger   logger.Logger
+	auditor  *audit.Recorder
+	upgrader websocket.Upgrader
+}
+
+// NewTapHandler creates a new tap handler
+func NewTapHandler(hubInstance *hub.Hub, logger logger.Logger, auditor *audit.Recorder) *TapHandler {
+	return &TapHandler{
+		hub:     hubInstance,
+		logger:  logger.WithField("handler", "tap"),
+		auditor: auditor,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+	}
+}
+
+// StreamSSE mirrors matching messages and delivery outcomes as server-sent events.
+// Filters: topic, user, connection, type, sample (0-1], max_rate (events/s) and
+// include_data
+func (h *TapHandler) StreamSSE(c *gin.Context) {
+	filter, ok := h.bindFilter(c)
+	if !ok {
+		return
+	}
+
+	tap := h.open(c, filter)
+	defer tap.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Render(-1, sse.Event{Event: "tap", Data: filter})
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(tapPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-tap.Events():
+			if !ok {
+				return
+			}
+			c.Render(-1, sse.Event{Event: event.Topic, Data: event})
+		case <-ticker.C:
+			c.Render(-1, sse.Event{Event: "keepalive", Data: gin.H{"dropped": tap.Dropped()}})
+		}
+		c.Writer.Flush()
+	}
+}
+
+// StreamWebSocket mirrors matching messages and delivery outcomes as JSON text
+// frames; it accepts the same filters as StreamSSE
+func (h *TapHandler) StreamWebSocket(c *gin.Context) {
+	filter, ok := h.bindFilter(c)
+	if !ok {
+		return
+	}
+
+	ws, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Errorf("Failed to upgrade tap connection: %v", err)
+		return
+	}
+	defer ws.Close()
+
+	tap := h.open(c, filter)
+	defer tap.Close()
+
+	// The tap is read-only; reading only detects the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(tapPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-tap.Events():
+			if !ok {
+				return
+			}
+			ws.SetWriteDeadline(time.Now().Add(tapWriteWait))
+			if err := ws.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			ws.SetWriteDeadline(time.Now().Add(tapWriteWait))
+			if err := ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// open starts a tap for the request and records who opened it
+func (h *TapHandler) open(c *gin.Context, filter hub.TapFilter) *hub.Tap {
+	entry := middleware.NewAuditEntry(c, audit.ActionTap, "tap")
+	entry.Details = map[string]any{"filter": filter}
+	h.auditor.Record(c.Request.Context(), entry)
+
+	h.logger.WithContext(c.Request.Context()).Infof("Tap opened by %s with filter %+v", entry.Actor, filter)
+	return h.hub.Tap(c.Request.Context(), filter)
+}
+
+// bindFilter reads the tap filter from query parameters, writing a 400 response
+// on invalid input
+func (h *TapHandler) bindFilter(c *gin.Context) (hub.TapFilter, bool) {
+	filter := hub.TapFilter{
+		Topic:        c.Query("topic"),
+		UserID:       c.Query("user"),
+		ConnectionID: c.Query("connection"),
+		MessageType:  c.Query("type"),
+		SampleRate:   1,
+		MaxRate:      defaultTapMaxRate,
+		IncludeData:  c.Query("include_data") == "true",
+	}
+
+	if value := c.Query("sample"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 || rate > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid sample rate, expected a value in (0, 1]",
+			})
+			return hub.TapFilter{}, false
+		}
+		filter.SampleRate = rate
+	}
+
+	if value := c.Query("max_rate"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid max_rate",
+			})
+			return hub.TapFilter{}, false
+		}
+		filter.MaxRate = rate
+	}
+
+	return filter, true
+}
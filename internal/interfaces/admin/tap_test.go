@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
+	"go-notification-sse/internal/interfaces/transporttest"
+)
+
+// testTapKey is the API key accepted by the test tap
+const testTapKey = "tap-key"
+
+func initTap(log logger.Logger, hubInstance *hub.Hub, auditor *audit.Recorder, rg *gin.RouterGroup) {
+	InitTapRouter(log, hubInstance, auditor, rg, middleware.RequireAPIKey([]string{testTapKey}))
+}
+
+// publishedMessage decodes the message of a message.published tap event
+func publishedMessage(t *testing.T, data []byte) *hub.Message {
+	t.Helper()
+
+	var published struct {
+		Payload hub.MessageEvent `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &published); err != nil || published.Payload.Message == nil {
+		t.Fatalf("Expected a published message, got %s", data)
+	}
+	return published.Payload.Message
+}
+
+func TestTap_RequiresAPIKey(t *testing.T) {
+	srv := newTestServer(t, initTap)
+
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		status int
+	}{
+		{"no key", "/admin/tap", nil, http.StatusUnauthorized},
+		{"wrong key", "/admin/tap", http.Header{middleware.HeaderAPIKey: {"wrong"}}, http.StatusUnauthorized},
+		{"wrong bearer token", "/admin/tap", http.Header{"Authorization": {"Bearer wrong"}}, http.StatusUnauthorized},
+		{"no key over WebSocket", "/admin/tap/ws", nil, http.StatusUnauthorized},
+		{"bad filter", "/admin/tap?sample=2", http.Header{middleware.HeaderAPIKey: {testTapKey}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.url+tt.path, nil)
+			req.Header = tt.header
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+
+	entries := transporttest.AuditEntries(t, srv.auditor, &audit.Filter{Action: audit.ActionTap}, 0)
+	if len(entries) != 0 {
+		t.Errorf("Expected rejected taps not to be audited, got %d entries", len(entries))
+	}
+}
+
+func TestTap_InvalidFilter(t *testing.T) {
+	srv := newTestServer(t, initTap)
+
+	for _, query := range []string{"sample=0", "sample=1.5", "sample=half", "max_rate=0", "max_rate=-1", "max_rate=fast"} {
+		t.Run(query, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.url+"/admin/tap?"+query, nil)
+			req.Header.Set(middleware.HeaderAPIKey, testTapKey)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected 400, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestTap_StreamSSE(t *testing.T) {
+	srv := newTestServer(t, initTap)
+	stream := openStream(t, srv.url+"/admin/tap?topic=orders", http.Header{middleware.HeaderAPIKey: {testTapKey}})
+
+	event := stream.next()
+	var filter hub.TapFilter
+	if event.Event != "tap" || json.Unmarshal([]byte(event.Data), &filter) != nil || filter.Topic != "orders" {
+		t.Fatalf("Expected the tap filter first, got %+v", event)
+	}
+
+	ctx := context.Background()
+	srv.hub.PublishToTopic(ctx, "news", &hub.Message{ID: "m1", Type: "test", Data: "news"})
+	srv.hub.PublishToTopic(ctx, "orders", &hub.Message{ID: "m2", Type: "test", Data: "secret"})
+
+	event = stream.next()
+	if event.Event != hub.EventMessagePublished {
+		t.Fatalf("Expected a published message, got %+v", event)
+	}
+	message := publishedMessage(t, []byte(event.Data))
+	if message.ID != "m2" {
+		t.Errorf("Expected only m2 to match the filter, got %s", message.ID)
+	}
+	if message.Data != nil {
+		t.Errorf("Expected the payload to be redacted, got %v", message.Data)
+	}
+
+	entries := transporttest.AuditEntries(t, srv.auditor, &audit.Filter{Action: audit.ActionTap}, 1)
+	if len(entries) != 1 || entries[0].Target != "tap" {
+		t.Errorf("Expected the tap to be audited, got %v", entries)
+	}
+}
+
+func TestTap_StreamWebSocket(t *testing.T) {
+	srv := newTestServer(t, initTap)
+
+	url := "ws" + strings.TrimPrefix(srv.url, "http") + "/admin/tap/ws?type=order.created&include_data=true"
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{middleware.HeaderAPIKey: {testTapKey}})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// The tap opens after the upgrade completes, so publish until an event arrives
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			srv.hub.PublishToTopic(ctx, "news", &hub.Message{ID: "m1", Type: "test"})
+			srv.hub.PublishToTopic(ctx, "orders", &hub.Message{ID: "m2", Type: "order.created", Data: "o-1"})
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read tap event: %v", err)
+	}
+	message := publishedMessage(t, data)
+	if message.ID != "m2" || message.Data != "o-1" {
+		t.Errorf("Expected m2 with its payload, got %+v", message)
+	}
+}
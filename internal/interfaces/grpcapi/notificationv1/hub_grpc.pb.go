@@ -0,0 +1,295 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: notificationv1/hub.proto
+
+package notificationv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Hub_Publish_FullMethodName          = "/notification.v1.Hub/Publish"
+	Hub_SendToUser_FullMethodName       = "/notification.v1.Hub/SendToUser"
+	Hub_SendToConnection_FullMethodName = "/notification.v1.Hub/SendToConnection"
+	Hub_Subscribe_FullMethodName        = "/notification.v1.Hub/Subscribe"
+	Hub_Connect_FullMethodName          = "/notification.v1.Hub/Connect"
+)
+
+// HubClient is the client API for Hub service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Hub publishes messages to the hub's connections and streams messages to
+// internal services. Every call must present one of the gateway's API keys in
+// the x-api-key metadata or as an "authorization: Bearer" token.
+type HubClient interface {
+	// Publish broadcasts a message, or publishes it to a topic when topic is set
+	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+	// SendToUser sends a message to every connection of a user
+	SendToUser(ctx context.Context, in *SendToUserRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+	// SendToConnection sends a message to a single connection
+	SendToConnection(ctx context.Context, in *SendToConnectionRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+	// Subscribe registers a connection and streams the messages routed to it
+	// until the client cancels the call. The connection ID is sent in the
+	// x-connection-id response header.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamedMessage], error)
+	// Connect registers a connection bound to the user in the x-user-id metadata,
+	// streaming the messages routed to it while handling the client's commands
+	Connect(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Command, StreamedMessage], error)
+}
+
+type hubClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHubClient(cc grpc.ClientConnInterface) HubClient {
+	return &hubClient{cc}
+}
+
+func (c *hubClient) Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishResponse)
+	err := c.cc.Invoke(ctx, Hub_Publish_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hubClient) SendToUser(ctx context.Context, in *SendToUserRequest, opts ...grpc.CallOption) (*PublishResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishResponse)
+	err := c.cc.Invoke(ctx, Hub_SendToUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hubClient) SendToConnection(ctx context.Context, in *SendToConnectionRequest, opts ...grpc.CallOption) (*PublishResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishResponse)
+	err := c.cc.Invoke(ctx, Hub_SendToConnection_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hubClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamedMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Hub_ServiceDesc.Streams[0], Hub_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, StreamedMessage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Hub_SubscribeClient = grpc.ServerStreamingClient[StreamedMessage]
+
+func (c *hubClient) Connect(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Command, StreamedMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Hub_ServiceDesc.Streams[1], Hub_Connect_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Command, StreamedMessage]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Hub_ConnectClient = grpc.BidiStreamingClient[Command, StreamedMessage]
+
+// HubServer is the server API for Hub service.
+// All implementations must embed UnimplementedHubServer
+// for forward compatibility.
+//
+// Hub publishes messages to the hub's connections and streams messages to
+// internal services. Every call must present one of the gateway's API keys in
+// the x-api-key metadata or as an "authorization: Bearer" token.
+type HubServer interface {
+	// Publish broadcasts a message, or publishes it to a topic when topic is set
+	Publish(context.Context, *PublishRequest) (*PublishResponse, error)
+	// SendToUser sends a message to every connection of a user
+	SendToUser(context.Context, *SendToUserRequest) (*PublishResponse, error)
+	// SendToConnection sends a message to a single connection
+	SendToConnection(context.Context, *SendToConnectionRequest) (*PublishResponse, error)
+	// Subscribe registers a connection and streams the messages routed to it
+	// until the client cancels the call. The connection ID is sent in the
+	// x-connection-id response header.
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[StreamedMessage]) error
+	// Connect registers a connection bound to the user in the x-user-id metadata,
+	// streaming the messages routed to it while handling the client's commands
+	Connect(grpc.BidiStreamingServer[Command, StreamedMessage]) error
+	mustEmbedUnimplementedHubServer()
+}
+
+// UnimplementedHubServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedHubServer struct{}
+
+func (UnimplementedHubServer) Publish(context.Context, *PublishRequest) (*PublishResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Publish not implemented")
+}
+func (UnimplementedHubServer) SendToUser(context.Context, *SendToUserRequest) (*PublishResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendToUser not implemented")
+}
+func (UnimplementedHubServer) SendToConnection(context.Context, *SendToConnectionRequest) (*PublishResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendToConnection not implemented")
+}
+func (UnimplementedHubServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[StreamedMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedHubServer) Connect(grpc.BidiStreamingServer[Command, StreamedMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method Connect not implemented")
+}
+func (UnimplementedHubServer) mustEmbedUnimplementedHubServer() {}
+func (UnimplementedHubServer) testEmbeddedByValue()             {}
+
+// UnsafeHubServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HubServer will
+// result in compilation errors.
+type UnsafeHubServer interface {
+	mustEmbedUnimplementedHubServer()
+}
+
+func RegisterHubServer(s grpc.ServiceRegistrar, srv HubServer) {
+	// If the following call pancis, it indicates UnimplementedHubServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Hub_ServiceDesc, srv)
+}
+
+func _Hub_Publish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HubServer).Publish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Hub_Publish_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HubServer).Publish(ctx, req.(*PublishRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Hub_SendToUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendToUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HubServer).SendToUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Hub_SendToUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HubServer).SendToUser(ctx, req.(*SendToUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Hub_SendToConnection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendToConnectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HubServer).SendToConnection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Hub_SendToConnection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HubServer).SendToConnection(ctx, req.(*SendToConnectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Hub_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(HubServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, StreamedMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Hub_SubscribeServer = grpc.ServerStreamingServer[StreamedMessage]
+
+func _Hub_Connect_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(HubServer).Connect(&grpc.GenericServerStream[Command, StreamedMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Hub_ConnectServer = grpc.BidiStreamingServer[Command, StreamedMessage]
+
+// Hub_ServiceDesc is the grpc.ServiceDesc for Hub service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Hub_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "notification.v1.Hub",
+	HandlerType: (*HubServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Publish",
+			Handler:    _Hub_Publish_Handler,
+		},
+		{
+			MethodName: "SendToUser",
+			Handler:    _Hub_SendToUser_Handler,
+		},
+		{
+			MethodName: "SendToConnection",
+			Handler:    _Hub_SendToConnection_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Hub_Subscribe_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Connect",
+			Handler:       _Hub_Connect_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "notificationv1/hub.proto",
+}
@@ -0,0 +1,459 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/identity"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/ratelimit"
+	"go-notification-sse/internal/interfaces/grpcapi/notificationv1"
+)
+
+// Metadata keys read by the gateway, matching the HTTP headers of the same name
+const (
+	MetadataUserID = "x-user-id"
+	MetadataAPIKey = "x-api-key"
+
+	// MetadataConnectionID is sent in the response header of streams so that
+	// clients can target their own connection
+	MetadataConnectionID = "x-connection-id"
+)
+
+// ErrNoAPIKeys is returned when the gateway is created without API keys
+var ErrNoAPIKeys = errors.New("the gRPC gateway requires at least one API key")
+
+// Server implements the hub gateway service
+type Server struct {
+	notificationv1.UnimplementedHubServer
+
+	hub     *hub.Hub
+	logger  logger.Logger
+	auditor *audit.Recorder
+
+	// Inbound command rate limiting for Connect streams
+	inboundLimiter *ratelimit.Limiter
+	maxViolations  int
+
+	validator *hub.MessageValidator
+}
+
+var _ notificationv1.HubServer = (*Server)(nil)
+
+// NewServer creates the gateway service. inboundLimiter, keyed by connection ID,
+// limits Connect commands; after maxViolations rejected commands the stream is
+// closed (0 never disconnects). A nil limiter disables the limit.
+func NewServer(
+	hubInstance *hub.Hub,
+	logger logger.Logger,
+	auditor *audit.Recorder,
+	inboundLimiter *ratelimit.Limiter,
+	maxViolations int,
+) *Server {
+	return &Server{
+		hub:            hubInstance,
+		logger:         logger.WithField("handler", "grpc"),
+		auditor:        auditor,
+		inboundLimiter: inboundLimiter,
+		maxViolations:  maxViolations,
+		validator:      hub.NewMessageValidator(),
+	}
+}
+
+// NewGRPCServer creates a gRPC server serving the gateway along with server
+// reflection, so that tools such as grpcurl can discover the schema. Every RPC
+// must present one of apiKeys in x-api-key or as a bearer token; without keys
+// the gateway refuses to start with ErrNoAPIKeys.
+func NewGRPCServer(srv notificationv1.HubServer, apiKeys []string, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	if len(apiKeys) == 0 {
+		return nil, ErrNoAPIKeys
+	}
+
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := authorize(ctx, apiKeys); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := authorize(ss.Context(), apiKeys); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+
+	s := grpc.NewServer(opts...)
+	notificationv1.RegisterHubServer(s, srv)
+	reflection.Register(s)
+	return s, nil
+}
+
+// Publish broadcasts a message, or publishes it to a topic
+func (s *Server) Publish(ctx context.Context, req *notificationv1.PublishRequest) (*notificationv1.PublishResponse, error) {
+	message, err := s.message(req.GetMessage())
+	if err != nil {
+		return nil, err
+	}
+
+	action, target := audit.ActionBroadcast, "all"
+	if req.GetTopic() != "" {
+		action, target = audit.ActionPublishToTopic, "topic:"+req.GetTopic()
+	}
+	entry := newAuditEntry(ctx, action, target, message.ID)
+	defer s.auditor.Record(ctx, entry)
+
+	var recipients int
+	if req.GetTopic() != "" {
+		recipients, err = s.hub.PublishToTopic(ctx, req.GetTopic(), message)
+	} else {
+		err = s.hub.Broadcast(ctx, message)
+	}
+	if err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		s.logger.WithContext(ctx).Errorf("Failed to publish message %s to %s: %v", message.ID, target, err)
+		return nil, status.Error(codes.Unavailable, "Failed to publish message")
+	}
+
+	return &notificationv1.PublishResponse{MessageId: message.ID, Recipients: int32(recipients)}, nil
+}
+
+// SendToUser sends a message to every connection of a user
+func (s *Server) SendToUser(ctx context.Context, req *notificationv1.SendToUserRequest) (*notificationv1.PublishResponse, error) {
+	userID := req.GetUserId()
+	if userID == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	message, err := s.message(req.GetMessage())
+	if err != nil {
+		return nil, err
+	}
+
+	entry := newAuditEntry(ctx, audit.ActionSendToUser, "user:"+userID, message.ID)
+	defer s.auditor.Record(ctx, entry)
+
+	recipients, err := s.hub.SendToUser(ctx, userID, message)
+	if err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		s.logger.WithContext(ctx).Errorf("Failed to send message %s to user %s: %v", message.ID, userID, err)
+		return nil, status.Error(codes.Unavailable, "Failed to send message")
+	}
+
+	return &notificationv1.PublishResponse{MessageId: message.ID, Recipients: int32(recipients)}, nil
+}
+
+// SendToConnection sends a message to a single connection
+func (s *Server) SendToConnection(ctx context.Context, req *notificationv1.SendToConnectionRequest) (*notificationv1.PublishResponse, error) {
+	connID := req.GetConnectionId()
+	if connID == "" {
+		return nil, status.Error(codes.InvalidArgument, "connection_id is required")
+	}
+	message, err := s.message(req.GetMessage())
+	if err != nil {
+		return nil, err
+	}
+
+	entry := newAuditEntry(ctx, audit.ActionSendToConn, connID, message.ID)
+	defer s.auditor.Record(ctx, entry)
+
+	if _, exists := s.hub.GetConnection(connID); !exists {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = "connection not found"
+		return nil, status.Errorf(codes.NotFound, "connection %s not found", connID)
+	}
+
+	if err := s.hub.SendToConnection(ctx, connID, message); err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		s.logger.WithContext(ctx).Errorf("Failed to send message %s to connection %s: %v", message.ID, connID, err)
+		return nil, status.Error(codes.Unavailable, "Failed to send message")
+	}
+
+	return &notificationv1.PublishResponse{MessageId: message.ID, Recipients: 1}, nil
+}
+
+// Subscribe streams the messages routed to a new connection until the client
+// cancels the RPC. The connection is bound to the user named by the calling
+// service, which the API key vouches for.
+func (s *Server) Subscribe(req *notificationv1.SubscribeRequest, stream grpc.ServerStreamingServer[notificationv1.StreamedMessage]) error {
+	conn, err := s.open(stream, req.GetUserId(), req.GetTopics(), req.GetTags())
+	if err != nil {
+		return err
+	}
+	log := s.logger.WithContext(conn.Context())
+
+	var replay []hub.JournalEntry
+	if req.Cursor != nil {
+		entries, complete := s.hub.Replay(conn.ID(), *req.Cursor)
+		if !complete {
+			log.Warnf("Cursor %d is no longer retained, some messages cannot be replayed", *req.Cursor)
+			replay = append(replay, hub.JournalEntry{
+				Message: hub.ErrorMessage("cursor_expired", "Some messages after the cursor are no longer available", map[string]any{
+					"cursor": *req.Cursor,
+				}),
+			})
+		}
+		replay = append(replay, entries...)
+	}
+	if err := conn.Replay(replay); err != nil {
+		s.hub.UnregisterConnection(conn.ID())
+		return err
+	}
+
+	log.Infof("gRPC subscription %s connected and registered (replayed %d messages)", conn.ID(), len(replay))
+	err = conn.Serve()
+	log.Infof("gRPC subscription %s disconnected", conn.ID())
+	return err
+}
+
+// Connect runs a bidirectional stream: routed messages flow to the client while
+// its commands are handled as they arrive
+func (s *Server) Connect(stream grpc.BidiStreamingServer[notificationv1.Command, notificationv1.StreamedMessage]) error {
+	ctx := stream.Context()
+	conn, err := s.open(stream, firstMetadata(ctx, MetadataUserID), nil, nil)
+	if err != nil {
+		return err
+	}
+	log := s.logger.WithContext(conn.Context())
+	log.Infof("gRPC stream %s connected and registered", conn.ID())
+
+	go s.readCommands(conn, stream)
+
+	err = conn.Serve()
+	if s.inboundLimiter != nil {
+		s.inboundLimiter.Forget(conn.ID())
+	}
+	log.Infof("gRPC stream %s disconnected", conn.ID())
+	return err
+}
+
+// readCommands handles client commands until the client half-closes the stream
+func (s *Server) readCommands(conn *hub.GRPCConnection, stream grpc.BidiStreamingServer[notificationv1.Command, notificationv1.StreamedMessage]) {
+	defer s.hub.UnregisterConnection(conn.ID())
+	log := s.logger.WithContext(conn.Context())
+
+	violations := 0
+	for {
+		cmd, err := stream.Recv()
+		if err != nil {
+			return
+		}
+
+		if s.inboundLimiter != nil {
+			if res := s.inboundLimiter.Take(conn.ID()); !res.Allowed {
+				violations++
+				log.Debugf("Inbound command dropped by rate limit (%d violations)", violations)
+				if s.maxViolations > 0 && violations >= s.maxViolations {
+					log.Warnf("Closing stream after %d rate limit violations", violations)
+					return
+				}
+				s.reply(conn, hub.ErrorMessage("rate_limited", "Too many messages", map[string]any{
+					"limit":          res.Limit,
+					"remaining":      res.Remaining,
+					"retry_after_ms": res.RetryAfter.Milliseconds(),
+				}))
+				continue
+			}
+		}
+
+		switch cmd.GetType() {
+		case CommandSubscribe:
+			s.hub.Subscribe(conn.ID(), cmd.GetTopics()...)
+			s.reply(conn, hub.SystemMessage("subscribed", map[string]any{"topics": s.hub.TopicsOf(conn.ID())}))
+		case CommandUnsubscribe:
+			s.hub.Unsubscribe(conn.ID(), cmd.GetTopics()...)
+			s.reply(conn, hub.SystemMessage("unsubscribed", map[string]any{"topics": s.hub.TopicsOf(conn.ID())}))
+		case CommandPing:
+			s.reply(conn, &hub.Message{
+				ID:   generateMessageID(),
+				Type: "pong",
+				Data: map[string]any{"timestamp": time.Now().Unix()},
+			})
+		default:
+			log.Debugf("Received command of type %q", cmd.GetType())
+			s.reply(conn, &hub.Message{
+				ID:   fmt.Sprintf("echo-%d", time.Now().Unix()),
+				Type: "echo",
+				Data: map[string]any{
+					"original": map[string]any{
+						"type":   cmd.GetType(),
+						"topics": cmd.GetTopics(),
+						"data":   cmd.GetData().AsInterface(),
+					},
+					"timestamp": time.Now().Unix(),
+				},
+			})
+		}
+	}
+}
+
+// reply queues a response to a command on the stream
+func (s *Server) reply(conn *hub.GRPCConnection, message *hub.Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := conn.Send(ctx, message); err != nil {
+		s.logger.WithContext(conn.Context()).Errorf("Failed to send command response: %v", err)
+	}
+}
+
+// open registers a connection for a stream and binds its routes
+func (s *Server) open(stream grpc.ServerStream, userID string, topics, tags []string) (*hub.GRPCConnection, error) {
+	ctx := stream.Context()
+	if !s.hub.IsRunning() {
+		s.logger.WithContext(ctx).Error("Hub is not running")
+		return nil, status.Error(codes.Unavailable, "Service temporarily unavailable")
+	}
+
+	var opts []hub.GRPCOption
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		opts = append(opts, hub.WithPeer(p.Addr.String(), firstMetadata(ctx, "user-agent")))
+	}
+
+	// Carry the user into the connection's logs
+	connStream := &messageStream{ServerStream: stream, ctx: ctx}
+	if userID != "" {
+		connStream.ctx = logger.ContextWithUserID(ctx, userID)
+	}
+	conn := hub.NewGRPCConnection(generateConnectionID(), connStream, s.logger, opts...)
+
+	if err := s.hub.RegisterConnection(conn); err != nil {
+		s.logger.WithContext(conn.Context()).Errorf("Failed to register gRPC stream: %v", err)
+		conn.Close()
+		return nil, status.Error(codes.Unavailable, "Failed to register connection")
+	}
+
+	// Bind routing so user- and topic-targeted messages reach this connection
+	s.hub.BindUser(conn.ID(), userID)
+	s.hub.Subscribe(conn.ID(), topics...)
+	s.hub.Tag(conn.ID(), tags...)
+
+	if err := stream.SendHeader(metadata.Pairs(MetadataConnectionID, conn.ID())); err != nil {
+		s.hub.UnregisterConnection(conn.ID())
+		return nil, err
+	}
+	return conn, nil
+}
+
+// messageStream adapts a server stream to GRPCConnection, converting the
+// hub.StreamedMessage values it sends into their protobuf form, and overrides
+// the stream's context
+type messageStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *messageStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *messageStream) SendMsg(m any) error {
+	streamed, ok := m.(*hub.StreamedMessage)
+	if !ok {
+		return s.ServerStream.SendMsg(m)
+	}
+
+	message, err := toProtoMessage(streamed.Message)
+	if err != nil {
+		return err
+	}
+	return s.ServerStream.SendMsg(&notificationv1.StreamedMessage{Message: message, Cursor: streamed.Cursor})
+}
+
+// message validates a message from a request, generating its ID when missing
+func (s *Server) message(request *notificationv1.Message) (*hub.Message, error) {
+	if request == nil {
+		return nil, status.Error(codes.InvalidArgument, "message is required")
+	}
+	message := toHubMessage(request)
+	if message.ID == "" {
+		message.ID = generateMessageID()
+	}
+	if err := s.validator.Validate(message); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return message, nil
+}
+
+// newAuditEntry creates an audit entry for an RPC. The actor is the API key
+// the interceptor accepted; a user named in the metadata is only claimed by the
+// calling service and recorded as a detail.
+func newAuditEntry(ctx context.Context, action audit.Action, target, messageID string) *audit.Entry {
+	entry := &audit.Entry{
+		Timestamp: time.Now().UTC(),
+		Actor:     "key:" + maskKey(presentedKey(ctx)),
+		Action:    action,
+		Target:    target,
+		MessageID: messageID,
+		Outcome:   audit.OutcomeSuccess,
+	}
+	if userID := firstMetadata(ctx, MetadataUserID); userID != "" {
+		entry.Details = map[string]any{"unverified_user_id": userID}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		entry.SourceIP = p.Addr.String()
+	}
+	return entry
+}
+
+// authorize checks the API key presented in the RPC metadata
+func authorize(ctx context.Context, keys []string) error {
+	if !identity.ValidAPIKey(presentedKey(ctx), keys) {
+		return status.Error(codes.Unauthenticated, "Invalid or missing API key")
+	}
+	return nil
+}
+
+// presentedKey returns the API key in x-api-key or the bearer token, if any
+func presentedKey(ctx context.Context) string {
+	if key := firstMetadata(ctx, MetadataAPIKey); key != "" {
+		return key
+	}
+	key, _ := strings.CutPrefix(firstMetadata(ctx, "authorization"), "Bearer ")
+	return key
+}
+
+// maskKey keeps only a short prefix of an API key
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return key[:4] + "****"
+}
+
+// firstMetadata returns the first value of an incoming metadata key
+func firstMetadata(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// generateConnectionID generates a unique gRPC connection ID
+func generateConnectionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("grpc-%x", b)
+}
+
+// generateMessageID generates a unique message ID
+func generateMessageID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("msg-%x", b)
+}
@@ -0,0 +1,200 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/grpcapi/notificationv1"
+)
+
+// testAPIKey is the API key accepted by the test gateway
+const testAPIKey = "secret-key"
+
+// newTestGateway serves the gateway over an in-memory listener and returns a
+// client, whose calls must present testAPIKey
+func newTestGateway(t *testing.T) (notificationv1.HubClient, *hub.Hub, *audit.Recorder) {
+	t.Helper()
+
+	log := logger.New(logger.NewDefaultConfig())
+	log.SetOutput(io.Discard)
+
+	hubInstance := hub.New(log)
+	hubInstance.Start(context.Background())
+	t.Cleanup(func() { hubInstance.Stop(context.Background()) })
+
+	auditConfig := audit.NewDefaultConfig()
+	auditConfig.FilePath = filepath.Join(t.TempDir(), "audit.jsonl")
+	sink := audit.NewFileSink(auditConfig)
+	t.Cleanup(func() { sink.Close() })
+	auditor := audit.NewRecorder(sink, log)
+
+	listener := bufconn.Listen(1 << 20)
+	srv, err := NewGRPCServer(NewServer(hubInstance, log, auditor, nil, 0), []string{testAPIKey})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { cc.Close() })
+
+	return notificationv1.NewHubClient(cc), hubInstance, auditor
+}
+
+// withAPIKey returns a context presenting testAPIKey
+func withAPIKey(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, MetadataAPIKey, testAPIKey)
+}
+
+func TestServer_SubscribeAndPublish(t *testing.T) {
+	client, _, auditor := newTestGateway(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = withAPIKey(ctx)
+
+	stream, err := client.Subscribe(ctx, &notificationv1.SubscribeRequest{UserId: "alice", Topics: []string{"orders"}})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	header, err := stream.Header()
+	if err != nil || len(header.Get(MetadataConnectionID)) == 0 {
+		t.Fatalf("Expected a connection ID header, got %v (%v)", header, err)
+	}
+	connID := header.Get(MetadataConnectionID)[0]
+	time.Sleep(100 * time.Millisecond)
+
+	data, _ := structpb.NewValue(map[string]any{"order": 1})
+	published, err := client.Publish(ctx, &notificationv1.PublishRequest{
+		Topic:   "orders",
+		Message: &notificationv1.Message{Type: "notification", Data: data},
+	})
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if published.GetMessageId() == "" || published.GetRecipients() != 1 {
+		t.Errorf("Unexpected publish response %+v", published)
+	}
+
+	if _, err := client.SendToUser(ctx, &notificationv1.SendToUserRequest{UserId: "alice", Message: &notificationv1.Message{Id: "to-alice", Type: "notification"}}); err != nil {
+		t.Fatalf("SendToUser failed: %v", err)
+	}
+	if _, err := client.SendToConnection(ctx, &notificationv1.SendToConnectionRequest{ConnectionId: connID, Message: &notificationv1.Message{Id: "to-conn", Type: "notification"}}); err != nil {
+		t.Fatalf("SendToConnection failed: %v", err)
+	}
+
+	received := map[string]uint64{}
+	for len(received) < 3 {
+		msg, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed after %v: %v", received, err)
+		}
+		received[msg.GetMessage().GetId()] = msg.GetCursor()
+		if msg.GetMessage().GetId() == published.GetMessageId() {
+			if order := msg.GetMessage().GetData().GetStructValue().GetFields()["order"].GetNumberValue(); order != 1 {
+				t.Errorf("Expected the published data to round-trip, got %v", msg.GetMessage().GetData())
+			}
+		}
+	}
+	if _, ok := received[published.GetMessageId()]; !ok || received["to-alice"] == 0 || received["to-conn"] == 0 {
+		t.Errorf("Expected all three messages with cursors, got %v", received)
+	}
+
+	entries, _ := auditor.Query(ctx, &audit.Filter{Action: audit.ActionPublishToTopic})
+	if len(entries) != 1 || entries[0].Target != "topic:orders" || entries[0].Actor != "key:secr****" {
+		t.Errorf("Expected a topic publish audited with the API key, got %+v", entries)
+	}
+
+	// Resuming from the first message replays the other two
+	resumed, err := client.Subscribe(ctx, &notificationv1.SubscribeRequest{UserId: "alice", Topics: []string{"orders"}, Cursor: ptr(received[published.GetMessageId()])})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	msg, err := resumed.Recv()
+	if err != nil || msg.GetMessage().GetId() != "to-alice" {
+		t.Errorf("Expected to-alice to be replayed first, got %+v (%v)", msg, err)
+	}
+}
+
+func TestServer_ConnectCommands(t *testing.T) {
+	client, hubInstance, _ := newTestGateway(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Connect(metadata.AppendToOutgoingContext(withAPIKey(ctx), MetadataUserID, "bob"))
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	stream.Send(&notificationv1.Command{Type: CommandSubscribe, Topics: []string{"alerts"}})
+	if msg, err := stream.Recv(); err != nil || msg.GetMessage().GetType() != string(hub.MessageTypeSystem) {
+		t.Fatalf("Expected a subscribed reply, got %+v (%v)", msg, err)
+	}
+
+	hubInstance.PublishToTopic(ctx, "alerts", &hub.Message{ID: "alert-1", Type: "alert"})
+	if msg, err := stream.Recv(); err != nil || msg.GetMessage().GetId() != "alert-1" {
+		t.Fatalf("Expected alert-1, got %+v (%v)", msg, err)
+	}
+
+	stream.Send(&notificationv1.Command{Type: "hello", Data: structpb.NewStringValue("world")})
+	if msg, err := stream.Recv(); err != nil || msg.GetMessage().GetType() != "echo" {
+		t.Fatalf("Expected an echo, got %+v (%v)", msg, err)
+	}
+
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected the stream to end after CloseSend, got %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if count := hubInstance.CountByType()["grpc"]; count != 0 {
+		t.Errorf("Expected the connection to be unregistered, got %d", count)
+	}
+}
+
+func TestServer_RequiresAPIKey(t *testing.T) {
+	if _, err := NewGRPCServer(&Server{}, nil); err != ErrNoAPIKeys {
+		t.Errorf("Expected the gateway to refuse to start without keys, got %v", err)
+	}
+
+	client, _, _ := newTestGateway(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	request := &notificationv1.PublishRequest{Message: &notificationv1.Message{Type: "notification"}}
+	if _, err := client.Publish(ctx, request); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without a key, got %v", err)
+	}
+	if _, err := client.Publish(withAPIKey(ctx), request); err != nil {
+		t.Errorf("Expected publish with a valid key to succeed, got %v", err)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
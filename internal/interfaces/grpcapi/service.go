@@ -0,0 +1,57 @@
+// Package grpcapi is the gRPC gateway internal services use to publish to and
+// subscribe on the hub.
+//
+// The service is defined by notificationv1/hub.proto and speaks standard
+// protobuf, so stubs generated in any language can call it, and the server
+// reflection service lets tools such as grpcurl discover it. Every call must
+// present one of the gateway's API keys.
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative notificationv1/hub.proto
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/interfaces/grpcapi/notificationv1"
+)
+
+// Command types understood by the Connect stream
+const (
+	CommandSubscribe   = "subscribe"
+	CommandUnsubscribe = "unsubscribe"
+	CommandPing        = "ping"
+)
+
+// toHubMessage converts a message of a request into a hub message
+func toHubMessage(message *notificationv1.Message) *hub.Message {
+	return &hub.Message{
+		ID:      message.GetId(),
+		Type:    message.GetType(),
+		Data:    message.GetData().AsInterface(),
+		Headers: message.GetHeaders(),
+	}
+}
+
+// toProtoMessage converts a hub message, whose data may be any value that
+// encodes to JSON, into its protobuf form
+func toProtoMessage(message *hub.Message) (*notificationv1.Message, error) {
+	converted := &notificationv1.Message{
+		Id:      message.ID,
+		Type:    message.Type,
+		Headers: message.Headers,
+	}
+	if message.Data != nil {
+		payload, err := json.Marshal(message.Data)
+		if err != nil {
+			return nil, err
+		}
+		converted.Data = &structpb.Value{}
+		if err := converted.Data.UnmarshalJSON(payload); err != nil {
+			return nil, err
+		}
+	}
+	return converted, nil
+}
@@ -0,0 +1,185 @@
+package longpoll
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
+)
+
+// HeaderSessionToken carries the session token returned by Connect; clients
+// that cannot set headers may send it as the "token" query parameter instead
+const HeaderSessionToken = "X-Session-Token"
+
+const (
+	// defaultPollWait is how long a poll waits for messages unless the client asks
+	// for less; maxPollWait keeps polls under common proxy idle timeouts
+	defaultPollWait = 25 * time.Second
+	maxPollWait     = 60 * time.Second
+)
+
+// LongPollHandler serves HTTP long-polling sessions for clients that cannot
+// use SSE or WebSocket
+type LongPollHandler struct {
+	hub         *hub.Hub
+	logger      logger.Logger
+	connOptions []hub.LongPollOption
+}
+
+// NewLongPollHandler creates a new long-polling handler
+func NewLongPollHandler(
+	hubInstance *hub.Hub,
+	logger logger.Logger,
+	connOptions ...hub.LongPollOption,
+) *LongPollHandler {
+	return &LongPollHandler{
+		hub:         hubInstance,
+		logger:      logger.WithField("handler", "longpoll"),
+		connOptions: connOptions,
+	}
+}
+
+// Connect opens a long-polling session. It accepts the same user, topic and tag
+// parameters as the streaming transports. The response carries the session
+// token required by Poll and Disconnect; it is not returned anywhere else.
+func (h *LongPollHandler) Connect(c *gin.Context) {
+	log := h.logger.WithContext(c.Request.Context())
+
+	if !h.hub.IsRunning() {
+		log.Error("Hub is not running")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Service temporarily unavailable",
+		})
+		return
+	}
+
+	connID := generatePollConnectionID()
+	middleware.SetConnectionID(c, connID)
+	log = h.logger.WithContext(c.Request.Context())
+
+	token := generateSessionToken()
+	conn := hub.NewLongPollConnection(
+		connID,
+		h.logger,
+		append([]hub.LongPollOption{hub.WithPollRequest(c.Request), hub.WithSessionToken(token)}, h.connOptions...)...,
+	)
+
+	if err := h.hub.RegisterConnection(conn); err != nil {
+		log.Errorf("Failed to register long-poll session: %v", err)
+		conn.Close()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to register connection",
+		})
+		return
+	}
+
+	// Bind routing so user- and topic-targeted messages reach this session
+	h.hub.BindUser(conn.ID(), middleware.UserID(c))
+	h.hub.Subscribe(conn.ID(), middleware.Topics(c)...)
+	h.hub.Tag(conn.ID(), middleware.Tags(c)...)
+
+	log.Infof("Long-poll session %s connected and registered", conn.ID())
+	c.JSON(http.StatusCreated, gin.H{
+		"connection_id": conn.ID(),
+		"session_token": token,
+		"cursor":        0,
+		"timestamp":     time.Now().Format(time.RFC3339),
+	})
+}
+
+// Poll waits up to the "timeout" query parameter (seconds) for messages after
+// "cursor" and returns them as a batch with the cursor to send next
+func (h *LongPollHandler) Poll(c *gin.Context) {
+	conn, ok := h.session(c)
+	if !ok {
+		return
+	}
+
+	cursor, err := strconv.ParseUint(c.DefaultQuery("cursor", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid cursor",
+		})
+		return
+	}
+
+	wait := defaultPollWait
+	if value := c.Query("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid timeout",
+			})
+			return
+		}
+		wait = min(time.Duration(seconds)*time.Second, maxPollWait)
+	}
+
+	result, err := conn.Poll(c.Request.Context(), cursor, wait)
+	if err != nil {
+		if c.Request.Context().Err() == nil {
+			c.JSON(http.StatusGone, gin.H{
+				"error": "Session expired",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Disconnect closes a long-polling session
+func (h *LongPollHandler) Disconnect(c *gin.Context) {
+	conn, ok := h.session(c)
+	if !ok {
+		return
+	}
+
+	h.hub.UnregisterConnection(conn.ID())
+	c.Status(http.StatusNoContent)
+}
+
+// session looks up the session named in the path, writing a 404 response when
+// it does not exist, has expired or the session token does not match
+func (h *LongPollHandler) session(c *gin.Context) (*hub.LongPollConnection, bool) {
+	connID := c.Param("connectionId")
+	middleware.SetConnectionID(c, connID)
+
+	token := c.GetHeader(HeaderSessionToken)
+	if token == "" {
+		token = c.Query("token")
+	}
+
+	existing, exists := h.hub.GetConnection(connID)
+	conn, ok := existing.(*hub.LongPollConnection)
+	if !exists || !ok || conn.IsClosed() || !conn.Authorize(token) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return nil, false
+	}
+	return conn, true
+}
+
+// generateSessionToken generates the secret that authorizes requests for a
+// long-polling session
+func generateSessionToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// generatePollConnectionID generates a unique long-polling session ID
+func generatePollConnectionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("poll-%x", b)
+}
@@ -0,0 +1,25 @@
+package longpoll
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
+)
+
+// InitLongPollRouter initializes long-polling routes
+func InitLongPollRouter(
+	logger logger.Logger,
+	hubInstance *hub.Hub,
+	rg *gin.RouterGroup,
+	connOptions ...hub.LongPollOption,
+) {
+	pollHandler := NewLongPollHandler(hubInstance, logger, connOptions...)
+
+	// Session endpoints; polls outlive the server write timeout
+	pollGroup := rg.Group("/poll")
+	pollGroup.POST("", pollHandler.Connect)
+	pollGroup.GET("/:connectionId", middleware.Streaming(), pollHandler.Poll)
+	pollGroup.DELETE("/:connectionId", pollHandler.Disconnect)
+}
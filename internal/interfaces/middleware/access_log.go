@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/logger"
+)
+
+const (
+	// HeaderRequestID carries the request ID, accepted from callers and echoed back
+	HeaderRequestID = "X-Request-ID"
+
+	requestIDContextKey    = "request_id"
+	connectionIDContextKey = "connection_id"
+
+	// maxRequestIDLength bounds caller-supplied request IDs
+	maxRequestIDLength = 128
+)
+
+// RequestID returns the ID assigned to the request by AccessLog
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDContextKey)
+}
+
+// SetConnectionID records the hub connection served by a streaming request so it
+// appears in the access log and in logs written with the request context
+func SetConnectionID(c *gin.Context, connID string) {
+	c.Set(connectionIDContextKey, connID)
+	c.Request = c.Request.WithContext(logger.ContextWithConnectionID(c.Request.Context(), connID))
+}
+
+// AccessLog assigns each request an ID (reusing a valid X-Request-ID header),
+// stores it on the request context and logs a structured line once the request
+// completes, including the caller's user ID as resolved by then, i.e. after
+// Authenticate ran. For streams the line is written when the stream closes.
+// Requests to quietPaths are logged at debug level.
+func AccessLog(log logger.Logger, quietPaths ...string) gin.HandlerFunc {
+	log = log.WithField("component", logger.ComponentHTTP)
+
+	quiet := make(map[string]struct{}, len(quietPaths))
+	for _, path := range quietPaths {
+		quiet[path] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(HeaderRequestID)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = newRequestID()
+		}
+		c.Set(requestIDContextKey, requestID)
+		c.Header(HeaderRequestID, requestID)
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), requestID))
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		latency := time.Since(start)
+
+		fields := logger.Fields{
+			"method":     c.Request.Method,
+			"route":      route,
+			"path":       c.Request.URL.Path,
+			"status":     status,
+			"latency_ms": float64(latency.Microseconds()) / 1000,
+			"bytes":      max(c.Writer.Size(), 0),
+			"client_ip":  c.ClientIP(),
+			"user_agent": c.Request.UserAgent(),
+			"request_id": requestID,
+		}
+		if userID := UserID(c); userID != "" {
+			fields["user_id"] = userID
+		}
+		if len(c.Errors) > 0 {
+			fields["errors"] = c.Errors.String()
+		}
+
+		msg := "HTTP request"
+		if connID := c.GetString(connectionIDContextKey); connID != "" {
+			msg = "Stream closed"
+			fields["connection_id"] = connID
+			fields["stream_duration"] = latency.String()
+		}
+
+		entry := log.WithFields(fields)
+		switch _, isQuiet := quiet[c.Request.URL.Path]; {
+		case status >= http.StatusInternalServerError:
+			entry.Error(msg)
+		case status >= http.StatusBadRequest:
+			entry.Warn(msg)
+		case isQuiet:
+			entry.Debug(msg)
+		default:
+			entry.Info(msg)
+		}
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
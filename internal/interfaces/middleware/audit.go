@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/audit"
+)
+
+// NewAuditEntry creates an audit entry for the caller of c; the outcome defaults
+// to success and should be overwritten by the handler on failure
+func NewAuditEntry(c *gin.Context, action audit.Action, target string) *audit.Entry {
+	return &audit.Entry{
+		Timestamp: time.Now().UTC(),
+		Actor:     Actor(c),
+		Action:    action,
+		Target:    target,
+		Outcome:   audit.OutcomeSuccess,
+		SourceIP:  c.ClientIP(),
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/identity"
+)
+
+// RequireAPIKey rejects requests that do not present one of keys, either in the
+// X-API-Key header or as an Authorization bearer token
+func RequireAPIKey(keys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented := APIKey(c)
+		if presented == "" {
+			presented, _ = strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+
+		if !identity.ValidAPIKey(presented, keys) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or missing API key",
+			})
+			return
+		}
+
+		c.Set(verifiedAPIKeyKey, presented)
+		c.Next()
+	}
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/identity"
+	"go-notification-sse/internal/infrastructure/logger"
+)
+
+const (
+	// HeaderAPIKey carries the caller's API key
+	HeaderAPIKey = "X-API-Key"
+	// HeaderUserID carries the caller's user ID
+	HeaderUserID = "X-User-ID"
+	// HeaderUserToken carries a user token signed by the application backend
+	HeaderUserToken = "X-User-Token"
+
+	verifiedAPIKeyKey = "verified_api_key"
+	verifiedUserKey   = "verified_user_id"
+	claimedUsersKey   = "claimed_user_ids_trusted"
+)
+
+// Authenticate verifies the user token presented in the X-User-Token header, or
+// the user_token query parameter for clients such as EventSource that cannot set
+// headers, and records the verified user. Requests without a token continue
+// anonymously; invalid tokens are rejected. The verified user is added to the
+// request's log context.
+func Authenticate(tokens *identity.Tokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader(HeaderUserToken)
+		if token == "" {
+			token = c.Query("user_token")
+		}
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		userID, err := tokens.Verify(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or expired user token",
+			})
+			return
+		}
+
+		c.Set(verifiedUserKey, userID)
+		c.Request = c.Request.WithContext(logger.ContextWithUserID(c.Request.Context(), userID))
+		c.Next()
+	}
+}
+
+// TrustClaimedUserIDs makes UserID fall back to the unverified X-User-ID header
+// or user_id query parameter. Anyone can then claim any user and receive their
+// messages, so it is only meant for deployments whose clients are all trusted.
+func TrustClaimedUserIDs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(claimedUsersKey, true)
+		c.Next()
+	}
+}
+
+// ClientAddr records the client IP, resolved by gin from the forwarding headers
+// of trusted proxies only, for the connections a request opens
+func ClientAddr() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(hub.ContextWithClientAddr(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
+
+// RequireUser rejects requests without a user verified by Authenticate
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if VerifiedUserID(c) == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "User token required",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// VerifiedUserID returns the user ID proven by a user token, if any
+func VerifiedUserID(c *gin.Context) string {
+	return c.GetString(verifiedUserKey)
+}
+
+// VerifiedAPIKey returns the API key accepted by RequireAPIKey, if any
+func VerifiedAPIKey(c *gin.Context) string {
+	return c.GetString(verifiedAPIKeyKey)
+}
+
+// APIKey returns the API key presented by the caller, if any
+func APIKey(c *gin.Context) string {
+	return c.GetHeader(HeaderAPIKey)
+}
+
+// UserID returns the user ID of the caller, as verified by a user token. Only
+// behind TrustClaimedUserIDs does it fall back to the unverified X-User-ID
+// header or user_id query parameter; otherwise callers without a token have no
+// user.
+func UserID(c *gin.Context) string {
+	if userID := VerifiedUserID(c); userID != "" || !c.GetBool(claimedUsersKey) {
+		return userID
+	}
+	return claimedUserID(c)
+}
+
+// Topics returns the topics requested via repeated "topic" or comma-separated "topics"
+// query parameters
+func Topics(c *gin.Context) []string {
+	return queryList(c, "topic", "topics")
+}
+
+// Tags returns the connection labels requested via repeated "tag" or comma-separated
+// "tags" query parameters
+func Tags(c *gin.Context) []string {
+	return queryList(c, "tag", "tags")
+}
+
+func queryList(c *gin.Context, single, plural string) []string {
+	var values []string
+	for _, value := range append(c.QueryArray(single), strings.Split(c.Query(plural), ",")...) {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Actor identifies the caller for audit purposes: the user verified by a user
+// token, otherwise the masked API key accepted by RequireAPIKey, otherwise
+// "anonymous". User IDs and API keys the caller merely claims are recorded with
+// an "unverified-" prefix, so that they cannot pass for proven identities.
+func Actor(c *gin.Context) string {
+	if userID := VerifiedUserID(c); userID != "" {
+		return "user:" + userID
+	}
+	if key := VerifiedAPIKey(c); key != "" {
+		return "key:" + maskKey(key)
+	}
+	if userID := claimedUserID(c); userID != "" {
+		return "unverified-user:" + userID
+	}
+	if key := APIKey(c); key != "" {
+		return "unverified-key:" + maskKey(key)
+	}
+	return "anonymous"
+}
+
+// claimedUserID returns the unverified X-User-ID header or user_id query
+// parameter, if any
+func claimedUserID(c *gin.Context) string {
+	if userID := c.GetHeader(HeaderUserID); userID != "" {
+		return userID
+	}
+	return c.Query("user_id")
+}
+
+// maskKey keeps only a short prefix of an API key
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return key[:4] + "****"
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/identity"
+	"go-notification-sse/internal/infrastructure/logger"
+)
+
+// serveUserID returns the UserID seen by a handler behind the middleware
+func serveUserID(t *testing.T, request *http.Request, middleware ...gin.HandlerFunc) string {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	var userID string
+	router.GET("/", append(middleware, func(c *gin.Context) { userID = UserID(c) })...)
+	router.ServeHTTP(httptest.NewRecorder(), request)
+	return userID
+}
+
+func TestUserID(t *testing.T) {
+	tokens := identity.NewTokens([]string{"secret"})
+	token := tokens.Issue("alice", time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name       string
+		url        string
+		header     string
+		middleware []gin.HandlerFunc
+		want       string
+	}{
+		{"unverified header", "/", "bob", nil, ""},
+		{"unverified query", "/?user_id=bob", "", nil, ""},
+		{"trusted unverified header", "/", "bob", []gin.HandlerFunc{TrustClaimedUserIDs()}, "bob"},
+		{"trusted unverified query", "/?user_id=bob", "", []gin.HandlerFunc{TrustClaimedUserIDs()}, "bob"},
+		{"unverified header with tokens", "/", "bob", []gin.HandlerFunc{Authenticate(tokens)}, ""},
+		{"unverified query with tokens", "/?user_id=bob", "", []gin.HandlerFunc{Authenticate(tokens)}, ""},
+		{"verified token", "/?user_id=bob&user_token=" + token, "bob", []gin.HandlerFunc{Authenticate(tokens)}, "alice"},
+		{"verified token over trusted claim", "/?user_token=" + token, "bob", []gin.HandlerFunc{TrustClaimedUserIDs(), Authenticate(tokens)}, "alice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.header != "" {
+				request.Header.Set(HeaderUserID, tt.header)
+			}
+			if got := serveUserID(t, request, tt.middleware...); got != tt.want {
+				t.Errorf("Expected user %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestActor(t *testing.T) {
+	tokens := identity.NewTokens([]string{"secret"})
+	token := tokens.Issue("alice", time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		middleware []gin.HandlerFunc
+		want       string
+	}{
+		{"anonymous", nil, nil, "anonymous"},
+		{"claimed user", map[string]string{HeaderUserID: "bob"}, nil, "unverified-user:bob"},
+		{"claimed key", map[string]string{HeaderAPIKey: "key-12345"}, nil, "unverified-key:key-****"},
+		{"verified user", map[string]string{HeaderUserID: "bob", HeaderUserToken: token}, []gin.HandlerFunc{Authenticate(tokens)}, "user:alice"},
+		{"verified key", map[string]string{HeaderUserID: "bob", HeaderAPIKey: "key-12345"}, []gin.HandlerFunc{RequireAPIKey([]string{"key-12345"})}, "key:key-****"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			var actor string
+			router.GET("/", append(tt.middleware, func(c *gin.Context) { actor = Actor(c) })...)
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, value := range tt.headers {
+				request.Header.Set(key, value)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), request)
+
+			if actor != tt.want {
+				t.Errorf("Expected actor %q, got %q", tt.want, actor)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_AddsVerifiedUserToLogContext(t *testing.T) {
+	tokens := identity.NewTokens([]string{"secret"})
+	token := tokens.Issue("alice", time.Now().Add(time.Hour))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	var fields logger.Fields
+	router.GET("/", Authenticate(tokens), func(c *gin.Context) { fields = logger.ContextFields(c.Request.Context()) })
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set(HeaderUserID, "bob")
+	request.Header.Set(HeaderUserToken, token)
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	if fields[logger.FieldUserID] != "alice" {
+		t.Errorf("Expected the verified user in the log context, got %v", fields)
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/ratelimit"
+)
+
+// KeyFunc extracts the rate limit key from a request; an empty key means "not applicable"
+type KeyFunc func(c *gin.Context) string
+
+// KeyByAPIKey keys requests by the API key RequireAPIKey accepted. Unverified
+// keys are ignored, as a client could send a new one with every request.
+func KeyByAPIKey(c *gin.Context) string {
+	if key := VerifiedAPIKey(c); key != "" {
+		return "key:" + key
+	}
+	return ""
+}
+
+// KeyByUser keys requests by the user ID verified by Authenticate
+func KeyByUser(c *gin.Context) string {
+	if userID := VerifiedUserID(c); userID != "" {
+		return "user:" + userID
+	}
+	return ""
+}
+
+// KeyByIP keys requests by client IP
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByFirst returns the first non-empty key produced by funcs
+func KeyByFirst(funcs ...KeyFunc) KeyFunc {
+	return func(c *gin.Context) string {
+		for _, fn := range funcs {
+			if key := fn(c); key != "" {
+				return key
+			}
+		}
+		return ""
+	}
+}
+
+// RateLimit rejects requests exceeding the limiter's budget with 429 and
+// reports the current limit state in X-RateLimit-* headers
+func RateLimit(limiter *ratelimit.Limiter, keyFunc KeyFunc, log logger.Logger) gin.HandlerFunc {
+	log = log.WithField("middleware", "ratelimit")
+
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		res := limiter.Take(key)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+
+		if !res.Allowed {
+			retryAfter := ceilSeconds(res.RetryAfter)
+			log.Warnf("Rate limit exceeded for %s on %s", key, c.FullPath())
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Rate limit exceeded",
+				"retry_after": retryAfter,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
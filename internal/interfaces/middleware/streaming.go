@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Streaming lifts the server write timeout for long-lived responses such as
+// event streams, which would otherwise be cut off after WriteTimeout
+func Streaming() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+		c.Next()
+	}
+}
@@ -0,0 +1,140 @@
+package ndjson
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
+)
+
+// HeaderStreamCursor carries the hub cursor at the time a stream was opened, so a
+// client that has not received any message yet can still resume without gaps
+const HeaderStreamCursor = "X-Stream-Cursor"
+
+// StreamHandler serves newline-delimited JSON streams for server-to-server consumers
+type StreamHandler struct {
+	hub         *hub.Hub
+	logger      logger.Logger
+	connOptions []hub.NDJSONOption
+}
+
+// NewStreamHandler creates a new NDJSON stream handler
+func NewStreamHandler(
+	hubInstance *hub.Hub,
+	logger logger.Logger,
+	connOptions ...hub.NDJSONOption,
+) *StreamHandler {
+	return &StreamHandler{
+		hub:         hubInstance,
+		logger:      logger.WithField("handler", "ndjson"),
+		connOptions: connOptions,
+	}
+}
+
+// Connect streams messages as one JSON object per line. It accepts the same user,
+// topic and tag parameters as the other transports; "cursor" resumes after the
+// last message the client saw, replaying what it missed (0 replays everything
+// still retained).
+func (h *StreamHandler) Connect(c *gin.Context) {
+	log := h.logger.WithContext(c.Request.Context())
+
+	if !h.hub.IsRunning() {
+		log.Error("Hub is not running")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Service temporarily unavailable",
+		})
+		return
+	}
+
+	var cursor uint64
+	value, resume := c.GetQuery("cursor")
+	if resume {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid cursor",
+			})
+			return
+		}
+		cursor = parsed
+	}
+
+	connID := generateStreamConnectionID()
+	middleware.SetConnectionID(c, connID)
+	log = h.logger.WithContext(c.Request.Context())
+
+	conn := hub.NewNDJSONConnection(connID, c.Writer, c.Request, h.logger, h.connOptions...)
+	c.Header(HeaderStreamCursor, strconv.FormatUint(h.hub.Cursor(), 10))
+
+	if err := h.hub.RegisterConnection(conn); err != nil {
+		log.Errorf("Failed to register NDJSON stream: %v", err)
+		conn.Close()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to register connection",
+		})
+		return
+	}
+
+	// Bind routing before replaying so nothing published in between is missed
+	h.hub.BindUser(conn.ID(), middleware.UserID(c))
+	h.hub.Subscribe(conn.ID(), middleware.Topics(c)...)
+	h.hub.Tag(conn.ID(), middleware.Tags(c)...)
+
+	var replay []hub.JournalEntry
+	if resume {
+		entries, complete := h.hub.Replay(conn.ID(), cursor)
+		if !complete {
+			log.Warnf("Cursor %d is no longer retained, some messages cannot be replayed", cursor)
+			replay = append(replay, hub.JournalEntry{
+				Message: hub.ErrorMessage("cursor_expired", "Some messages after the cursor are no longer available", gin.H{
+					"cursor": cursor,
+				}),
+			})
+		}
+		// A user ID the client merely claims routes live messages, when claimed
+		// IDs are trusted, but does not unlock the user's history
+		if middleware.VerifiedUserID(c) == "" {
+			entries = slices.DeleteFunc(entries, func(entry hub.JournalEntry) bool {
+				return strings.HasPrefix(entry.Target, "user:")
+			})
+		}
+		replay = append(replay, entries...)
+	}
+
+	// Replaying also sends the response headers
+	if err := conn.Replay(replay); err != nil {
+		log.Errorf("Failed to replay messages: %v", err)
+		h.hub.UnregisterConnection(conn.ID())
+		return
+	}
+
+	log.Infof("NDJSON stream %s connected and registered (replayed %d messages)", conn.ID(), len(replay))
+	conn.Serve()
+	log.Infof("NDJSON stream %s disconnected", conn.ID())
+}
+
+// GetConnections returns information about NDJSON streams
+func (h *StreamHandler) GetConnections(c *gin.Context) {
+	connections := h.hub.SummarizeConnections(&hub.ConnectionFilter{Type: "ndjson"})
+
+	c.JSON(http.StatusOK, gin.H{
+		"total_connections": len(connections),
+		"connections":       connections,
+		"hub_running":       h.hub.IsRunning(),
+	})
+}
+
+// generateStreamConnectionID generates a unique NDJSON stream ID
+func generateStreamConnectionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("ndjson-%x", b)
+}
@@ -0,0 +1,86 @@
+package ndjson
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/identity"
+	"go-notification-sse/internal/interfaces/middleware"
+	"go-notification-sse/internal/interfaces/transporttest"
+)
+
+// replayedIDs opens a stream resuming from cursor 0 and returns the IDs of the
+// messages replayed to it
+func replayedIDs(t *testing.T, hubInstance *hub.Hub, url string) []string {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	// The replay is written before the headers are flushed; a live message marks
+	// its end
+	hubInstance.PublishToTopic(ctx, "news", &hub.Message{ID: "end", Type: "test"})
+
+	var ids []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var line hub.StreamedMessage
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil || line.Message == nil {
+			continue // heartbeat
+		}
+		if line.ID == "end" {
+			return ids
+		}
+		ids = append(ids, line.ID)
+	}
+	t.Fatalf("Stream ended before the live message: %v", scanner.Err())
+	return nil
+}
+
+func TestConnect_ReplaysUserMessagesToVerifiedUsersOnly(t *testing.T) {
+	hubInstance, log := transporttest.NewHub(t)
+	tokens := identity.NewTokens([]string{"secret"})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.TrustClaimedUserIDs(), middleware.Authenticate(tokens))
+	InitStreamRouter(log, hubInstance, &router.RouterGroup)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx := context.Background()
+	hubInstance.PublishToTopic(ctx, "news", &hub.Message{ID: "news-1", Type: "test"})
+	hubInstance.SendToUser(ctx, "victim", &hub.Message{ID: "private-1", Type: "test"})
+
+	t.Run("claimed user", func(t *testing.T) {
+		ids := replayedIDs(t, hubInstance, server.URL+"/stream?topic=news&user_id=victim&cursor=0")
+		if len(ids) != 1 || ids[0] != "news-1" {
+			t.Errorf("Expected only news-1 to be replayed, got %v", ids)
+		}
+	})
+
+	t.Run("verified user", func(t *testing.T) {
+		token := tokens.Issue("victim", time.Now().Add(time.Hour))
+		ids := replayedIDs(t, hubInstance, server.URL+"/stream?topic=news&cursor=0&user_token="+token)
+		if len(ids) != 2 || ids[0] != "news-1" || ids[1] != "private-1" {
+			t.Errorf("Expected news-1 and private-1 to be replayed, got %v", ids)
+		}
+	})
+}
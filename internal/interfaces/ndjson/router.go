@@ -0,0 +1,24 @@
+package ndjson
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
+)
+
+// InitStreamRouter initializes NDJSON streaming routes
+func InitStreamRouter(
+	logger logger.Logger,
+	hubInstance *hub.Hub,
+	rg *gin.RouterGroup,
+	connOptions ...hub.NDJSONOption,
+) {
+	streamHandler := NewStreamHandler(hubInstance, logger, connOptions...)
+
+	rg.GET("/stream", middleware.Streaming(), streamHandler.Connect)
+
+	apiGroup := rg.Group("/api/v1/stream")
+	apiGroup.GET("/connections", streamHandler.GetConnections)
+}
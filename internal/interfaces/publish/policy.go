@@ -0,0 +1,93 @@
+// Package publish authorizes and audits the messages clients of the streaming
+// transports publish themselves, such as STOMP SEND frames, MQTT PUBLISH packets
+// and Socket.IO publish events. Clients may only publish to topics matching the
+// configured patterns; every attempt is recorded in the audit trail, as for the
+// REST and gRPC publish APIs.
+package publish
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+)
+
+// ErrTopicNotAllowed is returned when clients may not publish to a topic
+var ErrTopicNotAllowed = errors.New("publishing to this topic is not allowed")
+
+// Policy decides which topics clients may publish to and audits their publishes
+type Policy struct {
+	hub     *hub.Hub
+	auditor *audit.Recorder
+	topics  []string // topic patterns, see hub.TopicMatches
+}
+
+// NewPolicy allows clients to publish to the topics matching any of the patterns
+// ("+" matches one level and a trailing "#" any number of levels); with no
+// patterns clients cannot publish at all
+func NewPolicy(hubInstance *hub.Hub, auditor *audit.Recorder, topics []string) *Policy {
+	return &Policy{
+		hub:     hubInstance,
+		auditor: auditor,
+		topics:  topics,
+	}
+}
+
+// Allowed reports whether clients may publish to topic. A nil policy allows none.
+func (p *Policy) Allowed(topic string) bool {
+	if p == nil {
+		return false
+	}
+	for _, pattern := range p.topics {
+		if hub.TopicMatches(pattern, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish publishes a message a client sent on conn to a topic, returning the
+// number of targeted connections. The attempt is audited whether it is denied,
+// fails or succeeds.
+func (p *Policy) Publish(ctx context.Context, conn hub.Connection, topic string, message *hub.Message) (int, error) {
+	if !p.Allowed(topic) {
+		if p != nil {
+			entry := p.newAuditEntry(conn, topic, message.ID)
+			entry.Outcome = audit.OutcomeDenied
+			entry.Error = ErrTopicNotAllowed.Error()
+			p.auditor.Record(ctx, entry)
+		}
+		return 0, ErrTopicNotAllowed
+	}
+
+	entry := p.newAuditEntry(conn, topic, message.ID)
+	defer p.auditor.Record(ctx, entry)
+
+	recipients, err := p.hub.PublishToTopic(ctx, topic, message)
+	if err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		return 0, err
+	}
+	return recipients, nil
+}
+
+// newAuditEntry records the connection's user, if one is bound, as the actor
+func (p *Policy) newAuditEntry(conn hub.Connection, topic, messageID string) *audit.Entry {
+	entry := &audit.Entry{
+		Timestamp: time.Now().UTC(),
+		Actor:     "anonymous",
+		Action:    audit.ActionPublishToTopic,
+		Target:    "topic:" + topic,
+		MessageID: messageID,
+		Outcome:   audit.OutcomeSuccess,
+		SourceIP:  conn.Info().RemoteAddr,
+		Details:   map[string]any{"connection_id": conn.ID(), "transport": conn.Type()},
+	}
+	if userID := p.hub.UserOf(conn.ID()); userID != "" {
+		entry.Actor = "user:" + userID
+	}
+	return entry
+}
@@ -0,0 +1,23 @@
+package publish
+
+import "testing"
+
+func TestPolicy_Allowed(t *testing.T) {
+	policy := NewPolicy(nil, nil, []string{"orders", "chat/+", "devices/#"})
+
+	for _, topic := range []string{"orders", "chat/lobby", "devices", "devices/a/b"} {
+		if !policy.Allowed(topic) {
+			t.Errorf("Expected %q to be allowed", topic)
+		}
+	}
+	for _, topic := range []string{"order", "orders/eu", "chat", "chat/a/b", "$sys/devices"} {
+		if policy.Allowed(topic) {
+			t.Errorf("Expected %q to be refused", topic)
+		}
+	}
+
+	var none *Policy
+	if none.Allowed("orders") || NewPolicy(nil, nil, nil).Allowed("orders") {
+		t.Error("Expected a missing or empty policy to refuse every topic")
+	}
+}
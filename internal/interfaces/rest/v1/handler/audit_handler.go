@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/logger"
+)
+
+const (
+	defaultAuditQueryLimit = 100
+	maxAuditQueryLimit     = 1000
+)
+
+type AuditHandler struct {
+	auditor *audit.Recorder
+	logger  logger.Logger
+}
+
+func NewAuditHandler(auditor *audit.Recorder, logger logger.Logger) *AuditHandler {
+	return &AuditHandler{
+		auditor: auditor,
+		logger:  logger.WithField("handler", "audit"),
+	}
+}
+
+// Query searches the audit trail by time range (RFC3339 "from"/"to"), actor and action
+func (h *AuditHandler) Query(c *gin.Context) {
+	filter := &audit.Filter{
+		Actor:  c.Query("actor"),
+		Action: audit.Action(c.Query("action")),
+		Limit:  defaultAuditQueryLimit,
+	}
+
+	var err error
+	if from := c.Query("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid from timestamp, expected RFC3339",
+			})
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid to timestamp, expected RFC3339",
+			})
+			return
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid limit",
+			})
+			return
+		}
+		filter.Limit = min(n, maxAuditQueryLimit)
+	}
+
+	entries, err := h.auditor.Query(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Errorf("Failed to query audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to query audit log",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":   len(entries),
+		"entries": entries,
+	})
+}
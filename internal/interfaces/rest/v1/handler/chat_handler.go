@@ -6,13 +6,16 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"go-notification-sse/internal/infrastructure/audit"
 	"go-notification-sse/internal/infrastructure/hub"
 	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
 )
 
 type ChatHandler struct {
-	hub    *hub.Hub
-	logger logger.Logger
+	hub     *hub.Hub
+	logger  logger.Logger
+	auditor *audit.Recorder
 }
 
 type ChatMessageRequest struct {
@@ -28,17 +31,20 @@ type ChatMessageResponse struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
-func NewChatHandler(hubInstance *hub.Hub, logger logger.Logger) *ChatHandler {
+func NewChatHandler(hubInstance *hub.Hub, logger logger.Logger, auditor *audit.Recorder) *ChatHandler {
 	return &ChatHandler{
-		hub:    hubInstance,
-		logger: logger.WithField("handler", "chat"),
+		hub:     hubInstance,
+		logger:  logger.WithField("handler", "chat"),
+		auditor: auditor,
 	}
 }
 
 func (h *ChatHandler) SendMessage(c *gin.Context) {
+	log := h.logger.WithContext(c.Request.Context())
+
 	var req ChatMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Errorf("Invalid request format: %v", err)
+		log.Errorf("Invalid request format: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid message format",
 		})
@@ -73,16 +79,23 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 		Data: chatMessage,
 	}
 
+	entry := middleware.NewAuditEntry(c, audit.ActionChatMessage, "all")
+	entry.MessageID = messageID
+	entry.Details = map[string]any{"username": req.Username}
+	defer h.auditor.Record(c.Request.Context(), entry)
+
 	// Broadcast to all connected clients
 	if err := h.hub.Broadcast(c.Request.Context(), hubMessage); err != nil {
-		h.logger.Errorf("Failed to broadcast message: %v", err)
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		log.Errorf("Failed to broadcast message: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to send message",
 		})
 		return
 	}
 
-	h.logger.Infof("Chat message sent by %s to %d connections", req.Username, h.hub.ConnectionCount())
+	log.Infof("Chat message sent by %s to %d connections", req.Username, h.hub.ConnectionCount())
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":      "sent",
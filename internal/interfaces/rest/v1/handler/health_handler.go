@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/health"
+)
+
+type HealthHandler struct {
+	registry *health.Registry
+	verbose  bool
+}
+
+// NewHealthHandler creates a probe handler; verbose handlers include per-check
+// results and are meant for the admin listener only
+func NewHealthHandler(registry *health.Registry, verbose bool) *HealthHandler {
+	return &HealthHandler{
+		registry: registry,
+		verbose:  verbose,
+	}
+}
+
+// Livez reports whether the process is alive
+func (h *HealthHandler) Livez(c *gin.Context) {
+	h.respond(c, h.registry.Live(c.Request.Context()))
+}
+
+// Readyz reports whether the process should receive traffic
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	h.respond(c, h.registry.Ready(c.Request.Context()))
+}
+
+// Details returns both liveness and readiness reports with per-check results
+func (h *HealthHandler) Details(c *gin.Context) {
+	live := h.registry.Live(c.Request.Context())
+	ready := h.registry.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if !live.Healthy() || !ready.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, gin.H{
+		"liveness":  live,
+		"readiness": ready,
+		"draining":  h.registry.IsDraining(),
+	})
+}
+
+func (h *HealthHandler) respond(c *gin.Context, report *health.Report) {
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+
+	if h.verbose {
+		c.JSON(status, report)
+		return
+	}
+	c.JSON(status, gin.H{"status": report.Status})
+}
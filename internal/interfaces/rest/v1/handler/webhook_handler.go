@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/idempotency"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/webhook"
+	"go-notification-sse/internal/interfaces/middleware"
+)
+
+type WebhookHandler struct {
+	hub          *hub.Hub
+	logger       logger.Logger
+	verifier     *webhook.Verifier
+	store        idempotency.Store
+	auditor      *audit.Recorder
+	maxBodyBytes int64
+}
+
+type WebhookPublishRequest struct {
+	ID       string            `json:"id"        binding:"required"`
+	Type     string            `json:"type"      binding:"required"`
+	Data     interface{}       `json:"data"`
+	Topic    string            `json:"topic"`
+	UserID   string            `json:"user_id"`
+	Priority string            `json:"priority"`
+	Headers  map[string]string `json:"headers"`
+}
+
+func NewWebhookHandler(
+	hubInstance *hub.Hub,
+	logger logger.Logger,
+	config *webhook.Config,
+	store idempotency.Store,
+	auditor *audit.Recorder,
+) *WebhookHandler {
+	return &WebhookHandler{
+		hub:          hubInstance,
+		logger:       logger.WithField("handler", "webhook"),
+		verifier:     webhook.NewVerifier(config),
+		store:        store,
+		auditor:      auditor,
+		maxBodyBytes: config.MaxBodyBytes,
+	}
+}
+
+// Publish verifies a signed webhook and delivers its payload through the hub.
+// Requests are idempotent on the payload ID.
+func (h *WebhookHandler) Publish(c *gin.Context) {
+	log := h.logger.WithContext(c.Request.Context())
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBodyBytes))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "Request body too large",
+		})
+		return
+	}
+
+	if err := h.verifier.Verify(
+		c.GetHeader(webhook.HeaderSignature),
+		c.GetHeader(webhook.HeaderTimestamp),
+		body,
+	); err != nil {
+		entry := middleware.NewAuditEntry(c, audit.ActionWebhookPublish, "")
+		entry.Actor = "webhook"
+		entry.Outcome = audit.OutcomeDenied
+		entry.Error = err.Error()
+		h.auditor.Record(c.Request.Context(), entry)
+
+		log.Warnf("Rejected webhook from %s: %v", c.ClientIP(), err)
+		status := http.StatusUnauthorized
+		if errors.Is(err, webhook.ErrInvalidTimestamp) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var req WebhookPublishRequest
+	if err := binding.JSON.BindBody(body, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid message format",
+		})
+		return
+	}
+
+	if req.Topic != "" && req.UserID != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Specify at most one of topic or user_id",
+		})
+		return
+	}
+
+	if state, reserved := h.store.Reserve(req.ID); !reserved {
+		if state == idempotency.StatePending {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":      "Message is still being processed",
+				"message_id": req.ID,
+			})
+			return
+		}
+		log.Infof("Ignoring duplicate webhook message %s", req.ID)
+		c.JSON(http.StatusOK, gin.H{
+			"status":     "duplicate",
+			"message_id": req.ID,
+		})
+		return
+	}
+
+	message := buildWebhookMessage(&req)
+
+	var (
+		target      string
+		connections int
+	)
+	switch {
+	case req.UserID != "":
+		target = "user:" + req.UserID
+		connections, err = h.hub.SendToUser(c.Request.Context(), req.UserID, message)
+	case req.Topic != "":
+		target = "topic:" + req.Topic
+		connections, err = h.hub.PublishToTopic(c.Request.Context(), req.Topic, message)
+	default:
+		target = "broadcast"
+		connections = h.hub.ConnectionCount()
+		err = h.hub.Broadcast(c.Request.Context(), message)
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionWebhookPublish, target)
+	entry.Actor = "webhook"
+	entry.MessageID = req.ID
+	defer h.auditor.Record(c.Request.Context(), entry)
+
+	if err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		h.store.Release(req.ID)
+		log.Errorf("Failed to publish webhook message %s: %v", req.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to publish message",
+		})
+		return
+	}
+
+	h.store.Complete(req.ID)
+	log.Infof("Webhook message %s published to %s (%d connections)", req.ID, target, connections)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":      "published",
+		"message_id":  req.ID,
+		"target":      target,
+		"connections": connections,
+	})
+}
+
+// buildWebhookMessage maps a webhook payload onto a hub message
+func buildWebhookMessage(req *WebhookPublishRequest) *hub.Message {
+	builder := hub.NewMessageBuilder().
+		WithID(req.ID).
+		WithType(hub.MessageType(req.Type)).
+		WithData(req.Data)
+
+	for key, value := range req.Headers {
+		builder.WithHeader(key, value)
+	}
+	if req.Priority != "" {
+		builder.WithPriority(hub.MessagePriority(req.Priority))
+	}
+	if req.Topic != "" {
+		builder.WithHeader("topic", req.Topic)
+	}
+	builder.WithHeader("source", "webhook")
+
+	return builder.Build()
+}
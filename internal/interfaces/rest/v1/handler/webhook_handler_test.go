@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/idempotency"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/webhook"
+)
+
+const testWebhookSecret = "secret"
+
+func newTestWebhookRouter(t *testing.T) (*gin.Engine, *hub.Hub, *idempotency.MemoryStore) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	log := logger.New(logger.NewDefaultConfig())
+	hubInstance := hub.New(log)
+
+	auditConfig := audit.NewDefaultConfig()
+	auditConfig.FilePath = filepath.Join(t.TempDir(), "audit.jsonl")
+	sink := audit.NewFileSink(auditConfig)
+	t.Cleanup(func() { sink.Close() })
+
+	config := webhook.NewDefaultConfig()
+	config.Secrets = []string{testWebhookSecret}
+	store := idempotency.NewMemoryStore(time.Hour)
+	h := NewWebhookHandler(hubInstance, log, config, store, audit.NewRecorder(sink, log))
+
+	router := gin.New()
+	router.POST("/webhooks/publish", h.Publish)
+	return router, hubInstance, store
+}
+
+func publishWebhook(router http.Handler, payload map[string]any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	timestamp := time.Now().Unix()
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/publish", bytes.NewReader(body))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(testWebhookSecret, timestamp, body))
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func webhookStatus(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	status, _ := body["status"].(string)
+	return status
+}
+
+func TestWebhookHandler_DuplicateDelivery(t *testing.T) {
+	router, hubInstance, _ := newTestWebhookRouter(t)
+	ctx := context.Background()
+	hubInstance.Start(ctx)
+	defer hubInstance.Stop(ctx)
+
+	payload := map[string]any{"id": "msg-1", "type": "update", "topic": "orders"}
+	if rec := publishWebhook(router, payload); rec.Code != http.StatusOK || webhookStatus(t, rec) != "published" {
+		t.Fatalf("Expected the first delivery to be published, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := publishWebhook(router, payload); rec.Code != http.StatusOK || webhookStatus(t, rec) != "duplicate" {
+		t.Errorf("Expected the redelivery to be a duplicate, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestWebhookHandler_PendingConflict(t *testing.T) {
+	router, hubInstance, store := newTestWebhookRouter(t)
+	ctx := context.Background()
+	hubInstance.Start(ctx)
+	defer hubInstance.Stop(ctx)
+
+	// Another request is still processing msg-1
+	store.Reserve("msg-1")
+
+	rec := publishWebhook(router, map[string]any{"id": "msg-1", "type": "update"})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 while the key is pending, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestWebhookHandler_ReleasesKeyOnFailure(t *testing.T) {
+	router, hubInstance, _ := newTestWebhookRouter(t)
+	payload := map[string]any{"id": "msg-1", "type": "update", "user_id": "alice"}
+
+	// Publishing fails while the hub is stopped
+	if rec := publishWebhook(router, payload); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500 while the hub is stopped, got %d %s", rec.Code, rec.Body)
+	}
+
+	ctx := context.Background()
+	hubInstance.Start(ctx)
+	defer hubInstance.Stop(ctx)
+
+	if rec := publishWebhook(router, payload); rec.Code != http.StatusOK || webhookStatus(t, rec) != "published" {
+		t.Errorf("Expected the retry to be published, got %d %s", rec.Code, rec.Body)
+	}
+}
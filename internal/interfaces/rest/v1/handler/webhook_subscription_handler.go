@@ -0,0 +1,377 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/webhook"
+	"go-notification-sse/internal/interfaces/middleware"
+)
+
+// WebhookSubscriptionHandler manages outbound webhook subscriptions. Each
+// subscription is a hub connection that POSTs the messages routed to it.
+type WebhookSubscriptionHandler struct {
+	hub     *hub.Hub
+	logger  logger.Logger
+	auditor *audit.Recorder
+	config  *webhook.DeliveryConfig
+	client  *http.Client
+
+	mu            sync.RWMutex
+	subscriptions map[string]*webhookSubscription
+}
+
+type webhookSubscription struct {
+	conn      *hub.WebhookConnection
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// WebhookSubscriptionRequest creates or replaces a subscription. Messages for
+// its topics and user, and broadcasts, are delivered; types narrows them down.
+type WebhookSubscriptionRequest struct {
+	URL     string            `json:"url"     binding:"required"`
+	Secret  string            `json:"secret"` // generated on creation when empty
+	Topics  []string          `json:"topics"`
+	UserID  string            `json:"user_id"`
+	Types   []string          `json:"types"`
+	Headers map[string]string `json:"headers"`
+}
+
+// WebhookSubscription describes a subscription and its delivery state
+type WebhookSubscription struct {
+	ID          string             `json:"id"`
+	URL         string             `json:"url"`
+	Secret      string             `json:"secret,omitempty"` // only returned on creation
+	Topics      []string           `json:"topics"`
+	UserID      string             `json:"user_id,omitempty"`
+	Types       []string           `json:"types"`
+	Headers     map[string]string  `json:"headers,omitempty"`
+	Circuit     string             `json:"circuit"`
+	DeadLetters int                `json:"dead_letters"`
+	Connection  hub.ConnectionInfo `json:"connection"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// NewWebhookSubscriptionHandler creates the handler; a nil client uses
+// webhook.NewClient for deliveries, which only connects to public addresses
+func NewWebhookSubscriptionHandler(
+	hubInstance *hub.Hub,
+	logger logger.Logger,
+	auditor *audit.Recorder,
+	config *webhook.DeliveryConfig,
+	client *http.Client,
+) *WebhookSubscriptionHandler {
+	return &WebhookSubscriptionHandler{
+		hub:           hubInstance,
+		logger:        logger.WithField("handler", "webhook_subscriptions"),
+		auditor:       auditor,
+		config:        config,
+		client:        client,
+		subscriptions: make(map[string]*webhookSubscription),
+	}
+}
+
+// Create registers a subscription and returns it with its signing secret
+func (h *WebhookSubscriptionHandler) Create(c *gin.Context) {
+	req, ok := h.bind(c)
+	if !ok {
+		return
+	}
+	if req.Secret == "" {
+		req.Secret = generateWebhookSecret()
+	}
+
+	id := generateWebhookSubscriptionID()
+	entry := middleware.NewAuditEntry(c, audit.ActionWebhookSubscribe, id)
+	defer h.auditor.Record(c.Request.Context(), entry)
+
+	sender := webhook.NewSender(webhook.Endpoint{URL: req.URL, Secret: req.Secret, Headers: req.Headers}, h.config, h.client)
+	conn := hub.NewWebhookConnection(id, sender, h.logger, hub.WithWebhookMessageTypes(req.Types...))
+	if err := h.hub.RegisterConnection(conn); err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		h.logger.WithContext(c.Request.Context()).Errorf("Failed to register webhook subscription: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Service temporarily unavailable",
+		})
+		return
+	}
+
+	h.hub.BindUser(id, req.UserID)
+	h.hub.Subscribe(id, req.Topics...)
+
+	now := time.Now().UTC()
+	sub := &webhookSubscription{conn: conn, createdAt: now, updatedAt: now}
+	h.mu.Lock()
+	h.subscriptions[id] = sub
+	h.mu.Unlock()
+
+	go func() {
+		conn.Serve()
+		h.forget(id, conn)
+	}()
+
+	h.logger.WithContext(c.Request.Context()).Infof("Webhook subscription %s created for %s", id, req.URL)
+	view := h.describe(id, sub)
+	view.Secret = req.Secret
+	c.JSON(http.StatusCreated, view)
+}
+
+// List returns every subscription
+func (h *WebhookSubscriptionHandler) List(c *gin.Context) {
+	h.mu.RLock()
+	subscriptions := maps.Clone(h.subscriptions)
+	h.mu.RUnlock()
+
+	views := make([]WebhookSubscription, 0, len(subscriptions))
+	for id, sub := range subscriptions {
+		views = append(views, h.describe(id, sub))
+	}
+
+	sort.Slice(views, func(i, j int) bool { return views[i].CreatedAt.Before(views[j].CreatedAt) })
+	c.JSON(http.StatusOK, gin.H{
+		"subscriptions": views,
+		"total":         len(views),
+	})
+}
+
+// Get returns one subscription
+func (h *WebhookSubscriptionHandler) Get(c *gin.Context) {
+	id, sub, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.describe(id, sub))
+}
+
+// Update replaces a subscription's endpoint and routing. The secret is kept
+// when the request omits it, as is the user, which can be changed but not
+// removed; queued messages and dead letters are kept.
+func (h *WebhookSubscriptionHandler) Update(c *gin.Context) {
+	id, sub, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	req, ok := h.bind(c)
+	if !ok {
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionWebhookUpdate, id)
+	defer h.auditor.Record(c.Request.Context(), entry)
+
+	sender := sub.conn.Sender()
+	if req.Secret == "" {
+		req.Secret = sender.Endpoint().Secret
+	}
+	sender.SetEndpoint(webhook.Endpoint{URL: req.URL, Secret: req.Secret, Headers: req.Headers})
+	sub.conn.SetMessageTypes(req.Types)
+
+	h.hub.Unsubscribe(id, h.hub.TopicsOf(id)...)
+	h.hub.Subscribe(id, req.Topics...)
+	if req.UserID != "" {
+		h.hub.BindUser(id, req.UserID)
+	}
+
+	h.mu.Lock()
+	sub.updatedAt = time.Now().UTC()
+	h.mu.Unlock()
+
+	c.JSON(http.StatusOK, h.describe(id, sub))
+}
+
+// Delete unregisters a subscription, discarding undelivered messages
+func (h *WebhookSubscriptionHandler) Delete(c *gin.Context) {
+	id, sub, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionWebhookUnsubscribe, id)
+	defer h.auditor.Record(c.Request.Context(), entry)
+
+	h.mu.Lock()
+	delete(h.subscriptions, id)
+	h.mu.Unlock()
+
+	if err := h.hub.UnregisterConnection(id); err != nil {
+		// Stop deliveries anyway; the hub drops closed connections
+		h.logger.WithContext(c.Request.Context()).Warnf("Failed to unregister webhook subscription %s: %v", id, err)
+		sub.conn.Close()
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Deliveries returns the most recent deliveries, newest first
+func (h *WebhookSubscriptionHandler) Deliveries(c *gin.Context) {
+	_, sub, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid limit",
+			})
+			return
+		}
+		limit = n
+	}
+
+	deliveries := sub.conn.Sender().Log().Recent(limit)
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"total":      len(deliveries),
+	})
+}
+
+// DeadLetters returns the undeliverable messages, oldest first
+func (h *WebhookSubscriptionHandler) DeadLetters(c *gin.Context) {
+	_, sub, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	letters := sub.conn.Sender().DeadLetters().List()
+	c.JSON(http.StatusOK, gin.H{
+		"dead_letters": letters,
+		"total":        len(letters),
+	})
+}
+
+// Redeliver queues the dead letters for delivery again
+func (h *WebhookSubscriptionHandler) Redeliver(c *gin.Context) {
+	id, sub, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionWebhookRedeliver, id)
+	defer h.auditor.Record(c.Request.Context(), entry)
+
+	queued := sub.conn.Redeliver(c.Request.Context())
+	c.JSON(http.StatusAccepted, gin.H{
+		"queued":    queued,
+		"remaining": sub.conn.Sender().DeadLetters().Len(),
+	})
+}
+
+// PurgeDeadLetters discards the dead letters
+func (h *WebhookSubscriptionHandler) PurgeDeadLetters(c *gin.Context) {
+	_, sub, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"removed": sub.conn.Sender().DeadLetters().Clear(),
+	})
+}
+
+// bind reads and validates a subscription request
+func (h *WebhookSubscriptionHandler) bind(c *gin.Context) (*WebhookSubscriptionRequest, bool) {
+	var req WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid subscription format",
+		})
+		return nil, false
+	}
+
+	if err := webhook.CheckURL(c.Request.Context(), req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	return &req, true
+}
+
+// lookup finds the subscription named in the path
+func (h *WebhookSubscriptionHandler) lookup(c *gin.Context) (string, *webhookSubscription, bool) {
+	id := c.Param("id")
+
+	h.mu.RLock()
+	sub, ok := h.subscriptions[id]
+	h.mu.RUnlock()
+
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Webhook subscription not found",
+		})
+	}
+	return id, sub, ok
+}
+
+// forget drops a subscription whose connection was closed, e.g. by an
+// administrative disconnect
+func (h *WebhookSubscriptionHandler) forget(id string, conn *hub.WebhookConnection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if sub, ok := h.subscriptions[id]; ok && sub.conn == conn {
+		delete(h.subscriptions, id)
+	}
+}
+
+// describe builds the view of a subscription, without its secret
+func (h *WebhookSubscriptionHandler) describe(id string, sub *webhookSubscription) WebhookSubscription {
+	sender := sub.conn.Sender()
+	endpoint := sender.Endpoint()
+	topics := h.hub.TopicsOf(id)
+	slices.Sort(topics)
+
+	h.mu.RLock()
+	createdAt, updatedAt := sub.createdAt, sub.updatedAt
+	h.mu.RUnlock()
+
+	info, registered := h.hub.DescribeConnection(id)
+	if !registered {
+		info = sub.conn.Info()
+	}
+
+	return WebhookSubscription{
+		ID:          id,
+		URL:         endpoint.URL,
+		Topics:      append([]string{}, topics...),
+		UserID:      h.hub.UserOf(id),
+		Types:       append([]string{}, sub.conn.MessageTypes()...),
+		Headers:     endpoint.Headers,
+		Circuit:     sender.Breaker().State(),
+		DeadLetters: sender.DeadLetters().Len(),
+		Connection:  info,
+		CreatedAt:   createdAt,
+		UpdatedAt:   updatedAt,
+	}
+}
+
+// generateWebhookSubscriptionID generates a unique subscription (and connection) ID
+func generateWebhookSubscriptionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("webhook-%x", b)
+}
+
+// generateWebhookSecret generates a signing secret for a subscription
+func generateWebhookSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/infrastructure/webpush"
+	"go-notification-sse/internal/interfaces/middleware"
+)
+
+// WebPushHandler registers the browsers users want notified through Web Push
+// while they have no live connection. The subscription routes must run behind
+// middleware.RequireUser: they act for the user of the verified user token.
+type WebPushHandler struct {
+	store        *webpush.Store
+	vapid        *webpush.VAPID
+	pushServices []string
+	logger       logger.Logger
+	auditor      *audit.Recorder
+}
+
+// WebPushSubscription describes a stored subscription without the browser's
+// encryption keys
+type WebPushSubscription struct {
+	Endpoint       string    `json:"endpoint"`
+	ExpirationTime *int64    `json:"expirationTime,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// WebPushUnsubscribeRequest names the subscription to remove
+type WebPushUnsubscribeRequest struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+}
+
+// NewWebPushHandler creates the handler, accepting subscriptions whose
+// endpoints are on one of the push services
+func NewWebPushHandler(
+	store *webpush.Store,
+	vapid *webpush.VAPID,
+	pushServices []string,
+	logger logger.Logger,
+	auditor *audit.Recorder,
+) *WebPushHandler {
+	return &WebPushHandler{
+		store:        store,
+		vapid:        vapid,
+		pushServices: pushServices,
+		logger:       logger.WithField("handler", "webpush"),
+		auditor:      auditor,
+	}
+}
+
+// PublicKey returns the VAPID public key browsers pass to pushManager.subscribe
+// as the applicationServerKey
+func (h *WebPushHandler) PublicKey(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"public_key": h.vapid.PublicKey(),
+	})
+}
+
+// Subscribe stores the browser's PushSubscription for the user
+func (h *WebPushHandler) Subscribe(c *gin.Context) {
+	userID := middleware.VerifiedUserID(c)
+
+	var sub webpush.Subscription
+	if err := c.ShouldBindJSON(&sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid subscription format",
+		})
+		return
+	}
+	if err := sub.Validate(h.pushServices); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionPushSubscribe, "user:"+userID)
+	defer h.auditor.Record(c.Request.Context(), entry)
+
+	registration, created, err := h.store.Add(userID, sub)
+	if errors.Is(err, webpush.ErrEndpointInUse) {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Push subscription is registered for another user",
+		})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		h.logger.WithContext(c.Request.Context()).Infof("Push subscription added for user %s", userID)
+	}
+	c.JSON(status, newWebPushSubscription(registration))
+}
+
+// List returns the user's push subscriptions
+func (h *WebPushHandler) List(c *gin.Context) {
+	registrations := h.store.List(middleware.VerifiedUserID(c))
+	subscriptions := make([]WebPushSubscription, 0, len(registrations))
+	for _, registration := range registrations {
+		subscriptions = append(subscriptions, newWebPushSubscription(registration))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"subscriptions": subscriptions,
+		"total":         len(subscriptions),
+	})
+}
+
+// Unsubscribe removes one of the user's push subscriptions
+func (h *WebPushHandler) Unsubscribe(c *gin.Context) {
+	userID := middleware.VerifiedUserID(c)
+
+	var req WebPushUnsubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "endpoint is required",
+		})
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionPushUnsubscribe, "user:"+userID)
+	defer h.auditor.Record(c.Request.Context(), entry)
+
+	if !h.store.Remove(userID, req.Endpoint) {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = "subscription not found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Push subscription not found",
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// newWebPushSubscription describes a registration
+func newWebPushSubscription(registration webpush.Registration) WebPushSubscription {
+	return WebPushSubscription{
+		Endpoint:       registration.Subscription.Endpoint,
+		ExpirationTime: registration.Subscription.ExpirationTime,
+		CreatedAt:      registration.CreatedAt,
+	}
+}
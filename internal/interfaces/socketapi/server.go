@@ -0,0 +1,262 @@
+// Package socketapi serves hub traffic to on-host agents over raw TCP and Unix
+// domain sockets, without HTTP.
+//
+// Each side writes JSON frames, one per line or each preceded by its 4-byte
+// big-endian length depending on the configured framing. The client opens with
+// a hello frame, presenting an API key when the server requires one:
+//
+//	{"type":"hello","api_key":"...","user_id":"agent-1","client":"agent/1.2","topics":["orders"],"tags":["host-a"]}
+//
+// and the server answers with a welcome frame carrying the connection ID, or
+// an error frame before closing the connection. Afterwards the server writes
+// hub messages as
+//
+//	{"type":"message","target":"topic:orders","cursor":42,"message":{...}}
+//
+// and the client may send commands, replied to with the same id:
+//
+//	{"type":"subscribe","id":"1","topics":["invoices"]}    -> {"type":"subscribed","id":"1","topics":["invoices","orders"]}
+//	{"type":"unsubscribe","id":"2","topics":["orders"]}    -> {"type":"unsubscribed","id":"2","topics":["invoices"]}
+//	{"type":"ping","id":"3"}                               -> {"type":"pong","id":"3"}
+//
+// The server writes a heartbeat frame whenever it has been silent for the
+// heartbeat interval. Clients must send a frame (a heartbeat, a ping or an
+// empty keep-alive frame) at least once per idle timeout, or are disconnected.
+package socketapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/identity"
+	"go-notification-sse/internal/infrastructure/logger"
+)
+
+// Frame types
+const (
+	FrameHello        = "hello"
+	FrameWelcome      = "welcome"
+	FrameSubscribe    = "subscribe"
+	FrameSubscribed   = "subscribed"
+	FrameUnsubscribe  = "unsubscribe"
+	FrameUnsubscribed = "unsubscribed"
+	FramePing         = "ping"
+	FramePong         = "pong"
+	FrameHeartbeat    = "heartbeat"
+	FrameMessage      = "message"
+	FrameError        = "error"
+)
+
+// Frame is a protocol frame other than a hub message (see hub.SocketMessage)
+type Frame struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+
+	// hello
+	APIKey string   `json:"api_key,omitempty"`
+	UserID string   `json:"user_id,omitempty"`
+	Client string   `json:"client,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+
+	// hello, subscribe and unsubscribe, and their replies
+	Topics []string `json:"topics,omitempty"`
+
+	// welcome
+	ConnectionID        string `json:"connection_id,omitempty"`
+	HeartbeatIntervalMS int64  `json:"heartbeat_interval_ms,omitempty"`
+	IdleTimeoutMS       int64  `json:"idle_timeout_ms,omitempty"`
+
+	// error
+	Error string `json:"error,omitempty"`
+}
+
+// Config holds socket protocol settings
+type Config struct {
+	// APIKeys lists the keys clients may present in their hello; empty accepts
+	// any client
+	APIKeys []string
+
+	Framing hub.SocketFraming
+
+	// HandshakeTimeout bounds the wait for the hello frame
+	HandshakeTimeout time.Duration
+
+	HeartbeatInterval time.Duration
+
+	// IdleTimeout disconnects clients that send nothing for this long; zero
+	// disables it
+	IdleTimeout time.Duration
+
+	// MaxFrameSize bounds the size of frames read from clients
+	MaxFrameSize int
+}
+
+// NewDefaultConfig returns line-delimited framing without authentication
+func NewDefaultConfig() *Config {
+	return &Config{
+		Framing:           hub.FramingLines,
+		HandshakeTimeout:  5 * time.Second,
+		HeartbeatInterval: 30 * time.Second,
+		IdleTimeout:       90 * time.Second,
+		MaxFrameSize:      1 << 20,
+	}
+}
+
+// Server runs the socket protocol on accepted connections
+type Server struct {
+	hub    *hub.Hub
+	logger logger.Logger
+	config *Config
+}
+
+// NewServer creates the socket protocol server
+func NewServer(hubInstance *hub.Hub, logger logger.Logger, config *Config) *Server {
+	return &Server{
+		hub:    hubInstance,
+		logger: logger.WithField("handler", "socket"),
+		config: config,
+	}
+}
+
+// ServeConn runs the protocol on an accepted connection until it closes; it is
+// a server.ConnHandler
+func (s *Server) ServeConn(ctx context.Context, netConn net.Conn) {
+	connID := generateConnectionID()
+	conn := hub.NewSocketConnection(
+		ctx,
+		connID,
+		netConn,
+		s.logger,
+		hub.WithSocketFraming(s.config.Framing),
+		hub.WithSocketHeartbeat(s.config.HeartbeatInterval),
+		hub.WithSocketIdleTimeout(s.config.IdleTimeout),
+		hub.WithSocketMaxFrameSize(s.config.MaxFrameSize),
+	)
+	defer conn.Close()
+	log := s.logger.WithContext(conn.Context())
+
+	hello, err := s.handshake(conn)
+	if err != nil {
+		log.Warnf("Socket handshake from %s failed: %v", netConn.RemoteAddr(), err)
+		conn.WriteFrame(Frame{Type: FrameError, Error: err.Error()})
+		return
+	}
+	conn.SetClient(hello.Client)
+
+	if err := s.hub.RegisterConnection(conn); err != nil {
+		log.Errorf("Failed to register socket connection: %v", err)
+		conn.WriteFrame(Frame{Type: FrameError, Error: "Service temporarily unavailable"})
+		return
+	}
+	defer s.hub.UnregisterConnection(connID)
+
+	// Bind routing so user- and topic-targeted messages reach this connection
+	s.hub.BindUser(connID, hello.UserID)
+	s.hub.Subscribe(connID, hello.Topics...)
+	s.hub.Tag(connID, hello.Tags...)
+
+	// The welcome goes out before Serve writes any queued message
+	err = conn.WriteFrame(Frame{
+		Type:                FrameWelcome,
+		ConnectionID:        connID,
+		Topics:              s.topicsOf(connID),
+		HeartbeatIntervalMS: s.config.HeartbeatInterval.Milliseconds(),
+		IdleTimeoutMS:       s.config.IdleTimeout.Milliseconds(),
+	})
+	if err != nil {
+		return
+	}
+	log.Infof("Socket client %q connected from %s", hello.Client, conn.Info().RemoteAddr)
+
+	go conn.Serve()
+
+	for {
+		data, err := conn.ReadFrame()
+		if err != nil {
+			if !conn.IsClosed() && !errors.Is(err, io.EOF) {
+				log.Debugf("Socket read failed: %v", err)
+			}
+			return
+		}
+
+		var command Frame
+		if err := json.Unmarshal(data, &command); err != nil {
+			conn.WriteFrame(Frame{Type: FrameError, Error: "Invalid frame"})
+			continue
+		}
+		if reply := s.handleCommand(connID, command); reply != nil {
+			if err := conn.WriteFrame(reply); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handshake reads and authenticates the hello frame
+func (s *Server) handshake(conn *hub.SocketConnection) (*Frame, error) {
+	timer := time.AfterFunc(s.config.HandshakeTimeout, func() { conn.Close() })
+	data, err := conn.ReadFrame()
+	if !timer.Stop() {
+		return nil, fmt.Errorf("Handshake timed out")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var hello Frame
+	if err := json.Unmarshal(data, &hello); err != nil || hello.Type != FrameHello {
+		return nil, fmt.Errorf("Expected a hello frame")
+	}
+	if !s.authorize(hello.APIKey) {
+		return nil, fmt.Errorf("Invalid or missing API key")
+	}
+	return &hello, nil
+}
+
+// authorize checks an API key against the configured keys
+func (s *Server) authorize(presented string) bool {
+	if len(s.config.APIKeys) == 0 {
+		return true
+	}
+	return identity.ValidAPIKey(presented, s.config.APIKeys)
+}
+
+// handleCommand handles a client command and returns the reply, if any
+func (s *Server) handleCommand(connID string, command Frame) *Frame {
+	switch command.Type {
+	case FrameSubscribe:
+		s.hub.Subscribe(connID, command.Topics...)
+		return &Frame{Type: FrameSubscribed, ID: command.ID, Topics: s.topicsOf(connID)}
+	case FrameUnsubscribe:
+		s.hub.Unsubscribe(connID, command.Topics...)
+		return &Frame{Type: FrameUnsubscribed, ID: command.ID, Topics: s.topicsOf(connID)}
+	case FramePing:
+		return &Frame{Type: FramePong, ID: command.ID}
+	case FrameHeartbeat:
+		return nil
+	default:
+		return &Frame{Type: FrameError, ID: command.ID, Error: fmt.Sprintf("Unknown command %q", command.Type)}
+	}
+}
+
+// topicsOf returns the sorted subscriptions of a connection
+func (s *Server) topicsOf(connID string) []string {
+	topics := s.hub.TopicsOf(connID)
+	slices.Sort(topics)
+	return topics
+}
+
+// generateConnectionID generates a unique socket connection ID
+func generateConnectionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("sock-%x", b)
+}
@@ -0,0 +1,238 @@
+package socketapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/server"
+	"go-notification-sse/internal/interfaces/transporttest"
+)
+
+// newTestServer starts a socket server and returns its hub and address
+func newTestServer(t *testing.T, network, addr string, config *Config) (*hub.Hub, *server.SocketServer) {
+	t.Helper()
+
+	hubInstance, log := transporttest.NewHub(t)
+	srv := server.NewSocketServer(network, addr, NewServer(hubInstance, log, config).ServeConn)
+	go srv.Start(context.Background())
+	t.Cleanup(func() { srv.Stop(context.Background()) })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.HealthCheck(context.Background()) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Socket server did not start listening")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return hubInstance, srv
+}
+
+// client is a test socket client
+type client struct {
+	t       *testing.T
+	conn    net.Conn
+	reader  *bufio.Reader
+	framing hub.SocketFraming
+}
+
+func dial(t *testing.T, srv *server.SocketServer, framing hub.SocketFraming) *client {
+	t.Helper()
+
+	addr := srv.Addr()
+	conn, err := net.Dial(addr.Network(), addr.String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &client{t: t, conn: conn, reader: bufio.NewReader(conn), framing: framing}
+}
+
+func (c *client) send(v any) {
+	c.t.Helper()
+	data, _ := json.Marshal(v)
+	if c.framing == hub.FramingLengthPrefixed {
+		data = append(binary.BigEndian.AppendUint32(nil, uint32(len(data))), data...)
+	} else {
+		data = append(data, '\n')
+	}
+	if _, err := c.conn.Write(data); err != nil {
+		c.t.Fatalf("Write failed: %v", err)
+	}
+}
+
+func (c *client) read() ([]byte, error) {
+	if c.framing == hub.FramingLengthPrefixed {
+		var header [4]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			return nil, err
+		}
+		frame := make([]byte, binary.BigEndian.Uint32(header[:]))
+		_, err := io.ReadFull(c.reader, frame)
+		return frame, err
+	}
+	return c.reader.ReadBytes('\n')
+}
+
+// receive reads the next frame into v, skipping heartbeats
+func (c *client) receive(v any) {
+	c.t.Helper()
+	for {
+		data, err := c.read()
+		if err != nil {
+			c.t.Fatalf("Read failed: %v", err)
+		}
+		var frame Frame
+		json.Unmarshal(data, &frame)
+		if frame.Type == FrameHeartbeat {
+			continue
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			c.t.Fatalf("Invalid frame %s: %v", data, err)
+		}
+		return
+	}
+}
+
+func (c *client) hello(hello Frame) Frame {
+	c.t.Helper()
+	hello.Type = FrameHello
+	c.send(hello)
+	var welcome Frame
+	c.receive(&welcome)
+	if welcome.Type != FrameWelcome || welcome.ConnectionID == "" {
+		c.t.Fatalf("Expected a welcome, got %+v", welcome)
+	}
+	return welcome
+}
+
+func TestServer_LinesOverTCP(t *testing.T) {
+	config := NewDefaultConfig()
+	config.APIKeys = []string{"secret"}
+	hubInstance, srv := newTestServer(t, "tcp", "127.0.0.1:0", config)
+
+	agent := dial(t, srv, hub.FramingLines)
+	welcome := agent.hello(Frame{APIKey: "secret", UserID: "agent-1", Client: "agent/1.0", Topics: []string{"orders"}})
+	if !slices.Equal(welcome.Topics, []string{"orders"}) || welcome.HeartbeatIntervalMS != 30000 {
+		t.Fatalf("Unexpected welcome %+v", welcome)
+	}
+
+	info, ok := hubInstance.DescribeConnection(welcome.ConnectionID)
+	if !ok || info.Type != "socket" || info.UserAgent != "agent/1.0" {
+		t.Fatalf("Expected a registered socket connection, got %+v", info)
+	}
+
+	hubInstance.PublishToTopic(context.Background(), "orders", &hub.Message{ID: "m1", Type: "order.created", Data: "o-1"})
+	var message hub.SocketMessage
+	agent.receive(&message)
+	if message.Type != FrameMessage || message.Target != "topic:orders" || message.Cursor == 0 || message.Message.ID != "m1" {
+		t.Fatalf("Unexpected message %+v", message)
+	}
+
+	agent.send(Frame{Type: FrameSubscribe, ID: "1", Topics: []string{"invoices"}})
+	var reply Frame
+	agent.receive(&reply)
+	if reply.Type != FrameSubscribed || reply.ID != "1" || !slices.Equal(reply.Topics, []string{"invoices", "orders"}) {
+		t.Fatalf("Unexpected subscribe reply %+v", reply)
+	}
+
+	agent.send(Frame{Type: FrameUnsubscribe, ID: "2", Topics: []string{"orders"}})
+	agent.receive(&reply)
+	if reply.Type != FrameUnsubscribed || !slices.Equal(reply.Topics, []string{"invoices"}) {
+		t.Fatalf("Unexpected unsubscribe reply %+v", reply)
+	}
+
+	agent.send(Frame{Type: FramePing, ID: "3"})
+	agent.receive(&reply)
+	if reply.Type != FramePong || reply.ID != "3" {
+		t.Fatalf("Expected a pong, got %+v", reply)
+	}
+
+	hubInstance.SendToUser(context.Background(), "agent-1", &hub.Message{ID: "m2", Type: "alert", Data: "hi"})
+	agent.receive(&message)
+	if message.Target != "user:agent-1" || message.Message.ID != "m2" {
+		t.Fatalf("Unexpected user message %+v", message)
+	}
+}
+
+func TestServer_RejectsInvalidAPIKey(t *testing.T) {
+	config := NewDefaultConfig()
+	config.APIKeys = []string{"secret"}
+	hubInstance, srv := newTestServer(t, "tcp", "127.0.0.1:0", config)
+
+	agent := dial(t, srv, hub.FramingLines)
+	agent.send(Frame{Type: FrameHello, APIKey: "wrong"})
+	var reply Frame
+	agent.receive(&reply)
+	if reply.Type != FrameError || reply.Error != "Invalid or missing API key" {
+		t.Fatalf("Expected an authentication error, got %+v", reply)
+	}
+	if _, err := agent.read(); err != io.EOF {
+		t.Errorf("Expected the connection to close, got %v", err)
+	}
+	if count := hubInstance.CountByType()["socket"]; count != 0 {
+		t.Errorf("Expected no registered connection, got %d", count)
+	}
+}
+
+func TestServer_LengthPrefixedOverUnixSocket(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Framing = hub.FramingLengthPrefixed
+	hubInstance, srv := newTestServer(t, "unix", filepath.Join(t.TempDir(), "hub.sock"), config)
+
+	agent := dial(t, srv, hub.FramingLengthPrefixed)
+	agent.hello(Frame{})
+
+	hubInstance.Broadcast(context.Background(), &hub.Message{ID: "m1", Type: "announcement", Data: map[string]any{"text": "line\nbreak"}})
+	var message hub.SocketMessage
+	agent.receive(&message)
+	if message.Target != "broadcast" || message.Message.ID != "m1" {
+		t.Fatalf("Unexpected message %+v", message)
+	}
+
+	// Stopping the server closes its connections
+	srv.Stop(context.Background())
+	for {
+		if _, err := agent.read(); err != nil {
+			break
+		}
+	}
+}
+
+func TestServer_HeartbeatsAndIdleTimeout(t *testing.T) {
+	config := NewDefaultConfig()
+	config.HeartbeatInterval = 20 * time.Millisecond
+	config.IdleTimeout = 150 * time.Millisecond
+	_, srv := newTestServer(t, "tcp", "127.0.0.1:0", config)
+
+	agent := dial(t, srv, hub.FramingLines)
+	agent.hello(Frame{})
+
+	heartbeats := 0
+	start := time.Now()
+	for {
+		data, err := agent.read()
+		if err != nil {
+			break
+		}
+		var frame Frame
+		if json.Unmarshal(data, &frame); frame.Type == FrameHeartbeat {
+			heartbeats++
+		}
+	}
+	if heartbeats == 0 {
+		t.Error("Expected heartbeats while the client was idle")
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected the idle client to be disconnected after the idle timeout, took %s", elapsed)
+	}
+}
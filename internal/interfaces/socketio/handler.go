@@ -0,0 +1,338 @@
+// Package socketio implements Engine.IO v4 and the Socket.IO v5 protocol on top
+// of the hub, so socket.io-client applications can connect unchanged.
+//
+// Sessions start with HTTP long-polling or directly over WebSocket, and polling
+// sessions upgrade to WebSocket as Engine.IO does. Each session is one hub
+// connection. Hub messages are emitted as events named after the message type,
+// with the data and an object holding the message ID, topic, cursor and headers
+// as arguments; acknowledging them reports the delivery to the hub.
+//
+// Rooms are hub topics. Clients manage them and publish with events:
+//
+//	socket.emit("join", "orders", ack)        // or a list of rooms
+//	socket.emit("leave", "orders", ack)
+//	socket.emit("publish", {topic: "orders", type: "update", data: {...}}, ack)
+//
+// Clients may only publish to the topics the handler's publish policy allows,
+// and every publish is audited. Topic messages go to the sockets in that room,
+// in whichever namespace they joined it. Everything else (broadcasts, messages
+// to the user or connection, topics subscribed with the handshake request) goes
+// to the main namespace.
+package socketio
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
+	"go-notification-sse/internal/interfaces/publish"
+)
+
+// Config holds Socket.IO settings
+type Config struct {
+	// PingInterval and PingTimeout drive Engine.IO heartbeats: the server pings
+	// every interval and closes sessions that do not answer within the timeout
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+
+	// MaxPayload bounds the size of a polling request body or WebSocket message
+	MaxPayload int64
+
+	// Namespaces lists the namespaces clients may connect to; empty allows any
+	Namespaces []string
+
+	// MaxPendingAcks bounds the unacknowledged events tracked per session
+	MaxPendingAcks int
+}
+
+// NewDefaultConfig returns the Engine.IO default heartbeat and payload settings
+func NewDefaultConfig() *Config {
+	return &Config{
+		PingInterval:   25 * time.Second,
+		PingTimeout:    20 * time.Second,
+		MaxPayload:     1_000_000,
+		MaxPendingAcks: 1024,
+	}
+}
+
+// Engine.IO error codes, returned with 400 Bad Request
+const (
+	errorUnknownTransport   = 0
+	errorUnknownSID         = 1
+	errorBadHandshake       = 2
+	errorBadRequest         = 3
+	errorUnsupportedVersion = 5
+)
+
+var engineErrors = map[int]string{
+	errorUnknownTransport:   "Transport unknown",
+	errorUnknownSID:         "Session ID unknown",
+	errorBadHandshake:       "Bad handshake method",
+	errorBadRequest:         "Bad request",
+	errorUnsupportedVersion: "Unsupported protocol version",
+}
+
+// SocketIOHandler serves Engine.IO sessions on /socket.io/
+type SocketIOHandler struct {
+	hub         *hub.Hub
+	logger      logger.Logger
+	config      *Config
+	publisher   *publish.Policy
+	upgrader    websocket.Upgrader
+	connOptions []hub.SocketIOOption
+
+	mu       sync.Mutex
+	sessions map[string]*session // by Engine.IO session ID
+}
+
+// NewSocketIOHandler creates a new Socket.IO handler; publisher authorizes and
+// audits the messages clients publish
+func NewSocketIOHandler(
+	hubInstance *hub.Hub,
+	logger logger.Logger,
+	publisher *publish.Policy,
+	config *Config,
+	connOptions ...hub.SocketIOOption,
+) *SocketIOHandler {
+	return &SocketIOHandler{
+		hub:         hubInstance,
+		logger:      logger.WithField("handler", "socketio"),
+		config:      config,
+		publisher:   publisher,
+		connOptions: connOptions,
+		sessions:    make(map[string]*session),
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin: func(r *http.Request) bool {
+				// Socket.IO clients are served from any origin, as on /ws
+				return true
+			},
+		},
+	}
+}
+
+// Serve handles every Engine.IO request: handshakes, polls, posted packets and
+// WebSocket upgrades
+func (h *SocketIOHandler) Serve(c *gin.Context) {
+	if !h.hub.IsRunning() {
+		h.logger.WithContext(c.Request.Context()).Error("Hub is not running")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Service temporarily unavailable",
+		})
+		return
+	}
+
+	if c.Query("EIO") != "4" {
+		engineError(c, errorUnsupportedVersion)
+		return
+	}
+	transport := c.Query("transport")
+	if transport != transportPolling && transport != transportWebSocket {
+		engineError(c, errorUnknownTransport)
+		return
+	}
+
+	sid := c.Query("sid")
+	if sid == "" {
+		h.open(c, transport)
+		return
+	}
+
+	s := h.session(sid)
+	if s == nil {
+		engineError(c, errorUnknownSID)
+		return
+	}
+	middleware.SetConnectionID(c, s.conn.ID())
+
+	switch {
+	case transport == transportWebSocket:
+		h.upgrade(c, s)
+	case c.Request.Method == http.MethodGet:
+		h.poll(c, s)
+	case c.Request.Method == http.MethodPost:
+		h.post(c, s)
+	default:
+		engineError(c, errorBadRequest)
+	}
+}
+
+// open starts a session, over polling or directly over WebSocket. It accepts
+// the same user, topic and tag parameters as the other transports.
+func (h *SocketIOHandler) open(c *gin.Context, transport string) {
+	if c.Request.Method != http.MethodGet {
+		engineError(c, errorBadHandshake)
+		return
+	}
+
+	var ws *websocket.Conn
+	if transport == transportWebSocket {
+		var err error
+		if ws, err = h.upgrader.Upgrade(c.Writer, c.Request, nil); err != nil {
+			h.logger.WithContext(c.Request.Context()).Errorf("Failed to upgrade connection: %v", err)
+			return
+		}
+	}
+
+	connID := generateSocketIOConnectionID()
+	middleware.SetConnectionID(c, connID)
+	log := h.logger.WithContext(c.Request.Context())
+
+	conn := hub.NewSocketIOConnection(
+		connID,
+		h.logger,
+		append([]hub.SocketIOOption{hub.WithSocketIORequest(c.Request)}, h.connOptions...)...,
+	)
+	if err := h.hub.RegisterConnection(conn); err != nil {
+		log.Errorf("Failed to register Socket.IO session: %v", err)
+		conn.Close()
+		if ws != nil {
+			ws.Close()
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to register connection",
+			})
+		}
+		return
+	}
+
+	// Bind routing so user- and topic-targeted messages reach this session
+	h.hub.BindUser(conn.ID(), middleware.UserID(c))
+	h.hub.Subscribe(conn.ID(), middleware.Topics(c)...)
+	h.hub.Tag(conn.ID(), middleware.Tags(c)...)
+
+	s := newSession(h, conn, transport)
+	h.mu.Lock()
+	h.sessions[s.sid] = s
+	h.mu.Unlock()
+	s.start()
+
+	log.Infof("Socket.IO session %s connected over %s", connID, transport)
+
+	if ws != nil {
+		s.enqueue(s.openPacket([]string{}))
+		s.serveWebSocket(ws, false)
+		return
+	}
+	writePayload(c, []enginePacket{s.openPacket([]string{transportWebSocket})})
+}
+
+// poll returns the packets queued for a polling session
+func (h *SocketIOHandler) poll(c *gin.Context, s *session) {
+	packets, err := s.poll(c.Request.Context(), h.config.PingInterval+h.config.PingTimeout)
+	switch {
+	case errors.Is(err, errPollActive):
+		// Engine.IO forbids concurrent polls
+		s.close(false)
+		engineError(c, errorBadRequest)
+		return
+	case errors.Is(err, errUpgraded):
+		engineError(c, errorBadRequest)
+		return
+	case err != nil:
+		if c.Request.Context().Err() == nil {
+			engineError(c, errorUnknownSID)
+		}
+		return
+	}
+
+	writePayload(c, packets)
+}
+
+// post handles the packets a polling client sends
+func (h *SocketIOHandler) post(c *gin.Context, s *session) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, h.config.MaxPayload+1))
+	if err != nil {
+		engineError(c, errorBadRequest)
+		return
+	}
+	if int64(len(body)) > h.config.MaxPayload {
+		s.close(false)
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "Payload too large",
+		})
+		return
+	}
+
+	packets, err := decodePayload(body)
+	if err == nil {
+		err = s.handlePackets(packets)
+	}
+	if err != nil {
+		s.logger.Warnf("Closing session after protocol error: %v", err)
+		s.close(false)
+		engineError(c, errorBadRequest)
+		return
+	}
+
+	c.Data(http.StatusOK, "text/plain; charset=UTF-8", []byte("ok"))
+}
+
+// upgrade moves a polling session to WebSocket
+func (h *SocketIOHandler) upgrade(c *gin.Context, s *session) {
+	if !websocket.IsWebSocketUpgrade(c.Request) || !s.pollingTransport() {
+		engineError(c, errorBadRequest)
+		return
+	}
+
+	ws, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to upgrade connection: %v", err)
+		return
+	}
+	s.serveWebSocket(ws, true)
+}
+
+// session returns an open session
+func (h *SocketIOHandler) session(sid string) *session {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sessions[sid]
+}
+
+// removeSession forgets a closed session
+func (h *SocketIOHandler) removeSession(sid string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.sessions, sid)
+}
+
+// writePayload writes packets as a polling response
+func writePayload(c *gin.Context, packets []enginePacket) {
+	c.Data(http.StatusOK, "text/plain; charset=UTF-8", encodePayload(packets))
+}
+
+// engineError writes an Engine.IO error response
+func engineError(c *gin.Context, code int) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"code":    code,
+		"message": engineErrors[code],
+	})
+}
+
+// generateSessionID generates an Engine.IO session ID; it must be unguessable
+// as it is the only credential of the session's requests
+func generateSessionID() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// generateSocketIOConnectionID generates the hub connection ID of a session
+func generateSocketIOConnectionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("sio-%x", b)
+}
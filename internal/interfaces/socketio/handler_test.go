@@ -0,0 +1,352 @@
+package socketio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/interfaces/publish"
+	"go-notification-sse/internal/interfaces/transporttest"
+)
+
+// newTestServer serves Socket.IO with a running hub; clients may publish to the
+// "uploads" topic
+func newTestServer(t *testing.T, config *Config) (*hub.Hub, string, *audit.Recorder) {
+	t.Helper()
+
+	hubInstance, log := transporttest.NewHub(t)
+	auditor := transporttest.NewAuditor(t, log)
+	policy := publish.NewPolicy(hubInstance, auditor, []string{"uploads"})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	InitSocketIORouter(log, hubInstance, policy, &router.RouterGroup, config)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return hubInstance, server.URL + "/socket.io/?EIO=4", auditor
+}
+
+// pollingClient is a test Engine.IO client on the polling transport
+type pollingClient struct {
+	t   *testing.T
+	url string
+	sid string
+}
+
+func handshake(t *testing.T, url string) *pollingClient {
+	t.Helper()
+
+	c := &pollingClient{t: t, url: url + "&transport=polling"}
+	packets := c.get()
+	if len(packets) != 1 || !strings.HasPrefix(packets[0], "0") {
+		t.Fatalf("Expected an open packet, got %q", packets)
+	}
+	var open struct {
+		SID      string   `json:"sid"`
+		Upgrades []string `json:"upgrades"`
+	}
+	if err := json.Unmarshal([]byte(packets[0][1:]), &open); err != nil || open.SID == "" {
+		t.Fatalf("Invalid open packet %q: %v", packets[0], err)
+	}
+	if len(open.Upgrades) != 1 || open.Upgrades[0] != "websocket" {
+		t.Fatalf("Expected a websocket upgrade to be offered, got %v", open.Upgrades)
+	}
+	c.sid = open.SID
+	c.url += "&sid=" + c.sid
+	return c
+}
+
+func (c *pollingClient) get() []string {
+	c.t.Helper()
+	resp, err := http.Get(c.url)
+	if err != nil {
+		c.t.Fatalf("Poll failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		c.t.Fatalf("Poll returned %d: %s", resp.StatusCode, body)
+	}
+	return strings.Split(string(body), "\x1e")
+}
+
+// receive polls until a packet other than a ping or noop arrives
+func (c *pollingClient) receive() string {
+	c.t.Helper()
+	for {
+		for _, p := range c.get() {
+			if p != "2" && p != "6" {
+				return p
+			}
+		}
+	}
+}
+
+func (c *pollingClient) post(packets ...string) {
+	c.t.Helper()
+	resp, err := http.Post(c.url, "text/plain", strings.NewReader(strings.Join(packets, "\x1e")))
+	if err != nil {
+		c.t.Fatalf("Post failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || string(body) != "ok" {
+		c.t.Fatalf("Post returned %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestPolling_EventsAndAcks(t *testing.T) {
+	hubInstance, url, _ := newTestServer(t, NewDefaultConfig())
+	deliveries := hubInstance.Events().Subscribe(16, hub.EventMessageDelivered)
+	defer deliveries.Close()
+
+	client := handshake(t, url)
+	client.post("40")
+	if p := client.receive(); !strings.HasPrefix(p, `40{"sid":`) {
+		t.Fatalf("Expected a namespace connect, got %q", p)
+	}
+
+	// The session ID is a secret separate from the listed hub connection ID
+	connections := hubInstance.FindConnections(&hub.ConnectionFilter{Type: "socketio"})
+	if len(connections) != 1 || connections[0].ID == client.sid || strings.Contains(client.sid, connections[0].ID) {
+		t.Fatalf("Expected a session ID unrelated to the connection ID, got %q for %+v", client.sid, connections)
+	}
+	connID := connections[0].ID
+
+	client.post(`421["join",["orders","invoices"]]`)
+	if p := client.receive(); p != `431[{"rooms":["invoices","orders"]}]` {
+		t.Fatalf("Unexpected join ack %q", p)
+	}
+
+	hubInstance.PublishToTopic(context.Background(), "orders", &hub.Message{ID: "m1", Type: "order.created", Data: map[string]any{"total": 3}})
+	p := client.receive()
+	if !strings.HasPrefix(p, `421["order.created",{"total":3},{"id":"m1","topic":"orders"`) {
+		t.Fatalf("Unexpected event %q", p)
+	}
+	client.post("431[]")
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-deliveries.Events():
+			delivery := event.Payload.(hub.DeliveryEvent)
+			if delivery.Status == hub.DeliveryStatusAcknowledged {
+				if delivery.MessageID != "m1" || delivery.Target != "topic:orders" || delivery.ConnectionID != connID {
+					t.Errorf("Unexpected acknowledgement %+v", delivery)
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("Expected the ack to be reported as an acknowledged delivery")
+		}
+	}
+}
+
+func TestPolling_UpgradeToWebSocket(t *testing.T) {
+	hubInstance, url, _ := newTestServer(t, NewDefaultConfig())
+
+	client := handshake(t, url)
+	client.post("40")
+
+	wsURL := "ws" + strings.TrimPrefix(url, "http") + "&transport=websocket&sid=" + client.sid
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	ws.WriteMessage(websocket.TextMessage, []byte("2probe"))
+	if _, data, err := ws.ReadMessage(); err != nil || string(data) != "3probe" {
+		t.Fatalf("Expected a probe pong, got %q (%v)", data, err)
+	}
+	ws.WriteMessage(websocket.TextMessage, []byte("5"))
+
+	// Packets queued before the upgrade move to the WebSocket
+	if _, data, err := ws.ReadMessage(); err != nil || !strings.HasPrefix(string(data), `40{"sid":`) {
+		t.Fatalf("Expected the namespace connect, got %q (%v)", data, err)
+	}
+
+	hubInstance.Broadcast(context.Background(), &hub.Message{ID: "m2", Type: "announcement", Data: "hello"})
+	if _, data, err := ws.ReadMessage(); err != nil || !strings.HasPrefix(string(data), `421["announcement","hello",{"id":"m2"`) {
+		t.Fatalf("Expected the broadcast, got %q (%v)", data, err)
+	}
+
+	resp, err := http.Get(client.url)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected polling an upgraded session to fail, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebSocket_BinaryPublish(t *testing.T) {
+	_, url, auditor := newTestServer(t, NewDefaultConfig())
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"&transport=websocket", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	read := func() (int, string) {
+		t.Helper()
+		messageType, data, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		return messageType, string(data)
+	}
+
+	if _, open := read(); !strings.Contains(open, `"upgrades":[]`) {
+		t.Fatalf("Expected an open packet without upgrades, got %q", open)
+	}
+	ws.WriteMessage(websocket.TextMessage, []byte("40/files,"))
+	if _, p := read(); !strings.HasPrefix(p, `40/files,{"sid":`) {
+		t.Fatalf("Expected a namespace connect, got %q", p)
+	}
+	ws.WriteMessage(websocket.TextMessage, []byte(`42/files,1["join","uploads"]`))
+	if _, p := read(); p != `43/files,1[{"rooms":["uploads"]}]` {
+		t.Fatalf("Unexpected join ack %q", p)
+	}
+
+	ws.WriteMessage(websocket.TextMessage, []byte(`451-/files,2["publish",{"topic":"uploads","type":"file","data":{"_placeholder":true,"num":0}}]`))
+	ws.WriteMessage(websocket.BinaryMessage, []byte{0xde, 0xad})
+
+	var event, ack string
+	var attachment []byte
+	for event == "" || ack == "" || attachment == nil {
+		messageType, p := read()
+		switch {
+		case messageType == websocket.BinaryMessage:
+			attachment = []byte(p)
+		case strings.HasPrefix(p, "43/files,2"):
+			ack = p
+		default:
+			event = p
+		}
+	}
+	if !strings.HasPrefix(ack, `43/files,2[{"id":"msg-`) || !strings.HasSuffix(ack, `"recipients":1}]`) {
+		t.Errorf("Unexpected publish ack %q", ack)
+	}
+	if !strings.HasPrefix(event, `451-/files,1["file",{"_placeholder":true,"num":0},{"id":"msg-`) {
+		t.Errorf("Unexpected binary event %q", event)
+	}
+	if !bytes.Equal(attachment, []byte{0xde, 0xad}) {
+		t.Errorf("Unexpected attachment %x", attachment)
+	}
+
+	ws.WriteMessage(websocket.TextMessage, []byte(`42/files,3["publish",{"topic":"orders"}]`))
+	if _, p := read(); p != `43/files,3[{"error":"publishing to this topic is not allowed"}]` {
+		t.Errorf("Expected the publish to be refused, got %q", p)
+	}
+
+	entries := transporttest.AuditEntries(t, auditor, &audit.Filter{Action: audit.ActionPublishToTopic}, 2)
+	outcomes := map[string]audit.Outcome{}
+	for _, entry := range entries {
+		outcomes[entry.Target] = entry.Outcome
+	}
+	if len(entries) != 2 || outcomes["topic:uploads"] != audit.OutcomeSuccess || outcomes["topic:orders"] != audit.OutcomeDenied {
+		t.Errorf("Expected both publishes to be audited, got %+v", entries)
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Namespaces = []string{"/"}
+	_, url, _ := newTestServer(t, config)
+
+	client := handshake(t, url)
+	client.post("40/admin,")
+	if p := client.receive(); p != `44/admin,{"message":"Invalid namespace"}` {
+		t.Fatalf("Expected a connect error, got %q", p)
+	}
+}
+
+func TestServe_Errors(t *testing.T) {
+	_, url, _ := newTestServer(t, NewDefaultConfig())
+	base := strings.TrimSuffix(url, "?EIO=4")
+
+	tests := []struct {
+		name   string
+		method string
+		query  string
+		code   int
+	}{
+		{"unsupported version", http.MethodGet, "?EIO=3&transport=polling", errorUnsupportedVersion},
+		{"unknown transport", http.MethodGet, "?EIO=4&transport=flash", errorUnknownTransport},
+		{"unknown session", http.MethodGet, "?EIO=4&transport=polling&sid=missing", errorUnknownSID},
+		{"handshake with POST", http.MethodPost, "?EIO=4&transport=polling", errorBadHandshake},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, base+tt.query, nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			var body struct {
+				Code int `json:"code"`
+			}
+			json.NewDecoder(resp.Body).Decode(&body)
+			if resp.StatusCode != http.StatusBadRequest || body.Code != tt.code {
+				t.Errorf("Expected 400 with code %d, got %d with code %d", tt.code, resp.StatusCode, body.Code)
+			}
+		})
+	}
+}
+
+func TestDecodeSocketPacket(t *testing.T) {
+	tests := []struct {
+		in          string
+		typ         int
+		namespace   string
+		id          int64
+		attachments int
+		data        string
+	}{
+		{"0", socketConnect, "/", -1, 0, ""},
+		{"0/admin,", socketConnect, "/admin", -1, 0, ""},
+		{"1/admin", socketDisconnect, "/admin", -1, 0, ""},
+		{`2["hello",1]`, socketEvent, "/", -1, 0, `["hello",1]`},
+		{`2/chat,12["hello"]`, socketEvent, "/chat", 12, 0, `["hello"]`},
+		{"3/chat,12[]", socketAck, "/chat", 12, 0, "[]"},
+		{`52-/chat,3["x",{"_placeholder":true,"num":0}]`, socketBinaryEvent, "/chat", 3, 2, `["x",{"_placeholder":true,"num":0}]`},
+	}
+	for _, tt := range tests {
+		p, err := decodeSocketPacket(tt.in)
+		if err != nil {
+			t.Errorf("decodeSocketPacket(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if p.typ != tt.typ || p.namespace != tt.namespace || p.id != tt.id || p.attachments != tt.attachments || string(p.data) != tt.data {
+			t.Errorf("decodeSocketPacket(%q) = %+v", tt.in, p)
+			continue
+		}
+		if out := string(p.packets()[0].data); out != tt.in && tt.in != "1/admin" {
+			t.Errorf("Encoding %q gave %q", tt.in, out)
+		}
+	}
+
+	for _, in := range []string{"", "9", "5", `2["unterminated"`} {
+		if _, err := decodeSocketPacket(in); err == nil {
+			t.Errorf("Expected decodeSocketPacket(%q) to fail", in)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package socketio
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// Engine.IO packet types
+const (
+	engineOpen    byte = '0'
+	engineClose   byte = '1'
+	enginePing    byte = '2'
+	enginePong    byte = '3'
+	engineMessage byte = '4'
+	engineUpgrade byte = '5'
+	engineNoop    byte = '6'
+)
+
+// recordSeparator separates the packets of a polling payload
+const recordSeparator = '\x1e'
+
+// enginePacket is an Engine.IO packet. Binary packets are always messages.
+type enginePacket struct {
+	typ    byte
+	data   []byte
+	binary bool
+}
+
+func textPacket(typ byte, data string) enginePacket {
+	return enginePacket{typ: typ, data: []byte(data)}
+}
+
+// frame returns the WebSocket frame of a packet: binary messages are sent as
+// is, other packets as their type followed by their data
+func (p enginePacket) frame() (int, []byte) {
+	if p.binary {
+		return websocket.BinaryMessage, p.data
+	}
+	return websocket.TextMessage, append([]byte{p.typ}, p.data...)
+}
+
+// decodeFrame decodes the packet of a WebSocket frame
+func decodeFrame(messageType int, data []byte) (enginePacket, error) {
+	if messageType == websocket.BinaryMessage {
+		return enginePacket{typ: engineMessage, data: data, binary: true}, nil
+	}
+	if len(data) == 0 || data[0] < engineOpen || data[0] > engineNoop {
+		return enginePacket{}, fmt.Errorf("invalid packet %q", data)
+	}
+	return enginePacket{typ: data[0], data: data[1:]}, nil
+}
+
+// encodePayload encodes packets for an HTTP polling response. Binary packets
+// are base64 encoded with a "b" prefix.
+func encodePayload(packets []enginePacket) []byte {
+	var buf bytes.Buffer
+	for i, p := range packets {
+		if i > 0 {
+			buf.WriteByte(recordSeparator)
+		}
+		if p.binary {
+			buf.WriteByte('b')
+			buf.WriteString(base64.StdEncoding.EncodeToString(p.data))
+			continue
+		}
+		buf.WriteByte(p.typ)
+		buf.Write(p.data)
+	}
+	return buf.Bytes()
+}
+
+// decodePayload decodes the packets of an HTTP polling request body
+func decodePayload(body []byte) ([]enginePacket, error) {
+	var packets []enginePacket
+	for _, record := range bytes.Split(body, []byte{recordSeparator}) {
+		if len(record) > 0 && record[0] == 'b' {
+			data, err := base64.StdEncoding.DecodeString(string(record[1:]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid binary packet: %w", err)
+			}
+			packets = append(packets, enginePacket{typ: engineMessage, data: data, binary: true})
+			continue
+		}
+
+		p, err := decodeFrame(websocket.TextMessage, record)
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, p)
+	}
+	return packets, nil
+}
+
+// Socket.IO packet types
+const (
+	socketConnect      = 0
+	socketDisconnect   = 1
+	socketEvent        = 2
+	socketAck          = 3
+	socketConnectError = 4
+	socketBinaryEvent  = 5
+	socketBinaryAck    = 6
+)
+
+// socketPacket is a Socket.IO packet, carried in Engine.IO messages. Binary
+// packets are followed by one binary message per attachment.
+type socketPacket struct {
+	typ         int
+	namespace   string
+	id          int64 // -1 when no acknowledgement is requested
+	data        json.RawMessage
+	attachments int
+	buffers     [][]byte
+}
+
+// decodeSocketPacket decodes the text part of a Socket.IO packet:
+// <type>[<attachments>-][<namespace>,][<id>][<JSON data>]
+func decodeSocketPacket(s string) (*socketPacket, error) {
+	if s == "" || s[0] < '0' || s[0] > '6' {
+		return nil, fmt.Errorf("invalid packet type in %q", s)
+	}
+	p := &socketPacket{typ: int(s[0] - '0'), namespace: "/", id: -1}
+	s = s[1:]
+
+	if p.typ == socketBinaryEvent || p.typ == socketBinaryAck {
+		count, rest, ok := cutDigits(s)
+		if !ok || rest == "" || rest[0] != '-' {
+			return nil, fmt.Errorf("invalid attachment count")
+		}
+		p.attachments, s = int(count), rest[1:]
+	}
+
+	if s != "" && s[0] == '/' {
+		end := len(s)
+		if i := strings.IndexByte(s, ','); i >= 0 {
+			end = i
+		}
+		p.namespace = s[:end]
+		s = s[min(end+1, len(s)):]
+	}
+
+	if id, rest, ok := cutDigits(s); ok {
+		p.id, s = id, rest
+	}
+
+	if s != "" {
+		if !json.Valid([]byte(s)) {
+			return nil, fmt.Errorf("invalid packet data")
+		}
+		p.data = json.RawMessage(s)
+	}
+	return p, nil
+}
+
+// cutDigits splits a leading decimal number off s
+func cutDigits(s string) (int64, string, bool) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i == 0 {
+		return 0, s, false
+	}
+	n, err := strconv.ParseInt(s[:i], 10, 64)
+	if err != nil {
+		return 0, s, false
+	}
+	return n, s[i:], true
+}
+
+// newSocketPacket creates a packet carrying v as its data. Byte slices in v are
+// sent as binary attachments, making event and ack packets binary.
+func newSocketPacket(typ int, namespace string, id int64, v any) (*socketPacket, error) {
+	p := &socketPacket{typ: typ, namespace: namespace, id: id}
+	v = p.deconstruct(v)
+	if len(p.buffers) > 0 {
+		p.attachments = len(p.buffers)
+		switch typ {
+		case socketEvent:
+			p.typ = socketBinaryEvent
+		case socketAck:
+			p.typ = socketBinaryAck
+		}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	p.data = data
+	return p, nil
+}
+
+// deconstruct replaces the byte slices in v with attachment placeholders
+func (p *socketPacket) deconstruct(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		p.buffers = append(p.buffers, v)
+		return map[string]any{"_placeholder": true, "num": len(p.buffers) - 1}
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = p.deconstruct(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[key] = p.deconstruct(item)
+		}
+		return out
+	}
+	return v
+}
+
+// packets returns the Engine.IO messages of the packet: its text part, then its
+// attachments
+func (p *socketPacket) packets() []enginePacket {
+	var buf bytes.Buffer
+	buf.WriteString(strconv.Itoa(p.typ))
+	if p.typ == socketBinaryEvent || p.typ == socketBinaryAck {
+		buf.WriteString(strconv.Itoa(p.attachments))
+		buf.WriteByte('-')
+	}
+	if p.namespace != "/" {
+		buf.WriteString(p.namespace)
+		buf.WriteByte(',')
+	}
+	if p.id >= 0 {
+		buf.WriteString(strconv.FormatInt(p.id, 10))
+	}
+	buf.Write(p.data)
+
+	packets := []enginePacket{{typ: engineMessage, data: buf.Bytes()}}
+	for _, attachment := range p.buffers {
+		packets = append(packets, enginePacket{typ: engineMessage, data: attachment, binary: true})
+	}
+	return packets
+}
+
+// complete reports whether every attachment of the packet has arrived
+func (p *socketPacket) complete() bool {
+	return len(p.buffers) >= p.attachments
+}
+
+// args decodes the data of an event or ack packet, restoring attachments
+func (p *socketPacket) args() ([]any, error) {
+	var args []any
+	if err := json.Unmarshal(p.data, &args); err != nil {
+		return nil, fmt.Errorf("packet data is not an array")
+	}
+	for i, arg := range args {
+		args[i] = p.reconstruct(arg)
+	}
+	return args, nil
+}
+
+// reconstruct replaces attachment placeholders in v with the attachments
+func (p *socketPacket) reconstruct(v any) any {
+	switch v := v.(type) {
+	case []any:
+		for i, item := range v {
+			v[i] = p.reconstruct(item)
+		}
+	case map[string]any:
+		if placeholder, _ := v["_placeholder"].(bool); placeholder {
+			if num, ok := v["num"].(float64); ok && int(num) >= 0 && int(num) < len(p.buffers) {
+				return p.buffers[int(num)]
+			}
+		}
+		for key, item := range v {
+			v[key] = p.reconstruct(item)
+		}
+	}
+	return v
+}
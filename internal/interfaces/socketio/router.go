@@ -0,0 +1,25 @@
+package socketio
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
+	"go-notification-sse/internal/interfaces/publish"
+)
+
+// InitSocketIORouter initializes Socket.IO routes
+func InitSocketIORouter(
+	logger logger.Logger,
+	hubInstance *hub.Hub,
+	publisher *publish.Policy,
+	rg *gin.RouterGroup,
+	config *Config,
+	connOptions ...hub.SocketIOOption,
+) {
+	socketIOHandler := NewSocketIOHandler(hubInstance, logger, publisher, config, connOptions...)
+
+	rg.GET("/socket.io/", middleware.Streaming(), socketIOHandler.Serve)
+	rg.POST("/socket.io/", socketIOHandler.Serve)
+}
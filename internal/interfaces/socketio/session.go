@@ -0,0 +1,374 @@
+package socketio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+)
+
+// Engine.IO transports
+const (
+	transportPolling   = "polling"
+	transportWebSocket = "websocket"
+)
+
+var (
+	errSessionClosed = errors.New("session closed")
+	errPollActive    = errors.New("a poll is already in progress")
+	errUpgraded      = errors.New("session upgraded to WebSocket")
+)
+
+// session is an Engine.IO session and the Socket.IO sockets multiplexed on it.
+// Packets for the client are queued in the outbox and written by whichever
+// transport is current: each poll takes the whole outbox, and once upgraded a
+// writer goroutine sends it on the WebSocket.
+type session struct {
+	handler *SocketIOHandler
+	conn    *hub.SocketIOConnection
+	logger  logger.Logger
+
+	// sid is the Engine.IO session ID, known only to the client: it authorizes
+	// every request of the session, so unlike the hub connection ID it is never
+	// listed or logged
+	sid string
+
+	mu        sync.Mutex
+	outbox    []enginePacket
+	arrived   chan struct{} // closed and replaced whenever packets are queued
+	done      chan struct{} // closed when the session closes
+	closed    bool
+	transport string
+	polling   bool
+	lastPong  time.Time
+
+	// Socket.IO state, guarded by mu
+	sockets map[string]*socket
+	topics  *hub.TopicRefs
+	pending []pendingAck
+	ackSeq  int64
+	partial *socketPacket // binary packet awaiting attachments
+	pumping bool
+}
+
+func newSession(h *SocketIOHandler, conn *hub.SocketIOConnection, transport string) *session {
+	return &session{
+		handler:   h,
+		conn:      conn,
+		logger:    h.logger.WithContext(conn.Context()),
+		sid:       generateSessionID(),
+		arrived:   make(chan struct{}),
+		done:      make(chan struct{}),
+		transport: transport,
+		lastPong:  time.Now(),
+		sockets:   make(map[string]*socket),
+		topics:    hub.NewTopicRefs(h.hub, conn.ID()),
+	}
+}
+
+// start runs the heartbeat, and closes the session with its hub connection
+func (s *session) start() {
+	go s.heartbeat()
+	go func() {
+		select {
+		case <-s.conn.Context().Done():
+			s.close(true)
+		case <-s.done:
+		}
+	}()
+}
+
+// openPacket returns the Engine.IO handshake
+func (s *session) openPacket(upgrades []string) enginePacket {
+	cfg := s.handler.config
+	return enginePacket{typ: engineOpen, data: mustJSON(map[string]any{
+		"sid":          s.sid,
+		"upgrades":     upgrades,
+		"pingInterval": cfg.PingInterval.Milliseconds(),
+		"pingTimeout":  cfg.PingTimeout.Milliseconds(),
+		"maxPayload":   cfg.MaxPayload,
+	})}
+}
+
+// enqueue queues packets for the client
+func (s *session) enqueue(packets ...enginePacket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enqueueLocked(packets...)
+}
+
+func (s *session) enqueueLocked(packets ...enginePacket) {
+	if s.closed {
+		return
+	}
+	s.outbox = append(s.outbox, packets...)
+	close(s.arrived)
+	s.arrived = make(chan struct{})
+}
+
+// poll waits up to wait for queued packets and takes them all. An empty wait
+// returns a noop packet so the client polls again.
+func (s *session) poll(ctx context.Context, wait time.Duration) ([]enginePacket, error) {
+	s.mu.Lock()
+	if s.polling {
+		s.mu.Unlock()
+		return nil, errPollActive
+	}
+	if s.transport != transportPolling {
+		s.mu.Unlock()
+		return nil, errUpgraded
+	}
+	s.polling = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.polling = false
+		s.mu.Unlock()
+	}()
+
+	s.conn.Touch()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		if len(s.outbox) > 0 {
+			packets := s.outbox
+			s.outbox = nil
+			s.mu.Unlock()
+			return packets, nil
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return nil, errSessionClosed
+		}
+		if s.transport != transportPolling {
+			s.mu.Unlock()
+			return []enginePacket{textPacket(engineNoop, "")}, nil
+		}
+		arrived := s.arrived
+		s.mu.Unlock()
+
+		select {
+		case <-arrived:
+		case <-s.done:
+		case <-timer.C:
+			return []enginePacket{textPacket(engineNoop, "")}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// pollingTransport reports whether the session still uses HTTP polling
+func (s *session) pollingTransport() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transport == transportPolling
+}
+
+// handlePackets handles packets received on any transport
+func (s *session) handlePackets(packets []enginePacket) error {
+	s.conn.Touch()
+	for _, p := range packets {
+		var err error
+		switch p.typ {
+		case enginePong:
+			s.mu.Lock()
+			s.lastPong = time.Now()
+			s.mu.Unlock()
+		case engineMessage:
+			if p.binary {
+				err = s.handleAttachment(p.data)
+			} else {
+				err = s.handleMessage(string(p.data))
+			}
+		case engineClose:
+			s.close(false)
+			return nil
+		case engineNoop:
+		default:
+			err = fmt.Errorf("unexpected packet type %c", p.typ)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// heartbeat pings the client every ping interval and closes the session when a
+// pong does not arrive within the ping timeout
+func (s *session) heartbeat() {
+	cfg := s.handler.config
+	ticker := time.NewTicker(cfg.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			late := time.Since(s.lastPong) > cfg.PingInterval+cfg.PingTimeout
+			s.mu.Unlock()
+
+			if late {
+				s.logger.Infof("No pong within %s, closing session", cfg.PingTimeout)
+				s.close(false)
+				return
+			}
+			s.enqueue(textPacket(enginePing, ""))
+		}
+	}
+}
+
+// serveWebSocket runs a WebSocket transport until it fails or the session
+// closes. Sessions opened over polling first complete the upgrade handshake:
+// probe ping and pong, then an upgrade packet, after which polling stops.
+func (s *session) serveWebSocket(ws *websocket.Conn, upgrade bool) {
+	ws.SetReadLimit(s.handler.config.MaxPayload)
+
+	if upgrade {
+		if err := s.upgrade(ws); err != nil {
+			s.logger.Debugf("WebSocket upgrade failed: %v", err)
+			ws.Close()
+			return
+		}
+	}
+
+	go s.writeLoop(ws)
+	defer func() {
+		ws.Close()
+		s.close(false)
+	}()
+
+	for {
+		messageType, data, err := ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				s.logger.Debugf("WebSocket error: %v", err)
+			}
+			return
+		}
+
+		p, err := decodeFrame(messageType, data)
+		if err == nil {
+			err = s.handlePackets([]enginePacket{p})
+		}
+		if err != nil {
+			s.logger.Warnf("Closing session after protocol error: %v", err)
+			return
+		}
+	}
+}
+
+// upgrade moves a polling session to a WebSocket
+func (s *session) upgrade(ws *websocket.Conn) error {
+	ws.SetReadDeadline(time.Now().Add(s.handler.config.PingTimeout))
+	defer ws.SetReadDeadline(time.Time{})
+
+	for {
+		messageType, data, err := ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+		p, err := decodeFrame(messageType, data)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case p.typ == enginePing && string(p.data) == "probe":
+			if err := ws.WriteMessage(websocket.TextMessage, []byte("3probe")); err != nil {
+				return err
+			}
+			// Wake up the pending poll so the client can send the upgrade
+			s.enqueue(textPacket(engineNoop, ""))
+
+		case p.typ == engineUpgrade:
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.closed {
+				return errSessionClosed
+			}
+			s.transport = transportWebSocket
+			// Noops only served to end polls
+			s.outbox = slices.DeleteFunc(s.outbox, func(p enginePacket) bool { return p.typ == engineNoop })
+			close(s.arrived) // ends a poll still waiting
+			s.arrived = make(chan struct{})
+			s.logger.Debug("Upgraded to WebSocket")
+			return nil
+
+		default:
+			return fmt.Errorf("unexpected packet %c during upgrade", p.typ)
+		}
+	}
+}
+
+// writeLoop writes the outbox to the WebSocket
+func (s *session) writeLoop(ws *websocket.Conn) {
+	for {
+		s.mu.Lock()
+		packets := s.outbox
+		s.outbox = nil
+		arrived, closed := s.arrived, s.closed
+		s.mu.Unlock()
+
+		for _, p := range packets {
+			messageType, data := p.frame()
+			ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := ws.WriteMessage(messageType, data); err != nil {
+				s.logger.Debugf("WebSocket write failed: %v", err)
+				ws.Close()
+				return
+			}
+		}
+		if closed {
+			ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+			ws.Close()
+			return
+		}
+
+		select {
+		case <-arrived:
+		case <-s.done:
+		}
+	}
+}
+
+// close ends the session. When the server closes it, the client is told so
+// its sockets do not reconnect.
+func (s *session) close(byServer bool) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	if byServer {
+		for _, sock := range s.sockets {
+			packet := &socketPacket{typ: socketDisconnect, namespace: sock.namespace, id: -1}
+			s.enqueueLocked(packet.packets()...)
+		}
+		s.enqueueLocked(textPacket(engineClose, ""))
+	}
+	s.closed = true
+	s.partial = nil
+	s.pending = nil
+	clear(s.sockets)
+	close(s.done)
+	s.mu.Unlock()
+
+	s.handler.removeSession(s.sid)
+	if !s.conn.IsClosed() {
+		s.handler.hub.UnregisterConnection(s.conn.ID())
+	}
+	s.conn.Close()
+}
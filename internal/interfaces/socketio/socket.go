@@ -0,0 +1,381 @@
+package socketio
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/hub"
+)
+
+// Events clients emit to use the hub; any other event is rejected
+const (
+	EventJoin    = "join"
+	EventLeave   = "leave"
+	EventPublish = "publish"
+)
+
+// socket is the connection of a session to a namespace. Its rooms are hub topics.
+type socket struct {
+	namespace string
+	id        string
+	rooms     map[string]struct{}
+}
+
+// pendingAck is a delivered event awaiting the client's acknowledgement
+type pendingAck struct {
+	id        int64
+	namespace string
+	target    string
+	message   *hub.Message
+}
+
+// eventMeta is the second argument of the events delivering hub messages
+type eventMeta struct {
+	ID      string            `json:"id"`
+	Topic   string            `json:"topic,omitempty"`
+	Cursor  uint64            `json:"cursor,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// handleMessage handles the text part of a Socket.IO packet
+func (s *session) handleMessage(data string) error {
+	p, err := decodeSocketPacket(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.partial != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("packet received while awaiting %d attachments", s.partial.attachments-len(s.partial.buffers))
+	}
+	if !p.complete() {
+		s.partial = p
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	return s.handleSocketPacket(p)
+}
+
+// handleAttachment adds a binary attachment to the pending binary packet
+func (s *session) handleAttachment(data []byte) error {
+	s.mu.Lock()
+	p := s.partial
+	if p == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("unexpected binary message")
+	}
+	p.buffers = append(p.buffers, data)
+	if !p.complete() {
+		s.mu.Unlock()
+		return nil
+	}
+	s.partial = nil
+	s.mu.Unlock()
+
+	return s.handleSocketPacket(p)
+}
+
+// handleSocketPacket handles a complete Socket.IO packet from the client
+func (s *session) handleSocketPacket(p *socketPacket) error {
+	switch p.typ {
+	case socketConnect:
+		s.connectNamespace(p.namespace)
+		return nil
+	case socketDisconnect:
+		s.disconnectNamespace(p.namespace)
+		return nil
+	case socketEvent, socketBinaryEvent:
+		return s.event(p)
+	case socketAck, socketBinaryAck:
+		s.ack(p)
+		return nil
+	default:
+		return fmt.Errorf("unexpected packet type %d", p.typ)
+	}
+}
+
+// connectNamespace connects the client to a namespace, and starts delivering
+// hub messages once it first connects to one
+func (s *session) connectNamespace(namespace string) {
+	if allowed := s.handler.config.Namespaces; len(allowed) > 0 && !slices.Contains(allowed, namespace) {
+		s.emitPacket(&socketPacket{typ: socketConnectError, namespace: namespace, id: -1,
+			data: mustJSON(map[string]string{"message": "Invalid namespace"})})
+		return
+	}
+
+	s.mu.Lock()
+	sock, exists := s.sockets[namespace]
+	if !exists {
+		sock = &socket{namespace: namespace, id: generateSocketID(), rooms: make(map[string]struct{})}
+		s.sockets[namespace] = sock
+	}
+	pump := !s.pumping
+	s.pumping = true
+	s.mu.Unlock()
+
+	s.emitPacket(&socketPacket{typ: socketConnect, namespace: namespace, id: -1,
+		data: mustJSON(map[string]string{"sid": sock.id})})
+	if pump {
+		go s.pump()
+	}
+	s.logger.Debugf("Socket %s connected to namespace %s", sock.id, namespace)
+}
+
+// disconnectNamespace disconnects the client from a namespace, leaving its rooms
+func (s *session) disconnectNamespace(namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sock, exists := s.sockets[namespace]
+	if !exists {
+		return
+	}
+	delete(s.sockets, namespace)
+	for room := range sock.rooms {
+		s.topics.Release(room)
+	}
+	s.pending = slices.DeleteFunc(s.pending, func(p pendingAck) bool { return p.namespace == namespace })
+}
+
+// event handles an event emitted by the client
+func (s *session) event(p *socketPacket) error {
+	args, err := p.args()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("event without a name")
+	}
+	name, ok := args[0].(string)
+	if !ok || name == "" {
+		return fmt.Errorf("event without a name")
+	}
+
+	s.mu.Lock()
+	sock := s.sockets[p.namespace]
+	s.mu.Unlock()
+	if sock == nil {
+		s.logger.Debugf("Ignoring event %s for unconnected namespace %s", name, p.namespace)
+		return nil
+	}
+
+	var result any
+	switch name {
+	case EventJoin, EventLeave:
+		result = s.rooms(sock, name == EventJoin, args[1:])
+	case EventPublish:
+		result = s.publish(args[1:])
+	default:
+		result = map[string]string{"error": "Unknown event " + name}
+	}
+
+	if p.id >= 0 {
+		reply, err := newSocketPacket(socketAck, p.namespace, p.id, []any{result})
+		if err != nil {
+			return err
+		}
+		s.emitPacket(reply)
+	}
+	return nil
+}
+
+// rooms joins or leaves the rooms named by args, each a room or a list of
+// rooms, and returns the rooms of the socket
+func (s *session) rooms(sock *socket, join bool, args []any) any {
+	var names []string
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			names = append(names, v)
+		case []any:
+			for _, item := range v {
+				if name, ok := item.(string); ok {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		_, joined := sock.rooms[name]
+		switch {
+		case join && !joined:
+			sock.rooms[name] = struct{}{}
+			s.topics.Acquire(name)
+		case !join && joined:
+			delete(sock.rooms, name)
+			s.topics.Release(name)
+		}
+	}
+	return map[string]any{"rooms": slices.Sorted(maps.Keys(sock.rooms))}
+}
+
+// publish publishes a message to a topic on behalf of the client, if the publish
+// policy allows the topic. The argument is an object with the topic, and
+// optionally the type, data and headers.
+func (s *session) publish(args []any) any {
+	var request map[string]any
+	if len(args) > 0 {
+		request, _ = args[0].(map[string]any)
+	}
+	topic, _ := request["topic"].(string)
+	if topic == "" {
+		return map[string]string{"error": "publish requires a topic"}
+	}
+
+	message := &hub.Message{
+		ID:   generateMessageID(),
+		Type: string(hub.MessageTypeNotification),
+		Data: request["data"],
+	}
+	if messageType, _ := request["type"].(string); messageType != "" {
+		message.Type = messageType
+	}
+	if headers, ok := request["headers"].(map[string]any); ok {
+		message.Headers = make(map[string]string, len(headers))
+		for key, value := range headers {
+			message.Headers[key] = fmt.Sprint(value)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(s.conn.Context(), 5*time.Second)
+	defer cancel()
+	recipients, err := s.handler.publisher.Publish(ctx, s.conn, topic, message)
+	if err != nil {
+		return map[string]string{"error": err.Error()}
+	}
+	return map[string]any{"id": message.ID, "recipients": recipients}
+}
+
+// ack reports the client's acknowledgement of a delivered event to the hub
+func (s *session) ack(p *socketPacket) {
+	s.mu.Lock()
+	index := slices.IndexFunc(s.pending, func(a pendingAck) bool { return a.id == p.id && a.namespace == p.namespace })
+	if index < 0 {
+		s.mu.Unlock()
+		return
+	}
+	acked := s.pending[index]
+	s.pending = slices.Delete(s.pending, index, index+1)
+	s.mu.Unlock()
+
+	s.handler.hub.Acknowledge(acked.target, s.conn, acked.message, true)
+}
+
+// pump delivers queued hub messages until the session closes
+func (s *session) pump() {
+	for {
+		outbound, err := s.conn.Next(context.Background())
+		if err != nil {
+			return
+		}
+
+		start := time.Now()
+		packets, err := s.deliver(outbound)
+		if err != nil {
+			s.conn.RecordFailed(outbound.Message)
+			s.logger.Errorf("Failed to encode message %s: %v", outbound.Message.ID, err)
+			continue
+		}
+		if len(packets) == 0 {
+			continue
+		}
+
+		size := 0
+		for _, p := range packets {
+			size += len(p.data)
+		}
+		s.enqueue(packets...)
+		s.conn.RecordSent(outbound.Message, size, start)
+	}
+}
+
+// deliver converts a hub message into an event, named after the message type,
+// for each socket it is addressed to. Topic messages go to the sockets in that
+// room, or to the main namespace for topics subscribed at the handshake; other
+// messages go to the main namespace. Each event asks for an acknowledgement.
+func (s *session) deliver(outbound hub.OutboundMessage) ([]enginePacket, error) {
+	message := outbound.Message
+	meta := eventMeta{ID: message.ID, Cursor: outbound.Cursor, Headers: message.Headers}
+	topic, isTopic := strings.CutPrefix(outbound.Target, "topic:")
+	if isTopic {
+		meta.Topic = topic
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var recipients []*socket
+	if isTopic {
+		for _, sock := range s.sockets {
+			if _, joined := sock.rooms[topic]; joined {
+				recipients = append(recipients, sock)
+			}
+		}
+	}
+	if main, connected := s.sockets["/"]; connected && len(recipients) == 0 {
+		recipients = append(recipients, main)
+	}
+
+	var packets []enginePacket
+	for _, sock := range recipients {
+		s.ackSeq++
+		p, err := newSocketPacket(socketEvent, sock.namespace, s.ackSeq, []any{message.Type, message.Data, meta})
+		if err != nil {
+			return nil, err
+		}
+		s.track(pendingAck{id: s.ackSeq, namespace: sock.namespace, target: outbound.Target, message: message})
+		packets = append(packets, p.packets()...)
+	}
+	return packets, nil
+}
+
+// track records an event awaiting acknowledgement, forgetting the oldest
+// beyond the limit; the caller holds s.mu
+func (s *session) track(p pendingAck) {
+	if limit := s.handler.config.MaxPendingAcks; limit > 0 && len(s.pending) >= limit {
+		s.pending = slices.Delete(s.pending, 0, len(s.pending)-limit+1)
+	}
+	s.pending = append(s.pending, p)
+}
+
+// emitPacket queues a Socket.IO packet for the client
+func (s *session) emitPacket(p *socketPacket) {
+	s.enqueue(p.packets()...)
+}
+
+// mustJSON encodes values that always marshal, such as maps of strings
+func mustJSON(v any) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// generateSocketID generates the public identifier of a socket
+func generateSocketID() string {
+	b := make([]byte, 15)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// generateMessageID generates a unique message ID
+func generateMessageID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("msg-%x", b)
+}
@@ -9,31 +9,40 @@ import (
 	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 
+	"go-notification-sse/internal/infrastructure/audit"
 	"go-notification-sse/internal/infrastructure/hub"
 	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
 )
 
 type ServerSentEventHandler struct {
-	hub    *hub.Hub
-	logger logger.Logger
+	hub     *hub.Hub
+	logger  logger.Logger
+	auditor *audit.Recorder
 }
 
-func NewServerSentEventHandler(hubInstance *hub.Hub, logger logger.Logger) *ServerSentEventHandler {
+func NewServerSentEventHandler(
+	hubInstance *hub.Hub,
+	logger logger.Logger,
+	auditor *audit.Recorder,
+) *ServerSentEventHandler {
 	return &ServerSentEventHandler{
-		hub:    hubInstance,
-		logger: logger.WithField("handler", "sse"),
+		hub:     hubInstance,
+		logger:  logger.WithField("handler", "sse"),
+		auditor: auditor,
 	}
 }
 
 // Connect handles SSE connection requests
 func (h *ServerSentEventHandler) Connect(c *gin.Context) {
-	h.logger.Info("New SSE connection request")
+	log := h.logger.WithContext(c.Request.Context())
+	log.Info("New SSE connection request")
 
 	// Check if hub is running
 	isRunning := h.hub.IsRunning()
-	h.logger.Infof("Hub running check in SSE handler: %v", isRunning)
+	log.Infof("Hub running check in SSE handler: %v", isRunning)
 	if !isRunning {
-		h.logger.Error("Hub is not running")
+		log.Error("Hub is not running")
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error": "Service temporarily unavailable",
 		})
@@ -44,13 +53,15 @@ func (h *ServerSentEventHandler) Connect(c *gin.Context) {
 
 	// Generate unique connection ID
 	connID := generateConnectionID()
+	middleware.SetConnectionID(c, connID)
+	log = h.logger.WithContext(c.Request.Context())
 
 	// Create SSE connection
 	conn := hub.NewSSEConnection(c.Request.Context(), connID, w, c.Request, h.logger)
 
 	// Register connection with hub
 	if err := h.hub.RegisterConnection(conn); err != nil {
-		h.logger.Errorf("Failed to register connection: %v", err)
+		log.Errorf("Failed to register connection: %v", err)
 		_ = conn.Close()
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to register connection",
@@ -58,7 +69,12 @@ func (h *ServerSentEventHandler) Connect(c *gin.Context) {
 		return
 	}
 
-	h.logger.Infof("SSE connection %s connected and registered", conn.ID())
+	// Bind routing so user- and topic-targeted messages reach this connection
+	h.hub.BindUser(conn.ID(), middleware.UserID(c))
+	h.hub.Subscribe(conn.ID(), middleware.Topics(c)...)
+	h.hub.Tag(conn.ID(), middleware.Tags(c)...)
+
+	log.Infof("SSE connection %s connected and registered", conn.ID())
 	sse.Encode(w, sse.Event{
 		Event: "connected",
 		Data: map[string]interface{}{
@@ -73,10 +89,10 @@ func (h *ServerSentEventHandler) Connect(c *gin.Context) {
 	for {
 		select {
 		case <-conn.Context().Done():
-			h.logger.Infof("client connection context canceled %s", conn.ID())
+			log.Infof("client connection context canceled %s", conn.ID())
 			return
 		case <-clientGone:
-			h.logger.Infof("clietn disconnected %s", conn.ID())
+			log.Infof("clietn disconnected %s", conn.ID())
 			return
 		}
 	}
@@ -84,6 +100,8 @@ func (h *ServerSentEventHandler) Connect(c *gin.Context) {
 
 // SendMessage sends a message to a specific client (for testing/admin purposes)
 func (h *ServerSentEventHandler) SendMessage(c *gin.Context) {
+	log := h.logger.WithContext(c.Request.Context())
+
 	clientID := c.Param("clientId")
 	if clientID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -110,8 +128,14 @@ func (h *ServerSentEventHandler) SendMessage(c *gin.Context) {
 		Data: messageReq.Data,
 	}
 
+	entry := middleware.NewAuditEntry(c, audit.ActionSendToConn, clientID)
+	entry.MessageID = message.ID
+	defer h.auditor.Record(c.Request.Context(), entry)
+
 	if err := h.hub.SendToConnection(c.Request.Context(), clientID, message); err != nil {
-		h.logger.Errorf("Failed to send message to client %s: %v", clientID, err)
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		log.Errorf("Failed to send message to client %s: %v", clientID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to send message",
 		})
@@ -127,6 +151,8 @@ func (h *ServerSentEventHandler) SendMessage(c *gin.Context) {
 
 // BroadcastMessage broadcasts a message to all connected clients
 func (h *ServerSentEventHandler) BroadcastMessage(c *gin.Context) {
+	log := h.logger.WithContext(c.Request.Context())
+
 	var messageReq struct {
 		Type string      `json:"type"`
 		Data interface{} `json:"data"`
@@ -145,8 +171,14 @@ func (h *ServerSentEventHandler) BroadcastMessage(c *gin.Context) {
 		Data: messageReq.Data,
 	}
 
+	entry := middleware.NewAuditEntry(c, audit.ActionBroadcast, "all")
+	entry.MessageID = message.ID
+	defer h.auditor.Record(c.Request.Context(), entry)
+
 	if err := h.hub.Broadcast(c.Request.Context(), message); err != nil {
-		h.logger.Errorf("Failed to broadcast message: %v", err)
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		log.Errorf("Failed to broadcast message: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to broadcast message",
 		})
@@ -160,22 +192,13 @@ func (h *ServerSentEventHandler) BroadcastMessage(c *gin.Context) {
 	})
 }
 
-// GetConnections returns information about connected connections
+// GetConnections returns connection counts and IDs; details are served by the admin API
 func (h *ServerSentEventHandler) GetConnections(c *gin.Context) {
-	connections := h.hub.GetConnections()
-	connectionInfo := make([]gin.H, len(connections))
-
-	for i, conn := range connections {
-		connectionInfo[i] = gin.H{
-			"id":     conn.ID(),
-			"type":   conn.Type(),
-			"closed": conn.IsClosed(),
-		}
-	}
+	connections := h.hub.SummarizeConnections(&hub.ConnectionFilter{})
 
 	c.JSON(http.StatusOK, gin.H{
 		"total_connections": len(connections),
-		"connections":       connectionInfo,
+		"connections":       connections,
 		"hub_running":       h.hub.IsRunning(),
 	})
 }
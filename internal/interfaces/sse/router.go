@@ -3,20 +3,28 @@ package sse
 import (
 	"github.com/gin-gonic/gin"
 
+	"go-notification-sse/internal/infrastructure/audit"
 	"go-notification-sse/internal/infrastructure/hub"
 	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
 )
 
-func InitSSERouter(logger logger.Logger, hubInstance *hub.Hub, rg *gin.RouterGroup) {
-	sseHandler := NewServerSentEventHandler(hubInstance, logger)
+func InitSSERouter(
+	logger logger.Logger,
+	hubInstance *hub.Hub,
+	auditor *audit.Recorder,
+	rg *gin.RouterGroup,
+	publishMiddleware ...gin.HandlerFunc,
+) {
+	sseHandler := NewServerSentEventHandler(hubInstance, logger, auditor)
 
 	// SSE connection endpoint
 	sseGroup := rg.Group("/sse")
-	sseGroup.GET("", SSEHeadersMiddleware(), sseHandler.Connect)
+	sseGroup.GET("", middleware.Streaming(), SSEHeadersMiddleware(), sseHandler.Connect)
 
 	// Broadcasting API endpoints
 	apiGroup := rg.Group("/api/v1/sse")
 	apiGroup.GET("/connections", sseHandler.GetConnections)
-	apiGroup.POST("/broadcast", sseHandler.BroadcastMessage)
-	apiGroup.POST("/send/:clientId", sseHandler.SendMessage)
+	apiGroup.POST("/broadcast", append(publishMiddleware, sseHandler.BroadcastMessage)...)
+	apiGroup.POST("/send/:clientId", append(publishMiddleware, sseHandler.SendMessage)...)
 }
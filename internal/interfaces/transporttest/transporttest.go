@@ -0,0 +1,58 @@
+// Package transporttest provides the hub and audit fixtures shared by the tests
+// of the transports
+package transporttest
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+)
+
+// NewHub starts a hub with a silent logger, stopped when the test ends
+func NewHub(t *testing.T) (*hub.Hub, logger.Logger) {
+	t.Helper()
+
+	log := logger.New(logger.NewDefaultConfig())
+	log.SetOutput(io.Discard)
+
+	hubInstance := hub.New(log)
+	hubInstance.Start(context.Background())
+	t.Cleanup(func() { hubInstance.Stop(context.Background()) })
+
+	return hubInstance, log
+}
+
+// NewAuditor returns a recorder writing to a file in the test's temporary
+// directory, so tests can query what was audited
+func NewAuditor(t *testing.T, log logger.Logger) *audit.Recorder {
+	t.Helper()
+
+	config := audit.NewDefaultConfig()
+	config.FilePath = filepath.Join(t.TempDir(), "audit.jsonl")
+	return audit.NewRecorder(audit.NewFileSink(config), log)
+}
+
+// AuditEntries waits up to 2 seconds for n entries matching filter, since an
+// entry may be recorded after the client sees the outcome, and returns the
+// entries recorded by then
+func AuditEntries(t *testing.T, auditor *audit.Recorder, filter *audit.Filter, n int) []*audit.Entry {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entries, err := auditor.Query(context.Background(), filter)
+		if err != nil {
+			t.Fatalf("Failed to query audit entries: %v", err)
+		}
+		if len(entries) >= n || time.Now().After(deadline) {
+			return entries
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
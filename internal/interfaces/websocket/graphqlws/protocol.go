@@ -0,0 +1,273 @@
+// Package graphqlws implements the graphql-transport-ws WebSocket subprotocol
+// used by Apollo and graphql-ws clients, serving GraphQL subscriptions from hub
+// topics.
+package graphqlws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+)
+
+// Subprotocol is the WebSocket subprotocol name negotiated by clients
+const Subprotocol = "graphql-transport-ws"
+
+// Message types of the protocol
+const (
+	MessageConnectionInit = "connection_init"
+	MessageConnectionAck  = "connection_ack"
+	MessagePing           = "ping"
+	MessagePong           = "pong"
+	MessageSubscribe      = "subscribe"
+	MessageNext           = "next"
+	MessageError          = "error"
+	MessageComplete       = "complete"
+)
+
+// Close codes defined by the protocol
+const (
+	CloseInvalidMessage      = 4400
+	CloseUnauthorized        = 4401
+	CloseInitTimeout         = 4408
+	CloseSubscriberExists    = 4409
+	CloseTooManyInitRequests = 4429
+)
+
+// defaultConnectionInitWait is how long clients have to send connection_init
+const defaultConnectionInitWait = 10 * time.Second
+
+// Envelope is a protocol message
+type Envelope struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// SubscribePayload is the payload of a subscribe message
+type SubscribePayload struct {
+	OperationName string         `json:"operationName,omitempty"`
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+// GraphQLError is an entry of the payload of an error message
+type GraphQLError struct {
+	Message string `json:"message"`
+}
+
+// Option configures a Protocol
+type Option func(*Protocol)
+
+// WithConnectionInitWait sets how long a client may take to send
+// connection_init before the connection is closed
+func WithConnectionInitWait(d time.Duration) Option {
+	return func(p *Protocol) {
+		p.initWait = d
+	}
+}
+
+// Protocol is the graphql-transport-ws state of one WebSocket connection.
+// Subscription operations map onto hub topics: topic arguments subscribe the
+// connection to those topics for as long as an operation needs them.
+type Protocol struct {
+	hub      *hub.Hub
+	logger   logger.Logger
+	initWait time.Duration
+
+	mu            sync.Mutex
+	initTimer     *time.Timer
+	initialised   bool
+	acknowledged  bool
+	subscriptions map[string]*subscription
+	topics        *hub.TopicRefs
+}
+
+// New creates the protocol state for a connection
+func New(hubInstance *hub.Hub, logger logger.Logger, opts ...Option) *Protocol {
+	p := &Protocol{
+		hub:           hubInstance,
+		logger:        logger.WithField("protocol", Subprotocol),
+		initWait:      defaultConnectionInitWait,
+		subscriptions: make(map[string]*subscription),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Open starts the connection_init timeout
+func (p *Protocol) Open(conn *hub.WebSocketConnection) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.topics = hub.NewTopicRefs(p.hub, conn.ID())
+	p.initTimer = time.AfterFunc(p.initWait, func() {
+		p.mu.Lock()
+		initialised := p.initialised
+		p.mu.Unlock()
+
+		if !initialised {
+			conn.CloseWithCode(CloseInitTimeout, "Connection initialisation timeout")
+		}
+	})
+}
+
+// HandleFrame handles a message from the client
+func (p *Protocol) HandleFrame(conn *hub.WebSocketConnection, messageType int, data []byte) error {
+	var envelope Envelope
+	if messageType != websocket.TextMessage || json.Unmarshal(data, &envelope) != nil || envelope.Type == "" {
+		return p.close(conn, CloseInvalidMessage, "Invalid message received")
+	}
+
+	switch envelope.Type {
+	case MessageConnectionInit:
+		p.mu.Lock()
+		initialised := p.initialised
+		p.initialised = true
+		p.acknowledged = true
+		p.mu.Unlock()
+
+		if initialised {
+			return p.close(conn, CloseTooManyInitRequests, "Too many initialisation requests")
+		}
+		return p.write(conn, Envelope{Type: MessageConnectionAck})
+
+	case MessagePing:
+		return p.write(conn, Envelope{Type: MessagePong, Payload: envelope.Payload})
+
+	case MessagePong:
+		return nil
+
+	case MessageSubscribe:
+		return p.subscribe(conn, envelope)
+
+	case MessageComplete:
+		if envelope.ID == "" {
+			return p.close(conn, CloseInvalidMessage, "Invalid message received")
+		}
+		p.complete(conn, envelope.ID)
+		return nil
+	}
+
+	return p.close(conn, CloseInvalidMessage, fmt.Sprintf("Unexpected message type %q", envelope.Type))
+}
+
+// subscribe starts a subscription operation
+func (p *Protocol) subscribe(conn *hub.WebSocketConnection, envelope Envelope) error {
+	var payload SubscribePayload
+	if envelope.ID == "" || json.Unmarshal(envelope.Payload, &payload) != nil {
+		return p.close(conn, CloseInvalidMessage, "Invalid message received")
+	}
+
+	p.mu.Lock()
+	acknowledged := p.acknowledged
+	_, exists := p.subscriptions[envelope.ID]
+	p.mu.Unlock()
+
+	if !acknowledged {
+		return p.close(conn, CloseUnauthorized, "Unauthorized")
+	}
+	if exists {
+		return p.close(conn, CloseSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", envelope.ID))
+	}
+
+	op, err := parseDocument(payload.Query, payload.OperationName)
+	var sub *subscription
+	if err == nil {
+		sub, err = newSubscription(op, payload.Variables)
+	}
+	if err != nil {
+		p.logger.Debugf("Rejected operation %s: %v", envelope.ID, err)
+		errors, _ := json.Marshal([]GraphQLError{{Message: err.Error()}})
+		return p.write(conn, Envelope{ID: envelope.ID, Type: MessageError, Payload: errors})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The connection may have closed, or the ID been reused, while parsing
+	if _, exists := p.subscriptions[envelope.ID]; exists || conn.IsClosed() {
+		return nil
+	}
+	p.subscriptions[envelope.ID] = sub
+	p.topics.Acquire(sub.topics...)
+
+	p.logger.Debugf("Started operation %s on topics %v", envelope.ID, sub.topics)
+	return nil
+}
+
+// complete stops a subscription operation, releasing the topics only it used
+func (p *Protocol) complete(conn *hub.WebSocketConnection, id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sub, exists := p.subscriptions[id]
+	if !exists {
+		return
+	}
+	delete(p.subscriptions, id)
+	p.topics.Release(sub.topics...)
+
+	p.logger.Debugf("Completed operation %s", id)
+}
+
+// Encode sends a next message for every operation the hub message matches
+func (p *Protocol) Encode(conn *hub.WebSocketConnection, message hub.OutboundMessage) ([]hub.WebSocketFrame, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var frames []hub.WebSocketFrame
+	for id, sub := range p.subscriptions {
+		if !sub.matches(message) {
+			continue
+		}
+		payload, err := json.Marshal(sub.result(message))
+		if err != nil {
+			return nil, err
+		}
+		frame, err := json.Marshal(Envelope{ID: id, Type: MessageNext, Payload: payload})
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, hub.TextFrame(frame))
+	}
+	return frames, nil
+}
+
+// Close stops the init timeout and drops the connection's operations
+func (p *Protocol) Close(conn *hub.WebSocketConnection) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.initTimer != nil {
+		p.initTimer.Stop()
+	}
+	clear(p.subscriptions)
+}
+
+// write queues a protocol message
+func (p *Protocol) write(conn *hub.WebSocketConnection, envelope Envelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return conn.WriteFrame(ctx, hub.TextFrame(data))
+}
+
+// close closes the connection with a protocol close code
+func (p *Protocol) close(conn *hub.WebSocketConnection, code int, reason string) error {
+	conn.CloseWithCode(code, reason)
+	return fmt.Errorf("%d: %s", code, reason)
+}
@@ -0,0 +1,179 @@
+package graphqlws
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/interfaces/transporttest"
+	"go-notification-sse/internal/interfaces/websocket/wstest"
+)
+
+// newTestServer serves graphql-transport-ws connections registered with a hub
+func newTestServer(t *testing.T, opts ...Option) (*hub.Hub, string) {
+	hubInstance, log := transporttest.NewHub(t)
+	url := wstest.Serve(t, hubInstance, log, Subprotocol, func() hub.WebSocketProtocol {
+		return New(hubInstance, log, opts...)
+	})
+	return hubInstance, url
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	return wstest.Dial(t, url, Subprotocol)
+}
+
+func send(t *testing.T, conn *websocket.Conn, message string) {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) Envelope {
+	t.Helper()
+	var envelope Envelope
+	if err := conn.ReadJSON(&envelope); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	return envelope
+}
+
+func TestProtocol_SubscriptionLifecycle(t *testing.T) {
+	hubInstance, url := newTestServer(t)
+	conn := dial(t, url)
+
+	send(t, conn, `{"type":"connection_init"}`)
+	if msg := receive(t, conn); msg.Type != MessageConnectionAck {
+		t.Fatalf("Expected connection_ack, got %+v", msg)
+	}
+
+	send(t, conn, `{"type":"ping"}`)
+	if msg := receive(t, conn); msg.Type != MessagePong {
+		t.Fatalf("Expected pong, got %+v", msg)
+	}
+
+	send(t, conn, `{"id":"1","type":"subscribe","payload":{
+		"query":"subscription OnAlert($topic: String!) { latest: alert(topic: $topic) { id type topic data } }",
+		"variables":{"topic":"orders"}}}`)
+	send(t, conn, `{"id":"2","type":"subscribe","payload":{"query":"subscription { messages { nope } }"}}`)
+
+	msg := receive(t, conn)
+	if msg.ID != "2" || msg.Type != MessageError || !strings.Contains(string(msg.Payload), `nope`) {
+		t.Fatalf("Expected an error for the invalid operation, got %+v", msg)
+	}
+	if topics := hubInstance.TopicsOf("ws-test-1"); !slices.Equal(topics, []string{"orders"}) {
+		t.Fatalf("Expected the connection to be subscribed to orders, got %v", topics)
+	}
+
+	ctx := context.Background()
+	hubInstance.PublishToTopic(ctx, "orders", &hub.Message{ID: "n-1", Type: "notification"})
+	hubInstance.PublishToTopic(ctx, "orders", &hub.Message{ID: "a-1", Type: "alert", Data: map[string]any{"level": "high"}})
+
+	msg = receive(t, conn)
+	if msg.ID != "1" || msg.Type != MessageNext {
+		t.Fatalf("Expected next for operation 1, got %+v", msg)
+	}
+	var result struct {
+		Data struct {
+			Latest map[string]any `json:"latest"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(msg.Payload, &result); err != nil {
+		t.Fatalf("Invalid payload: %v", err)
+	}
+	if latest := result.Data.Latest; latest["id"] != "a-1" || latest["topic"] != "orders" || latest["data"] == nil || len(latest) != 4 {
+		t.Errorf("Unexpected result %v", latest)
+	}
+
+	send(t, conn, `{"id":"1","type":"complete"}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(hubInstance.TopicsOf("ws-test-1")) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if topics := hubInstance.TopicsOf("ws-test-1"); len(topics) != 0 {
+		t.Errorf("Expected completing the operation to release its topic, got %v", topics)
+	}
+}
+
+func TestProtocol_CloseCodes(t *testing.T) {
+	_, url := newTestServer(t, WithConnectionInitWait(100*time.Millisecond))
+
+	tests := []struct {
+		name     string
+		messages []string
+		code     int
+	}{
+		{"init timeout", nil, CloseInitTimeout},
+		{"subscribe before init", []string{`{"id":"1","type":"subscribe","payload":{"query":"subscription { messages { id } }"}}`}, CloseUnauthorized},
+		{"duplicate init", []string{`{"type":"connection_init"}`, `{"type":"connection_init"}`}, CloseTooManyInitRequests},
+		{"duplicate id", []string{
+			`{"type":"connection_init"}`,
+			`{"id":"1","type":"subscribe","payload":{"query":"subscription { messages { id } }"}}`,
+			`{"id":"1","type":"subscribe","payload":{"query":"subscription { messages { id } }"}}`,
+		}, CloseSubscriberExists},
+		{"invalid message", []string{`not json`}, CloseInvalidMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dial(t, url)
+			for _, message := range tt.messages {
+				send(t, conn, message)
+			}
+
+			for {
+				_, _, err := conn.ReadMessage()
+				if err == nil {
+					continue
+				}
+				if !websocket.IsCloseError(err, tt.code) {
+					t.Errorf("Expected close code %d, got %v", tt.code, err)
+				}
+				return
+			}
+		})
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	op, err := parseDocument(`
+		query Ignored { x }
+		# comment
+		subscription Feed($types: [String!] = ["alert", "update"]) {
+			messages(topics: ["a", "b"], types: $types) { id, type }
+		}`, "Feed")
+	if err != nil {
+		t.Fatalf("parseDocument failed: %v", err)
+	}
+
+	sub, err := newSubscription(op, nil)
+	if err != nil {
+		t.Fatalf("newSubscription failed: %v", err)
+	}
+	if !slices.Equal(sub.topics, []string{"a", "b"}) || !slices.Equal(sub.types, []string{"alert", "update"}) {
+		t.Errorf("Unexpected arguments: topics %v, types %v", sub.topics, sub.types)
+	}
+
+	for _, query := range []string{
+		`{ messages { id } }`,
+		`subscription { messages { id } alert { id } }`,
+		`subscription { unknown { id } }`,
+		`subscription { alert(types: ["x"]) { id } }`,
+		`subscription { messages { ...F } }`,
+		`subscription { messages { id }`,
+	} {
+		op, err := parseDocument(query, "")
+		if err == nil {
+			_, err = newSubscription(op, nil)
+		}
+		if err == nil {
+			t.Errorf("Expected %q to be rejected", query)
+		}
+	}
+}
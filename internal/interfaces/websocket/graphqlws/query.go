@@ -0,0 +1,448 @@
+package graphqlws
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The parser below understands the subset of GraphQL needed for the hub's
+// subscription schema: operations with variables, fields with aliases and
+// arguments, and nested selection sets. Fragments and directives are rejected.
+
+// operation is a parsed GraphQL operation
+type operation struct {
+	kind      string // query, mutation or subscription
+	name      string
+	variables map[string]any // default values of declared variables
+	selection []field
+}
+
+// field is a selected field with its arguments, which may still reference variables
+type field struct {
+	alias     string
+	name      string
+	args      map[string]any
+	selection []field
+}
+
+// responseKey is the key of the field in the result
+func (f field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// variable is a reference to an operation variable in an argument value
+type variable string
+
+// token kinds
+const (
+	tokenEOF = iota
+	tokenPunct
+	tokenName
+	tokenString
+	tokenNumber
+)
+
+type token struct {
+	kind   int
+	value  string
+	offset int
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+// parseDocument parses a GraphQL document and returns the operation to execute,
+// selected by operationName when the document has several
+func parseDocument(src, operationName string) (*operation, error) {
+	p := &parser{src: src}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var operations []*operation
+	for p.tok.kind != tokenEOF {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, op)
+	}
+
+	switch {
+	case len(operations) == 0:
+		return nil, fmt.Errorf("document does not contain an operation")
+	case operationName != "":
+		for _, op := range operations {
+			if op.name == operationName {
+				return op, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown operation named %q", operationName)
+	case len(operations) > 1:
+		return nil, fmt.Errorf("operationName is required for documents with several operations")
+	}
+	return operations[0], nil
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	op := &operation{kind: "query", variables: map[string]any{}}
+
+	// The shorthand { ... } is a query
+	if p.is(tokenPunct, "{") {
+		selection, err := p.parseSelectionSet()
+		op.selection = selection
+		return op, err
+	}
+
+	if p.tok.kind != tokenName {
+		return nil, p.unexpected()
+	}
+	switch p.tok.value {
+	case "query", "mutation", "subscription":
+		op.kind = p.tok.value
+	case "fragment":
+		return nil, fmt.Errorf("fragments are not supported")
+	default:
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName {
+		op.name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.is(tokenPunct, "(") {
+		if err := p.parseVariableDefinitions(op.variables); err != nil {
+			return nil, err
+		}
+	}
+	if p.is(tokenPunct, "@") {
+		return nil, fmt.Errorf("directives are not supported")
+	}
+
+	selection, err := p.parseSelectionSet()
+	op.selection = selection
+	return op, err
+}
+
+// parseVariableDefinitions parses ($name: Type = default, ...), keeping defaults
+func (p *parser) parseVariableDefinitions(defaults map[string]any) error {
+	if err := p.expect(tokenPunct, "("); err != nil {
+		return err
+	}
+	for !p.is(tokenPunct, ")") {
+		if err := p.expect(tokenPunct, "$"); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(tokenPunct, ":"); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if p.is(tokenPunct, "=") {
+			if err := p.advance(); err != nil {
+				return err
+			}
+			value, err := p.parseValue()
+			if err != nil {
+				return err
+			}
+			defaults[name] = value
+		}
+	}
+	return p.advance()
+}
+
+// skipType skips a type reference such as [String!]!
+func (p *parser) skipType() error {
+	if p.is(tokenPunct, "[") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expect(tokenPunct, "]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+
+	if p.is(tokenPunct, "!") {
+		return p.advance()
+	}
+	return nil
+}
+
+func (p *parser) parseSelectionSet() ([]field, error) {
+	if err := p.expect(tokenPunct, "{"); err != nil {
+		return nil, err
+	}
+
+	var selection []field
+	for !p.is(tokenPunct, "}") {
+		if p.is(tokenPunct, "...") {
+			return nil, fmt.Errorf("fragments are not supported")
+		}
+		f, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		selection = append(selection, f)
+	}
+	if len(selection) == 0 {
+		return nil, fmt.Errorf("selection set must not be empty")
+	}
+	return selection, p.advance()
+}
+
+func (p *parser) parseField() (field, error) {
+	var f field
+
+	name, err := p.name()
+	if err != nil {
+		return f, err
+	}
+	if p.is(tokenPunct, ":") {
+		if err := p.advance(); err != nil {
+			return f, err
+		}
+		f.alias = name
+		if name, err = p.name(); err != nil {
+			return f, err
+		}
+	}
+	f.name = name
+
+	if p.is(tokenPunct, "(") {
+		if err := p.advance(); err != nil {
+			return f, err
+		}
+		f.args = map[string]any{}
+		for !p.is(tokenPunct, ")") {
+			argName, err := p.name()
+			if err != nil {
+				return f, err
+			}
+			if err := p.expect(tokenPunct, ":"); err != nil {
+				return f, err
+			}
+			if f.args[argName], err = p.parseValue(); err != nil {
+				return f, err
+			}
+		}
+		if err := p.advance(); err != nil {
+			return f, err
+		}
+	}
+	if p.is(tokenPunct, "@") {
+		return f, fmt.Errorf("directives are not supported")
+	}
+
+	if p.is(tokenPunct, "{") {
+		if f.selection, err = p.parseSelectionSet(); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// parseValue parses an argument value into Go values as decoded from JSON, with
+// variable references left as variable
+func (p *parser) parseValue() (any, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == tokenPunct && tok.value == "$":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return variable(name), err
+
+	case tok.kind == tokenPunct && tok.value == "[":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		list := []any{}
+		for !p.is(tokenPunct, "]") {
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, p.advance()
+
+	case tok.kind == tokenPunct && tok.value == "{":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		object := map[string]any{}
+		for !p.is(tokenPunct, "}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(tokenPunct, ":"); err != nil {
+				return nil, err
+			}
+			if object[name], err = p.parseValue(); err != nil {
+				return nil, err
+			}
+		}
+		return object, p.advance()
+
+	case tok.kind == tokenString:
+		return tok.value, p.advance()
+
+	case tok.kind == tokenNumber:
+		number, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", tok.value)
+		}
+		return number, p.advance()
+
+	case tok.kind == tokenName:
+		var value any
+		switch tok.value {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		default:
+			value = tok.value // enum values are passed as strings
+		}
+		return value, p.advance()
+	}
+	return nil, p.unexpected()
+}
+
+func (p *parser) is(kind int, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+func (p *parser) expect(kind int, value string) error {
+	if !p.is(kind, value) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.unexpected()
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return fmt.Errorf("syntax error: unexpected end of document")
+	}
+	return fmt.Errorf("syntax error: unexpected %q at offset %d", p.tok.value, p.tok.offset)
+}
+
+// advance reads the next token, skipping whitespace, commas and comments
+func (p *parser) advance() error {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		if c == ',' || c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokenEOF}
+		return nil
+	}
+
+	start := p.pos
+	c := p.src[p.pos]
+	defer func() { p.tok.offset = start }()
+
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{kind: tokenPunct, value: "..."}
+
+	case strings.ContainsRune("{}()[]:$!=@", rune(c)):
+		p.pos++
+		p.tok = token{kind: tokenPunct, value: string(c)}
+
+	case c == '_' || isAlnum(c) && (c < '0' || c > '9'):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isAlnum(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: tokenName, value: p.src[start:p.pos]}
+
+	case c == '-' || (c >= '0' && c <= '9'):
+		p.pos++
+		for p.pos < len(p.src) && (isAlnum(p.src[p.pos]) || strings.ContainsRune(".+-", rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		p.tok = token{kind: tokenNumber, value: p.src[start:p.pos]}
+
+	case c == '"':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			end := strings.Index(p.src[p.pos+3:], `"""`)
+			if end < 0 {
+				return fmt.Errorf("syntax error: unterminated string")
+			}
+			p.tok = token{kind: tokenString, value: p.src[p.pos+3 : p.pos+3+end]}
+			p.pos += end + 6
+			return nil
+		}
+		p.pos++
+		for p.pos < len(p.src) && p.src[p.pos] != '"' && p.src[p.pos] != '\n' {
+			if p.src[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.src) || p.src[p.pos] != '"' {
+			return fmt.Errorf("syntax error: unterminated string")
+		}
+		p.pos++
+		value, err := strconv.Unquote(p.src[start:p.pos])
+		if err != nil {
+			return fmt.Errorf("syntax error: invalid string %s", p.src[start:p.pos])
+		}
+		p.tok = token{kind: tokenString, value: value}
+
+	default:
+		return fmt.Errorf("syntax error: unexpected character %q at offset %d", c, p.pos)
+	}
+	return nil
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
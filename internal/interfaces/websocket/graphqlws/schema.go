@@ -0,0 +1,206 @@
+package graphqlws
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go-notification-sse/internal/infrastructure/hub"
+)
+
+// Schema is the subscription schema served over the protocol. Each root field
+// streams hub messages: messages streams every type, the others one type each.
+// Without topic arguments a subscription receives every message delivered to
+// the connection, including ones routed by user or by the connection's own
+// topic subscriptions.
+const Schema = `scalar JSON
+
+type Message {
+  id: ID!
+  type: String!
+  data: JSON
+  headers: JSON
+  topic: String
+  cursor: Int
+}
+
+type Subscription {
+  messages(topic: String, topics: [String!], types: [String!]): Message!
+  notification(topic: String, topics: [String!]): Message!
+  alert(topic: String, topics: [String!]): Message!
+  update(topic: String, topics: [String!]): Message!
+  system(topic: String, topics: [String!]): Message!
+  broadcast(topic: String, topics: [String!]): Message!
+}
+`
+
+// typedFields are the root fields that stream a single message type
+var typedFields = []hub.MessageType{
+	hub.MessageTypeNotification,
+	hub.MessageTypeAlert,
+	hub.MessageTypeUpdate,
+	hub.MessageTypeSystem,
+	hub.MessageTypeBroadcast,
+}
+
+// messageFields are the fields of the Message type
+var messageFields = []string{"id", "type", "data", "headers", "topic", "cursor", "__typename"}
+
+// subscription is a validated subscription operation
+type subscription struct {
+	key       string // response key of the root field
+	topics    []string
+	types     []string // empty for every type
+	selection []field
+}
+
+// newSubscription validates a parsed operation against the schema and resolves
+// its arguments using the client's variables
+func newSubscription(op *operation, variables map[string]any) (*subscription, error) {
+	if op.kind != "subscription" {
+		return nil, fmt.Errorf("only subscription operations are supported, got %s", op.kind)
+	}
+	if len(op.selection) != 1 {
+		return nil, fmt.Errorf("subscription must select exactly one root field")
+	}
+	root := op.selection[0]
+
+	sub := &subscription{key: root.responseKey(), selection: root.selection}
+	allowed := []string{"topic", "topics"}
+	switch {
+	case root.name == "messages":
+		allowed = append(allowed, "types")
+	case slices.Contains(typedFields, hub.MessageType(root.name)):
+		sub.types = []string{root.name}
+	default:
+		return nil, fmt.Errorf("cannot query field %q on type \"Subscription\"", root.name)
+	}
+
+	for name, raw := range root.args {
+		if !slices.Contains(allowed, name) {
+			return nil, fmt.Errorf("unknown argument %q on field \"Subscription.%s\"", name, root.name)
+		}
+		value := resolve(raw, op.variables, variables)
+		if value == nil {
+			continue
+		}
+
+		switch name {
+		case "topic":
+			topic, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("argument \"topic\" must be a String")
+			}
+			sub.topics = append(sub.topics, topic)
+		case "topics", "types":
+			list, err := stringList(value)
+			if err != nil {
+				return nil, fmt.Errorf("argument %q %v", name, err)
+			}
+			if name == "topics" {
+				sub.topics = append(sub.topics, list...)
+			} else {
+				sub.types = list
+			}
+		}
+	}
+	slices.Sort(sub.topics)
+	sub.topics = slices.Compact(sub.topics)
+
+	if len(sub.selection) == 0 {
+		return nil, fmt.Errorf("field %q of type \"Message!\" must have a selection of subfields", root.name)
+	}
+	for _, f := range sub.selection {
+		if !slices.Contains(messageFields, f.name) {
+			return nil, fmt.Errorf("cannot query field %q on type \"Message\"", f.name)
+		}
+		if len(f.args) > 0 || len(f.selection) > 0 {
+			return nil, fmt.Errorf("field \"Message.%s\" takes no arguments or subfields", f.name)
+		}
+	}
+	return sub, nil
+}
+
+// matches reports whether a message sent to the connection belongs to the subscription
+func (s *subscription) matches(message hub.OutboundMessage) bool {
+	if len(s.types) > 0 && !slices.Contains(s.types, message.Message.Type) {
+		return false
+	}
+	if len(s.topics) == 0 {
+		return true
+	}
+	topic, ok := strings.CutPrefix(message.Target, "topic:")
+	return ok && slices.Contains(s.topics, topic)
+}
+
+// result builds the execution result of the subscription for a message
+func (s *subscription) result(message hub.OutboundMessage) map[string]any {
+	object := make(map[string]any, len(s.selection))
+	for _, f := range s.selection {
+		var value any
+		switch f.name {
+		case "id":
+			value = message.Message.ID
+		case "type":
+			value = message.Message.Type
+		case "data":
+			value = message.Message.Data
+		case "headers":
+			if len(message.Message.Headers) > 0 {
+				value = message.Message.Headers
+			}
+		case "topic":
+			if topic, ok := strings.CutPrefix(message.Target, "topic:"); ok {
+				value = topic
+			}
+		case "cursor":
+			if message.Cursor > 0 {
+				value = message.Cursor
+			}
+		case "__typename":
+			value = "Message"
+		}
+		object[f.responseKey()] = value
+	}
+	return map[string]any{"data": map[string]any{s.key: object}}
+}
+
+// resolve substitutes variable references in an argument value
+func resolve(value any, defaults, variables map[string]any) any {
+	switch v := value.(type) {
+	case variable:
+		if provided, ok := variables[string(v)]; ok {
+			return provided
+		}
+		return defaults[string(v)]
+	case []any:
+		resolved := make([]any, len(v))
+		for i, item := range v {
+			resolved[i] = resolve(item, defaults, variables)
+		}
+		return resolved
+	}
+	return value
+}
+
+// stringList coerces a list argument, accepting a single string as a list of one
+// as GraphQL input coercion does
+func stringList(value any) ([]string, error) {
+	if s, ok := value.(string); ok {
+		return []string{s}, nil
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("must be a list of strings")
+	}
+
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a list of strings")
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
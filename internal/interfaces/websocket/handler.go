@@ -3,30 +3,75 @@ package websocket
 import (
 	"crypto/rand"
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 
 	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/identity"
 	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/middleware"
+	"go-notification-sse/internal/interfaces/publish"
+	"go-notification-sse/internal/interfaces/websocket/graphqlws"
+	"go-notification-sse/internal/interfaces/websocket/mqtt"
+	"go-notification-sse/internal/interfaces/websocket/stomp"
 )
 
+// ProtocolFactory creates the subprotocol state of a new connection
+type ProtocolFactory func(hubInstance *hub.Hub, logger logger.Logger) hub.WebSocketProtocol
+
+// newProtocols returns the subprotocols clients may negotiate on /ws.
+// Connections that request none of them get the default JSON messages.
+func newProtocols(hubInstance *hub.Hub, log logger.Logger, publisher *publish.Policy, tokens *identity.Tokens) map[string]ProtocolFactory {
+	// MQTT sessions share retained messages and client identifiers
+	broker := mqtt.NewBroker(hubInstance, log, mqtt.WithPublishPolicy(publisher), mqtt.WithUserTokens(tokens))
+
+	return map[string]ProtocolFactory{
+		graphqlws.Subprotocol: func(hubInstance *hub.Hub, logger logger.Logger) hub.WebSocketProtocol {
+			return graphqlws.New(hubInstance, logger)
+		},
+		stomp.Subprotocol: func(hubInstance *hub.Hub, logger logger.Logger) hub.WebSocketProtocol {
+			return stomp.New(hubInstance, logger, stomp.WithPublishPolicy(publisher))
+		},
+		mqtt.Subprotocol: func(_ *hub.Hub, logger logger.Logger) hub.WebSocketProtocol {
+			return broker.NewSession(logger)
+		},
+	}
+}
+
 // WebSocketHandler handles WebSocket connections and messages
 type WebSocketHandler struct {
-	hub      *hub.Hub
-	logger   logger.Logger
-	upgrader websocket.Upgrader
+	hub         *hub.Hub
+	logger      logger.Logger
+	upgrader    websocket.Upgrader
+	protocols   map[string]ProtocolFactory
+	connOptions []hub.WebSocketOption
 }
 
-// NewWebSocketHandler creates a new WebSocket handler instance
-func NewWebSocketHandler(hubInstance *hub.Hub, logger logger.Logger) *WebSocketHandler {
+// NewWebSocketHandler creates a new WebSocket handler instance. Messages clients
+// publish over a subprotocol are authorized and audited by publisher; tokens,
+// when set, verify the user tokens MQTT clients send as their password.
+func NewWebSocketHandler(
+	hubInstance *hub.Hub,
+	logger logger.Logger,
+	publisher *publish.Policy,
+	tokens *identity.Tokens,
+	connOptions ...hub.WebSocketOption,
+) *WebSocketHandler {
+	protocols := newProtocols(hubInstance, logger, publisher, tokens)
+
 	return &WebSocketHandler{
-		hub:    hubInstance,
-		logger: logger.WithField("handler", "websocket"),
+		hub:         hubInstance,
+		logger:      logger.WithField("handler", "websocket"),
+		protocols:   protocols,
+		connOptions: connOptions,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
+			Subprotocols:    slices.Sorted(maps.Keys(protocols)),
 			CheckOrigin: func(r *http.Request) bool {
 				// Allow connections from any origin for development
 				// In production, you should implement proper origin checking
@@ -38,11 +83,12 @@ func NewWebSocketHandler(hubInstance *hub.Hub, logger logger.Logger) *WebSocketH
 
 // Connect handles WebSocket connection upgrade requests
 func (h *WebSocketHandler) Connect(c *gin.Context) {
-	h.logger.Info("New WebSocket connection request")
+	log := h.logger.WithContext(c.Request.Context())
+	log.Info("New WebSocket connection request")
 
 	// Check if hub is running
 	if !h.hub.IsRunning() {
-		h.logger.Error("Hub is not running")
+		log.Error("Hub is not running")
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error": "Service temporarily unavailable",
 		})
@@ -52,46 +98,53 @@ func (h *WebSocketHandler) Connect(c *gin.Context) {
 	// Upgrade HTTP connection to WebSocket
 	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		h.logger.Errorf("Failed to upgrade connection: %v", err)
+		log.Errorf("Failed to upgrade connection: %v", err)
 		return
 	}
 
 	// Generate unique connection ID
 	connID := generateWebSocketConnectionID()
+	middleware.SetConnectionID(c, connID)
+	log = h.logger.WithContext(c.Request.Context())
+
+	opts := append([]hub.WebSocketOption{hub.WithRequest(c.Request)}, h.connOptions...)
+	if factory, ok := h.protocols[conn.Subprotocol()]; ok {
+		log.Infof("Negotiated subprotocol %s", conn.Subprotocol())
+		opts = append(opts, hub.WithProtocol(factory(h.hub, h.logger.WithField("connection_id", connID))))
+	}
 
 	// Create WebSocket connection
-	wsConn := hub.NewWebSocketConnection(connID, conn, h.logger)
+	wsConn := hub.NewWebSocketConnection(connID, conn, h.logger, opts...)
 
 	// Register connection with hub
 	if err := h.hub.RegisterConnection(wsConn); err != nil {
-		h.logger.Errorf("Failed to register WebSocket connection: %v", err)
+		log.Errorf("Failed to register WebSocket connection: %v", err)
 		wsConn.Close()
 		return
 	}
 
-	h.logger.Infof("WebSocket connection %s connected and registered", wsConn.ID())
+	// Bind routing so user- and topic-targeted messages reach this connection.
+	// MQTT clients authenticate in their CONNECT packet, which binds the user.
+	if conn.Subprotocol() != mqtt.Subprotocol {
+		h.hub.BindUser(wsConn.ID(), middleware.UserID(c))
+	}
+	h.hub.Subscribe(wsConn.ID(), middleware.Topics(c)...)
+	h.hub.Tag(wsConn.ID(), middleware.Tags(c)...)
+
+	log.Infof("WebSocket connection %s connected and registered", wsConn.ID())
 
 	// Keep the connection alive until client disconnects
 	<-wsConn.Context().Done()
-	h.logger.Infof("WebSocket connection %s disconnected", wsConn.ID())
+	log.Infof("WebSocket connection %s disconnected", wsConn.ID())
 }
 
 // GetConnections returns information about WebSocket connections
 func (h *WebSocketHandler) GetConnections(c *gin.Context) {
-	connections := h.hub.GetConnectionsByType("websocket")
-	connectionInfo := make([]gin.H, len(connections))
-
-	for i, conn := range connections {
-		connectionInfo[i] = gin.H{
-			"id":     conn.ID(),
-			"type":   conn.Type(),
-			"closed": conn.IsClosed(),
-		}
-	}
+	connections := h.hub.SummarizeConnections(&hub.ConnectionFilter{Type: "websocket"})
 
 	c.JSON(http.StatusOK, gin.H{
 		"total_connections": len(connections),
-		"connections":       connectionInfo,
+		"connections":       connections,
 		"hub_running":       h.hub.IsRunning(),
 	})
 }
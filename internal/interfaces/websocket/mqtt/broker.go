@@ -0,0 +1,262 @@
+// Package mqtt implements MQTT 3.1.1 and 5 over WebSocket (the mqtt
+// subprotocol), so devices and mobile clients can use the hub as their broker.
+//
+// Every MQTT session is a hub WebSocket connection. Topic names are hub topics:
+// PUBLISH publishes to the topic, and SUBSCRIBE subscribes the connection to it,
+// with "+" and "#" filters mapped onto the hub's pattern subscriptions. Messages
+// not sent to a topic are delivered under the reserved $hub/ prefix:
+//
+//	$hub/broadcast              broadcasts
+//	$hub/users/<user>           messages sent to the connection's user
+//	$hub/types/<type>           messages sent to a connection type
+//	$hub/connections/<id>       messages sent to the connection itself
+//
+// QoS 0 and 1 are supported; QoS 1 deliveries acknowledged with PUBACK are
+// reported to the hub's delivery tracking. Retained messages and last wills are
+// kept by the Broker shared by all sessions. Sessions are not persisted: a
+// session always starts clean when its connection opens.
+//
+// Clients only publish, retain and set wills on topics the broker's publish
+// policy allows, and every publish is audited. A CONNECT binds the connection to
+// a user only when its password is a valid user token for that user name.
+package mqtt
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/identity"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/publish"
+)
+
+// Subprotocol is the WebSocket subprotocol name negotiated by clients
+const Subprotocol = "mqtt"
+
+// HubPrefix is the topic prefix of messages not sent to a hub topic
+const HubPrefix = "$hub/"
+
+const (
+	// defaultConnectWait is how long a client may take to send CONNECT
+	defaultConnectWait = 10 * time.Second
+
+	// defaultMaxInflight bounds the unacknowledged QoS 1 deliveries per session
+	defaultMaxInflight = 1024
+
+	// defaultMaxRetained bounds the number of retained topics
+	defaultMaxRetained = 10000
+)
+
+// Option configures a Broker
+type Option func(*Broker)
+
+// WithConnectWait sets how long a client may take to send CONNECT before the
+// connection is closed
+func WithConnectWait(d time.Duration) Option {
+	return func(b *Broker) {
+		b.connectWait = d
+	}
+}
+
+// WithMaxInflight bounds the unacknowledged QoS 1 deliveries per session; beyond
+// it messages are delivered with QoS 0
+func WithMaxInflight(n int) Option {
+	return func(b *Broker) {
+		b.maxInflight = n
+	}
+}
+
+// WithMaxRetained bounds the number of topics with a retained message
+func WithMaxRetained(n int) Option {
+	return func(b *Broker) {
+		b.maxRetained = n
+	}
+}
+
+// WithPublishPolicy sets the topics clients may publish to and audits each
+// publish; without a policy clients cannot publish
+func WithPublishPolicy(policy *publish.Policy) Option {
+	return func(b *Broker) {
+		b.publisher = policy
+	}
+}
+
+// WithUserTokens verifies the user token clients send as the CONNECT password;
+// without it the CONNECT user name is ignored
+func WithUserTokens(tokens *identity.Tokens) Option {
+	return func(b *Broker) {
+		b.tokens = tokens
+	}
+}
+
+// retainedMessage is the retained message of a topic
+type retainedMessage struct {
+	topic   string
+	message *hub.Message
+}
+
+// Broker holds the state shared by the MQTT sessions of a hub: retained
+// messages and the sessions by client identifier
+type Broker struct {
+	hub         *hub.Hub
+	logger      logger.Logger
+	connectWait time.Duration
+	maxInflight int
+	maxRetained int
+	publisher   *publish.Policy
+	tokens      *identity.Tokens
+
+	mu       sync.Mutex
+	retained map[string]retainedMessage
+	sessions map[string]*Session
+}
+
+// NewBroker creates the MQTT broker of a hub
+func NewBroker(hubInstance *hub.Hub, logger logger.Logger, opts ...Option) *Broker {
+	b := &Broker{
+		hub:         hubInstance,
+		logger:      logger.WithField("protocol", Subprotocol),
+		connectWait: defaultConnectWait,
+		maxInflight: defaultMaxInflight,
+		maxRetained: defaultMaxRetained,
+		retained:    make(map[string]retainedMessage),
+		sessions:    make(map[string]*Session),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// NewSession creates the MQTT session of a connection
+func (b *Broker) NewSession(logger logger.Logger) *Session {
+	return &Session{
+		broker:        b,
+		hub:           b.hub,
+		logger:        logger.WithField("protocol", Subprotocol),
+		subscriptions: make(map[string]*subscription),
+		inflight:      make(map[uint16]inflight),
+	}
+}
+
+// claim registers a session under its client identifier, returning the session
+// it takes over, if any. A session may only be taken over by a session of the
+// same verified user, so that no client can disconnect another user's, nor an
+// anonymous client's, session; otherwise claim reports false.
+func (b *Broker) claim(clientID string, s *Session) (*Session, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous := b.sessions[clientID]
+	if previous != nil && (s.userID == "" || previous.userID != s.userID) {
+		return nil, false
+	}
+	b.sessions[clientID] = s
+	return previous, true
+}
+
+// release forgets a session unless another session took over its client identifier
+func (b *Broker) release(clientID string, s *Session) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.sessions[clientID] == s {
+		delete(b.sessions, clientID)
+	}
+}
+
+// newMessage converts an MQTT application message into a hub message. The MQTT 5
+// user property "type" sets the message type; other user properties become headers.
+func newMessage(payload []byte, props Properties) *hub.Message {
+	message := &hub.Message{
+		ID:   generateMessageID(),
+		Type: string(hub.MessageTypeNotification),
+		Data: decodePayload(payload, props),
+	}
+	for _, pair := range props.UserProperties {
+		if pair[0] == "type" {
+			message.Type = pair[1]
+			continue
+		}
+		if message.Headers == nil {
+			message.Headers = make(map[string]string)
+		}
+		message.Headers[pair[0]] = pair[1]
+	}
+	return message
+}
+
+// retain stores or, for an empty payload, deletes the retained message of a topic
+func (b *Broker) retain(topic string, message *hub.Message, remove bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if remove {
+		delete(b.retained, topic)
+		return
+	}
+	if _, exists := b.retained[topic]; !exists && len(b.retained) >= b.maxRetained {
+		b.logger.Warnf("Not retaining message on %s: %d topics already have one", topic, b.maxRetained)
+		return
+	}
+	b.retained[topic] = retainedMessage{topic: topic, message: message}
+}
+
+// retainedFor returns the retained messages of the topics matching a filter
+func (b *Broker) retainedFor(filter string) []retainedMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var matches []retainedMessage
+	for topic, retained := range b.retained {
+		if hub.TopicMatches(filter, topic) {
+			matches = append(matches, retained)
+		}
+	}
+	return matches
+}
+
+// decodePayload converts an MQTT payload into hub message data: JSON payloads
+// are decoded, other UTF-8 payloads become strings and binary payloads stay bytes
+func decodePayload(payload []byte, props Properties) any {
+	var data any
+	if json.Unmarshal(payload, &data) == nil {
+		return data
+	}
+	if (props.PayloadFormat != nil && *props.PayloadFormat == 1) || utf8.Valid(payload) {
+		return string(payload)
+	}
+	return payload
+}
+
+// encodePayload converts hub message data into an MQTT payload, reporting
+// whether it is JSON
+func encodePayload(data any) ([]byte, bool, error) {
+	switch v := data.(type) {
+	case []byte:
+		return v, false, nil
+	case string:
+		return []byte(v), false, nil
+	}
+	payload, err := json.Marshal(data)
+	return payload, true, err
+}
+
+// generateMessageID generates a unique message ID
+func generateMessageID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("msg-%x", b)
+}
+
+// generateClientID generates a client identifier for clients that send none
+func generateClientID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("mqtt-%x", b)
+}
@@ -0,0 +1,697 @@
+package mqtt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Packet types
+const (
+	Connect     byte = 1
+	Connack     byte = 2
+	Publish     byte = 3
+	Puback      byte = 4
+	Pubrec      byte = 5
+	Pubrel      byte = 6
+	Pubcomp     byte = 7
+	Subscribe   byte = 8
+	Suback      byte = 9
+	Unsubscribe byte = 10
+	Unsuback    byte = 11
+	Pingreq     byte = 12
+	Pingresp    byte = 13
+	Disconnect  byte = 14
+	Auth        byte = 15
+)
+
+// Protocol levels of CONNECT
+const (
+	Version311 byte = 4
+	Version5   byte = 5
+)
+
+// maxPacketSize bounds the size of a packet buffered from the client
+const maxPacketSize = 1 << 20
+
+// errIncomplete reports that more data is needed to decode a packet
+var errIncomplete = errors.New("incomplete packet")
+
+// Will is the last-will message of a CONNECT
+type Will struct {
+	Topic      string
+	Payload    []byte
+	QoS        byte
+	Retain     bool
+	Properties Properties
+}
+
+// SubscriptionRequest is a topic filter of a SUBSCRIBE or UNSUBSCRIBE
+type SubscriptionRequest struct {
+	Filter string
+	QoS    byte
+
+	// MQTT 5 subscription options
+	NoLocal           bool
+	RetainAsPublished bool
+	RetainHandling    byte
+}
+
+// Properties are the MQTT 5 properties used by the broker and its clients;
+// others are skipped when decoding
+type Properties struct {
+	PayloadFormat     *byte
+	ContentType       string
+	ResponseTopic     string
+	CorrelationData   []byte
+	SessionExpiry     *uint32
+	AssignedClientID  string
+	ServerKeepAlive   *uint16
+	ReasonString      string
+	ReceiveMaximum    *uint16
+	MaximumQoS        *byte
+	RetainAvailable   *byte
+	UserProperties    [][2]string
+	MaximumPacketSize *uint32
+	WildcardAvailable *byte
+	SubIDAvailable    *byte
+	SharedAvailable   *byte
+}
+
+// Packet is a decoded MQTT control packet. Which fields are used depends on the
+// packet type.
+type Packet struct {
+	Type    byte
+	Version byte // protocol level, needed to encode and decode MQTT 5 fields
+
+	// CONNECT
+	ProtocolName string
+	CleanStart   bool
+	KeepAlive    uint16
+	ClientID     string
+	Will         *Will
+	Username     string
+	Password     []byte
+
+	// CONNACK
+	SessionPresent bool
+
+	// CONNACK, PUBACK, DISCONNECT (MQTT 5), SUBACK and UNSUBACK (one per filter)
+	ReasonCode  byte
+	ReasonCodes []byte
+
+	// PUBLISH
+	Topic   string
+	QoS     byte
+	Retain  bool
+	Dup     bool
+	Payload []byte
+
+	// PUBLISH (QoS > 0), PUBACK, SUBSCRIBE, SUBACK, UNSUBSCRIBE, UNSUBACK
+	PacketID uint16
+
+	// SUBSCRIBE and UNSUBSCRIBE
+	Subscriptions []SubscriptionRequest
+
+	// MQTT 5
+	Properties Properties
+}
+
+// Decoder splits the data received from a client into packets. Clients may send
+// several packets in one WebSocket message or split a packet across messages.
+type Decoder struct {
+	buf []byte
+
+	// Version is the protocol level of the packets. The server learns it from
+	// CONNECT; clients set it to the level they connect with.
+	Version byte
+}
+
+// Decode adds data to the buffer and returns the complete packets it holds
+func (d *Decoder) Decode(data []byte) ([]*Packet, error) {
+	d.buf = append(d.buf, data...)
+
+	var packets []*Packet
+	for len(d.buf) > 0 {
+		length, n, err := readVarint(d.buf[1:])
+		if errors.Is(err, errIncomplete) {
+			break
+		}
+		if err != nil {
+			return packets, err
+		}
+		if length > maxPacketSize {
+			return packets, fmt.Errorf("packet exceeds %d bytes", maxPacketSize)
+		}
+		end := 1 + n + length
+		if len(d.buf) < end {
+			break
+		}
+
+		p, err := decodePacket(d.buf[0], d.buf[1+n:end], d.Version)
+		if err != nil {
+			return packets, err
+		}
+		if p.Type == Connect {
+			d.Version = p.Version
+		}
+		packets = append(packets, p)
+		d.buf = d.buf[end:]
+	}
+
+	if len(d.buf) == 0 {
+		d.buf = nil
+	}
+	return packets, nil
+}
+
+func decodePacket(header byte, body []byte, version byte) (*Packet, error) {
+	p := &Packet{Type: header >> 4, Version: version}
+	flags := header & 0x0f
+	r := &reader{data: body}
+
+	switch p.Type {
+	case Connect:
+		p.ProtocolName = r.string()
+		p.Version = r.byte()
+		if r.err == nil && (p.ProtocolName != "MQTT" || (p.Version != Version311 && p.Version != Version5)) {
+			return p, nil // the session rejects the protocol version
+		}
+		connectFlags := r.byte()
+		p.CleanStart = connectFlags&0x02 != 0
+		p.KeepAlive = r.uint16()
+		if p.Version == Version5 {
+			p.Properties = r.properties()
+		}
+		p.ClientID = r.string()
+		if connectFlags&0x04 != 0 {
+			p.Will = &Will{QoS: connectFlags >> 3 & 0x03, Retain: connectFlags&0x20 != 0}
+			if p.Version == Version5 {
+				p.Will.Properties = r.properties()
+			}
+			p.Will.Topic = r.string()
+			p.Will.Payload = r.binary()
+		}
+		if connectFlags&0x80 != 0 {
+			p.Username = r.string()
+		}
+		if connectFlags&0x40 != 0 {
+			p.Password = r.binary()
+		}
+
+	case Publish:
+		p.Dup = flags&0x08 != 0
+		p.QoS = flags >> 1 & 0x03
+		p.Retain = flags&0x01 != 0
+		p.Topic = r.string()
+		if p.QoS > 0 {
+			p.PacketID = r.uint16()
+		}
+		if version == Version5 {
+			p.Properties = r.properties()
+		}
+		p.Payload = bytes.Clone(r.rest())
+
+	case Puback, Pubrec, Pubrel, Pubcomp:
+		p.PacketID = r.uint16()
+		if version == Version5 && r.remaining() > 0 {
+			p.ReasonCode = r.byte()
+		}
+
+	case Subscribe, Unsubscribe:
+		if flags != 0x02 {
+			return nil, fmt.Errorf("invalid flags %#x", flags)
+		}
+		p.PacketID = r.uint16()
+		if version == Version5 {
+			p.Properties = r.properties()
+		}
+		for r.err == nil && r.remaining() > 0 {
+			sub := SubscriptionRequest{Filter: r.string()}
+			if p.Type == Subscribe {
+				options := r.byte()
+				sub.QoS = options & 0x03
+				sub.NoLocal = options&0x04 != 0
+				sub.RetainAsPublished = options&0x08 != 0
+				sub.RetainHandling = options >> 4 & 0x03
+			}
+			p.Subscriptions = append(p.Subscriptions, sub)
+		}
+		if len(p.Subscriptions) == 0 {
+			return nil, fmt.Errorf("no topic filters")
+		}
+
+	case Disconnect:
+		if version == Version5 && r.remaining() > 0 {
+			p.ReasonCode = r.byte()
+			if r.remaining() > 0 {
+				p.Properties = r.properties()
+			}
+		}
+
+	case Connack:
+		p.SessionPresent = r.byte()&0x01 != 0
+		p.ReasonCode = r.byte()
+		if version == Version5 {
+			p.Properties = r.properties()
+		}
+
+	case Suback, Unsuback:
+		p.PacketID = r.uint16()
+		if version == Version5 {
+			p.Properties = r.properties()
+		}
+		p.ReasonCodes = bytes.Clone(r.rest())
+
+	case Pingreq, Pingresp, Auth:
+
+	default:
+		return nil, fmt.Errorf("unexpected packet type %d", p.Type)
+	}
+
+	if r.err != nil {
+		return nil, fmt.Errorf("malformed packet type %d: %w", p.Type, r.err)
+	}
+	return p, nil
+}
+
+// Marshal encodes a packet
+func (p *Packet) Marshal() []byte {
+	w := &writer{}
+	header := p.Type << 4
+	v5 := p.Version == Version5
+
+	switch p.Type {
+	case Connect:
+		var flags byte
+		if p.CleanStart {
+			flags |= 0x02
+		}
+		if p.Will != nil {
+			flags |= 0x04 | p.Will.QoS<<3
+			if p.Will.Retain {
+				flags |= 0x20
+			}
+		}
+		if p.Password != nil {
+			flags |= 0x40
+		}
+		if p.Username != "" {
+			flags |= 0x80
+		}
+		w.string("MQTT")
+		w.byte(p.Version)
+		w.byte(flags)
+		w.uint16(p.KeepAlive)
+		if v5 {
+			w.properties(p.Properties)
+		}
+		w.string(p.ClientID)
+		if p.Will != nil {
+			if v5 {
+				w.properties(p.Will.Properties)
+			}
+			w.string(p.Will.Topic)
+			w.binary(p.Will.Payload)
+		}
+		if p.Username != "" {
+			w.string(p.Username)
+		}
+		if p.Password != nil {
+			w.binary(p.Password)
+		}
+
+	case Connack:
+		if p.SessionPresent {
+			w.byte(1)
+		} else {
+			w.byte(0)
+		}
+		w.byte(p.ReasonCode)
+		if v5 {
+			w.properties(p.Properties)
+		}
+
+	case Publish:
+		header |= p.QoS << 1
+		if p.Dup {
+			header |= 0x08
+		}
+		if p.Retain {
+			header |= 0x01
+		}
+		w.string(p.Topic)
+		if p.QoS > 0 {
+			w.uint16(p.PacketID)
+		}
+		if v5 {
+			w.properties(p.Properties)
+		}
+		w.buf.Write(p.Payload)
+
+	case Puback:
+		w.uint16(p.PacketID)
+		if v5 && p.ReasonCode != 0 {
+			w.byte(p.ReasonCode)
+		}
+
+	case Subscribe, Unsubscribe:
+		header |= 0x02
+		w.uint16(p.PacketID)
+		if v5 {
+			w.properties(p.Properties)
+		}
+		for _, sub := range p.Subscriptions {
+			w.string(sub.Filter)
+			if p.Type == Subscribe {
+				options := sub.QoS | sub.RetainHandling<<4
+				if sub.NoLocal {
+					options |= 0x04
+				}
+				if sub.RetainAsPublished {
+					options |= 0x08
+				}
+				w.byte(options)
+			}
+		}
+
+	case Suback, Unsuback:
+		w.uint16(p.PacketID)
+		if v5 {
+			w.properties(p.Properties)
+		}
+		// MQTT 3.1.1 UNSUBACK has no payload
+		if p.Type == Suback || v5 {
+			w.buf.Write(p.ReasonCodes)
+		}
+
+	case Disconnect:
+		if v5 {
+			w.byte(p.ReasonCode)
+			w.properties(p.Properties)
+		}
+	}
+
+	body := w.buf.Bytes()
+	out := append([]byte{header}, appendVarint(nil, len(body))...)
+	return append(out, body...)
+}
+
+// Property identifiers
+const (
+	propPayloadFormat     = 0x01
+	propMessageExpiry     = 0x02
+	propContentType       = 0x03
+	propResponseTopic     = 0x08
+	propCorrelationData   = 0x09
+	propSubscriptionID    = 0x0B
+	propSessionExpiry     = 0x11
+	propAssignedClientID  = 0x12
+	propServerKeepAlive   = 0x13
+	propAuthMethod        = 0x15
+	propAuthData          = 0x16
+	propRequestProblem    = 0x17
+	propWillDelay         = 0x18
+	propRequestResponse   = 0x19
+	propResponseInfo      = 0x1A
+	propServerReference   = 0x1C
+	propReasonString      = 0x1F
+	propReceiveMaximum    = 0x21
+	propTopicAliasMaximum = 0x22
+	propTopicAlias        = 0x23
+	propMaximumQoS        = 0x24
+	propRetainAvailable   = 0x25
+	propUserProperty      = 0x26
+	propMaximumPacketSize = 0x27
+	propWildcardAvailable = 0x28
+	propSubIDAvailable    = 0x29
+	propSharedAvailable   = 0x2A
+)
+
+// reader decodes the fields of a packet, recording the first error
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *reader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *reader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.remaining() < n {
+		r.err = errors.New("truncated field")
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) rest() []byte {
+	if r.err != nil {
+		return nil
+	}
+	return r.take(r.remaining())
+}
+
+func (r *reader) byte() byte {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.take(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) varint() int {
+	if r.err != nil {
+		return 0
+	}
+	value, n, err := readVarint(r.data[r.pos:])
+	if err != nil {
+		r.err = err
+		return 0
+	}
+	r.pos += n
+	return value
+}
+
+func (r *reader) binary() []byte {
+	return bytes.Clone(r.take(int(r.uint16())))
+}
+
+func (r *reader) string() string {
+	return string(r.take(int(r.uint16())))
+}
+
+// properties decodes a property list, skipping the properties not kept
+func (r *reader) properties() Properties {
+	var props Properties
+	length := r.varint()
+	end := r.pos + length
+	if r.err == nil && end > len(r.data) {
+		r.err = errors.New("truncated properties")
+	}
+
+	for r.err == nil && r.pos < end {
+		switch id := r.varint(); id {
+		case propPayloadFormat:
+			props.PayloadFormat = ptr(r.byte())
+		case propContentType:
+			props.ContentType = r.string()
+		case propResponseTopic:
+			props.ResponseTopic = r.string()
+		case propCorrelationData:
+			props.CorrelationData = r.binary()
+		case propSessionExpiry:
+			props.SessionExpiry = ptr(r.uint32())
+		case propAssignedClientID:
+			props.AssignedClientID = r.string()
+		case propServerKeepAlive:
+			props.ServerKeepAlive = ptr(r.uint16())
+		case propReceiveMaximum:
+			props.ReceiveMaximum = ptr(r.uint16())
+		case propMaximumPacketSize:
+			props.MaximumPacketSize = ptr(r.uint32())
+		case propReasonString:
+			props.ReasonString = r.string()
+		case propUserProperty:
+			props.UserProperties = append(props.UserProperties, [2]string{r.string(), r.string()})
+		case propMaximumQoS:
+			props.MaximumQoS = ptr(r.byte())
+		case propRetainAvailable:
+			props.RetainAvailable = ptr(r.byte())
+		case propWildcardAvailable:
+			props.WildcardAvailable = ptr(r.byte())
+		case propSubIDAvailable:
+			props.SubIDAvailable = ptr(r.byte())
+		case propSharedAvailable:
+			props.SharedAvailable = ptr(r.byte())
+		case propRequestProblem, propRequestResponse:
+			r.byte()
+		case propTopicAliasMaximum, propTopicAlias:
+			r.uint16()
+		case propMessageExpiry, propWillDelay:
+			r.uint32()
+		case propSubscriptionID:
+			r.varint()
+		case propAuthMethod, propResponseInfo, propServerReference:
+			r.string()
+		case propAuthData:
+			r.binary()
+		default:
+			if r.err == nil {
+				r.err = fmt.Errorf("unknown property %#x", id)
+			}
+		}
+	}
+	return props
+}
+
+// writer encodes the fields of a packet
+type writer struct {
+	buf bytes.Buffer
+}
+
+func (w *writer) byte(b byte) {
+	w.buf.WriteByte(b)
+}
+
+func (w *writer) uint16(v uint16) {
+	w.buf.Write(binary.BigEndian.AppendUint16(nil, v))
+}
+
+func (w *writer) uint32(v uint32) {
+	w.buf.Write(binary.BigEndian.AppendUint32(nil, v))
+}
+
+func (w *writer) string(s string) {
+	w.uint16(uint16(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *writer) binary(b []byte) {
+	w.uint16(uint16(len(b)))
+	w.buf.Write(b)
+}
+
+// properties encodes the properties set on props, preceded by their length
+func (w *writer) properties(props Properties) {
+	p := &writer{}
+	if props.PayloadFormat != nil {
+		p.byte(propPayloadFormat)
+		p.byte(*props.PayloadFormat)
+	}
+	if props.ContentType != "" {
+		p.byte(propContentType)
+		p.string(props.ContentType)
+	}
+	if props.ResponseTopic != "" {
+		p.byte(propResponseTopic)
+		p.string(props.ResponseTopic)
+	}
+	if props.CorrelationData != nil {
+		p.byte(propCorrelationData)
+		p.binary(props.CorrelationData)
+	}
+	if props.SessionExpiry != nil {
+		p.byte(propSessionExpiry)
+		p.uint32(*props.SessionExpiry)
+	}
+	if props.AssignedClientID != "" {
+		p.byte(propAssignedClientID)
+		p.string(props.AssignedClientID)
+	}
+	if props.ServerKeepAlive != nil {
+		p.byte(propServerKeepAlive)
+		p.uint16(*props.ServerKeepAlive)
+	}
+	if props.ReasonString != "" {
+		p.byte(propReasonString)
+		p.string(props.ReasonString)
+	}
+	if props.ReceiveMaximum != nil {
+		p.byte(propReceiveMaximum)
+		p.uint16(*props.ReceiveMaximum)
+	}
+	for _, flag := range []struct {
+		id    byte
+		value *byte
+	}{
+		{propMaximumQoS, props.MaximumQoS},
+		{propRetainAvailable, props.RetainAvailable},
+		{propWildcardAvailable, props.WildcardAvailable},
+		{propSubIDAvailable, props.SubIDAvailable},
+		{propSharedAvailable, props.SharedAvailable},
+	} {
+		if flag.value != nil {
+			p.byte(flag.id)
+			p.byte(*flag.value)
+		}
+	}
+	for _, pair := range props.UserProperties {
+		p.byte(propUserProperty)
+		p.string(pair[0])
+		p.string(pair[1])
+	}
+	if props.MaximumPacketSize != nil {
+		p.byte(propMaximumPacketSize)
+		p.uint32(*props.MaximumPacketSize)
+	}
+
+	w.buf.Write(appendVarint(nil, p.buf.Len()))
+	w.buf.Write(p.buf.Bytes())
+}
+
+// readVarint decodes a variable byte integer, returning it and its size
+func readVarint(data []byte) (int, int, error) {
+	value, multiplier := 0, 1
+	for i := 0; i < 4; i++ {
+		if i >= len(data) {
+			return 0, 0, errIncomplete
+		}
+		value += int(data[i]&0x7f) * multiplier
+		if data[i]&0x80 == 0 {
+			return value, i + 1, nil
+		}
+		multiplier *= 128
+	}
+	return 0, 0, errors.New("malformed variable byte integer")
+}
+
+// appendVarint encodes a variable byte integer
+func appendVarint(b []byte, value int) []byte {
+	for {
+		digit := byte(value % 128)
+		value /= 128
+		if value > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if value == 0 {
+			return b
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,676 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/publish"
+)
+
+// MQTT 5 reason codes used by the session
+const (
+	ReasonSuccess               byte = 0x00
+	ReasonDisconnectWithWill    byte = 0x04
+	ReasonNoMatchingSubscribers byte = 0x10
+	ReasonNoSubscriptionExisted byte = 0x11
+	ReasonUnspecifiedError      byte = 0x80
+	ReasonMalformedPacket       byte = 0x81
+	ReasonProtocolError         byte = 0x82
+	ReasonUnsupportedVersion    byte = 0x84
+	ReasonClientIDNotValid      byte = 0x85
+	ReasonBadUserNameOrPassword byte = 0x86
+	ReasonNotAuthorized         byte = 0x87
+	ReasonKeepAliveTimeout      byte = 0x8D
+	ReasonSessionTakenOver      byte = 0x8E
+	ReasonTopicFilterInvalid    byte = 0x8F
+	ReasonTopicNameInvalid      byte = 0x90
+	ReasonQoSNotSupported       byte = 0x9B
+	ReasonSharedNotSupported    byte = 0x9E
+)
+
+// MQTT 3.1.1 CONNACK return codes
+const (
+	returnUnacceptableVersion   byte = 0x01
+	returnIdentifierRejected    byte = 0x02
+	returnBadUserNameOrPassword byte = 0x04
+	returnNotAuthorized         byte = 0x05
+)
+
+// maxLocalMessages bounds the IDs of published messages remembered for the
+// no-local subscription option
+const maxLocalMessages = 256
+
+// subscription is a topic filter the client subscribed to
+type subscription struct {
+	filter  string
+	qos     byte
+	noLocal bool
+}
+
+// inflight is a QoS 1 delivery awaiting PUBACK
+type inflight struct {
+	target  string
+	message *hub.Message
+}
+
+// Session is the MQTT state of one WebSocket connection
+type Session struct {
+	broker *Broker
+	hub    *hub.Hub
+	logger logger.Logger
+
+	decoder  Decoder // only used by the connection's read pump
+	lastRead atomic.Int64
+
+	mu            sync.Mutex
+	conn          *hub.WebSocketConnection
+	connectTimer  *time.Timer
+	connected     bool
+	disconnected  bool
+	version       byte
+	clientID      string
+	userID        string // verified by the CONNECT, empty for anonymous clients
+	receiveMax    int
+	will          *Will
+	subscriptions map[string]*subscription
+	topics        *hub.TopicRefs
+	packetID      uint16
+	inflight      map[uint16]inflight
+	local         []string // IDs of messages recently published by the client
+}
+
+// Open binds the session to its connection and starts the CONNECT timeout
+func (s *Session) Open(conn *hub.WebSocketConnection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conn = conn
+	s.topics = hub.NewTopicRefs(s.hub, conn.ID())
+	s.lastRead.Store(time.Now().UnixNano())
+	s.connectTimer = time.AfterFunc(s.broker.connectWait, func() {
+		s.mu.Lock()
+		connected := s.connected
+		s.mu.Unlock()
+
+		if !connected {
+			conn.CloseWithCode(websocket.ClosePolicyViolation, "CONNECT timeout")
+		}
+	})
+}
+
+// HandleFrame decodes and handles the MQTT packets in a WebSocket message
+func (s *Session) HandleFrame(conn *hub.WebSocketConnection, messageType int, data []byte) error {
+	s.lastRead.Store(time.Now().UnixNano())
+
+	if messageType != websocket.BinaryMessage {
+		return s.fail(ReasonMalformedPacket, "MQTT packets must be sent in binary frames")
+	}
+
+	packets, err := s.decoder.Decode(data)
+	for _, p := range packets {
+		if err := s.handle(p); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return s.fail(ReasonMalformedPacket, err.Error())
+	}
+	return nil
+}
+
+// handle handles a client packet
+func (s *Session) handle(p *Packet) error {
+	s.mu.Lock()
+	connected, disconnected := s.connected, s.disconnected
+	s.mu.Unlock()
+
+	if disconnected {
+		return nil // the connection is closing
+	}
+	if p.Type == Connect {
+		if connected {
+			return s.fail(ReasonProtocolError, "CONNECT sent twice")
+		}
+		return s.connect(p)
+	}
+	if !connected {
+		return s.fail(ReasonProtocolError, "the first packet must be CONNECT")
+	}
+
+	switch p.Type {
+	case Publish:
+		return s.publish(p)
+	case Puback:
+		s.puback(p)
+		return nil
+	case Subscribe:
+		return s.subscribe(p)
+	case Unsubscribe:
+		return s.unsubscribe(p)
+	case Pingreq:
+		return s.write(&Packet{Type: Pingresp})
+	case Disconnect:
+		s.mu.Lock()
+		s.disconnected = true
+		if p.ReasonCode != ReasonDisconnectWithWill {
+			s.will = nil
+		}
+		s.mu.Unlock()
+
+		s.conn.CloseWithCode(websocket.CloseNormalClosure, "")
+		return nil
+	default:
+		return s.fail(ReasonProtocolError, fmt.Sprintf("unexpected packet type %d", p.Type))
+	}
+}
+
+// connect accepts or refuses the CONNECT of the client
+func (s *Session) connect(p *Packet) error {
+	if p.ProtocolName != "MQTT" || (p.Version != Version311 && p.Version != Version5) {
+		return s.refuse(ReasonUnsupportedVersion, fmt.Sprintf("unsupported protocol %s level %d", p.ProtocolName, p.Version))
+	}
+
+	s.mu.Lock()
+	s.version = p.Version
+	s.mu.Unlock()
+
+	clientID, assigned := p.ClientID, ""
+	if clientID == "" {
+		if p.Version == Version311 && !p.CleanStart {
+			return s.refuse(ReasonClientIDNotValid, "an empty client identifier requires a clean session")
+		}
+		clientID = generateClientID()
+		assigned = clientID
+	}
+	userID, err := s.authenticate(p)
+	if err != nil {
+		return s.refuse(ReasonBadUserNameOrPassword, err.Error())
+	}
+	if will := p.Will; will != nil {
+		if will.QoS > 1 {
+			return s.refuse(ReasonQoSNotSupported, "will QoS 2 is not supported")
+		}
+		if !validTopicName(will.Topic) {
+			return s.refuse(ReasonTopicNameInvalid, "invalid will topic "+will.Topic)
+		}
+		if !s.broker.publisher.Allowed(will.Topic) {
+			return s.refuse(ReasonNotAuthorized, "not allowed to publish to will topic "+will.Topic)
+		}
+	}
+
+	s.mu.Lock()
+	s.userID = userID
+	s.mu.Unlock()
+
+	previous, ok := s.broker.claim(clientID, s)
+	if !ok {
+		return s.refuse(ReasonNotAuthorized, "client identifier "+clientID+" is in use by another user")
+	}
+
+	s.mu.Lock()
+	s.connected = true
+	s.connectTimer.Stop()
+	s.clientID = clientID
+	s.will = p.Will
+	s.receiveMax = s.broker.maxInflight
+	if limit := p.Properties.ReceiveMaximum; limit != nil && int(*limit) < s.receiveMax {
+		s.receiveMax = int(*limit)
+	}
+	s.mu.Unlock()
+
+	if previous != nil {
+		s.logger.Infof("Client %s took over its previous session", clientID)
+		previous.close(ReasonSessionTakenOver, websocket.CloseNormalClosure, "session taken over")
+	}
+
+	// Only the user the CONNECT proves is bound; the WebSocket handler does not
+	// bind the unverified user of the upgrade request to MQTT connections
+	connID := s.conn.ID()
+	if userID != "" {
+		s.hub.BindUser(connID, userID)
+	}
+	s.hub.Tag(connID, Subprotocol)
+
+	connack := &Packet{Type: Connack}
+	if p.Version == Version5 {
+		connack.Properties = Properties{
+			AssignedClientID:  assigned,
+			MaximumQoS:        ptr[byte](1),
+			RetainAvailable:   ptr[byte](1),
+			WildcardAvailable: ptr[byte](1),
+			SubIDAvailable:    ptr[byte](0),
+			SharedAvailable:   ptr[byte](0),
+		}
+		if expiry := p.Properties.SessionExpiry; expiry != nil && *expiry > 0 {
+			connack.Properties.SessionExpiry = ptr[uint32](0) // sessions are not persisted
+		}
+	}
+	err = s.write(connack)
+
+	if p.KeepAlive > 0 {
+		go s.keepAlive(time.Duration(p.KeepAlive) * time.Second)
+	}
+	s.logger.Debugf("Client %s connected with MQTT level %d", clientID, p.Version)
+	return err
+}
+
+// authenticate returns the user a CONNECT proves to be: its password must be a
+// user token for its user name. Without user tokens configured the user name is
+// not trusted and no user is returned.
+func (s *Session) authenticate(p *Packet) (string, error) {
+	if s.broker.tokens == nil {
+		if p.Username != "" {
+			s.logger.Debugf("Ignoring unverified user name %s", p.Username)
+		}
+		return "", nil
+	}
+	if p.Username == "" && p.Password == nil {
+		return "", nil
+	}
+
+	userID, err := s.broker.tokens.Verify(string(p.Password))
+	if err != nil {
+		return "", err
+	}
+	if p.Username != "" && p.Username != userID {
+		return "", fmt.Errorf("user token is not for user %s", p.Username)
+	}
+	return userID, nil
+}
+
+// keepAlive closes the connection when the client sends nothing for one and a
+// half times its keep alive interval
+func (s *Session) keepAlive(interval time.Duration) {
+	timeout := interval * 3 / 2
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.conn.Context().Done():
+			return
+		case now := <-ticker.C:
+			if now.Sub(time.Unix(0, s.lastRead.Load())) > timeout {
+				s.logger.Warnf("Closing connection after no packets for %s", timeout)
+				s.close(ReasonKeepAliveTimeout, websocket.CloseGoingAway, "keep alive timeout")
+				return
+			}
+		}
+	}
+}
+
+// publish publishes a PUBLISH of the client to its hub topic
+func (s *Session) publish(p *Packet) error {
+	if p.QoS > 1 {
+		return s.fail(ReasonQoSNotSupported, "QoS 2 is not supported")
+	}
+	if !validTopicName(p.Topic) {
+		return s.fail(ReasonTopicNameInvalid, "invalid topic name "+p.Topic)
+	}
+
+	ctx, cancel := context.WithTimeout(s.conn.Context(), 5*time.Second)
+	defer cancel()
+
+	reason := ReasonSuccess
+	recipients, err := s.publishMessage(ctx, p.Topic, p.Payload, p.Retain, p.Properties, true)
+	switch {
+	case errors.Is(err, publish.ErrTopicNotAllowed):
+		s.logger.Debugf("Refused publish to %s", p.Topic)
+		reason = ReasonNotAuthorized
+	case err != nil:
+		s.logger.Warnf("Failed to publish to %s: %v", p.Topic, err)
+		reason = ReasonUnspecifiedError
+	case recipients == 0:
+		reason = ReasonNoMatchingSubscribers
+	}
+
+	if p.QoS == 0 {
+		return nil
+	}
+	return s.write(&Packet{Type: Puback, PacketID: p.PacketID, ReasonCode: reason})
+}
+
+// publishMessage publishes an application message to a hub topic through the
+// publish policy, keeping it as the topic's retained message when retain is set.
+// Messages published by the client are remembered for its no-local subscriptions.
+func (s *Session) publishMessage(ctx context.Context, topic string, payload []byte, retain bool, props Properties, local bool) (int, error) {
+	message := newMessage(payload, props)
+	if local {
+		s.mu.Lock()
+		if len(s.local) >= maxLocalMessages {
+			s.local = slices.Delete(s.local, 0, len(s.local)-maxLocalMessages+1)
+		}
+		s.local = append(s.local, message.ID)
+		s.mu.Unlock()
+	}
+
+	recipients, err := s.broker.publisher.Publish(ctx, s.conn, topic, message)
+	if err != nil {
+		return 0, err
+	}
+	if retain {
+		s.broker.retain(topic, message, len(payload) == 0)
+	}
+	return recipients, nil
+}
+
+// puback resolves a QoS 1 delivery acknowledged by the client
+func (s *Session) puback(p *Packet) {
+	s.mu.Lock()
+	delivery, exists := s.inflight[p.PacketID]
+	delete(s.inflight, p.PacketID)
+	s.mu.Unlock()
+
+	if exists {
+		s.hub.Acknowledge(delivery.target, s.conn, delivery.message, p.ReasonCode < ReasonUnspecifiedError)
+	}
+}
+
+// subscribe adds subscriptions, then sends the retained messages they match
+func (s *Session) subscribe(p *Packet) error {
+	codes := make([]byte, len(p.Subscriptions))
+	var retained []*subscription
+
+	s.mu.Lock()
+	for i, req := range p.Subscriptions {
+		switch {
+		case strings.HasPrefix(req.Filter, "$share/"):
+			codes[i] = s.failure(ReasonSharedNotSupported)
+			continue
+		case !validTopicFilter(req.Filter):
+			codes[i] = s.failure(ReasonTopicFilterInvalid)
+			continue
+		}
+
+		sub := &subscription{filter: req.Filter, qos: min(req.QoS, 1)}
+		retainHandling := byte(0)
+		if s.version == Version5 {
+			sub.noLocal = req.NoLocal
+			retainHandling = req.RetainHandling
+		}
+
+		_, exists := s.subscriptions[req.Filter]
+		if !exists {
+			s.route(req.Filter, true)
+		}
+		s.subscriptions[req.Filter] = sub
+		codes[i] = sub.qos
+
+		// Retain handling 1 sends retained messages for new subscriptions only, 2 never
+		if retainHandling == 0 || (retainHandling == 1 && !exists) {
+			retained = append(retained, sub)
+		}
+	}
+	s.mu.Unlock()
+
+	if err := s.write(&Packet{Type: Suback, PacketID: p.PacketID, ReasonCodes: codes}); err != nil {
+		return err
+	}
+
+	for _, sub := range retained {
+		for _, r := range s.broker.retainedFor(sub.filter) {
+			s.mu.Lock()
+			publish, err := s.newPublish(r.topic, sub.qos, true, hub.OutboundMessage{Message: r.message, Target: "topic:" + r.topic})
+			s.mu.Unlock()
+			if err != nil {
+				s.logger.Warnf("Failed to encode retained message of %s: %v", r.topic, err)
+				continue
+			}
+			if err := s.write(publish); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// unsubscribe removes subscriptions
+func (s *Session) unsubscribe(p *Packet) error {
+	codes := make([]byte, len(p.Subscriptions))
+
+	s.mu.Lock()
+	for i, req := range p.Subscriptions {
+		if _, exists := s.subscriptions[req.Filter]; !exists {
+			codes[i] = ReasonNoSubscriptionExisted
+			continue
+		}
+		delete(s.subscriptions, req.Filter)
+		s.route(req.Filter, false)
+		codes[i] = ReasonSuccess
+	}
+	s.mu.Unlock()
+
+	return s.write(&Packet{Type: Unsuback, PacketID: p.PacketID, ReasonCodes: codes})
+}
+
+// route adds or removes the hub routing of a topic filter. Filters of $hub/
+// topics need none: those messages are addressed to the connection already.
+func (s *Session) route(filter string, subscribe bool) {
+	connID := s.conn.ID()
+	switch {
+	case strings.HasPrefix(filter, "$"):
+	case strings.ContainsAny(filter, "+#"):
+		if subscribe {
+			s.hub.SubscribePattern(connID, filter)
+		} else {
+			s.hub.UnsubscribePattern(connID, filter)
+		}
+	case subscribe:
+		s.topics.Acquire(filter)
+	default:
+		s.topics.Release(filter)
+	}
+}
+
+// Encode converts a hub message into a PUBLISH when a subscription of the client
+// matches it, with the highest QoS of the matching subscriptions
+func (s *Session) Encode(conn *hub.WebSocketConnection, message hub.OutboundMessage) ([]hub.WebSocketFrame, error) {
+	topic := topicOf(message.Target, conn.ID())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	local := slices.Contains(s.local, message.Message.ID)
+	matched, qos := false, byte(0)
+	for _, sub := range s.subscriptions {
+		if (sub.noLocal && local) || !hub.TopicMatches(sub.filter, topic) {
+			continue
+		}
+		matched, qos = true, max(qos, sub.qos)
+	}
+	if !matched {
+		return nil, nil
+	}
+
+	publish, err := s.newPublish(topic, qos, false, message)
+	if err != nil {
+		return nil, err
+	}
+	return []hub.WebSocketFrame{hub.BinaryFrame(publish.Marshal())}, nil
+}
+
+// newPublish builds the PUBLISH of a hub message, tracking QoS 1 deliveries
+// until the client acknowledges them. Deliveries beyond the client's receive
+// maximum are sent with QoS 0. The caller holds s.mu.
+func (s *Session) newPublish(topic string, qos byte, retain bool, message hub.OutboundMessage) (*Packet, error) {
+	payload, isJSON, err := encodePayload(message.Message.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Packet{Type: Publish, Version: s.version, Topic: topic, Retain: retain, Payload: payload}
+	if qos > 0 && len(s.inflight) < s.receiveMax {
+		p.QoS = 1
+		p.PacketID = s.nextPacketID()
+		s.inflight[p.PacketID] = inflight{target: message.Target, message: message.Message}
+	}
+
+	if s.version == Version5 {
+		if isJSON {
+			p.Properties.ContentType = "application/json"
+		}
+		p.Properties.UserProperties = append(p.Properties.UserProperties, [2]string{"type", message.Message.Type})
+		if message.Cursor > 0 {
+			p.Properties.UserProperties = append(p.Properties.UserProperties, [2]string{"cursor", strconv.FormatUint(message.Cursor, 10)})
+		}
+		for _, key := range slices.Sorted(maps.Keys(message.Message.Headers)) {
+			p.Properties.UserProperties = append(p.Properties.UserProperties, [2]string{key, message.Message.Headers[key]})
+		}
+	}
+	return p, nil
+}
+
+// nextPacketID returns an unused packet identifier. The caller holds s.mu.
+func (s *Session) nextPacketID() uint16 {
+	for {
+		s.packetID++
+		if _, used := s.inflight[s.packetID]; s.packetID != 0 && !used {
+			return s.packetID
+		}
+	}
+}
+
+// Close publishes the will unless the client disconnected normally, and
+// releases the session's client identifier
+func (s *Session) Close(conn *hub.WebSocketConnection) {
+	s.mu.Lock()
+	if s.connectTimer != nil {
+		s.connectTimer.Stop()
+	}
+	connected, clientID, will := s.connected, s.clientID, s.will
+	s.will = nil
+	clear(s.subscriptions)
+	clear(s.inflight)
+	s.mu.Unlock()
+
+	if !connected {
+		return
+	}
+	s.broker.release(clientID, s)
+
+	// Close may run while the hub holds its connection lock, so the will is
+	// published from another goroutine
+	if will != nil {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := s.publishMessage(ctx, will.Topic, will.Payload, will.Retain, will.Properties, false); err != nil {
+				s.logger.Warnf("Failed to publish the will of %s: %v", clientID, err)
+			}
+		}()
+	}
+}
+
+// refuse answers CONNECT with a failure and closes the connection
+func (s *Session) refuse(reason byte, text string) error {
+	code := reason
+	if s.version != Version5 {
+		switch reason {
+		case ReasonUnsupportedVersion:
+			code = returnUnacceptableVersion
+		case ReasonClientIDNotValid:
+			code = returnIdentifierRejected
+		case ReasonBadUserNameOrPassword:
+			code = returnBadUserNameOrPassword
+		case ReasonNotAuthorized:
+			code = returnNotAuthorized
+		default:
+			code = 0 // MQTT 3.1.1 has no return code for it
+		}
+	}
+
+	if code != 0 {
+		s.write(&Packet{Type: Connack, ReasonCode: code})
+	}
+	s.conn.CloseWithCode(websocket.CloseProtocolError, text)
+	return errors.New(text)
+}
+
+// fail closes the connection after a protocol violation
+func (s *Session) fail(reason byte, text string) error {
+	s.close(reason, websocket.CloseProtocolError, text)
+	return errors.New(text)
+}
+
+// close closes the connection, telling MQTT 5 clients why with a DISCONNECT
+func (s *Session) close(reason byte, code int, text string) {
+	s.mu.Lock()
+	v5 := s.connected && s.version == Version5
+	s.mu.Unlock()
+
+	if v5 {
+		s.write(&Packet{Type: Disconnect, ReasonCode: reason, Properties: Properties{ReasonString: text}})
+	}
+	s.conn.CloseWithCode(code, text)
+}
+
+// failure returns the SUBACK code of a refused subscription
+func (s *Session) failure(reason byte) byte {
+	if s.version == Version5 {
+		return reason
+	}
+	return ReasonUnspecifiedError
+}
+
+// write queues a packet for the client
+func (s *Session) write(p *Packet) error {
+	s.mu.Lock()
+	p.Version = s.version
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.conn.WriteFrame(ctx, hub.BinaryFrame(p.Marshal()))
+}
+
+// topicOf returns the MQTT topic a hub message is delivered under
+func topicOf(target, connID string) string {
+	kind, value, _ := strings.Cut(target, ":")
+	switch kind {
+	case "topic":
+		return value
+	case "broadcast":
+		return HubPrefix + "broadcast"
+	case "user":
+		return HubPrefix + "users/" + value
+	case "type":
+		return HubPrefix + "types/" + value
+	}
+	return HubPrefix + "connections/" + connID
+}
+
+// validTopicName reports whether clients may publish to a topic. Topics
+// starting with "$" are reserved.
+func validTopicName(topic string) bool {
+	return topic != "" && !strings.HasPrefix(topic, "$") && !strings.ContainsAny(topic, "+#\x00")
+}
+
+// validTopicFilter reports whether a topic filter is well formed: "#" may only
+// be the last level and "+" must be a whole level
+func validTopicFilter(filter string) bool {
+	if filter == "" || strings.ContainsRune(filter, 0) {
+		return false
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return false
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,386 @@
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/identity"
+	"go-notification-sse/internal/interfaces/publish"
+	"go-notification-sse/internal/interfaces/transporttest"
+	"go-notification-sse/internal/interfaces/websocket/wstest"
+)
+
+// testTokens signs the user tokens clients send as their CONNECT password
+var testTokens = identity.NewTokens([]string{"test-secret"})
+
+// newTestServer serves MQTT connections registered with a hub; clients may
+// publish to chat, sensors/# and status/#
+func newTestServer(t *testing.T, opts ...Option) (*hub.Hub, string, *audit.Recorder) {
+	hubInstance, log := transporttest.NewHub(t)
+	auditor := transporttest.NewAuditor(t, log)
+	policy := publish.NewPolicy(hubInstance, auditor, []string{"chat", "sensors/#", "status/#"})
+
+	broker := NewBroker(hubInstance, log, append([]Option{WithPublishPolicy(policy), WithUserTokens(testTokens)}, opts...)...)
+	url := wstest.Serve(t, hubInstance, log, Subprotocol, func() hub.WebSocketProtocol {
+		return broker.NewSession(log)
+	})
+	return hubInstance, url, auditor
+}
+
+// client is a test MQTT client
+type client struct {
+	t       *testing.T
+	conn    *websocket.Conn
+	decoder Decoder
+	packets []*Packet
+}
+
+func dial(t *testing.T, url string, version byte) *client {
+	return &client{t: t, conn: wstest.Dial(t, url, Subprotocol), decoder: Decoder{Version: version}}
+}
+
+func (c *client) send(p *Packet) {
+	c.t.Helper()
+	p.Version = c.decoder.Version
+	if err := c.conn.WriteMessage(websocket.BinaryMessage, p.Marshal()); err != nil {
+		c.t.Fatalf("Write failed: %v", err)
+	}
+}
+
+func (c *client) receive() *Packet {
+	c.t.Helper()
+	for len(c.packets) == 0 {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			c.t.Fatalf("Read failed: %v", err)
+		}
+		packets, err := c.decoder.Decode(data)
+		if err != nil {
+			c.t.Fatalf("Decode failed: %v", err)
+		}
+		c.packets = append(c.packets, packets...)
+	}
+	p := c.packets[0]
+	c.packets = c.packets[1:]
+	return p
+}
+
+// expectClose reads until the connection closes with code
+func (c *client) expectClose(code int) {
+	c.t.Helper()
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, code) {
+				c.t.Errorf("Expected close code %d, got %v", code, err)
+			}
+			return
+		}
+	}
+}
+
+func (c *client) connect(connect *Packet) *Packet {
+	c.t.Helper()
+	connect.Type = Connect
+	c.send(connect)
+	connack := c.receive()
+	if connack.Type != Connack || connack.ReasonCode != ReasonSuccess {
+		c.t.Fatalf("Expected a successful CONNACK, got %+v", connack)
+	}
+	return connack
+}
+
+func TestSession_PublishSubscribe(t *testing.T) {
+	hubInstance, url, auditor := newTestServer(t)
+	deliveries := hubInstance.Events().Subscribe(16, hub.EventMessageDelivered)
+	defer deliveries.Close()
+
+	sub := dial(t, url, Version5)
+	token := testTokens.Issue("alice", time.Now().Add(time.Hour))
+	connack := sub.connect(&Packet{CleanStart: true, Username: "alice", Password: []byte(token)})
+	if props := connack.Properties; props.AssignedClientID == "" || props.MaximumQoS == nil || *props.MaximumQoS != 1 {
+		t.Fatalf("Expected an assigned client identifier and maximum QoS 1, got %+v", props)
+	}
+
+	sub.send(&Packet{Type: Subscribe, PacketID: 1, Subscriptions: []SubscriptionRequest{
+		{Filter: "sensors/+/temp", QoS: 1},
+		{Filter: "$hub/users/alice"},
+		{Filter: "chat", NoLocal: true},
+		{Filter: "$share/group/chat"},
+		{Filter: "bad/#/filter"},
+	}})
+	if suback := sub.receive(); suback.Type != Suback || suback.PacketID != 1 ||
+		!bytes.Equal(suback.ReasonCodes, []byte{1, 0, 0, ReasonSharedNotSupported, ReasonTopicFilterInvalid}) {
+		t.Fatalf("Unexpected SUBACK %+v", suback)
+	}
+
+	// An MQTT 3.1.1 client publishes with QoS 1
+	pub := dial(t, url, Version311)
+	pub.connect(&Packet{CleanStart: true, ClientID: "publisher"})
+	pub.send(&Packet{Type: Publish, QoS: 1, PacketID: 7, Topic: "sensors/kitchen/temp", Payload: []byte(`{"celsius":21}`)})
+	if puback := pub.receive(); puback.Type != Puback || puback.PacketID != 7 {
+		t.Fatalf("Expected PUBACK 7, got %+v", puback)
+	}
+
+	publish := sub.receive()
+	if publish.Type != Publish || publish.Topic != "sensors/kitchen/temp" || publish.QoS != 1 ||
+		string(publish.Payload) != `{"celsius":21}` || publish.Properties.ContentType != "application/json" ||
+		!slices.Contains(publish.Properties.UserProperties, [2]string{"type", "notification"}) {
+		t.Fatalf("Unexpected PUBLISH %+v (%s)", publish, publish.Payload)
+	}
+	sub.send(&Packet{Type: Puback, PacketID: publish.PacketID})
+
+	// The client does not receive its own messages on a no-local subscription
+	sub.send(&Packet{Type: Publish, Topic: "chat", Payload: []byte("mine")})
+	hubInstance.PublishToTopic(context.Background(), "chat", &hub.Message{ID: "theirs", Type: "notification", Data: "theirs"})
+	if publish := sub.receive(); publish.Topic != "chat" || string(publish.Payload) != "theirs" {
+		t.Fatalf("Expected only the other message on chat, got %+v (%s)", publish, publish.Payload)
+	}
+
+	// Topics outside the publish policy are refused
+	sub.send(&Packet{Type: Publish, QoS: 1, PacketID: 2, Topic: "secret", Payload: []byte("x")})
+	if puback := sub.receive(); puback.Type != Puback || puback.PacketID != 2 || puback.ReasonCode != ReasonNotAuthorized {
+		t.Fatalf("Expected PUBACK 0x87, got %+v", puback)
+	}
+	entries := transporttest.AuditEntries(t, auditor, &audit.Filter{Action: audit.ActionPublishToTopic}, 3)
+	outcomes := map[string]audit.Outcome{}
+	for _, entry := range entries {
+		outcomes[entry.Actor+" "+entry.Target] = entry.Outcome
+	}
+	if len(entries) != 3 || outcomes["anonymous topic:sensors/kitchen/temp"] != audit.OutcomeSuccess ||
+		outcomes["user:alice topic:chat"] != audit.OutcomeSuccess || outcomes["user:alice topic:secret"] != audit.OutcomeDenied {
+		t.Errorf("Expected every publish to be audited, got %v", outcomes)
+	}
+
+	// Messages addressed to the user arrive under $hub/
+	hubInstance.SendToUser(context.Background(), "alice", &hub.Message{ID: "direct", Type: "alert", Data: "hi"})
+	if publish := sub.receive(); publish.Topic != "$hub/users/alice" || publish.QoS != 0 || string(publish.Payload) != "hi" {
+		t.Fatalf("Expected the user message, got %+v (%s)", publish, publish.Payload)
+	}
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-deliveries.Events():
+			delivery := event.Payload.(hub.DeliveryEvent)
+			if delivery.Status == hub.DeliveryStatusAcknowledged {
+				if delivery.Target != "topic:sensors/kitchen/temp" {
+					t.Errorf("Unexpected acknowledgement %+v", delivery)
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("Expected the PUBACK to be reported as an acknowledged delivery")
+		}
+	}
+}
+
+func TestSession_RetainedAndWill(t *testing.T) {
+	_, url, _ := newTestServer(t)
+
+	device := dial(t, url, Version311)
+	device.connect(&Packet{CleanStart: true, ClientID: "device", Will: &Will{Topic: "status/device", Payload: []byte("offline"), Retain: true}})
+	device.send(&Packet{Type: Publish, Topic: "status/device", Retain: true, Payload: []byte("online")})
+	device.send(&Packet{Type: Pingreq})
+	if pingresp := device.receive(); pingresp.Type != Pingresp {
+		t.Fatalf("Expected PINGRESP, got %+v", pingresp)
+	}
+
+	monitor := dial(t, url, Version311)
+	monitor.connect(&Packet{CleanStart: true, ClientID: "monitor"})
+	monitor.send(&Packet{Type: Subscribe, PacketID: 1, Subscriptions: []SubscriptionRequest{{Filter: "status/#", QoS: 1}}})
+	if suback := monitor.receive(); suback.Type != Suback {
+		t.Fatalf("Expected SUBACK, got %+v", suback)
+	}
+	if retained := monitor.receive(); !retained.Retain || retained.Topic != "status/device" || string(retained.Payload) != "online" {
+		t.Fatalf("Expected the retained message, got %+v (%s)", retained, retained.Payload)
+	}
+
+	// Dropping the connection without DISCONNECT publishes the will
+	device.conn.Close()
+	will := monitor.receive()
+	if will.Retain || will.Topic != "status/device" || string(will.Payload) != "offline" {
+		t.Fatalf("Expected the will, got %+v (%s)", will, will.Payload)
+	}
+
+	// The will replaced the retained message
+	late := dial(t, url, Version311)
+	late.connect(&Packet{CleanStart: true, ClientID: "late"})
+	late.send(&Packet{Type: Subscribe, PacketID: 1, Subscriptions: []SubscriptionRequest{{Filter: "status/+"}}})
+	late.receive()
+	if retained := late.receive(); !retained.Retain || string(retained.Payload) != "offline" {
+		t.Fatalf("Expected the retained will, got %+v (%s)", retained, retained.Payload)
+	}
+
+	// A normal DISCONNECT discards the will
+	quiet := dial(t, url, Version311)
+	quiet.connect(&Packet{CleanStart: true, ClientID: "quiet", Will: &Will{Topic: "status/quiet", Payload: []byte("gone")}})
+	quiet.send(&Packet{Type: Disconnect})
+	quiet.expectClose(websocket.CloseNormalClosure)
+
+	late.send(&Packet{Type: Pingreq})
+	if p := late.receive(); p.Type != Pingresp {
+		t.Fatalf("Expected no will after DISCONNECT, got %+v (%s)", p, p.Payload)
+	}
+}
+
+func TestSession_TakeOver(t *testing.T) {
+	_, url, _ := newTestServer(t)
+
+	alice := []byte(testTokens.Issue("alice", time.Now().Add(time.Hour)))
+	first := dial(t, url, Version5)
+	first.connect(&Packet{CleanStart: true, ClientID: "phone", Username: "alice", Password: alice})
+
+	for name, packet := range map[string]*Packet{
+		"anonymous":    {Type: Connect, CleanStart: true, ClientID: "phone"},
+		"another user": {Type: Connect, CleanStart: true, ClientID: "phone", Password: []byte(testTokens.Issue("bob", time.Now().Add(time.Hour)))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := dial(t, url, Version5)
+			c.send(packet)
+			if connack := c.receive(); connack.Type != Connack || connack.ReasonCode != ReasonNotAuthorized {
+				t.Fatalf("Expected CONNACK 0x87, got %+v", connack)
+			}
+			c.expectClose(websocket.CloseProtocolError)
+		})
+	}
+
+	second := dial(t, url, Version5)
+	second.connect(&Packet{CleanStart: true, ClientID: "phone", Username: "alice", Password: alice})
+
+	if disconnect := first.receive(); disconnect.Type != Disconnect || disconnect.ReasonCode != ReasonSessionTakenOver {
+		t.Fatalf("Expected DISCONNECT for the taken over session, got %+v", disconnect)
+	}
+	first.expectClose(websocket.CloseNormalClosure)
+}
+
+func TestSession_ProtocolErrors(t *testing.T) {
+	_, url, _ := newTestServer(t, WithConnectWait(100*time.Millisecond))
+
+	t.Run("connect timeout", func(t *testing.T) {
+		c := dial(t, url, Version311)
+		c.expectClose(websocket.ClosePolicyViolation)
+	})
+
+	t.Run("first packet not CONNECT", func(t *testing.T) {
+		c := dial(t, url, Version311)
+		c.send(&Packet{Type: Pingreq})
+		c.expectClose(websocket.CloseProtocolError)
+	})
+
+	t.Run("text frame", func(t *testing.T) {
+		c := dial(t, url, Version311)
+		c.conn.WriteMessage(websocket.TextMessage, []byte("CONNECT"))
+		c.expectClose(websocket.CloseProtocolError)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		c := dial(t, url, 3)
+		c.send(&Packet{Type: Connect, ClientID: "old"})
+		c.decoder.Version = Version311
+		if connack := c.receive(); connack.Type != Connack || connack.ReasonCode != returnUnacceptableVersion {
+			t.Fatalf("Expected CONNACK 0x01, got %+v", connack)
+		}
+		c.expectClose(websocket.CloseProtocolError)
+	})
+
+	t.Run("QoS 2", func(t *testing.T) {
+		c := dial(t, url, Version5)
+		c.connect(&Packet{CleanStart: true})
+		c.send(&Packet{Type: Publish, QoS: 2, PacketID: 1, Topic: "a"})
+		if disconnect := c.receive(); disconnect.Type != Disconnect || disconnect.ReasonCode != ReasonQoSNotSupported {
+			t.Fatalf("Expected DISCONNECT 0x9B, got %+v", disconnect)
+		}
+		c.expectClose(websocket.CloseProtocolError)
+	})
+
+	t.Run("unverified user name", func(t *testing.T) {
+		c := dial(t, url, Version5)
+		c.send(&Packet{Type: Connect, CleanStart: true, Username: "alice", Password: []byte("not-a-token")})
+		if connack := c.receive(); connack.Type != Connack || connack.ReasonCode != ReasonBadUserNameOrPassword {
+			t.Fatalf("Expected CONNACK 0x86, got %+v", connack)
+		}
+		c.expectClose(websocket.CloseProtocolError)
+	})
+
+	t.Run("token of another user", func(t *testing.T) {
+		c := dial(t, url, Version311)
+		token := testTokens.Issue("bob", time.Now().Add(time.Hour))
+		c.send(&Packet{Type: Connect, CleanStart: true, Username: "alice", Password: []byte(token)})
+		if connack := c.receive(); connack.Type != Connack || connack.ReasonCode != returnBadUserNameOrPassword {
+			t.Fatalf("Expected CONNACK 0x04, got %+v", connack)
+		}
+		c.expectClose(websocket.CloseProtocolError)
+	})
+
+	t.Run("will topic not allowed", func(t *testing.T) {
+		c := dial(t, url, Version5)
+		c.send(&Packet{Type: Connect, CleanStart: true, Will: &Will{Topic: "secret", Payload: []byte("bye")}})
+		if connack := c.receive(); connack.Type != Connack || connack.ReasonCode != ReasonNotAuthorized {
+			t.Fatalf("Expected CONNACK 0x87, got %+v", connack)
+		}
+		c.expectClose(websocket.CloseProtocolError)
+	})
+
+	t.Run("reserved topic", func(t *testing.T) {
+		c := dial(t, url, Version311)
+		c.connect(&Packet{CleanStart: true})
+		c.send(&Packet{Type: Publish, Topic: "$hub/broadcast"})
+		c.expectClose(websocket.CloseProtocolError)
+	})
+}
+
+func TestDecoder(t *testing.T) {
+	publish := &Packet{
+		Type: Publish, Version: Version5, QoS: 1, PacketID: 42, Topic: "a/b",
+		Payload:    bytes.Repeat([]byte("x"), 200), // a two byte remaining length
+		Properties: Properties{ContentType: "text/plain", UserProperties: [][2]string{{"k", "v"}}},
+	}
+	subscribe := &Packet{Type: Subscribe, Version: Version5, PacketID: 3, Subscriptions: []SubscriptionRequest{
+		{Filter: "a/#", QoS: 1, NoLocal: true, RetainHandling: 2},
+	}}
+	data := append(publish.Marshal(), subscribe.Marshal()...)
+
+	// Two packets, the second split across messages
+	d := Decoder{Version: Version5}
+	packets, err := d.Decode(data[:len(data)-3])
+	if err != nil || len(packets) != 1 {
+		t.Fatalf("Expected one complete packet, got %v (%v)", packets, err)
+	}
+	if p := packets[0]; p.PacketID != 42 || p.Topic != "a/b" || !bytes.Equal(p.Payload, publish.Payload) ||
+		p.Properties.ContentType != "text/plain" || !slices.Equal(p.Properties.UserProperties, publish.Properties.UserProperties) {
+		t.Errorf("PUBLISH did not round-trip: %+v", p)
+	}
+
+	packets, err = d.Decode(data[len(data)-3:])
+	if err != nil || len(packets) != 1 {
+		t.Fatalf("Expected the split packet, got %v (%v)", packets, err)
+	}
+	if sub := packets[0].Subscriptions; len(sub) != 1 || sub[0] != subscribe.Subscriptions[0] {
+		t.Errorf("SUBSCRIBE did not round-trip: %+v", sub)
+	}
+
+	connect := &Packet{Type: Connect, Version: Version311, ClientID: "c", KeepAlive: 30, Username: "u", Password: []byte("p"),
+		Will: &Will{Topic: "w", Payload: []byte("bye"), QoS: 1, Retain: true}}
+	packets, err = (&Decoder{}).Decode(connect.Marshal())
+	if err != nil || len(packets) != 1 {
+		t.Fatalf("Expected CONNECT, got %v (%v)", packets, err)
+	}
+	if p := packets[0]; p.Version != Version311 || p.ClientID != "c" || p.KeepAlive != 30 || p.Username != "u" ||
+		string(p.Password) != "p" || p.Will == nil || p.Will.Topic != "w" || p.Will.QoS != 1 || !p.Will.Retain {
+		t.Errorf("CONNECT did not round-trip: %+v", p)
+	}
+
+	if _, err := (&Decoder{}).Decode([]byte{Subscribe << 4, 2, 0, 1}); err == nil {
+		t.Error("Expected SUBSCRIBE with invalid flags to be rejected")
+	}
+	if _, err := (&Decoder{}).Decode([]byte{Publish << 4, 0xff, 0xff, 0xff, 0xff}); err == nil {
+		t.Error("Expected a malformed remaining length to be rejected")
+	}
+}
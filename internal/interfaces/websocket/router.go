@@ -2,14 +2,23 @@ package websocket
 
 import (
 	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/identity"
 	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/publish"
 
 	"github.com/gin-gonic/gin"
 )
 
 // InitWebSocketRouter initializes WebSocket routes
-func InitWebSocketRouter(logger logger.Logger, hubInstance *hub.Hub, rg *gin.RouterGroup) {
-	wsHandler := NewWebSocketHandler(hubInstance, logger)
+func InitWebSocketRouter(
+	logger logger.Logger,
+	hubInstance *hub.Hub,
+	publisher *publish.Policy,
+	tokens *identity.Tokens,
+	rg *gin.RouterGroup,
+	connOptions ...hub.WebSocketOption,
+) {
+	wsHandler := NewWebSocketHandler(hubInstance, logger, publisher, tokens, connOptions...)
 
 	// WebSocket connection endpoint
 	wsGroup := rg.Group("/ws")
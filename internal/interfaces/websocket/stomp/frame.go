@@ -0,0 +1,231 @@
+package stomp
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Client commands
+const (
+	CommandConnect     = "CONNECT"
+	CommandStomp       = "STOMP"
+	CommandSend        = "SEND"
+	CommandSubscribe   = "SUBSCRIBE"
+	CommandUnsubscribe = "UNSUBSCRIBE"
+	CommandAck         = "ACK"
+	CommandNack        = "NACK"
+	CommandBegin       = "BEGIN"
+	CommandCommit      = "COMMIT"
+	CommandAbort       = "ABORT"
+	CommandDisconnect  = "DISCONNECT"
+)
+
+// Server commands
+const (
+	CommandConnected = "CONNECTED"
+	CommandMessage   = "MESSAGE"
+	CommandReceipt   = "RECEIPT"
+	CommandError     = "ERROR"
+)
+
+// maxFrameSize bounds the size of a frame buffered from the client
+const maxFrameSize = 1 << 20
+
+// Header is a frame header. Headers keep their order, and when a header is
+// repeated the first occurrence wins, as STOMP 1.2 specifies.
+type Header struct {
+	Key   string
+	Value string
+}
+
+// Frame is a STOMP frame
+type Frame struct {
+	Command string
+	Headers []Header
+	Body    []byte
+}
+
+// NewFrame creates a frame from a command and alternating header keys and values
+func NewFrame(command string, headers ...string) *Frame {
+	f := &Frame{Command: command}
+	for i := 0; i+1 < len(headers); i += 2 {
+		f.Set(headers[i], headers[i+1])
+	}
+	return f
+}
+
+// Get returns the value of a header
+func (f *Frame) Get(key string) string {
+	value, _ := f.Lookup(key)
+	return value
+}
+
+// Lookup returns the value of a header and whether it is present
+func (f *Frame) Lookup(key string) (string, bool) {
+	for _, h := range f.Headers {
+		if h.Key == key {
+			return h.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set replaces a header, or adds it when absent
+func (f *Frame) Set(key, value string) {
+	for i, h := range f.Headers {
+		if h.Key == key {
+			f.Headers[i].Value = value
+			return
+		}
+	}
+	f.Headers = append(f.Headers, Header{Key: key, Value: value})
+}
+
+// Marshal encodes the frame. A content-length header is added when the frame has
+// a body, so bodies may contain NUL octets.
+func (f *Frame) Marshal() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(f.Command)
+	buf.WriteByte('\n')
+
+	// CONNECT and CONNECTED frames do not escape headers, for 1.0 compatibility
+	escape := f.Command != CommandConnect && f.Command != CommandConnected
+	for _, h := range f.Headers {
+		if h.Key == "content-length" {
+			continue
+		}
+		if escape {
+			buf.WriteString(headerEscaper.Replace(h.Key))
+			buf.WriteByte(':')
+			buf.WriteString(headerEscaper.Replace(h.Value))
+		} else {
+			buf.WriteString(h.Key + ":" + h.Value)
+		}
+		buf.WriteByte('\n')
+	}
+	if len(f.Body) > 0 {
+		buf.WriteString("content-length:" + strconv.Itoa(len(f.Body)) + "\n")
+	}
+
+	buf.WriteByte('\n')
+	buf.Write(f.Body)
+	buf.WriteByte(0)
+	return buf.Bytes()
+}
+
+var (
+	headerEscaper   = strings.NewReplacer(`\`, `\\`, "\r", `\r`, "\n", `\n`, ":", `\c`)
+	headerUnescaper = strings.NewReplacer(`\\`, `\`, `\r`, "\r", `\n`, "\n", `\c`, ":")
+)
+
+// Decoder splits the data received from a client into frames. Clients may send
+// several frames in one WebSocket message or split a frame across messages.
+type Decoder struct {
+	buf []byte
+}
+
+// Decode adds data to the buffer and returns the complete frames it holds. A
+// nil frame is a heart-beat (an empty line between frames).
+func (d *Decoder) Decode(data []byte) ([]*Frame, error) {
+	d.buf = append(d.buf, data...)
+
+	var frames []*Frame
+	for len(d.buf) > 0 {
+		// Heart-beats are EOLs between frames
+		if d.buf[0] == '\n' {
+			d.buf = d.buf[1:]
+			frames = append(frames, nil)
+			continue
+		}
+		if bytes.HasPrefix(d.buf, []byte("\r\n")) {
+			d.buf = d.buf[2:]
+			frames = append(frames, nil)
+			continue
+		}
+
+		frame, n, err := parseFrame(d.buf)
+		if err != nil {
+			return frames, err
+		}
+		if frame == nil {
+			if len(d.buf) > maxFrameSize {
+				return frames, fmt.Errorf("frame exceeds %d bytes", maxFrameSize)
+			}
+			break
+		}
+		frames = append(frames, frame)
+		d.buf = d.buf[n:]
+	}
+
+	if len(d.buf) == 0 {
+		d.buf = nil
+	}
+	return frames, nil
+}
+
+// parseFrame parses the frame at the start of data, returning a nil frame when
+// data does not hold a complete frame yet, and the number of bytes consumed
+func parseFrame(data []byte) (*Frame, int, error) {
+	headerEnd := bytes.Index(data, []byte("\n\n"))
+	crlfEnd := bytes.Index(data, []byte("\r\n\r\n"))
+	sepLen := 2
+	if crlfEnd >= 0 && (headerEnd < 0 || crlfEnd < headerEnd) {
+		headerEnd, sepLen = crlfEnd, 4
+	}
+	if headerEnd < 0 {
+		return nil, 0, nil
+	}
+
+	lines := strings.Split(string(data[:headerEnd]), "\n")
+	f := &Frame{Command: strings.TrimSuffix(lines[0], "\r")}
+	if f.Command == "" {
+		return nil, 0, fmt.Errorf("missing command")
+	}
+
+	unescape := f.Command != CommandConnect && f.Command != CommandConnected
+	for _, line := range lines[1:] {
+		line = strings.TrimSuffix(line, "\r")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, 0, fmt.Errorf("malformed header %q", line)
+		}
+		if unescape {
+			key, value = headerUnescaper.Replace(key), headerUnescaper.Replace(value)
+		}
+		if _, exists := f.Lookup(key); !exists {
+			f.Headers = append(f.Headers, Header{Key: key, Value: value})
+		}
+	}
+
+	bodyStart := headerEnd + sepLen
+	var bodyEnd int
+	if length, ok := f.Lookup("content-length"); ok {
+		n, err := strconv.Atoi(length)
+		if err != nil || n < 0 {
+			return nil, 0, fmt.Errorf("invalid content-length %q", length)
+		}
+		if n > maxFrameSize {
+			return nil, 0, fmt.Errorf("frame exceeds %d bytes", maxFrameSize)
+		}
+		bodyEnd = bodyStart + n
+		if len(data) <= bodyEnd {
+			return nil, 0, nil
+		}
+		if data[bodyEnd] != 0 {
+			return nil, 0, fmt.Errorf("frame body is not terminated by NUL")
+		}
+	} else {
+		end := bytes.IndexByte(data[bodyStart:], 0)
+		if end < 0 {
+			return nil, 0, nil
+		}
+		bodyEnd = bodyStart + end
+	}
+
+	if bodyEnd > bodyStart {
+		f.Body = bytes.Clone(data[bodyStart:bodyEnd])
+	}
+	return f, bodyEnd + 1, nil
+}
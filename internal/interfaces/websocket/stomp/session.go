@@ -0,0 +1,625 @@
+// Package stomp implements STOMP 1.2 over WebSocket (the v12.stomp subprotocol),
+// so STOMP clients can use the hub as their broker.
+//
+// Destinations map onto hub routing: /topic/<name> is the hub topic <name>, and
+// /user/queue/messages receives everything else sent to the connection, such as
+// broadcasts and messages addressed to its user. SEND publishes to a topic when
+// the session's publish policy allows it, and is audited. With the client and
+// client-individual ack modes, ACK and NACK frames are reported to the hub's
+// delivery tracking as acknowledged and rejected deliveries.
+package stomp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+	"go-notification-sse/internal/interfaces/publish"
+)
+
+// Subprotocol is the WebSocket subprotocol name negotiated by clients
+const Subprotocol = "v12.stomp"
+
+// Version is the STOMP protocol version spoken
+const Version = "1.2"
+
+// Destinations understood by the session
+const (
+	TopicPrefix = "/topic/"
+	UserQueue   = "/user/queue/messages"
+)
+
+// Ack modes of a subscription
+const (
+	AckAuto             = "auto"
+	AckClient           = "client"
+	AckClientIndividual = "client-individual"
+)
+
+const (
+	// defaultHeartBeat is the heart-beat interval offered to clients
+	defaultHeartBeat = 10 * time.Second
+
+	// defaultMaxPendingAcks bounds the unacknowledged messages tracked per session
+	defaultMaxPendingAcks = 1024
+)
+
+// reservedHeaders are set by the session on MESSAGE frames and never copied
+// between hub message headers and STOMP headers
+var reservedHeaders = []string{
+	"destination", "message-id", "subscription", "ack", "content-type",
+	"content-length", "receipt", "transaction", "type", "cursor",
+}
+
+// Option configures a Session
+type Option func(*Session)
+
+// WithHeartBeat sets the heart-beat interval offered to clients in both
+// directions; zero disables heart-beating
+func WithHeartBeat(d time.Duration) Option {
+	return func(s *Session) {
+		s.heartBeat = d
+	}
+}
+
+// WithMaxPendingAcks bounds the number of unacknowledged messages tracked; the
+// oldest are forgotten beyond it
+func WithMaxPendingAcks(n int) Option {
+	return func(s *Session) {
+		s.maxPendingAcks = n
+	}
+}
+
+// WithPublishPolicy sets the topics SEND may publish to and audits each SEND;
+// without a policy every SEND is refused
+func WithPublishPolicy(policy *publish.Policy) Option {
+	return func(s *Session) {
+		s.publisher = policy
+	}
+}
+
+// subscription is a SUBSCRIBE of the client
+type subscription struct {
+	id          string
+	destination string
+	topic       string // empty for the user queue
+	ack         string
+}
+
+// matches reports whether a message sent to the connection belongs to the subscription
+func (s *subscription) matches(target string) bool {
+	topic, isTopic := strings.CutPrefix(target, "topic:")
+	if s.topic == "" {
+		return !isTopic
+	}
+	return isTopic && topic == s.topic
+}
+
+// pendingAck is a message awaiting ACK or NACK
+type pendingAck struct {
+	id           string
+	subscription string
+	target       string
+	message      *hub.Message
+}
+
+// Session is the STOMP state of one WebSocket connection
+type Session struct {
+	hub            *hub.Hub
+	logger         logger.Logger
+	heartBeat      time.Duration
+	maxPendingAcks int
+	publisher      *publish.Policy
+
+	decoder  Decoder // only used by the connection's read pump
+	lastRead atomic.Int64
+
+	mu            sync.Mutex
+	conn          *hub.WebSocketConnection
+	connected     bool
+	disconnected  bool
+	subscriptions map[string]*subscription
+	topics        *hub.TopicRefs
+	pending       []pendingAck
+	ackSeq        uint64
+	transactions  map[string][]*Frame
+}
+
+// New creates the STOMP session of a connection
+func New(hubInstance *hub.Hub, logger logger.Logger, opts ...Option) *Session {
+	s := &Session{
+		hub:            hubInstance,
+		logger:         logger.WithField("protocol", Subprotocol),
+		heartBeat:      defaultHeartBeat,
+		maxPendingAcks: defaultMaxPendingAcks,
+		subscriptions:  make(map[string]*subscription),
+		transactions:   make(map[string][]*Frame),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Open binds the session to its connection
+func (s *Session) Open(conn *hub.WebSocketConnection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conn = conn
+	s.topics = hub.NewTopicRefs(s.hub, conn.ID())
+	s.lastRead.Store(time.Now().UnixNano())
+}
+
+// HandleFrame decodes and handles the STOMP frames in a WebSocket message
+func (s *Session) HandleFrame(conn *hub.WebSocketConnection, messageType int, data []byte) error {
+	s.lastRead.Store(time.Now().UnixNano())
+
+	frames, err := s.decoder.Decode(data)
+	for _, frame := range frames {
+		if frame == nil {
+			continue // heart-beat
+		}
+		if err := s.handle(frame); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return s.fail(nil, "malformed frame", err.Error())
+	}
+	return nil
+}
+
+// handle handles a client frame
+func (s *Session) handle(frame *Frame) error {
+	s.mu.Lock()
+	connected, disconnected := s.connected, s.disconnected
+	s.mu.Unlock()
+
+	if disconnected {
+		return nil // the connection is closing
+	}
+	if frame.Command == CommandConnect || frame.Command == CommandStomp {
+		if connected {
+			return s.fail(frame, "already connected", "")
+		}
+		return s.connect(frame)
+	}
+	if !connected {
+		return s.fail(frame, "not connected", "Send CONNECT before "+frame.Command)
+	}
+
+	// Frames in a transaction are applied when it commits
+	if tx := frame.Get("transaction"); tx != "" && (frame.Command == CommandSend || frame.Command == CommandAck || frame.Command == CommandNack) {
+		s.mu.Lock()
+		_, exists := s.transactions[tx]
+		if exists {
+			s.transactions[tx] = append(s.transactions[tx], frame)
+		}
+		s.mu.Unlock()
+
+		if !exists {
+			return s.fail(frame, "unknown transaction", "Transaction "+tx+" has not begun")
+		}
+		return s.receipt(frame)
+	}
+
+	var err error
+	switch frame.Command {
+	case CommandSubscribe:
+		err = s.subscribe(frame)
+	case CommandUnsubscribe:
+		err = s.unsubscribe(frame)
+	case CommandSend:
+		err = s.send(frame)
+	case CommandAck, CommandNack:
+		err = s.ack(frame, frame.Command == CommandAck)
+	case CommandBegin, CommandCommit, CommandAbort:
+		err = s.transaction(frame)
+	case CommandDisconnect:
+		s.mu.Lock()
+		s.disconnected = true
+		s.mu.Unlock()
+
+		// The receipt is written before the close frame
+		err = s.receipt(frame)
+		s.conn.CloseWithCode(websocket.CloseNormalClosure, "")
+		return err
+	default:
+		return s.fail(frame, "unknown command", "Unknown command "+frame.Command)
+	}
+	if err != nil {
+		return err
+	}
+	return s.receipt(frame)
+}
+
+// connect negotiates the protocol version and heart-beats
+func (s *Session) connect(frame *Frame) error {
+	if versions, ok := frame.Lookup("accept-version"); ok && !slices.Contains(strings.Split(versions, ","), Version) {
+		s.write(NewFrame(CommandError, "version", Version, "message", "Supported protocol versions are "+Version))
+		s.conn.CloseWithCode(websocket.CloseProtocolError, "unsupported version")
+		return fmt.Errorf("unsupported versions %q", versions)
+	}
+
+	// heart-beat is cx,cy: how often the client sends, and wants to receive
+	var cx, cy int
+	if value := frame.Get("heart-beat"); value != "" {
+		if _, err := fmt.Sscanf(value, "%d,%d", &cx, &cy); err != nil || cx < 0 || cy < 0 {
+			return s.fail(frame, "invalid heart-beat", "Expected heart-beat:<cx>,<cy>, got "+value)
+		}
+	}
+	offered := int(s.heartBeat.Milliseconds())
+	outgoing, incoming := negotiate(offered, cy), negotiate(cx, offered)
+
+	s.mu.Lock()
+	s.connected = true
+	s.mu.Unlock()
+
+	err := s.write(NewFrame(CommandConnected,
+		"version", Version,
+		"heart-beat", fmt.Sprintf("%d,%d", offered, offered),
+		"session", s.conn.ID(),
+		"server", "go-notification-sse",
+	))
+	if outgoing > 0 || incoming > 0 {
+		go s.heartBeats(outgoing, incoming)
+	}
+	return err
+}
+
+// negotiate returns the heart-beat interval of one direction: none when either
+// side does not want it, otherwise the slower of the two
+func negotiate(sender, receiver int) time.Duration {
+	if sender == 0 || receiver == 0 {
+		return 0
+	}
+	return time.Duration(max(sender, receiver)) * time.Millisecond
+}
+
+// heartBeats sends heart-beats and closes the connection when the client stops
+// sending anything for twice the negotiated interval
+func (s *Session) heartBeats(outgoing, incoming time.Duration) {
+	interval := outgoing
+	if interval == 0 || (incoming > 0 && incoming < interval) {
+		interval = incoming
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastSent time.Time
+	for {
+		select {
+		case <-s.conn.Context().Done():
+			return
+		case now := <-ticker.C:
+			if incoming > 0 && now.Sub(time.Unix(0, s.lastRead.Load())) > 2*incoming {
+				s.logger.Warnf("Closing connection after missing heart-beats for %s", 2*incoming)
+				s.conn.CloseWithCode(websocket.CloseGoingAway, "heart-beat timeout")
+				return
+			}
+			if outgoing > 0 && now.Sub(lastSent) >= outgoing {
+				lastSent = now
+				s.writeRaw([]byte("\n"))
+			}
+		}
+	}
+}
+
+// subscribe starts delivering a destination to the client
+func (s *Session) subscribe(frame *Frame) error {
+	id, destination := frame.Get("id"), frame.Get("destination")
+	if id == "" || destination == "" {
+		return s.fail(frame, "invalid SUBSCRIBE", "SUBSCRIBE requires the id and destination headers")
+	}
+
+	sub := &subscription{id: id, destination: destination, ack: frame.Get("ack")}
+	switch sub.ack {
+	case "":
+		sub.ack = AckAuto
+	case AckAuto, AckClient, AckClientIndividual:
+	default:
+		return s.fail(frame, "invalid ack mode", "Unknown ack mode "+sub.ack)
+	}
+
+	topic, ok := destinationTopic(destination)
+	if !ok && destination != UserQueue {
+		return s.fail(frame, "unknown destination", fmt.Sprintf("Subscribe to %s<name> or %s", TopicPrefix, UserQueue))
+	}
+	sub.topic = topic
+
+	s.mu.Lock()
+	_, exists := s.subscriptions[id]
+	if !exists {
+		s.subscriptions[id] = sub
+		if topic != "" {
+			s.topics.Acquire(topic)
+		}
+	}
+	s.mu.Unlock()
+
+	if exists {
+		return s.fail(frame, "duplicate subscription", "Subscription "+id+" already exists")
+	}
+
+	s.logger.Debugf("Subscribed %s to %s (ack %s)", id, destination, sub.ack)
+	return nil
+}
+
+// unsubscribe stops a subscription and forgets its unacknowledged messages
+func (s *Session) unsubscribe(frame *Frame) error {
+	id := frame.Get("id")
+	if id == "" {
+		return s.fail(frame, "invalid UNSUBSCRIBE", "UNSUBSCRIBE requires the id header")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, exists := s.subscriptions[id]
+	if !exists {
+		return nil
+	}
+	delete(s.subscriptions, id)
+	if sub.topic != "" {
+		s.topics.Release(sub.topic)
+	}
+	s.pending = slices.DeleteFunc(s.pending, func(p pendingAck) bool { return p.subscription == id })
+	return nil
+}
+
+// send publishes the frame's body to a topic
+func (s *Session) send(frame *Frame) error {
+	destination := frame.Get("destination")
+	topic, ok := destinationTopic(destination)
+	if !ok {
+		return s.fail(frame, "invalid destination", fmt.Sprintf("SEND requires a %s<name> destination", TopicPrefix))
+	}
+
+	message := &hub.Message{
+		ID:   frame.Get("message-id"),
+		Type: frame.Get("type"),
+		Data: string(frame.Body),
+	}
+	if message.ID == "" {
+		message.ID = generateMessageID()
+	}
+	if message.Type == "" {
+		message.Type = string(hub.MessageTypeNotification)
+	}
+	if contentType := frame.Get("content-type"); contentType == "" || strings.HasPrefix(contentType, "application/json") {
+		var data any
+		if err := json.Unmarshal(frame.Body, &data); err == nil {
+			message.Data = data
+		} else if contentType != "" {
+			return s.fail(frame, "invalid body", err.Error())
+		}
+	}
+	for _, h := range frame.Headers {
+		if !slices.Contains(reservedHeaders, h.Key) {
+			if message.Headers == nil {
+				message.Headers = make(map[string]string)
+			}
+			message.Headers[h.Key] = h.Value
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(s.conn.Context(), 5*time.Second)
+	defer cancel()
+	if _, err := s.publisher.Publish(ctx, s.conn, topic, message); err != nil {
+		if errors.Is(err, publish.ErrTopicNotAllowed) {
+			return s.fail(frame, "forbidden", "Publishing to "+destination+" is not allowed")
+		}
+		return s.fail(frame, "publish failed", err.Error())
+	}
+	return nil
+}
+
+// ack resolves unacknowledged messages; in client mode an ACK or NACK covers
+// every earlier message of the subscription too
+func (s *Session) ack(frame *Frame, accepted bool) error {
+	id := frame.Get("id")
+	if id == "" {
+		return s.fail(frame, "invalid "+frame.Command, frame.Command+" requires the id header")
+	}
+
+	s.mu.Lock()
+	index := slices.IndexFunc(s.pending, func(p pendingAck) bool { return p.id == id })
+	if index < 0 {
+		s.mu.Unlock()
+		s.logger.Debugf("Ignoring %s of unknown message %s", frame.Command, id)
+		return nil
+	}
+
+	acked := s.pending[index]
+	var resolved []pendingAck
+	if sub, exists := s.subscriptions[acked.subscription]; exists && sub.ack == AckClient {
+		s.pending = slices.DeleteFunc(s.pending, func(p pendingAck) bool {
+			if p.subscription == acked.subscription && p.id <= acked.id {
+				resolved = append(resolved, p)
+				return true
+			}
+			return false
+		})
+	} else {
+		resolved = []pendingAck{acked}
+		s.pending = slices.Delete(s.pending, index, index+1)
+	}
+	s.mu.Unlock()
+
+	for _, p := range resolved {
+		s.hub.Acknowledge(p.target, s.conn, p.message, accepted)
+	}
+	return nil
+}
+
+// transaction begins, commits or aborts a transaction
+func (s *Session) transaction(frame *Frame) error {
+	tx := frame.Get("transaction")
+	if tx == "" {
+		return s.fail(frame, "invalid "+frame.Command, frame.Command+" requires the transaction header")
+	}
+
+	s.mu.Lock()
+	frames, exists := s.transactions[tx]
+	switch {
+	case frame.Command == CommandBegin && !exists:
+		s.transactions[tx] = []*Frame{}
+	case frame.Command != CommandBegin && exists:
+		delete(s.transactions, tx)
+	}
+	s.mu.Unlock()
+
+	if exists == (frame.Command == CommandBegin) {
+		return s.fail(frame, "invalid transaction", "Transaction "+tx+" cannot be used with "+frame.Command)
+	}
+	if frame.Command != CommandCommit {
+		return nil
+	}
+
+	for _, f := range frames {
+		var err error
+		if f.Command == CommandSend {
+			err = s.send(f)
+		} else {
+			err = s.ack(f, f.Command == CommandAck)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Encode converts a hub message into a MESSAGE frame per matching subscription
+func (s *Session) Encode(conn *hub.WebSocketConnection, message hub.OutboundMessage) ([]hub.WebSocketFrame, error) {
+	body, err := json.Marshal(message.Message.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var frames []hub.WebSocketFrame
+	for _, sub := range s.subscriptions {
+		if !sub.matches(message.Target) {
+			continue
+		}
+
+		s.ackSeq++
+		messageID := message.Message.ID
+		if messageID == "" {
+			messageID = fmt.Sprintf("%s-%d", conn.ID(), s.ackSeq)
+		}
+
+		frame := NewFrame(CommandMessage,
+			"subscription", sub.id,
+			"message-id", messageID,
+			"destination", sub.destination,
+			"content-type", "application/json",
+			"type", message.Message.Type,
+		)
+		if message.Cursor > 0 {
+			frame.Set("cursor", strconv.FormatUint(message.Cursor, 10))
+		}
+		for key, value := range message.Message.Headers {
+			if !slices.Contains(reservedHeaders, key) {
+				frame.Set(key, value)
+			}
+		}
+		if sub.ack != AckAuto {
+			ackID := fmt.Sprintf("%020d", s.ackSeq) // zero padded so IDs sort in delivery order
+			frame.Set("ack", ackID)
+			s.track(pendingAck{id: ackID, subscription: sub.id, target: message.Target, message: message.Message})
+		}
+		frame.Body = body
+
+		frames = append(frames, hub.TextFrame(frame.Marshal()))
+	}
+	return frames, nil
+}
+
+// track records a message awaiting acknowledgement, forgetting the oldest
+// beyond the limit
+func (s *Session) track(p pendingAck) {
+	if s.maxPendingAcks > 0 && len(s.pending) >= s.maxPendingAcks {
+		s.pending = slices.Delete(s.pending, 0, len(s.pending)-s.maxPendingAcks+1)
+	}
+	s.pending = append(s.pending, p)
+}
+
+// Close releases the session's subscriptions
+func (s *Session) Close(conn *hub.WebSocketConnection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.subscriptions)
+	clear(s.transactions)
+	s.pending = nil
+}
+
+// receipt acknowledges a frame that asked for a receipt
+func (s *Session) receipt(frame *Frame) error {
+	if id := frame.Get("receipt"); id != "" {
+		return s.write(NewFrame(CommandReceipt, "receipt-id", id))
+	}
+	return nil
+}
+
+// fail sends an ERROR frame and closes the connection, as STOMP requires
+func (s *Session) fail(frame *Frame, message, detail string) error {
+	errFrame := NewFrame(CommandError, "message", message)
+	if frame != nil {
+		if id := frame.Get("receipt"); id != "" {
+			errFrame.Set("receipt-id", id)
+		}
+	}
+	if detail != "" {
+		errFrame.Set("content-type", "text/plain")
+		errFrame.Body = []byte(detail)
+	}
+
+	s.write(errFrame)
+	s.conn.CloseWithCode(websocket.CloseProtocolError, message)
+	return fmt.Errorf("%s: %s", message, detail)
+}
+
+// write queues a frame for the client
+func (s *Session) write(frame *Frame) error {
+	return s.writeRaw(frame.Marshal())
+}
+
+func (s *Session) writeRaw(data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.conn.WriteFrame(ctx, hub.TextFrame(data))
+}
+
+// destinationTopic returns the hub topic of a /topic/ destination
+func destinationTopic(destination string) (string, bool) {
+	topic, ok := strings.CutPrefix(destination, TopicPrefix)
+	if !ok || topic == "" {
+		return "", false
+	}
+	return topic, true
+}
+
+// generateMessageID generates a unique message ID
+func generateMessageID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("msg-%x", b)
+}
@@ -0,0 +1,239 @@
+package stomp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"go-notification-sse/internal/infrastructure/audit"
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/interfaces/publish"
+	"go-notification-sse/internal/interfaces/transporttest"
+	"go-notification-sse/internal/interfaces/websocket/wstest"
+)
+
+// newTestServer serves STOMP connections of user alice registered with a hub;
+// clients may publish to the "orders" topic
+func newTestServer(t *testing.T, opts ...Option) (*hub.Hub, string, *audit.Recorder) {
+	hubInstance, log := transporttest.NewHub(t)
+	auditor := transporttest.NewAuditor(t, log)
+	policy := publish.NewPolicy(hubInstance, auditor, []string{"orders"})
+
+	url := wstest.Serve(t, hubInstance, log, Subprotocol, func() hub.WebSocketProtocol {
+		return New(hubInstance, log, append([]Option{WithPublishPolicy(policy)}, opts...)...)
+	}, func(conn *hub.WebSocketConnection) {
+		hubInstance.BindUser(conn.ID(), "alice")
+	})
+	return hubInstance, url, auditor
+}
+
+// client is a test STOMP client
+type client struct {
+	t       *testing.T
+	conn    *websocket.Conn
+	decoder Decoder
+	frames  []*Frame
+}
+
+func dial(t *testing.T, url string) *client {
+	return &client{t: t, conn: wstest.Dial(t, url, Subprotocol)}
+}
+
+func (c *client) send(frame *Frame) {
+	c.t.Helper()
+	if err := c.conn.WriteMessage(websocket.TextMessage, frame.Marshal()); err != nil {
+		c.t.Fatalf("Write failed: %v", err)
+	}
+}
+
+// receive returns the next frame, skipping heart-beats
+func (c *client) receive() *Frame {
+	c.t.Helper()
+	for len(c.frames) == 0 {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			c.t.Fatalf("Read failed: %v", err)
+		}
+		frames, err := c.decoder.Decode(data)
+		if err != nil {
+			c.t.Fatalf("Decode failed: %v", err)
+		}
+		for _, frame := range frames {
+			if frame != nil {
+				c.frames = append(c.frames, frame)
+			}
+		}
+	}
+	frame := c.frames[0]
+	c.frames = c.frames[1:]
+	return frame
+}
+
+func (c *client) connect() {
+	c.t.Helper()
+	c.send(NewFrame(CommandConnect, "accept-version", "1.1,1.2", "host", "localhost", "heart-beat", "0,0"))
+	if frame := c.receive(); frame.Command != CommandConnected || frame.Get("version") != Version {
+		c.t.Fatalf("Expected CONNECTED, got %+v", frame)
+	}
+}
+
+func TestSession_SubscribeSendAndAck(t *testing.T) {
+	hubInstance, url, auditor := newTestServer(t)
+	deliveries := hubInstance.Events().Subscribe(16, hub.EventMessageDelivered)
+	defer deliveries.Close()
+
+	c := dial(t, url)
+	c.connect()
+
+	c.send(NewFrame(CommandSubscribe, "id", "orders", "destination", "/topic/orders", "ack", AckClient))
+	c.send(NewFrame(CommandSubscribe, "id", "inbox", "destination", UserQueue, "receipt", "r-1"))
+	if frame := c.receive(); frame.Command != CommandReceipt || frame.Get("receipt-id") != "r-1" {
+		t.Fatalf("Expected a receipt, got %+v", frame)
+	}
+
+	send := NewFrame(CommandSend, "destination", "/topic/orders", "content-type", "application/json", "type", "alert", "region", "eu")
+	send.Body = []byte(`{"order":1}`)
+	c.send(send)
+
+	message := c.receive()
+	if message.Command != CommandMessage || message.Get("subscription") != "orders" || message.Get("type") != "alert" ||
+		message.Get("region") != "eu" || string(message.Body) != `{"order":1}` || message.Get("ack") == "" {
+		t.Fatalf("Unexpected MESSAGE %+v (%s)", message, message.Body)
+	}
+
+	hubInstance.SendToUser(context.Background(), "alice", &hub.Message{ID: "direct", Type: "notification", Data: "hi"})
+	if frame := c.receive(); frame.Get("subscription") != "inbox" || frame.Get("message-id") != "direct" {
+		t.Fatalf("Expected the user message on the user queue, got %+v", frame)
+	}
+
+	entries := transporttest.AuditEntries(t, auditor, &audit.Filter{Action: audit.ActionPublishToTopic}, 1)
+	if len(entries) != 1 || entries[0].Actor != "user:alice" || entries[0].Target != "topic:orders" ||
+		entries[0].MessageID != message.Get("message-id") || entries[0].Outcome != audit.OutcomeSuccess {
+		t.Errorf("Expected the SEND to be audited, got %+v", entries)
+	}
+
+	c.send(NewFrame(CommandAck, "id", message.Get("ack")))
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-deliveries.Events():
+			delivery := event.Payload.(hub.DeliveryEvent)
+			if delivery.Status == hub.DeliveryStatusAcknowledged {
+				if delivery.MessageID != message.Get("message-id") || delivery.Target != "topic:orders" {
+					t.Errorf("Unexpected acknowledgement %+v", delivery)
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("Expected the ACK to be reported as an acknowledged delivery")
+		}
+	}
+}
+
+func TestSession_Errors(t *testing.T) {
+	_, url, _ := newTestServer(t)
+
+	tests := []struct {
+		name    string
+		connect bool
+		frame   *Frame
+		message string
+	}{
+		{"not connected", false, NewFrame(CommandSubscribe, "id", "1", "destination", "/topic/a"), "not connected"},
+		{"unsupported version", false, NewFrame(CommandConnect, "accept-version", "1.0"), "Supported protocol versions are 1.2"},
+		{"unknown destination", true, NewFrame(CommandSubscribe, "id", "1", "destination", "/queue/a"), "unknown destination"},
+		{"send without topic", true, NewFrame(CommandSend, "destination", "/user/queue/messages"), "invalid destination"},
+		{"unknown transaction", true, NewFrame(CommandSend, "destination", "/topic/a", "transaction", "tx"), "unknown transaction"},
+		{"forbidden topic", true, NewFrame(CommandSend, "destination", "/topic/a"), "forbidden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := dial(t, url)
+			if tt.connect {
+				c.connect()
+			}
+			c.send(tt.frame)
+
+			frame := c.receive()
+			if frame.Command != CommandError || frame.Get("message") != tt.message {
+				t.Fatalf("Expected ERROR %q, got %+v", tt.message, frame)
+			}
+			if _, _, err := c.conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseProtocolError) {
+				t.Errorf("Expected the connection to be closed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSession_DisconnectWithReceipt(t *testing.T) {
+	_, url, _ := newTestServer(t)
+
+	c := dial(t, url)
+	c.connect()
+	c.send(NewFrame(CommandDisconnect, "receipt", "bye"))
+
+	if frame := c.receive(); frame.Command != CommandReceipt || frame.Get("receipt-id") != "bye" {
+		t.Fatalf("Expected the DISCONNECT receipt, got %+v", frame)
+	}
+	if _, _, err := c.conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("Expected a normal close, got %v", err)
+	}
+}
+
+func TestSession_HeartBeats(t *testing.T) {
+	_, url, _ := newTestServer(t, WithHeartBeat(50*time.Millisecond))
+
+	c := dial(t, url)
+	c.send(NewFrame(CommandConnect, "accept-version", "1.2", "heart-beat", "50,50"))
+	if frame := c.receive(); frame.Get("heart-beat") != "50,50" {
+		t.Fatalf("Expected heart-beat 50,50, got %+v", frame)
+	}
+
+	// The server sends heart-beats, then closes the connection when the client sends none
+	var heartBeats int
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if heartBeats == 0 || !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+				t.Errorf("Expected heart-beats then a close, got %d heart-beats and %v", heartBeats, err)
+			}
+			return
+		}
+		if string(data) == "\n" {
+			heartBeats++
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	var d Decoder
+
+	// Two frames and a heart-beat in one message, the second split across messages
+	frames, err := d.Decode([]byte("SEND\ndestination:/topic/a\\cb\ndestination:repeated\n\nhello\x00\nSEND\ncontent-length:3\n\na\x00"))
+	if err != nil || len(frames) != 2 {
+		t.Fatalf("Expected a frame and a heart-beat, got %v (%v)", frames, err)
+	}
+	if frames[0].Get("destination") != "/topic/a:b" || string(frames[0].Body) != "hello" || frames[1] != nil {
+		t.Errorf("Unexpected frames %+v %+v", frames[0], frames[1])
+	}
+
+	frames, err = d.Decode([]byte("b\x00"))
+	if err != nil || len(frames) != 1 || string(frames[0].Body) != "a\x00b" {
+		t.Fatalf("Expected the split frame with a NUL in its body, got %v (%v)", frames, err)
+	}
+
+	frame := NewFrame(CommandMessage, "destination", "/topic/a:b")
+	frame.Body = []byte("x\x00y")
+	frames, err = d.Decode(frame.Marshal())
+	if err != nil || len(frames) != 1 || frames[0].Get("destination") != "/topic/a:b" || string(frames[0].Body) != "x\x00y" {
+		t.Errorf("Expected the frame to round-trip, got %v (%v)", frames, err)
+	}
+
+	if _, err := d.Decode([]byte("SEND\nno-colon\n\n\x00")); err == nil {
+		t.Error("Expected a malformed header to be rejected")
+	}
+}
@@ -0,0 +1,69 @@
+// Package wstest provides the WebSocket fixtures shared by the tests of the
+// WebSocket subprotocols; hub and audit fixtures are in transporttest
+package wstest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"go-notification-sse/internal/infrastructure/hub"
+	"go-notification-sse/internal/infrastructure/logger"
+)
+
+// Serve starts a server upgrading requests that negotiate subprotocol and
+// registering each connection with the hub, running newProtocol on it. The
+// optional onRegister callbacks run once a connection is registered. It returns
+// the ws:// URL of the server.
+func Serve(
+	t *testing.T,
+	hubInstance *hub.Hub,
+	log logger.Logger,
+	subprotocol string,
+	newProtocol func() hub.WebSocketProtocol,
+	onRegister ...func(*hub.WebSocketConnection),
+) string {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{Subprotocols: []string{subprotocol}}
+	var seq atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		id := fmt.Sprintf("ws-test-%d", seq.Add(1))
+		wsConn := hub.NewWebSocketConnection(id, conn, log, hub.WithProtocol(newProtocol()))
+		hubInstance.RegisterConnection(wsConn)
+		for _, fn := range onRegister {
+			fn(wsConn)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// Dial connects to url, failing the test unless subprotocol is negotiated. The
+// connection is closed when the test ends and reads time out after 5 seconds.
+func Dial(t *testing.T, url, subprotocol string) *websocket.Conn {
+	t.Helper()
+
+	dialer := websocket.Dialer{Subprotocols: []string{subprotocol}}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	if conn.Subprotocol() != subprotocol {
+		t.Fatalf("Expected %s to be negotiated, got %q", subprotocol, conn.Subprotocol())
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}